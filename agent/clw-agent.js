@@ -93,6 +93,7 @@ Env:
   AGENT_STATE_DIR          optional (state dir override; for multi-agent/multi-session)
   AGENT_HEARTBEAT_INTERVAL_SEC  default: 15
   AGENT_WORK_POLL_INTERVAL_SEC  default: 5
+  AGENT_CLAIM_WAIT_SEC     default: 0 (long-poll claim; single channel only, keep below the offline threshold)
 `);
 }
 
@@ -943,7 +944,7 @@ async function hookDirectCoordinator(type, detectedPaneId, detectedTarget) {
 
     if (current && current.id) {
       console.log(`[agent] attempting to complete task ${current.id} as agent ${agentId}...`);
-      const result = await completeTask(current.id);
+      const result = await completeTask(current.id, collectTaskResult(process.cwd()));
 
       if (result) {
         console.log(`[agent] Task ${current.id} completed successfully`);
@@ -1102,7 +1103,7 @@ async function handleHookForward(agentdSocket, msg, tmuxTarget, tmuxPaneId) {
     if (hookType === 'completed') {
       const current = await fetchCurrentTaskFromCoordinator();
       if (current && current.id) {
-        const result = await completeTask(current.id);
+        const result = await completeTask(current.id, collectTaskResult(cwd));
         taskId = current.id;
 
         await emitEvent('task.completed', {
@@ -1284,25 +1285,52 @@ function parseFlag(args, name) {
   return String(v);
 }
 
-async function claimTask(channelName, idempotencyKey = '') {
+// waitSec > 0 holds the request open on the coordinator until a task is claimable.
+async function claimTask(channelName, idempotencyKey = '', waitSec = 0) {
   const agentId = getOrCreateAgentId();
   const res = await postJsonResult('/v1/tasks/claim', {
     agent_id: agentId,
     channel: channelName,
     idempotency_key: String(idempotencyKey || '').trim(),
+    ...(waitSec > 0 ? { wait: waitSec } : {}),
   });
 
   if (res.statusCode === 200 && res.body && res.body.task) return res.body.task;
   if (res.statusCode === 404) return null; // no queued tasks
+  if (res.statusCode === 429) return null; // channel/user concurrency limit reached
   throw new Error(`claim failed: ${res.statusCode} ${res.raw}`);
 }
 
-async function completeTask(taskId) {
+// Summarize the working tree for a task result: files touched and diff stats relative to
+// HEAD. Returns {} outside a git repository.
+function collectTaskResult(cwd) {
+  const git = (args) => {
+    const r = spawnSync('git', args, { cwd, encoding: 'utf8', timeout: 5000 });
+    return r.status === 0 ? String(r.stdout || '').trim() : null;
+  };
+  const head = git(['rev-parse', 'HEAD']);
+  if (head === null) return {};
+
+  const files = git(['diff', '--name-only', 'HEAD']);
+  const result = {
+    head_commit: head,
+    branch: git(['rev-parse', '--abbrev-ref', 'HEAD']) || '',
+    diff_stat: git(['diff', '--shortstat', 'HEAD']) || '',
+    files_touched: files ? files.split('\n').filter(Boolean) : [],
+  };
+  const stat = git(['diff', '--stat', 'HEAD']);
+  const artifacts = stat ? [{ name: 'diff_stat', content_type: 'text/plain', content: stat }] : [];
+  return { result, artifacts };
+}
+
+async function completeTask(taskId, report = {}) {
   const agentId = getOrCreateAgentId();
   const res = await postJsonResult('/v1/tasks/complete', {
     task_id: taskId,
     agent_id: agentId,
     idempotency_key: `hook:${taskId}:${Date.now()}`,
+    result: report.result,
+    artifacts: report.artifacts,
   });
 
   if (res.statusCode === 200 && res.body && res.body.task) {
@@ -1354,6 +1382,27 @@ async function claimTaskInput(taskId) {
   throw new Error(`claim input failed: ${res.statusCode} ${res.raw}`);
 }
 
+// Deliver pending inputs for a task to the pane. Control inputs come from the coordinator
+// itself: "cancel" interrupts the running Claude Code turn (Escape).
+async function applyTaskInputs(taskId, target, paneId) {
+  for (;;) {
+    const input = await claimTaskInput(taskId);
+    if (!input) return;
+
+    const kind = String(input.kind || 'text');
+    if (kind === 'control') {
+      if (input.text === 'cancel') {
+        console.log(`[agent] task ${taskId} cancelled, interrupting`);
+        tmuxSendKeys(target, ['Escape'], paneId);
+      }
+    } else if (kind === 'keys') {
+      tmuxSendKeys(target, parseTmuxKeySequence(input.text), paneId);
+    } else {
+      tmuxSend(target, input.text, { paneId, enter: !!input.send_enter });
+    }
+  }
+}
+
 function tmuxHasSession(sessionName) {
   const session = tmuxTargetSession(sessionName);
   if (!session) return false;
@@ -1946,10 +1995,32 @@ async function promptForRunMode() {
   }
 }
 
-function formatTaskForInjection(task) {
+// Results reported by the tasks this one waits on (GET /v1/tasks/{id}/result).
+async function fetchPredecessorResults(taskId) {
+  try {
+    const res = await getJson(`/v1/tasks/${encodeURIComponent(taskId)}/result`);
+    return Array.isArray(res?.predecessors) ? res.predecessors : [];
+  } catch (err) {
+    console.error(`[agent] fetch predecessor results failed for task ${taskId}: ${String(err?.message || err)}`);
+    return [];
+  }
+}
+
+function formatTaskForInjection(task, predecessors = []) {
   const title = String(task?.title || '').trim();
   const desc = String(task?.description || '').trim();
-  const combined = desc ? `[TASK] ${title} — ${desc}` : `[TASK] ${title}`;
+  let combined = desc ? `[TASK] ${title} — ${desc}` : `[TASK] ${title}`;
+
+  // Earlier tasks in the chain: their summary / PR so this task can build on them.
+  const previous = predecessors
+    .map((p) => {
+      const r = p?.result || {};
+      const parts = [r.summary, r.pr_url && `PR ${r.pr_url}`, r.diff_stat].filter(Boolean);
+      return parts.length ? parts.join(', ') : '';
+    })
+    .filter(Boolean);
+  if (previous.length) combined += ` [PREVIOUS RESULTS] ${previous.join(' | ')}`;
+
   return combined.replace(/\s+/g, ' ').trim();
 }
 
@@ -2079,8 +2150,10 @@ async function startWorkLoop(channels, initialTarget) {
   let tmuxSession = tmuxTargetSession(initialTarget);
 
   const pollSec = Math.max(2, parseInt(process.env.AGENT_WORK_POLL_INTERVAL_SEC || '2', 10) || 2);
+  const claimWaitSec = Math.max(0, parseInt(process.env.AGENT_CLAIM_WAIT_SEC || '0', 10) || 0);
   let rrIndex = 0;
   let lastPromptHash = '';
+  let lastTaskId = '';
   let channelPollCounter = 0;
   const channelPollInterval = Math.max(1, Math.ceil(30 / pollSec)); // ~30 seconds
 
@@ -2275,6 +2348,18 @@ async function startWorkLoop(channels, initialTarget) {
     // ============================================
     let inFlight = await fetchCurrentTaskFromCoordinator().catch(() => null);
 
+    // Inputs for the task we were running are still delivered after it stops being current:
+    // a cancel clears current_task_id and leaves a control input behind.
+    const inputTaskId = (inFlight && inFlight.id) || lastTaskId;
+    if (inputTaskId) {
+      try {
+        await applyTaskInputs(inputTaskId, tmuxTarget, tmuxPaneId);
+      } catch (err) {
+        console.error(`[agent] task input failed for task ${inputTaskId}: ${String(err?.message || err)}`);
+      }
+    }
+    lastTaskId = (inFlight && inFlight.id) || '';
+
     if (inFlight && inFlight.id) {
       const taskId = inFlight.id;
       let prompt = null;
@@ -2307,7 +2392,8 @@ async function startWorkLoop(channels, initialTarget) {
       for (let i = 0; i < channels.length; i++) {
         const ch = channels[(rrIndex + i) % channels.length];
         try {
-          const t = await claimTask(ch);
+          // Long-poll only with one channel so round-robin over several channels stays fair.
+          const t = await claimTask(ch, '', channels.length === 1 ? claimWaitSec : 0);
           if (t) {
             task = t;
             claimedFromChannel = ch;
@@ -2341,7 +2427,7 @@ async function startWorkLoop(channels, initialTarget) {
             await emitEvent('mode_switched', { task_id: task.id, target_mode: executionMode }, `mode_switched:${task.id}`, task.id);
           }
 
-          const payload = formatTaskForInjection(task);
+          const payload = formatTaskForInjection(task, await fetchPredecessorResults(task.id));
           tmuxInject(tmuxTarget, payload, tmuxPaneId);
           await emitEvent('task.injected', { task_id: task.id, payload }, `task.injected:${task.id}`, task.id);
         } catch (err) {
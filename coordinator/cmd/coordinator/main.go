@@ -6,11 +6,14 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"clwclw-monitor/coordinator/internal/artifact"
 	"clwclw-monitor/coordinator/internal/config"
 	"clwclw-monitor/coordinator/internal/httpapi"
+	"clwclw-monitor/coordinator/internal/leader"
 	"clwclw-monitor/coordinator/internal/store"
 	"clwclw-monitor/coordinator/internal/store/memory"
 	"clwclw-monitor/coordinator/internal/store/postgres"
@@ -23,6 +26,7 @@ func main() {
 
 	var st store.Store
 	var closer func()
+	var elector leader.Elector
 
 	if cfg.DatabaseURL != "" {
 		pg, err := postgres.NewStore(cfg.DatabaseURL)
@@ -31,9 +35,11 @@ func main() {
 		}
 		st = pg
 		closer = pg.Close
+		elector = postgres.NewLeaderElector(pg, "coordinator:background-jobs", time.Duration(cfg.LeaderCheckSec)*time.Second)
 		log.Printf("using postgres store")
 	} else {
 		st = memory.NewStore()
+		elector = &leader.Always{}
 		log.Printf("using memory store")
 	}
 
@@ -41,17 +47,70 @@ func main() {
 		defer closer()
 	}
 
+	var blobs artifact.Store
+	if cfg.ArtifactDir != "" {
+		fs, err := artifact.NewFS(cfg.ArtifactDir, artifact.Limits{
+			MaxBytes:   cfg.ArtifactMaxBytes,
+			QuotaBytes: cfg.ArtifactQuotaBytes,
+		})
+		if err != nil {
+			log.Fatalf("failed to init artifact store: %v", err)
+		}
+		blobs = fs
+		log.Printf("storing artifacts in %s", cfg.ArtifactDir)
+	}
+
+	var purger eventPurger
 	if cfg.EventRetentionDays > 0 {
-		if purger, ok := st.(interface {
-			PurgeEventsBefore(ctx context.Context, before time.Time) (int, error)
-		}); ok {
-			go runEventRetentionLoop(rootCtx, purger, cfg.EventRetentionDays, cfg.RetentionIntervalHours)
+		if p, ok := st.(eventPurger); ok {
+			purger = p
 		} else {
 			log.Printf("event retention enabled but store does not support purge")
 		}
 	}
 
 	srv := httpapi.NewServer(cfg, st)
+	if blobs != nil {
+		srv.SetArtifactStore(blobs)
+	}
+	srv.SetLeaderElector(elector)
+
+	// Shares bus events with the other replicas when the store supports it (postgres).
+	go srv.RunBusRelay(rootCtx)
+	// Every replica evaluates the notification rules against the changes it makes.
+	go srv.RunNotificationRules(rootCtx)
+	// Webhook deliveries are claimed under a lease, so every replica can send them.
+	go srv.RunWebhooks(rootCtx, time.Duration(cfg.WebhookIntervalSec)*time.Second)
+	// Agent socket sessions live in each replica's memory.
+	go srv.RunAgentSockets(rootCtx)
+	// Blobs live on each replica's own disk, so each replica collects its own.
+	if blobs != nil {
+		go runArtifactGCLoop(rootCtx, st, blobs, cfg)
+	}
+
+	// Background jobs that must not run on several replicas at once run on the leader only.
+	go elector.Run(rootCtx, func(ctx context.Context) {
+		var wg sync.WaitGroup
+		start := func(job func()) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				job()
+			}()
+		}
+		if purger != nil {
+			start(func() { runRetentionLoop(ctx, purger, cfg) })
+		}
+		if cfg.WebhookRetentionDays > 0 {
+			start(func() { runWebhookRetentionLoop(ctx, st, cfg) })
+		}
+		if cfg.TaskLeaseSeconds > 0 {
+			start(func() { runLeaseReaperLoop(ctx, st, srv, cfg.LeaseReaperIntervalSec) })
+		}
+		start(func() { srv.RunAgentWatcher(ctx, time.Duration(cfg.OfflineCheckSec)*time.Second) })
+		start(func() { srv.RunScheduler(ctx, time.Duration(cfg.SchedulerIntervalSec)*time.Second) })
+		wg.Wait()
+	})
 
 	httpServer := &http.Server{
 		Addr:              cfg.ListenAddr(),
@@ -82,25 +141,19 @@ func main() {
 	_ = httpServer.Shutdown(ctxShutdown)
 }
 
-func runEventRetentionLoop(
-	ctx context.Context,
-	purger interface {
-		PurgeEventsBefore(ctx context.Context, before time.Time) (int, error)
-	},
-	retentionDays int,
-	intervalHours int,
-) {
-	retention := time.Duration(retentionDays) * 24 * time.Hour
-	interval := time.Duration(intervalHours) * time.Hour
-	if interval <= 0 {
-		interval = 24 * time.Hour
-	}
+type eventPurger interface {
+	PurgeEventsBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// runRetentionLoop purges the events older than the retention period.
+func runRetentionLoop(ctx context.Context, purger eventPurger, cfg config.Config) {
+	retention := time.Duration(cfg.EventRetentionDays) * 24 * time.Hour
 
 	runOnce := func() {
-		before := time.Now().UTC().Add(-retention)
 		ctxPurge, cancel := context.WithTimeout(ctx, 30*time.Second)
 		defer cancel()
 
+		before := time.Now().UTC().Add(-retention)
 		n, err := purger.PurgeEventsBefore(ctxPurge, before)
 		if err != nil {
 			log.Printf("retention purge failed: %v", err)
@@ -111,6 +164,119 @@ func runEventRetentionLoop(
 		}
 	}
 
+	runEvery(ctx, retentionInterval(cfg), runOnce)
+}
+
+// runWebhookRetentionLoop deletes the finished webhook deliveries older than the webhook
+// retention period. Pending deliveries stay until they succeed or fail.
+func runWebhookRetentionLoop(ctx context.Context, st store.Store, cfg config.Config) {
+	retention := time.Duration(cfg.WebhookRetentionDays) * 24 * time.Hour
+
+	runOnce := func() {
+		ctxPurge, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+
+		before := time.Now().UTC().Add(-retention)
+		n, err := st.PurgeWebhookDeliveriesBefore(ctxPurge, before)
+		if err != nil {
+			log.Printf("webhook delivery purge failed: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("retention purged %d webhook deliveries (< %s)", n, before.Format(time.RFC3339))
+		}
+	}
+
+	runEvery(ctx, retentionInterval(cfg), runOnce)
+}
+
+// runArtifactGCLoop garbage-collects the artifact blobs of this replica that no event or
+// task result references anymore. Blobs whose last event was purged go on the next pass.
+func runArtifactGCLoop(ctx context.Context, st store.Store, blobs artifact.Store, cfg config.Config) {
+	grace := time.Duration(cfg.ArtifactGCGraceHours) * time.Hour
+
+	runOnce := func() {
+		ctxGC, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+
+		eventIDs, err := st.ListEventArtifactIDs(ctxGC)
+		if err != nil {
+			log.Printf("artifact gc failed: %v", err)
+			return
+		}
+		resultIDs, err := st.ListTaskResultArtifactIDs(ctxGC)
+		if err != nil {
+			log.Printf("artifact gc failed: %v", err)
+			return
+		}
+		referenced := make(map[string]struct{}, len(eventIDs)+len(resultIDs))
+		for _, id := range append(eventIDs, resultIDs...) {
+			referenced[id] = struct{}{}
+		}
+		n, freed, err := artifact.Collect(ctxGC, blobs, referenced, time.Now().UTC().Add(-grace))
+		if err != nil {
+			log.Printf("artifact gc failed after %d blobs: %v", n, err)
+			return
+		}
+		if n > 0 {
+			log.Printf("artifact gc removed %d unreferenced blobs (%d bytes)", n, freed)
+		}
+	}
+
+	runEvery(ctx, retentionInterval(cfg), runOnce)
+}
+
+// retentionInterval is how often the retention, webhook delivery and artifact gc passes run.
+func retentionInterval(cfg config.Config) time.Duration {
+	interval := time.Duration(cfg.RetentionIntervalHours) * time.Hour
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	return interval
+}
+
+// runEvery calls runOnce now and then every interval until ctx is done.
+func runEvery(ctx context.Context, interval time.Duration, runOnce func()) {
+	runOnce()
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			runOnce()
+		}
+	}
+}
+
+func runLeaseReaperLoop(ctx context.Context, st store.Store, srv *httpapi.Server, intervalSec int) {
+	interval := time.Duration(intervalSec) * time.Second
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	runOnce := func() {
+		ctxReap, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+
+		tasks, err := st.RequeueExpiredTasks(ctxReap, time.Now().UTC())
+		if err != nil {
+			log.Printf("lease reaper failed: %v", err)
+			return
+		}
+		if len(tasks) == 0 {
+			return
+		}
+
+		for _, t := range tasks {
+			log.Printf("lease expired: requeued task %s (chain=%s)", t.ID, t.ChainID)
+		}
+		srv.PublishLeaseExpired(ctxReap, tasks)
+	}
+
 	runOnce()
 
 	t := time.NewTicker(interval)
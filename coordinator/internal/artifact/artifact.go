@@ -0,0 +1,104 @@
+// Package artifact stores uploaded blobs (terminal traces, logs, diffs) outside the
+// database. Blobs are content-addressed: the ID is the hex SHA-256 of the content, so an
+// upload repeated by an agent or shared between events is stored once.
+package artifact
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("not_found")
+	ErrInvalidID     = errors.New("invalid_artifact_id")
+	ErrTooLarge      = errors.New("artifact_too_large")
+	ErrQuotaExceeded = errors.New("artifact_quota_exceeded")
+)
+
+// Blob is the metadata of one stored artifact. Owners are the users that uploaded it; an
+// owner is charged the blob's size once against their quota, however often they upload it.
+type Blob struct {
+	ID          string    `json:"id"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	Owners      []string  `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	// UploadedAt is the last time anyone uploaded the content; garbage collection keeps
+	// blobs uploaded within the grace period even if no event references them yet.
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// OwnedBy reports whether owner uploaded the blob. The empty owner is the single-tenant
+// (API token) caller.
+func (b Blob) OwnedBy(owner string) bool {
+	return slices.Contains(b.Owners, owner)
+}
+
+// Limits bounds what a Store accepts. Zero means unlimited.
+type Limits struct {
+	MaxBytes   int64 // size of a single blob
+	QuotaBytes int64 // total size of the blobs one owner uploaded
+}
+
+// Store is a blob backend. Implementations must be safe for concurrent use.
+type Store interface {
+	// Put stores the content read from r on behalf of owner and returns its metadata.
+	// It fails with ErrTooLarge or ErrQuotaExceeded without storing anything.
+	Put(ctx context.Context, owner, contentType string, r io.Reader) (Blob, error)
+	Stat(ctx context.Context, id string) (Blob, error)
+	Open(ctx context.Context, id string) (io.ReadCloser, Blob, error)
+	// DeleteIfOlder removes the blob if it was last uploaded before olderThan and reports
+	// whether it did. The check runs under the same lock as Put, so a blob re-uploaded
+	// after a caller listed it is kept.
+	DeleteIfOlder(ctx context.Context, id string, olderThan time.Time) (bool, error)
+	List(ctx context.Context) ([]Blob, error)
+	// Usage is the total size of the blobs owned by owner.
+	Usage(ctx context.Context, owner string) (int64, error)
+}
+
+// ValidID reports whether id looks like a blob ID (64 lowercase hex characters).
+func ValidID(id string) bool {
+	if len(id) != 64 {
+		return false
+	}
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Collect deletes the blobs that are not in referenced and were last uploaded before
+// olderThan. It returns how many blobs were removed and the bytes freed. The upload time
+// is checked again at deletion, since an agent may upload the content again meanwhile.
+func Collect(ctx context.Context, s Store, referenced map[string]struct{}, olderThan time.Time) (int, int64, error) {
+	blobs, err := s.List(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	removed := 0
+	var freed int64
+	for _, b := range blobs {
+		if _, ok := referenced[b.ID]; ok {
+			continue
+		}
+		if !b.UploadedAt.Before(olderThan) {
+			continue
+		}
+		deleted, err := s.DeleteIfOlder(ctx, b.ID, olderThan)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return removed, freed, err
+		}
+		if !deleted {
+			continue
+		}
+		removed++
+		freed += b.Size
+	}
+	return removed, freed, nil
+}
@@ -0,0 +1,271 @@
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FS stores blobs on the local filesystem:
+//
+//	<root>/blobs/ab/abcdef…       content
+//	<root>/blobs/ab/abcdef….json  metadata (Blob with owners)
+//	<root>/tmp/                   uploads in progress
+//
+// Metadata is loaded into memory when the store is opened, so Stat, List and Usage do not
+// touch the disk.
+type FS struct {
+	root   string
+	limits Limits
+
+	mu    sync.Mutex
+	blobs map[string]Blob
+}
+
+// fsMeta is the on-disk form of a Blob; Owners is not part of the API JSON.
+type fsMeta struct {
+	Blob
+	Owners []string `json:"owners"`
+}
+
+// NewFS opens (creating if needed) a filesystem store rooted at root.
+func NewFS(root string, limits Limits) (*FS, error) {
+	s := &FS{root: root, limits: limits, blobs: make(map[string]Blob)}
+	// Uploads interrupted by a restart are never completed.
+	if err := os.RemoveAll(s.tmpDir()); err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{s.blobDir(), s.tmpDir()} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FS) blobDir() string { return filepath.Join(s.root, "blobs") }
+func (s *FS) tmpDir() string  { return filepath.Join(s.root, "tmp") }
+
+func (s *FS) dataPath(id string) string {
+	return filepath.Join(s.blobDir(), id[:2], id)
+}
+
+func (s *FS) metaPath(id string) string {
+	return s.dataPath(id) + ".json"
+}
+
+// load reads every metadata file; metadata whose content is missing is dropped.
+func (s *FS) load() error {
+	return filepath.WalkDir(s.blobDir(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		id := strings.TrimSuffix(filepath.Base(path), ".json")
+		if !ValidID(id) {
+			return nil
+		}
+		if _, err := os.Stat(s.dataPath(id)); err != nil {
+			_ = os.Remove(path)
+			return nil
+		}
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var m fsMeta
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return err
+		}
+		m.Blob.ID = id
+		m.Blob.Owners = m.Owners
+		s.blobs[id] = m.Blob
+		return nil
+	})
+}
+
+// writeMeta persists b's metadata atomically. Must be called with s.mu held.
+func (s *FS) writeMeta(b Blob) error {
+	raw, err := json.Marshal(fsMeta{Blob: b, Owners: b.Owners})
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.tmpDir(), "meta-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(raw); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), s.metaPath(b.ID))
+}
+
+// usage sums the sizes of the blobs owned by owner. Must be called with s.mu held.
+func (s *FS) usage(owner string) int64 {
+	var n int64
+	for _, b := range s.blobs {
+		if b.OwnedBy(owner) {
+			n += b.Size
+		}
+	}
+	return n
+}
+
+func (s *FS) Put(_ context.Context, owner, contentType string, r io.Reader) (Blob, error) {
+	contentType = strings.TrimSpace(contentType)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// Spool to a temp file while hashing, so nothing is visible until the ID is known and
+	// the limits are checked.
+	tmp, err := os.CreateTemp(s.tmpDir(), "upload-*")
+	if err != nil {
+		return Blob{}, err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	src := r
+	if s.limits.MaxBytes > 0 {
+		src = io.LimitReader(r, s.limits.MaxBytes+1)
+	}
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), src)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return Blob{}, err
+	}
+	if s.limits.MaxBytes > 0 && size > s.limits.MaxBytes {
+		return Blob{}, ErrTooLarge
+	}
+	id := hex.EncodeToString(h.Sum(nil))
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, exists := s.blobs[id]
+	owned := exists && b.OwnedBy(owner)
+	if !owned && s.limits.QuotaBytes > 0 && s.usage(owner)+size > s.limits.QuotaBytes {
+		return Blob{}, ErrQuotaExceeded
+	}
+
+	if !exists {
+		if err := os.MkdirAll(filepath.Dir(s.dataPath(id)), 0o755); err != nil {
+			return Blob{}, err
+		}
+		if err := os.Rename(tmpName, s.dataPath(id)); err != nil {
+			return Blob{}, err
+		}
+		b = Blob{ID: id, Size: size, ContentType: contentType, CreatedAt: now}
+	}
+	if !owned {
+		b.Owners = append(append([]string(nil), b.Owners...), owner)
+	}
+	b.UploadedAt = now
+
+	if err := s.writeMeta(b); err != nil {
+		return Blob{}, err
+	}
+	s.blobs[id] = b
+	return b, nil
+}
+
+func (s *FS) Stat(_ context.Context, id string) (Blob, error) {
+	if !ValidID(id) {
+		return Blob{}, ErrInvalidID
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.blobs[id]
+	if !ok {
+		return Blob{}, ErrNotFound
+	}
+	return b, nil
+}
+
+func (s *FS) Open(ctx context.Context, id string) (io.ReadCloser, Blob, error) {
+	b, err := s.Stat(ctx, id)
+	if err != nil {
+		return nil, Blob{}, err
+	}
+	f, err := os.Open(s.dataPath(id))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, Blob{}, ErrNotFound
+		}
+		return nil, Blob{}, err
+	}
+	return f, b, nil
+}
+
+func (s *FS) DeleteIfOlder(_ context.Context, id string, olderThan time.Time) (bool, error) {
+	if !ValidID(id) {
+		return false, ErrInvalidID
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.blobs[id]
+	if !ok {
+		return false, ErrNotFound
+	}
+	if !b.UploadedAt.Before(olderThan) {
+		return false, nil
+	}
+	// Content first: metadata left behind by a crash is dropped on the next load.
+	if err := os.Remove(s.dataPath(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	if err := os.Remove(s.metaPath(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	delete(s.blobs, id)
+	return true, nil
+}
+
+func (s *FS) List(_ context.Context) ([]Blob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Blob, 0, len(s.blobs))
+	for _, b := range s.blobs {
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func (s *FS) Usage(_ context.Context, owner string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage(owner), nil
+}
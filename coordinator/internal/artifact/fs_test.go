@@ -0,0 +1,155 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFSPutDedupAndReload(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	s, err := NewFS(root, Limits{})
+	if err != nil {
+		t.Fatalf("new fs: %v", err)
+	}
+
+	first, err := s.Put(ctx, "alice", "text/plain", strings.NewReader("trace line\n"))
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if !ValidID(first.ID) || first.Size != 11 || first.ContentType != "text/plain" {
+		t.Fatalf("unexpected blob: %+v", first)
+	}
+
+	// The same content from another user is stored once, with both owners.
+	second, err := s.Put(ctx, "bob", "", strings.NewReader("trace line\n"))
+	if err != nil {
+		t.Fatalf("put again: %v", err)
+	}
+	if second.ID != first.ID || !second.OwnedBy("alice") || !second.OwnedBy("bob") || second.ContentType != "text/plain" {
+		t.Fatalf("expected a shared blob, got %+v", second)
+	}
+	if blobs, _ := s.List(ctx); len(blobs) != 1 {
+		t.Fatalf("expected one blob, got %d", len(blobs))
+	}
+
+	// Reopening reads the metadata back from disk.
+	reopened, err := NewFS(root, Limits{})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	rc, b, err := reopened.Open(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer rc.Close()
+	content, _ := io.ReadAll(rc)
+	if string(content) != "trace line\n" || !b.OwnedBy("bob") {
+		t.Fatalf("unexpected reopened blob %+v with content %q", b, content)
+	}
+
+	if _, err := reopened.Stat(ctx, "../../etc/passwd"); !errors.Is(err, ErrInvalidID) {
+		t.Fatalf("expected invalid id, got %v", err)
+	}
+	if _, err := reopened.Stat(ctx, strings.Repeat("0", 64)); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestFSLimits(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFS(t.TempDir(), Limits{MaxBytes: 8, QuotaBytes: 12})
+	if err != nil {
+		t.Fatalf("new fs: %v", err)
+	}
+
+	if _, err := s.Put(ctx, "alice", "", strings.NewReader("123456789")); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected too large, got %v", err)
+	}
+	if _, err := s.Put(ctx, "alice", "", strings.NewReader("12345678")); err != nil {
+		t.Fatalf("put within limit: %v", err)
+	}
+	// Uploading content the owner already has is not charged again.
+	if _, err := s.Put(ctx, "alice", "", strings.NewReader("12345678")); err != nil {
+		t.Fatalf("re-upload: %v", err)
+	}
+	if _, err := s.Put(ctx, "alice", "", strings.NewReader("abcde")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected quota exceeded, got %v", err)
+	}
+	// Quotas are per owner.
+	if _, err := s.Put(ctx, "bob", "", strings.NewReader("abcde")); err != nil {
+		t.Fatalf("put for another owner: %v", err)
+	}
+	if n, _ := s.Usage(ctx, "alice"); n != 8 {
+		t.Fatalf("expected alice to use 8 bytes, got %d", n)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFS(t.TempDir(), Limits{})
+	if err != nil {
+		t.Fatalf("new fs: %v", err)
+	}
+	kept, _ := s.Put(ctx, "alice", "", strings.NewReader("referenced"))
+	dropped, _ := s.Put(ctx, "alice", "", strings.NewReader("orphan"))
+
+	// Nothing is old enough yet.
+	if n, _, err := Collect(ctx, s, nil, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("expected the grace period to keep new blobs, got %d (%v)", n, err)
+	}
+
+	n, freed, err := Collect(ctx, s, map[string]struct{}{kept.ID: {}}, time.Now().Add(time.Second))
+	if err != nil || n != 1 || freed != dropped.Size {
+		t.Fatalf("expected the orphan to be collected, got %d blobs, %d bytes (%v)", n, freed, err)
+	}
+	if _, err := s.Stat(ctx, dropped.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected the orphan to be gone, got %v", err)
+	}
+	if _, err := s.Stat(ctx, kept.ID); err != nil {
+		t.Fatalf("expected the referenced blob to be kept, got %v", err)
+	}
+}
+
+// reuploadOnList uploads content again right after listing, like an agent racing the
+// collector between its List and its delete.
+type reuploadOnList struct {
+	*FS
+	content string
+}
+
+func (s reuploadOnList) List(ctx context.Context) ([]Blob, error) {
+	blobs, err := s.FS.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.FS.Put(ctx, "bob", "", strings.NewReader(s.content)); err != nil {
+		return nil, err
+	}
+	return blobs, nil
+}
+
+func TestCollectKeepsReuploadedBlob(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFS(t.TempDir(), Limits{})
+	if err != nil {
+		t.Fatalf("new fs: %v", err)
+	}
+	b, _ := fs.Put(ctx, "alice", "", strings.NewReader("trace"))
+	time.Sleep(5 * time.Millisecond)
+	olderThan := time.Now()
+	time.Sleep(5 * time.Millisecond)
+
+	// The listed upload time is old enough, but the content was uploaded again since.
+	n, _, err := Collect(ctx, reuploadOnList{FS: fs, content: "trace"}, nil, olderThan)
+	if err != nil || n != 0 {
+		t.Fatalf("expected the re-uploaded blob to be kept, got %d (%v)", n, err)
+	}
+	if _, err := fs.Stat(ctx, b.ID); err != nil {
+		t.Fatalf("expected the blob to still exist, got %v", err)
+	}
+}
@@ -3,6 +3,15 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
+)
+
+// Offline policies: what happens to an agent's in-flight work when it goes offline.
+const (
+	OfflinePolicyKeep    = "keep"    // leave the task and chain ownership untouched
+	OfflinePolicyRequeue = "requeue" // requeue the current task and release chain ownership
+	OfflinePolicyDetach  = "detach"  // detach the agent from its chains and requeue their tasks it was running
 )
 
 type Config struct {
@@ -12,6 +21,24 @@ type Config struct {
 	DatabaseURL            string
 	EventRetentionDays     int
 	RetentionIntervalHours int
+	TaskLeaseSeconds       int
+	LeaseReaperIntervalSec int
+	AgentOfflineAfterSec   int
+	OfflineCheckSec        int
+	OfflinePolicy          string
+	PriorityAgingSec       int
+	UserMaxInFlight        int
+	SchedulerIntervalSec   int
+	LeaderCheckSec         int
+	ArtifactDir            string
+	ArtifactMaxBytes       int64
+	ArtifactQuotaBytes     int64
+	ArtifactGCGraceHours   int
+	WebhookIntervalSec     int
+	WebhookMaxAttempts     int
+	WebhookAllowPrivate    bool
+	WebhookRetentionDays   int
+	AllowedOrigins         []string // extra browser origins allowed to open websockets
 }
 
 func Load() Config {
@@ -22,6 +49,22 @@ func Load() Config {
 		DatabaseURL:            os.Getenv("COORDINATOR_DATABASE_URL"),
 		EventRetentionDays:     30,
 		RetentionIntervalHours: 24,
+		TaskLeaseSeconds:       300,
+		LeaseReaperIntervalSec: 15,
+		AgentOfflineAfterSec:   30,
+		OfflineCheckSec:        10,
+		OfflinePolicy:          OfflinePolicyKeep,
+		PriorityAgingSec:       0,
+		UserMaxInFlight:        0,
+		SchedulerIntervalSec:   30,
+		LeaderCheckSec:         5,
+		ArtifactDir:            strings.TrimSpace(os.Getenv("COORDINATOR_ARTIFACT_DIR")),
+		ArtifactMaxBytes:       64 << 20,
+		ArtifactQuotaBytes:     1 << 30,
+		ArtifactGCGraceHours:   24,
+		WebhookIntervalSec:     5,
+		WebhookMaxAttempts:     8,
+		WebhookRetentionDays:   30,
 	}
 
 	if cfg.DatabaseURL == "" {
@@ -46,9 +89,120 @@ func Load() Config {
 		}
 	}
 
+	if v := os.Getenv("COORDINATOR_TASK_LEASE_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.TaskLeaseSeconds = n
+		}
+	}
+
+	if v := os.Getenv("COORDINATOR_LEASE_REAPER_INTERVAL_SEC"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.LeaseReaperIntervalSec = n
+		}
+	}
+
+	if v := os.Getenv("COORDINATOR_AGENT_OFFLINE_AFTER_SEC"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.AgentOfflineAfterSec = n
+		}
+	}
+
+	if v := os.Getenv("COORDINATOR_OFFLINE_CHECK_SEC"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.OfflineCheckSec = n
+		}
+	}
+
+	if v := strings.ToLower(strings.TrimSpace(os.Getenv("COORDINATOR_OFFLINE_POLICY"))); v != "" {
+		switch v {
+		case OfflinePolicyKeep, OfflinePolicyRequeue, OfflinePolicyDetach:
+			cfg.OfflinePolicy = v
+		}
+	}
+
+	if v := os.Getenv("COORDINATOR_PRIORITY_AGING_SEC"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.PriorityAgingSec = n
+		}
+	}
+
+	if v := os.Getenv("COORDINATOR_USER_MAX_IN_FLIGHT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.UserMaxInFlight = n
+		}
+	}
+
+	if v := os.Getenv("COORDINATOR_SCHEDULER_INTERVAL_SEC"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.SchedulerIntervalSec = n
+		}
+	}
+
+	if v := os.Getenv("COORDINATOR_LEADER_CHECK_SEC"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.LeaderCheckSec = n
+		}
+	}
+
+	if v := os.Getenv("COORDINATOR_ARTIFACT_MAX_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			cfg.ArtifactMaxBytes = n
+		}
+	}
+
+	if v := os.Getenv("COORDINATOR_ARTIFACT_QUOTA_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			cfg.ArtifactQuotaBytes = n
+		}
+	}
+
+	if v := os.Getenv("COORDINATOR_ARTIFACT_GC_GRACE_HOURS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.ArtifactGCGraceHours = n
+		}
+	}
+
+	if v := os.Getenv("COORDINATOR_WEBHOOK_INTERVAL_SEC"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.WebhookIntervalSec = n
+		}
+	}
+
+	if v := os.Getenv("COORDINATOR_WEBHOOK_MAX_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.WebhookMaxAttempts = n
+		}
+	}
+
+	if v := os.Getenv("COORDINATOR_WEBHOOK_ALLOW_PRIVATE"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			cfg.WebhookAllowPrivate = b
+		}
+	}
+
+	if v := os.Getenv("COORDINATOR_WEBHOOK_RETENTION_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.WebhookRetentionDays = n
+		}
+	}
+
+	for _, origin := range strings.Split(os.Getenv("COORDINATOR_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			cfg.AllowedOrigins = append(cfg.AllowedOrigins, origin)
+		}
+	}
+
 	return cfg
 }
 
 func (c Config) ListenAddr() string {
 	return ":" + strconv.Itoa(c.Port)
 }
+
+// AgentOfflineThreshold is how long after its last heartbeat an agent is considered offline.
+func (c Config) AgentOfflineThreshold() time.Duration {
+	if c.AgentOfflineAfterSec <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.AgentOfflineAfterSec) * time.Second
+}
@@ -0,0 +1,567 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+	"clwclw-monitor/coordinator/internal/websocket"
+)
+
+const (
+	// agentOutboxLimit caps the unacked messages kept per agent; the oldest are dropped first.
+	agentOutboxLimit = 1000
+	// agentSocketPing is how often the coordinator pings an idle agent socket.
+	agentSocketPing = 30 * time.Second
+	// agentSocketIdle drops a connection that sent nothing, not even a pong, for this long.
+	agentSocketIdle = 90 * time.Second
+	// agentSocketWriteTimeout bounds a single write to a slow agent.
+	agentSocketWriteTimeout = 10 * time.Second
+	// agentSocketQueue is how many messages may wait for a connection's writer: a full
+	// outbox replay plus the hello and replies. An agent that falls further behind is dropped
+	// and catches up from its cursor when it reconnects.
+	agentSocketQueue = agentOutboxLimit + 64
+	// agentSessionTTL is how long a session and its outbox outlive the agent's last
+	// connection. An agent that reconnects later starts over with an empty outbox.
+	agentSessionTTL = time.Hour
+	// agentSessionSweep is how often expired sessions are removed.
+	agentSessionSweep = time.Minute
+)
+
+// Downstream message types pushed to agents.
+const (
+	AgentMsgHello         = "hello"
+	AgentMsgReply         = "reply"
+	AgentMsgTaskAssigned  = "task_assigned"
+	AgentMsgTaskInput     = "task_input"
+	AgentMsgControl       = "control"
+	AgentMsgChainDetached = "chain_detached"
+)
+
+// agentSocketRoutes maps upstream message types to the REST endpoint that handles them, so
+// the socket and the REST API share one implementation.
+var agentSocketRoutes = map[string]string{
+	"heartbeat": "/v1/agents/heartbeat",
+	"event":     "/v1/events",
+	"complete":  "/v1/tasks/complete",
+	"fail":      "/v1/tasks/fail",
+}
+
+// agentMessage is one JSON message on the agent socket. Downstream messages that must reach
+// the agent carry a seq and stay in its outbox until acked; hello and replies do not.
+// Upstream messages may set id, which the reply echoes as ref.
+type agentMessage struct {
+	Type    string          `json:"type"`
+	Seq     int64           `json:"seq,omitempty"`
+	ID      string          `json:"id,omitempty"`
+	Ref     string          `json:"ref,omitempty"`
+	Status  int             `json:"status,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// agentHub tracks the agents that opened a socket. Sessions outlive connections so that
+// messages pushed while an agent reconnects are replayed from its cursor.
+type agentHub struct {
+	mu       sync.Mutex
+	sessions map[string]*agentSession
+}
+
+type agentSession struct {
+	mu         sync.Mutex
+	conn       *agentConn // nil while the agent is disconnected
+	detachedAt time.Time  // when the last connection went away; zero while connected
+	lastSeq    int64
+	outbox     []agentMessage // unacked messages, by seq
+}
+
+// agentConn is one open socket of an agent. Its writer goroutine does the network writes, so
+// senders only queue under sess.mu and never wait on a slow agent.
+type agentConn struct {
+	ws   *websocket.Conn
+	out  chan agentMessage
+	done chan struct{} // closed once the session lets go of the connection
+}
+
+func newAgentConn(ws *websocket.Conn) *agentConn {
+	c := &agentConn{ws: ws, out: make(chan agentMessage, agentSocketQueue), done: make(chan struct{})}
+	go c.writeLoop()
+	return c
+}
+
+// writeLoop writes queued messages until the session lets go of the connection. A failed
+// write closes it; the read loop then ends and detaches it.
+func (c *agentConn) writeLoop() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case m := <-c.out:
+			raw, err := json.Marshal(m)
+			if err != nil {
+				continue
+			}
+			_ = c.ws.SetWriteDeadline(time.Now().Add(agentSocketWriteTimeout))
+			if err := c.ws.WriteMessage(raw); err != nil {
+				c.ws.Close()
+				return
+			}
+		}
+	}
+}
+
+func newAgentHub() *agentHub {
+	return &agentHub{sessions: make(map[string]*agentSession)}
+}
+
+func (h *agentHub) session(agentID string) *agentSession {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sess := h.sessions[agentID]
+	if sess == nil {
+		sess = &agentSession{}
+		h.sessions[agentID] = sess
+	}
+	// The caller is about to attach a connection: keep expire from removing the session first.
+	sess.mu.Lock()
+	sess.detachedAt = time.Time{}
+	sess.mu.Unlock()
+	return sess
+}
+
+// expire removes the sessions whose agent has been disconnected for longer than ttl, with
+// their outboxes. It returns how many it removed.
+func (h *agentHub) expire(now time.Time, ttl time.Duration) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for agentID, sess := range h.sessions {
+		sess.mu.Lock()
+		expired := sess.conn == nil && !sess.detachedAt.IsZero() && now.Sub(sess.detachedAt) > ttl
+		sess.mu.Unlock()
+		if expired {
+			delete(h.sessions, agentID)
+			n++
+		}
+	}
+	return n
+}
+
+// connected reports whether agentID has a socket open right now.
+func (h *agentHub) connected(agentID string) bool {
+	h.mu.Lock()
+	sess := h.sessions[agentID]
+	h.mu.Unlock()
+	if sess == nil {
+		return false
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.conn != nil
+}
+
+// send queues a message for agentID and writes it at once if the agent is connected. Agents
+// that never opened a socket are skipped: they keep using the REST endpoints.
+func (h *agentHub) send(agentID, typ string, payload any) {
+	h.mu.Lock()
+	sess := h.sessions[agentID]
+	h.mu.Unlock()
+	if sess == nil {
+		return
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("agent socket: encode %s for agent %s failed: %v", typ, agentID, err)
+		return
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.lastSeq++
+	m := agentMessage{Type: typ, Seq: sess.lastSeq, Payload: raw}
+	sess.outbox = append(sess.outbox, m)
+	if over := len(sess.outbox) - agentOutboxLimit; over > 0 {
+		sess.outbox = append([]agentMessage(nil), sess.outbox[over:]...)
+	}
+	sess.queueLocked(m)
+}
+
+// attach makes conn the agent's connection, drops what the agent acked through cursor and
+// replays the rest. It returns the connection it replaced, if any.
+func (sess *agentSession) attach(conn *websocket.Conn, agentID string, cursor int64) *websocket.Conn {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	var prev *websocket.Conn
+	if sess.conn != nil {
+		prev = sess.conn.ws
+		close(sess.conn.done)
+	}
+	sess.conn = newAgentConn(conn)
+	if cursor > sess.lastSeq {
+		// The agent saw more than we remember (e.g. after a restart): keep seq monotonic.
+		sess.lastSeq = cursor
+	}
+	sess.ackLocked(cursor)
+
+	hello, _ := json.Marshal(map[string]any{
+		"agent_id": agentID,
+		"last_seq": sess.lastSeq,
+		"replayed": len(sess.outbox),
+	})
+	sess.queueLocked(agentMessage{Type: AgentMsgHello, Payload: hello})
+	for _, m := range sess.outbox {
+		sess.queueLocked(m)
+	}
+	return prev
+}
+
+// detach forgets conn unless a newer connection already replaced it.
+func (sess *agentSession) detach(conn *websocket.Conn) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.conn != nil && sess.conn.ws == conn {
+		sess.dropLocked()
+	}
+}
+
+func (sess *agentSession) ack(seq int64) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.ackLocked(seq)
+}
+
+// Must be called with sess.mu held.
+func (sess *agentSession) ackLocked(seq int64) {
+	i := 0
+	for i < len(sess.outbox) && sess.outbox[i].Seq <= seq {
+		i++
+	}
+	sess.outbox = sess.outbox[i:]
+}
+
+func (sess *agentSession) reply(conn *websocket.Conn, m agentMessage) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.conn != nil && sess.conn.ws == conn {
+		sess.queueLocked(m)
+	}
+}
+
+// queueLocked hands m to the current connection's writer, if any. If the writer is too far
+// behind, the connection is closed; the message stays in the outbox for the next one.
+// Must be called with sess.mu held.
+func (sess *agentSession) queueLocked(m agentMessage) {
+	if sess.conn == nil {
+		return
+	}
+	select {
+	case sess.conn.out <- m:
+	default:
+		sess.conn.ws.Close()
+		sess.dropLocked()
+	}
+}
+
+// dropLocked stops the current connection's writer and forgets the connection.
+// Must be called with sess.mu held.
+func (sess *agentSession) dropLocked() {
+	close(sess.conn.done)
+	sess.conn = nil
+	sess.detachedAt = time.Now()
+}
+
+// RunAgentSockets removes agent socket sessions that have been disconnected for longer than
+// agentSessionTTL. Sessions live in each replica's memory, so every replica runs it.
+func (s *Server) RunAgentSockets(ctx context.Context) {
+	t := time.NewTicker(agentSessionSweep)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			if n := s.agents.expire(now, agentSessionTTL); n > 0 {
+				log.Printf("agent socket: removed %d expired sessions", n)
+			}
+		}
+	}
+}
+
+// handleAgentSocket serves GET /v1/agents/socket?agent_id=&cursor=: one websocket per agent
+// carrying heartbeats, events and task results upstream and task assignments, inputs,
+// control messages and chain detach notices downstream. cursor is the last seq the agent
+// processed; unacked messages after it are replayed.
+func (s *Server) handleAgentSocket(w http.ResponseWriter, r *http.Request) {
+	agentID := strings.TrimSpace(r.URL.Query().Get("agent_id"))
+	if agentID == "" {
+		writeError(w, http.StatusBadRequest, "agent_id_required", "agent_id is required")
+		return
+	}
+	var cursor int64
+	if v := strings.TrimSpace(r.URL.Query().Get("cursor")); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "cursor_invalid", "cursor must be a non-negative integer")
+			return
+		}
+		cursor = n
+	}
+
+	userID := userIDFromContext(r.Context())
+	// The agent must have registered with POST /v1/agents/heartbeat before it opens a socket.
+	agent, err := s.store.GetAgent(r.Context(), agentID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			writeError(w, http.StatusNotFound, "not_found", "agent not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "internal", "failed to get agent")
+		return
+	}
+	if agent.UserID != userID {
+		writeError(w, http.StatusNotFound, "not_found", "agent not found")
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r, s.cfg.AllowedOrigins)
+	if err != nil {
+		return
+	}
+	s.serveAgentSocket(r.Context(), conn, userID, agentID, cursor)
+}
+
+func (s *Server) serveAgentSocket(ctx context.Context, conn *websocket.Conn, userID, agentID string, cursor int64) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sess := s.agents.session(agentID)
+	if prev := sess.attach(conn, agentID, cursor); prev != nil {
+		_ = prev.WriteClose(websocket.CloseGoingAway, "replaced by a new connection")
+		prev.Close()
+	}
+	defer func() {
+		sess.detach(conn)
+		conn.Close()
+	}()
+
+	// Inputs queued while the agent was away are delivered now.
+	if agent, err := s.store.GetAgent(ctx, agentID); err == nil {
+		s.pushTaskInputs(ctx, agent.CurrentTaskID, agentID)
+	}
+
+	go func() {
+		t := time.NewTicker(agentSocketPing)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if err := conn.Ping(); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	var dispatching chan struct{}
+	conn.SetIdleTimeout(agentSocketIdle)
+	for {
+		raw, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var m agentMessage
+		if err := json.Unmarshal(raw, &m); err != nil {
+			sess.reply(conn, agentErrorReply("", http.StatusBadRequest, "bad_json", "invalid json"))
+			continue
+		}
+
+		switch {
+		case m.Type == "ack":
+			sess.ack(m.Seq)
+		case m.Type == "ready":
+			if dispatching != nil {
+				select {
+				case <-dispatching:
+				default:
+					// Already waiting for a task; a second ready changes nothing.
+					sess.reply(conn, agentMessage{Type: AgentMsgReply, Ref: m.ID, Status: http.StatusAccepted})
+					continue
+				}
+			}
+			reqs, err := s.agentClaimRequests(ctx, agentID, m.Payload)
+			if err != nil {
+				sess.reply(conn, agentErrorReply(m.ID, http.StatusBadRequest, "channels_required", err.Error()))
+				continue
+			}
+			dispatching = make(chan struct{})
+			go s.dispatchToAgent(ctx, sess, conn, userID, agentID, m.ID, reqs, dispatching)
+			sess.reply(conn, agentMessage{Type: AgentMsgReply, Ref: m.ID, Status: http.StatusAccepted})
+		case agentSocketRoutes[m.Type] != "":
+			sess.reply(conn, s.forwardAgentMessage(ctx, agentID, m))
+		default:
+			sess.reply(conn, agentErrorReply(m.ID, http.StatusBadRequest, "unknown_type", "unknown message type "+strconv.Quote(m.Type)))
+		}
+	}
+}
+
+// agentClaimRequests builds one claim per channel the agent wants work from: the
+// "channels" of a ready message, or else the agent's subscriptions.
+func (s *Server) agentClaimRequests(ctx context.Context, agentID string, payload json.RawMessage) ([]store.ClaimTaskRequest, error) {
+	var ready struct {
+		Channels []string `json:"channels"`
+	}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &ready); err != nil {
+			return nil, err
+		}
+	}
+	channels := ready.Channels
+	if len(channels) == 0 {
+		if agent, err := s.store.GetAgent(ctx, agentID); err == nil {
+			subs, _ := agent.Meta["subscriptions"].([]any)
+			for _, sub := range subs {
+				if name, ok := sub.(string); ok {
+					channels = append(channels, name)
+				}
+			}
+		}
+	}
+
+	var reqs []store.ClaimTaskRequest
+	for _, name := range channels {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		reqs = append(reqs, store.ClaimTaskRequest{
+			AgentID:              agentID,
+			Channel:              name,
+			LeaseSeconds:         s.cfg.TaskLeaseSeconds,
+			PriorityAgingSeconds: s.cfg.PriorityAgingSec,
+			UserMaxInFlight:      s.cfg.UserMaxInFlight,
+		})
+	}
+	if len(reqs) == 0 {
+		return nil, errors.New("no channels given and the agent has no subscriptions")
+	}
+	return reqs, nil
+}
+
+// dispatchToAgent waits until one of reqs claims a task and pushes it as task_assigned. The
+// agent sends ready again once it is idle.
+func (s *Server) dispatchToAgent(ctx context.Context, sess *agentSession, conn *websocket.Conn, userID, agentID, ref string, reqs []store.ClaimTaskRequest, done chan struct{}) {
+	defer close(done)
+	t, err := s.claimUntil(ctx, userID, reqs, nil)
+	if err != nil {
+		if ctx.Err() == nil {
+			sess.reply(conn, agentErrorReply(ref, http.StatusBadRequest, "claim_failed", err.Error()))
+		}
+		return
+	}
+	s.bus.PublishIDs(EventTasks, userID, t.ID)
+	s.bus.PublishIDs(EventAgents, userID, agentID)
+	s.invalidateDashboardCache()
+	s.agents.send(agentID, AgentMsgTaskAssigned, map[string]any{"task": t})
+}
+
+// forwardAgentMessage runs an upstream message through its REST handler in-process, with
+// agent_id forced to the socket's agent, and wraps the response as the reply.
+func (s *Server) forwardAgentMessage(ctx context.Context, agentID string, m agentMessage) agentMessage {
+	body := map[string]any{}
+	if len(m.Payload) > 0 {
+		if err := json.Unmarshal(m.Payload, &body); err != nil {
+			return agentErrorReply(m.ID, http.StatusBadRequest, "bad_json", "payload must be a JSON object")
+		}
+	}
+	body["agent_id"] = agentID
+	raw, _ := json.Marshal(body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, agentSocketRoutes[m.Type], bytes.NewReader(raw))
+	if err != nil {
+		return agentErrorReply(m.ID, http.StatusInternalServerError, "internal", err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	rec := &capturedResponse{header: make(http.Header)}
+	s.mux.ServeHTTP(rec, req)
+
+	out := agentMessage{Type: AgentMsgReply, Ref: m.ID, Status: rec.status}
+	if out.Status == 0 {
+		out.Status = http.StatusOK
+	}
+	if b := bytes.TrimSpace(rec.body.Bytes()); json.Valid(b) {
+		out.Payload = b
+	}
+	return out
+}
+
+// pushTaskInputs hands the pending inputs of taskID to agentID over its socket, if one is
+// open. Agents without a socket keep polling POST /v1/tasks/inputs/claim.
+func (s *Server) pushTaskInputs(ctx context.Context, taskID, agentID string) {
+	if taskID == "" || !s.agents.connected(agentID) {
+		return
+	}
+	for {
+		in, err := s.store.ClaimTaskInput(ctx, store.ClaimTaskInputRequest{TaskID: taskID, AgentID: agentID})
+		if err != nil {
+			if err != store.ErrNoPendingInputs {
+				log.Printf("agent socket: claim inputs of task %s for agent %s failed: %v", taskID, agentID, err)
+			}
+			return
+		}
+		typ := AgentMsgTaskInput
+		if in.Kind == store.TaskInputKindControl {
+			typ = AgentMsgControl
+		}
+		s.agents.send(agentID, typ, map[string]any{"input": in})
+	}
+}
+
+// notifyChainDetached tells agentID it no longer owns chainID.
+func (s *Server) notifyChainDetached(agentID, chainID, reason string) {
+	s.agents.send(agentID, AgentMsgChainDetached, map[string]any{"chain_id": chainID, "reason": reason})
+}
+
+// notifyTaskAssigned pushes a task that was assigned to its agent by someone else.
+func (s *Server) notifyTaskAssigned(t *model.Task) {
+	if t == nil || t.AssignedAgentID == "" {
+		return
+	}
+	s.agents.send(t.AssignedAgentID, AgentMsgTaskAssigned, map[string]any{"task": t})
+}
+
+func agentErrorReply(ref string, status int, code, message string) agentMessage {
+	var res errorResponse
+	res.Error.Code = code
+	res.Error.Message = message
+	raw, _ := json.Marshal(res)
+	return agentMessage{Type: AgentMsgReply, Ref: ref, Status: status, Payload: raw}
+}
+
+// capturedResponse collects what a handler writes so it can be forwarded over the socket.
+type capturedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (c *capturedResponse) Header() http.Header { return c.header }
+
+func (c *capturedResponse) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+func (c *capturedResponse) Write(b []byte) (int, error) {
+	c.WriteHeader(http.StatusOK)
+	return c.body.Write(b)
+}
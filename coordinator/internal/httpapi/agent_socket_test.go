@@ -0,0 +1,243 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/websocket"
+)
+
+func TestAgentSocket(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	httpServer := httptest.NewServer(server.mux)
+	defer httpServer.Close()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "ws-jobs"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	agentID := "66666666-6666-4666-8666-666666666666"
+
+	// Agents register over HTTP before they open a socket; unknown ones are refused.
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/v1/agents/socket?agent_id=" + agentID
+	if _, resp, err := websocket.Dial(ctx, url, nil); err == nil || resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected an unregistered agent to get %d, got %v (%v)", http.StatusNotFound, resp, err)
+	}
+	if _, err := server.store.UpsertAgent(ctx, model.Agent{ID: agentID, Name: "pending"}); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+
+	connect := func(cursor string) *websocket.Conn {
+		t.Helper()
+		dialCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/v1/agents/socket?agent_id=" + agentID + "&cursor=" + cursor
+		conn, _, err := websocket.Dial(dialCtx, url, nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		return conn
+	}
+	send := func(conn *websocket.Conn, m map[string]any) {
+		t.Helper()
+		raw, _ := json.Marshal(m)
+		if err := conn.WriteMessage(raw); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	next := func(conn *websocket.Conn) agentMessage {
+		t.Helper()
+		raw, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		var m agentMessage
+		if err := json.Unmarshal(raw, &m); err != nil {
+			t.Fatalf("decode %s: %v", raw, err)
+		}
+		return m
+	}
+	expect := func(conn *websocket.Conn, typ string) agentMessage {
+		t.Helper()
+		m := next(conn)
+		if m.Type != typ {
+			t.Fatalf("expected %s, got %s: %s", typ, m.Type, m.Payload)
+		}
+		return m
+	}
+	post := func(path string, body any) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(raw)))
+		return rec
+	}
+
+	conn := connect("0")
+	expect(conn, AgentMsgHello)
+
+	send(conn, map[string]any{"type": "heartbeat", "id": "hb-1", "payload": map[string]any{
+		"name": "socket-agent",
+		"meta": map[string]any{"subscriptions": []string{"ws-jobs"}},
+	}})
+	if m := expect(conn, AgentMsgReply); m.Ref != "hb-1" || m.Status != http.StatusOK {
+		t.Fatalf("heartbeat: expected ok reply to hb-1, got %+v: %s", m, m.Payload)
+	}
+	if agent, err := server.store.GetAgent(ctx, agentID); err != nil || agent.Name != "socket-agent" {
+		t.Fatalf("heartbeat over the socket did not update the agent: %+v (%v)", agent, err)
+	}
+
+	send(conn, map[string]any{"type": "ready", "id": "ready-1"})
+	if m := expect(conn, AgentMsgReply); m.Ref != "ready-1" || m.Status != http.StatusAccepted {
+		t.Fatalf("ready: expected accepted reply, got %+v: %s", m, m.Payload)
+	}
+
+	if rec := post("/v1/tasks", map[string]any{"channel_id": ch.ID, "title": "pushed task"}); rec.Code != http.StatusCreated {
+		t.Fatalf("create task: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	assigned := expect(conn, AgentMsgTaskAssigned)
+	var got struct {
+		Task  model.Task      `json:"task"`
+		Input model.TaskInput `json:"input"`
+	}
+	if err := json.Unmarshal(assigned.Payload, &got); err != nil || got.Task.AssignedAgentID != agentID {
+		t.Fatalf("expected the task to be dispatched to the agent, got %s (%v)", assigned.Payload, err)
+	}
+	taskID := got.Task.ID
+	send(conn, map[string]any{"type": "ack", "seq": assigned.Seq})
+
+	if rec := post("/v1/tasks/inputs", map[string]any{"task_id": taskID, "agent_id": agentID, "text": "continue"}); rec.Code != http.StatusCreated {
+		t.Fatalf("create input: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	input := expect(conn, AgentMsgTaskInput)
+	if input.Seq != assigned.Seq+1 {
+		t.Fatalf("expected seq %d, got %d", assigned.Seq+1, input.Seq)
+	}
+
+	// Drop the connection without acking the input: it is replayed after the cursor.
+	conn.Close()
+	conn = connect(strconv.FormatInt(assigned.Seq, 10))
+	defer conn.Close()
+	expect(conn, AgentMsgHello)
+	if m := expect(conn, AgentMsgTaskInput); m.Seq != input.Seq {
+		t.Fatalf("expected the unacked input (seq %d) to be replayed, got seq %d", input.Seq, m.Seq)
+	}
+	send(conn, map[string]any{"type": "ack", "seq": input.Seq})
+
+	if rec := post("/v1/tasks/"+taskID+"/cancel", map[string]any{"reason": "stop"}); rec.Code != http.StatusOK {
+		t.Fatalf("cancel: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	control := expect(conn, AgentMsgControl)
+	if err := json.Unmarshal(control.Payload, &got); err != nil || got.Input.Text != "cancel" {
+		t.Fatalf("expected a cancel control message, got %s (%v)", control.Payload, err)
+	}
+
+	send(conn, map[string]any{"type": "bogus", "id": "x"})
+	if m := expect(conn, AgentMsgReply); m.Status != http.StatusBadRequest || !bytes.Contains(m.Payload, []byte("unknown_type")) {
+		t.Fatalf("expected unknown_type, got %+v: %s", m, m.Payload)
+	}
+}
+
+func TestAgentSocketSlowWriter(t *testing.T) {
+	server := newTestServer(t)
+	httpServer := httptest.NewServer(server.mux)
+	defer httpServer.Close()
+
+	// The socket only stands in for the network; the session below is wired by hand.
+	if _, err := server.store.UpsertAgent(context.Background(), model.Agent{ID: "other-agent", Name: "other"}); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/v1/agents/socket?agent_id=other-agent"
+	ws, _, err := websocket.Dial(context.Background(), url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer ws.Close()
+
+	// A connection whose writer is stuck: sends only queue, and once the queue is full the
+	// connection is dropped while the outbox keeps the messages for the next one.
+	agentID := "77777777-7777-4777-8777-777777777777"
+	stuck := &agentConn{ws: ws, out: make(chan agentMessage, 1), done: make(chan struct{})}
+	sess := &agentSession{conn: stuck}
+	server.agents.mu.Lock()
+	server.agents.sessions[agentID] = sess
+	server.agents.mu.Unlock()
+
+	server.agents.send(agentID, AgentMsgControl, map[string]any{"n": 1})
+	if !server.agents.connected(agentID) {
+		t.Fatal("expected the first message to fit the queue")
+	}
+	server.agents.send(agentID, AgentMsgControl, map[string]any{"n": 2})
+	if server.agents.connected(agentID) {
+		t.Fatal("expected the connection to be dropped once its queue is full")
+	}
+	select {
+	case <-stuck.done:
+	default:
+		t.Fatal("expected the dropped connection's writer to be stopped")
+	}
+	sess.mu.Lock()
+	n := len(sess.outbox)
+	sess.mu.Unlock()
+	if n != 2 {
+		t.Fatalf("expected both messages to stay in the outbox, got %d", n)
+	}
+}
+
+func TestAgentSocketSessionExpiry(t *testing.T) {
+	server := newTestServer(t)
+	httpServer := httptest.NewServer(server.mux)
+	defer httpServer.Close()
+
+	connect := func(agentID string) *websocket.Conn {
+		t.Helper()
+		if _, err := server.store.UpsertAgent(context.Background(), model.Agent{ID: agentID, Name: agentID}); err != nil {
+			t.Fatalf("upsert agent: %v", err)
+		}
+		url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/v1/agents/socket?agent_id=" + agentID
+		conn, _, err := websocket.Dial(context.Background(), url, nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err := conn.ReadMessage(); err != nil {
+			t.Fatalf("read hello: %v", err)
+		}
+		return conn
+	}
+	gone, stays := "88888888-8888-4888-8888-888888888888", "99999999-9999-4999-8999-999999999999"
+
+	connect(gone).Close()
+	online := connect(stays)
+	defer online.Close()
+	for deadline := time.Now().Add(5 * time.Second); server.agents.connected(gone); {
+		if time.Now().After(deadline) {
+			t.Fatal("the closed connection was never detached")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	server.agents.send(gone, AgentMsgControl, map[string]any{"n": 1})
+
+	if n := server.agents.expire(time.Now(), agentSessionTTL); n != 0 {
+		t.Fatalf("expected nothing to expire within the TTL, removed %d", n)
+	}
+	if n := server.agents.expire(time.Now().Add(agentSessionTTL+time.Minute), agentSessionTTL); n != 1 {
+		t.Fatalf("expected only the disconnected session to expire, removed %d", n)
+	}
+	server.agents.mu.Lock()
+	_, goneKept := server.agents.sessions[gone]
+	_, staysKept := server.agents.sessions[stays]
+	server.agents.mu.Unlock()
+	if goneKept || !staysKept {
+		t.Fatalf("expected only %s to be removed (gone kept: %v, connected kept: %v)", gone, goneKept, staysKept)
+	}
+}
@@ -0,0 +1,235 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/config"
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+const notificationTypeAgentOffline = "agent_offline"
+
+// agentWatcher remembers the last observed worker status per agent so that
+// the server only reacts to online <-> offline transitions, not to every poll.
+type agentWatcher struct {
+	mu     sync.Mutex
+	status map[string]model.WorkerStatus
+}
+
+func newAgentWatcher() *agentWatcher {
+	return &agentWatcher{status: make(map[string]model.WorkerStatus)}
+}
+
+// observe records the current status and returns the previous one.
+// seen is false the first time an agent is observed.
+func (w *agentWatcher) observe(agentID string, cur model.WorkerStatus) (prev model.WorkerStatus, seen bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	prev, seen = w.status[agentID]
+	w.status[agentID] = cur
+	return prev, seen
+}
+
+// RunAgentWatcher polls agent heartbeats until ctx is cancelled and applies the
+// configured offline policy whenever an agent goes from online to offline.
+func (s *Server) RunAgentWatcher(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	s.checkAgents(ctx)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			s.checkAgents(ctx)
+		}
+	}
+}
+
+func (s *Server) checkAgents(ctx context.Context) {
+	agents, err := s.store.ListAgents(ctx, "")
+	if err != nil {
+		log.Printf("agent watcher: list agents failed: %v", err)
+		return
+	}
+
+	threshold := s.cfg.AgentOfflineThreshold()
+	for _, a := range agents {
+		cur := a.DerivedWorkerStatus(threshold)
+		prev, seen := s.agentWatch.observe(a.ID, cur)
+		// The first observation (after a restart or leader handover) is a baseline, except
+		// for agents already offline that still hold a task or chain: the transition
+		// happened while nobody watched, so the policy is applied now.
+		if !seen {
+			if cur == model.WorkerStatusOffline && s.agentHoldsWork(ctx, a) {
+				s.handleAgentOffline(ctx, a)
+			}
+			continue
+		}
+		if prev == cur {
+			continue
+		}
+
+		if cur == model.WorkerStatusOffline {
+			s.handleAgentOffline(ctx, a)
+		} else {
+			s.resolveNotification(ctx, "", notificationKey(a.ID, notificationTypeAgentOffline))
+			s.bus.PublishIDs(EventAgents, a.UserID, a.ID)
+			s.invalidateDashboardCache()
+		}
+	}
+
+	s.evaluateAgentRules(ctx, agents, threshold)
+}
+
+func (s *Server) handleAgentOffline(ctx context.Context, a model.Agent) {
+	policy := s.cfg.OfflinePolicy
+	log.Printf("agent watcher: agent %s (%s) went offline (policy=%s)", a.ID, a.Name, policy)
+
+	var requeuedTaskID string
+	var detachedChainIDs, orphanedTaskIDs []string
+
+	switch policy {
+	case config.OfflinePolicyRequeue:
+		if a.CurrentTaskID != "" {
+			t, err := s.store.RequeueTask(ctx, store.RequeueTaskRequest{
+				TaskID:  a.CurrentTaskID,
+				AgentID: a.ID,
+				Reason:  notificationTypeAgentOffline,
+			})
+			if err == nil {
+				requeuedTaskID = t.ID
+			} else if err != store.ErrConflict && err != store.ErrNotFound {
+				log.Printf("agent watcher: requeue task %s failed: %v", a.CurrentTaskID, err)
+			}
+		}
+		// Release any chain the agent still owns (e.g. between tasks) so it can be claimed again.
+		detachedChainIDs, orphanedTaskIDs = s.detachOwnedChains(ctx, a)
+	case config.OfflinePolicyDetach:
+		detachedChainIDs, orphanedTaskIDs = s.detachOwnedChains(ctx, a)
+	}
+
+	msg := fmt.Sprintf("Agent '%s' went offline.", a.Name)
+	switch {
+	case requeuedTaskID != "":
+		msg += " Its current task was requeued."
+	case len(orphanedTaskIDs) > 0:
+		msg += " It was detached from its chain and its running tasks were requeued."
+	case len(detachedChainIDs) > 0:
+		msg += " It was detached from its chain."
+	case a.CurrentTaskID != "":
+		msg += " Its current task is kept assigned."
+	}
+
+	extra := map[string]any{"policy": policy}
+	if requeuedTaskID != "" {
+		extra["requeued_task_id"] = requeuedTaskID
+	}
+	if len(detachedChainIDs) > 0 {
+		extra["detached_chain_ids"] = detachedChainIDs
+	}
+	if len(orphanedTaskIDs) > 0 {
+		extra["requeued_task_ids"] = orphanedTaskIDs
+	}
+
+	s.bus.PublishIDs(EventAgents, a.UserID, a.ID)
+	if requeuedTaskID != "" || len(detachedChainIDs) > 0 {
+		s.bus.PublishIDs(EventTasks, a.UserID, append([]string{requeuedTaskID}, orphanedTaskIDs...)...)
+		s.bus.PublishIDs(EventChains, a.UserID, detachedChainIDs...)
+	}
+	s.invalidateDashboardCache()
+
+	s.raiseNotification(ctx, model.Notification{
+		Key:       notificationKey(a.ID, notificationTypeAgentOffline),
+		UserID:    a.UserID,
+		Type:      notificationTypeAgentOffline,
+		Severity:  model.NotificationSeverityWarning,
+		Message:   msg,
+		AgentID:   a.ID,
+		AgentName: a.Name,
+		TaskID:    requeuedTaskID,
+		Extra:     extra,
+	}, notificationCooldown)
+}
+
+// agentHoldsWork reports whether the agent has a current task or owns a chain.
+func (s *Server) agentHoldsWork(ctx context.Context, a model.Agent) bool {
+	if a.CurrentTaskID != "" {
+		return true
+	}
+	chains, err := s.store.ListChains(ctx, a.UserID, "")
+	if err != nil {
+		log.Printf("agent watcher: list chains failed: %v", err)
+		return false
+	}
+	for _, c := range chains {
+		if c.OwnerAgentID == a.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// detachOwnedChains detaches the agent from every chain it owns and returns the chain IDs
+// and the IDs of the chains' tasks it was still running. Those are requeued rather than
+// locked by the detach: the agent is gone, so another agent can pick the chain up at once.
+func (s *Server) detachOwnedChains(ctx context.Context, a model.Agent) (chainIDs, requeuedTaskIDs []string) {
+	chains, err := s.store.ListChains(ctx, a.UserID, "")
+	if err != nil {
+		log.Printf("agent watcher: list chains failed: %v", err)
+		return nil, nil
+	}
+
+	for _, c := range chains {
+		if c.OwnerAgentID != a.ID {
+			continue
+		}
+		running, err := s.store.ListTasks(ctx, store.TaskFilter{ChainID: c.ID, Status: model.TaskStatusInProgress})
+		if err != nil {
+			log.Printf("agent watcher: list tasks of chain %s failed: %v", c.ID, err)
+			continue
+		}
+		for _, t := range running {
+			if t.AssignedAgentID != a.ID {
+				continue
+			}
+			requeued, err := s.store.RequeueTask(ctx, store.RequeueTaskRequest{
+				TaskID:  t.ID,
+				AgentID: a.ID,
+				Reason:  notificationTypeAgentOffline,
+			})
+			if err != nil {
+				if err != store.ErrConflict && err != store.ErrNotFound {
+					log.Printf("agent watcher: requeue task %s failed: %v", t.ID, err)
+				}
+				continue
+			}
+			requeuedTaskIDs = append(requeuedTaskIDs, requeued.ID)
+		}
+
+		// Requeueing already released the ownership; detach covers a chain held between tasks.
+		err = s.store.DetachAgentFromChain(ctx, store.DetachAgentFromChainRequest{
+			ChainID: c.ID,
+			AgentID: a.ID,
+		})
+		if err != nil && err != store.ErrConflict {
+			log.Printf("agent watcher: detach agent %s from chain %s failed: %v", a.ID, c.ID, err)
+			continue
+		}
+		s.notifyChainDetached(a.ID, c.ID, "agent_offline")
+		chainIDs = append(chainIDs, c.ID)
+	}
+	return chainIDs, requeuedTaskIDs
+}
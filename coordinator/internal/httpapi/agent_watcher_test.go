@@ -0,0 +1,186 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/config"
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+	"clwclw-monitor/coordinator/internal/store/memory"
+)
+
+// staleAgentsStore reports every agent as last seen an hour ago.
+type staleAgentsStore struct {
+	*memory.Store
+}
+
+func (s staleAgentsStore) ListAgents(ctx context.Context, userID string) ([]model.Agent, error) {
+	agents, err := s.Store.ListAgents(ctx, userID)
+	for i := range agents {
+		agents[i].LastSeen = agents[i].LastSeen.Add(-time.Hour)
+	}
+	return agents, err
+}
+
+func setupOfflineAgent(t *testing.T, policy string) (*Server, model.Chain, *model.Task, string) {
+	t.Helper()
+	st := staleAgentsStore{memory.NewStore()}
+	server := NewServer(config.Config{AuthToken: "test-token", OfflinePolicy: policy}, st)
+	ctx := context.Background()
+
+	ch, err := st.CreateChannel(ctx, model.Channel{Name: "offline-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	chain, err := st.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: "offline-chain", Status: model.ChainStatusQueued})
+	if err != nil {
+		t.Fatalf("create chain: %v", err)
+	}
+	if _, err := st.CreateTask(ctx, model.Task{ChannelID: ch.ID, ChainID: chain.ID, Sequence: 1, Title: "offline task"}); err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	agentID := "44444444-4444-4444-8444-444444444444"
+	if _, err := st.UpsertAgent(ctx, model.Agent{ID: agentID, Name: "agent-d"}); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+	claimed, err := st.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentID, ChannelID: ch.ID})
+	if err != nil {
+		t.Fatalf("claim task: %v", err)
+	}
+
+	// Baseline: the watcher last saw the agent online.
+	server.agentWatch.observe(agentID, model.WorkerStatusOnline)
+	return server, chain, claimed, agentID
+}
+
+func TestCheckAgents_RequeuePolicy(t *testing.T) {
+	server, chain, claimed, agentID := setupOfflineAgent(t, config.OfflinePolicyRequeue)
+	ctx := context.Background()
+
+	server.checkAgents(ctx)
+
+	tasks, err := server.store.ListTasks(ctx, store.TaskFilter{ChainID: chain.ID})
+	if err != nil {
+		t.Fatalf("list tasks: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != claimed.ID || tasks[0].Status != model.TaskStatusQueued {
+		t.Fatalf("expected task to be requeued, got %+v", tasks)
+	}
+	updated, err := server.store.GetChain(ctx, chain.ID)
+	if err != nil {
+		t.Fatalf("get chain: %v", err)
+	}
+	if updated.OwnerAgentID != "" {
+		t.Fatalf("expected chain ownership to be released, got %q", updated.OwnerAgentID)
+	}
+
+	notifs, _ := server.store.ListNotifications(ctx, store.NotificationFilter{})
+	if len(notifs) != 1 || notifs[0].Type != notificationTypeAgentOffline || notifs[0].AgentID != agentID {
+		t.Fatalf("expected one agent_offline notification, got %+v", notifs)
+	}
+
+	// A second poll without a transition must not act again.
+	server.checkAgents(ctx)
+	if got, _ := server.store.ListNotifications(ctx, store.NotificationFilter{}); len(got) != 1 {
+		t.Fatalf("expected notification count to stay 1, got %d", len(got))
+	}
+}
+
+func TestCheckAgents_OfflineAtStartup(t *testing.T) {
+	server, chain, claimed, agentID := setupOfflineAgent(t, config.OfflinePolicyRequeue)
+	ctx := context.Background()
+	// A fresh watcher, as after a restart: the agent went offline while nobody watched.
+	server.agentWatch = newAgentWatcher()
+	idleID := "55555555-5555-4555-8555-555555555555"
+	if _, err := server.store.UpsertAgent(ctx, model.Agent{ID: idleID, Name: "agent-idle"}); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+
+	server.checkAgents(ctx)
+
+	tasks, err := server.store.ListTasks(ctx, store.TaskFilter{ChainID: chain.ID})
+	if err != nil {
+		t.Fatalf("list tasks: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != claimed.ID || tasks[0].Status != model.TaskStatusQueued {
+		t.Fatalf("expected the task held by the offline agent to be requeued, got %+v", tasks)
+	}
+	// The idle agent holds nothing, so it only sets its baseline.
+	notifs, _ := server.store.ListNotifications(ctx, store.NotificationFilter{})
+	if len(notifs) != 1 || notifs[0].AgentID != agentID {
+		t.Fatalf("expected one agent_offline notification for %s, got %+v", agentID, notifs)
+	}
+}
+
+func TestCheckAgents_DetachPolicy(t *testing.T) {
+	server, chain, claimed, _ := setupOfflineAgent(t, config.OfflinePolicyDetach)
+	ctx := context.Background()
+	events := server.bus.Subscribe("")
+	defer server.bus.Unsubscribe(events)
+
+	server.checkAgents(ctx)
+
+	updated, err := server.store.GetChain(ctx, chain.ID)
+	if err != nil {
+		t.Fatalf("get chain: %v", err)
+	}
+	if updated.OwnerAgentID != "" || updated.Status == model.ChainStatusLocked {
+		t.Fatalf("expected a detached, unlocked chain, got owner=%q status=%s", updated.OwnerAgentID, updated.Status)
+	}
+	tasks, err := server.store.ListTasks(ctx, store.TaskFilter{ChainID: chain.ID})
+	if err != nil {
+		t.Fatalf("list tasks: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != claimed.ID || tasks[0].Status != model.TaskStatusQueued || tasks[0].AssignedAgentID != "" {
+		t.Fatalf("expected the orphaned task to be requeued, got %+v", tasks)
+	}
+
+	published := false
+	for len(events) > 0 {
+		ev := <-events
+		if ev.Type == EventTasks && fmt.Sprint(ev.Payload["ids"]) == fmt.Sprint([]string{claimed.ID}) {
+			published = true
+		}
+	}
+	if !published {
+		t.Fatalf("expected a tasks event naming %s", claimed.ID)
+	}
+	notifs, _ := server.store.ListNotifications(ctx, store.NotificationFilter{})
+	if len(notifs) != 1 || fmt.Sprint(notifs[0].Extra["requeued_task_ids"]) != fmt.Sprint([]string{claimed.ID}) {
+		t.Fatalf("expected the notification to name the requeued task, got %+v", notifs)
+	}
+
+	// Another agent takes the chain over.
+	otherID := "66666666-6666-4666-8666-666666666666"
+	if _, err := server.store.UpsertAgent(ctx, model.Agent{ID: otherID, Name: "agent-e"}); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+	got, err := server.store.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: otherID, ChannelID: chain.ChannelID})
+	if err != nil || got.ID != claimed.ID {
+		t.Fatalf("expected the second agent to claim %s, got %+v (%v)", claimed.ID, got, err)
+	}
+	if owner, _ := server.store.GetChain(ctx, chain.ID); owner.OwnerAgentID != otherID {
+		t.Fatalf("expected the second agent to own the chain, got %q", owner.OwnerAgentID)
+	}
+}
+
+func TestCheckAgents_KeepPolicy(t *testing.T) {
+	server, chain, _, agentID := setupOfflineAgent(t, config.OfflinePolicyKeep)
+	ctx := context.Background()
+
+	server.checkAgents(ctx)
+
+	updated, err := server.store.GetChain(ctx, chain.ID)
+	if err != nil {
+		t.Fatalf("get chain: %v", err)
+	}
+	if updated.OwnerAgentID != agentID || updated.Status != model.ChainStatusInProgress {
+		t.Fatalf("expected chain to stay owned and in progress, got owner=%q status=%s", updated.OwnerAgentID, updated.Status)
+	}
+	if got, _ := server.store.ListNotifications(ctx, store.NotificationFilter{}); len(got) != 1 {
+		t.Fatalf("expected an agent_offline notification, got %d", len(got))
+	}
+}
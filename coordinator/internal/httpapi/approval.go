@@ -0,0 +1,95 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+const notificationTypeApprovalRequired = "approval_required"
+
+type reviewTaskRequest struct {
+	Comment    string `json:"comment"`
+	ReviewedBy string `json:"reviewed_by"` // Only used for shared-token access (no JWT username)
+}
+
+// handleTaskReview returns the handler for POST /v1/tasks/{id}/approve|reject. The
+// reviewer is the JWT username; approving lets the gate's successors be claimed, rejecting
+// fails the gate and with it the chain.
+func (s *Server) handleTaskReview(approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		taskID := strings.TrimSpace(r.PathValue("id"))
+		if taskID == "" {
+			writeError(w, http.StatusBadRequest, "task_id_required", "task ID is required")
+			return
+		}
+
+		var req reviewTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+			return
+		}
+
+		userID := userIDFromContext(r.Context())
+
+		t, err := s.store.ReviewApprovalTask(r.Context(), store.ReviewTaskRequest{
+			TaskID:     taskID,
+			Approve:    approve,
+			ReviewedBy: auditActor(r.Context(), req.ReviewedBy),
+			Comment:    strings.TrimSpace(req.Comment),
+		})
+		if err != nil {
+			switch err {
+			case store.ErrNotFound:
+				writeError(w, http.StatusNotFound, "not_found", "task not found")
+			case store.ErrConflict:
+				writeError(w, http.StatusConflict, "conflict", "only an approval gate whose predecessors have finished can be approved or rejected")
+			default:
+				writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			}
+			return
+		}
+
+		s.resolveNotification(r.Context(), "", notificationKey(t.ID, notificationTypeApprovalRequired))
+		s.bus.PublishIDs(EventTasks, userID, t.ID)
+		s.bus.PublishIDs(EventChains, userID, t.ChainID)
+		s.invalidateDashboardCache()
+		// Approving may reach the next gate of the chain.
+		s.notifyApprovalGates(r.Context(), t.ChainID)
+		writeJSON(w, http.StatusOK, map[string]any{"task": t})
+	}
+}
+
+// notifyApprovalGates raises an approval_required notification for each gate of the chain
+// that is now waiting for a user. Gates that already have an active notification are not
+// pushed again, so calling this after every change to the chain notifies once per gate.
+func (s *Server) notifyApprovalGates(ctx context.Context, chainID string) {
+	if chainID == "" {
+		return
+	}
+	chainTasks, err := s.store.ListTasks(ctx, store.TaskFilter{ChainID: chainID})
+	if err != nil {
+		log.Printf("approval gates: list tasks of chain %s failed: %v", chainID, err)
+		return
+	}
+
+	for _, gate := range store.AwaitingApproval(chainTasks) {
+		// No cooldown: a gate that is already waiting is not pushed again.
+		s.raiseNotification(ctx, model.Notification{
+			Key:      notificationKey(gate.ID, notificationTypeApprovalRequired),
+			UserID:   gate.UserID,
+			Type:     notificationTypeApprovalRequired,
+			Severity: model.NotificationSeverityWarning,
+			Message:  "Approval required: " + gate.Title,
+			TaskID:   gate.ID,
+			ChainID:  gate.ChainID,
+			Extra:    map[string]any{"title": gate.Title},
+		}, 0)
+	}
+}
@@ -0,0 +1,151 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+func TestHandleTaskReview(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "approval-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	events := server.bus.Subscribe("")
+	defer server.bus.Unsubscribe(events)
+
+	raw, _ := json.Marshal(map[string]any{
+		"channel_id": ch.ID,
+		"name":       "release",
+		"tasks": []map[string]any{
+			{"title": "sign off release", "type": "approval"},
+			{"title": "deploy"},
+		},
+	})
+	rec := httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/chains:bulk", bytes.NewReader(raw)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create chain: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Tasks []model.Task `json:"tasks"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	gate, deploy := created.Tasks[0], created.Tasks[1]
+
+	// The gate has no predecessors, so it is waiting for a user right away.
+	notified := false
+	for len(events) > 0 {
+		ev := <-events
+		if ev.Type == EventNotification && ev.Payload["notification_type"] == notificationTypeApprovalRequired && ev.Payload["task_id"] == gate.ID {
+			notified = true
+		}
+	}
+	if !notified {
+		t.Fatalf("expected an approval_required notification for the gate")
+	}
+	if got, _ := server.store.ListNotifications(ctx, store.NotificationFilter{}); len(got) != 1 || got[0].TaskID != gate.ID {
+		t.Fatalf("expected one stored notification for the gate, got %+v", got)
+	}
+
+	review := func(taskID, action string, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/v1/tasks/"+taskID+"/"+action, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), ctxUsername, "alice"))
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := review(deploy.ID, "approve", ""); rec.Code != http.StatusConflict {
+		t.Fatalf("approving a regular task: expected status %d, got %d", http.StatusConflict, rec.Code)
+	}
+
+	rec = review(gate.ID, "approve", `{"comment":"ship it","reviewed_by":"mallory"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("approve: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Task model.Task `json:"task"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	// The JWT username wins over the body.
+	if resp.Task.Status != model.TaskStatusDone || resp.Task.ReviewedBy != "alice" || resp.Task.ReviewComment != "ship it" {
+		t.Fatalf("unexpected reviewed gate: %+v", resp.Task)
+	}
+	if got, _ := server.store.ListNotifications(ctx, store.NotificationFilter{}); len(got) != 1 || got[0].ResolvedAt == nil {
+		t.Fatalf("expected the approval notification to be resolved, got %+v", got)
+	}
+
+	if rec := review(gate.ID, "reject", ""); rec.Code != http.StatusConflict {
+		t.Fatalf("rejecting an approved gate: expected status %d, got %d", http.StatusConflict, rec.Code)
+	}
+}
+
+func TestApprovalGateAfterFailure(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "gate-after-failure"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	post := func(path string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		raw, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(raw)))
+		return rec
+	}
+
+	rec := post("/v1/chains:bulk", map[string]any{
+		"channel_id": ch.ID,
+		"name":       "nightly",
+		"on_failure": "continue",
+		"tasks": []map[string]any{
+			{"title": "flaky build", "max_attempts": 1},
+			{"title": "sign off", "type": "approval"},
+		},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create chain: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Tasks []model.Task `json:"tasks"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	build, gate := created.Tasks[0], created.Tasks[1]
+
+	agentID := "a2000000-0000-4000-8000-000000000001"
+	if rec := post("/v1/tasks/claim", map[string]any{"agent_id": agentID, "channel_id": ch.ID}); rec.Code != http.StatusOK {
+		t.Fatalf("claim: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got, _ := server.store.ListNotifications(ctx, store.NotificationFilter{}); len(got) != 0 {
+		t.Fatalf("expected no notification while the build runs, got %+v", got)
+	}
+
+	rec = post("/v1/tasks/fail", map[string]any{"task_id": build.ID, "agent_id": agentID, "reason": "boom"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("fail: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	got, err := server.store.ListNotifications(ctx, store.NotificationFilter{})
+	if err != nil || len(got) != 1 || got[0].TaskID != gate.ID || got[0].Type != notificationTypeApprovalRequired {
+		t.Fatalf("expected an approval_required notification for the gate reached after the failure, got %+v (%v)", got, err)
+	}
+}
@@ -0,0 +1,149 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/artifact"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// SetArtifactStore enables the /v1/artifacts endpoints and artifact links in event payloads.
+// Without one, uploads are rejected and events may not reference artifacts.
+func (s *Server) SetArtifactStore(a artifact.Store) {
+	s.artifacts = a
+}
+
+// handleArtifacts serves POST /v1/artifacts (upload the raw request body) and GET
+// /v1/artifacts (the caller's blobs and quota usage).
+func (s *Server) handleArtifacts(w http.ResponseWriter, r *http.Request) {
+	if s.artifacts == nil {
+		writeError(w, http.StatusServiceUnavailable, "artifacts_disabled", "artifact storage is not configured")
+		return
+	}
+	userID := userIDFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		blobs, err := s.artifacts.List(r.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", "failed to list artifacts")
+			return
+		}
+		owned := make([]artifact.Blob, 0, len(blobs))
+		var usage int64
+		for _, b := range blobs {
+			if b.OwnedBy(userID) {
+				owned = append(owned, b)
+				usage += b.Size
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"artifacts":   owned,
+			"usage_bytes": usage,
+			"quota_bytes": s.cfg.ArtifactQuotaBytes,
+			"max_bytes":   s.cfg.ArtifactMaxBytes,
+		})
+		return
+
+	case http.MethodPost:
+		b, err := s.artifacts.Put(r.Context(), userID, r.Header.Get("Content-Type"), r.Body)
+		if err != nil {
+			switch {
+			case errors.Is(err, artifact.ErrTooLarge):
+				writeError(w, http.StatusRequestEntityTooLarge, "artifact_too_large",
+					fmt.Sprintf("artifact exceeds %d bytes", s.cfg.ArtifactMaxBytes))
+			case errors.Is(err, artifact.ErrQuotaExceeded):
+				writeError(w, http.StatusRequestEntityTooLarge, "artifact_quota_exceeded",
+					fmt.Sprintf("artifact quota of %d bytes exceeded", s.cfg.ArtifactQuotaBytes))
+			default:
+				writeError(w, http.StatusInternalServerError, "internal", "failed to store artifact")
+			}
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"artifact": b})
+		return
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+}
+
+// handleArtifactDownload serves GET /v1/artifacts/{id}. Blobs are immutable, so the ID is
+// the ETag. Content is sandboxed: uploaded HTML must not run with the dashboard's origin.
+func (s *Server) handleArtifactDownload(w http.ResponseWriter, r *http.Request) {
+	if s.artifacts == nil {
+		writeError(w, http.StatusServiceUnavailable, "artifacts_disabled", "artifact storage is not configured")
+		return
+	}
+	userID := userIDFromContext(r.Context())
+	id := strings.TrimSpace(r.PathValue("id"))
+
+	rc, b, err := s.artifacts.Open(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, artifact.ErrInvalidID):
+			writeError(w, http.StatusBadRequest, "invalid_artifact_id", "artifact ID must be a sha256 hex digest")
+		case errors.Is(err, artifact.ErrNotFound):
+			writeError(w, http.StatusNotFound, "not_found", "artifact not found")
+		default:
+			writeError(w, http.StatusInternalServerError, "internal", "failed to open artifact")
+		}
+		return
+	}
+	defer rc.Close()
+
+	// Without a user (API token), every blob is visible; otherwise only the caller's own.
+	if userID != "" && !b.OwnedBy(userID) {
+		writeError(w, http.StatusNotFound, "not_found", "artifact not found")
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", b.ContentType)
+	h.Set("ETag", `"`+b.ID+`"`)
+	h.Set("Cache-Control", "private, max-age=31536000, immutable")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Content-Security-Policy", "sandbox")
+
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", b.UploadedAt, rs)
+		return
+	}
+	h.Set("Content-Length", strconv.FormatInt(b.Size, 10))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, rc)
+	}
+}
+
+// checkEventArtifacts verifies that the blobs an event payload links to exist and were
+// uploaded by the caller, so an event cannot pin or expose someone else's upload.
+func (s *Server) checkEventArtifacts(ctx context.Context, userID string, payload map[string]any) error {
+	ids := store.EventArtifactIDs(payload)
+	if len(ids) == 0 {
+		return nil
+	}
+	if s.artifacts == nil {
+		return errors.New("artifact storage is not configured")
+	}
+	for _, id := range ids {
+		b, err := s.artifacts.Stat(ctx, id)
+		if errors.Is(err, artifact.ErrInvalidID) {
+			return fmt.Errorf("invalid artifact ID %q", id)
+		}
+		if errors.Is(err, artifact.ErrNotFound) || (err == nil && userID != "" && !b.OwnedBy(userID)) {
+			return fmt.Errorf("artifact %s not found", id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
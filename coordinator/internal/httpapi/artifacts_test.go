@@ -0,0 +1,94 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/artifact"
+)
+
+func TestHandleArtifacts(t *testing.T) {
+	server := newTestServer(t)
+
+	// Without a store the endpoints are disabled.
+	rec := httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/artifacts", strings.NewReader("x")))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d without a store, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	blobs, err := artifact.NewFS(t.TempDir(), artifact.Limits{MaxBytes: 32})
+	if err != nil {
+		t.Fatalf("new fs: %v", err)
+	}
+	server.SetArtifactStore(blobs)
+
+	asUser := func(r *http.Request, userID string) *http.Request {
+		return r.WithContext(context.WithValue(r.Context(), ctxUserID, userID))
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/artifacts", strings.NewReader("$ make test\nok\n"))
+	req.Header.Set("Content-Type", "text/plain")
+	rec = httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, asUser(req, "user-1"))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("upload: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var uploaded struct {
+		Artifact artifact.Blob `json:"artifact"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&uploaded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	id := uploaded.Artifact.ID
+
+	rec = httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, asUser(httptest.NewRequest(http.MethodGet, "/v1/artifacts/"+id, nil), "user-1"))
+	if rec.Code != http.StatusOK || rec.Body.String() != "$ make test\nok\n" {
+		t.Fatalf("download: got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "text/plain" || rec.Header().Get("Content-Security-Policy") != "sandbox" {
+		t.Fatalf("unexpected download headers: %v", rec.Header())
+	}
+
+	// Other users cannot see the blob.
+	rec = httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, asUser(httptest.NewRequest(http.MethodGet, "/v1/artifacts/"+id, nil), "user-2"))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for another user, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/artifacts", strings.NewReader(strings.Repeat("x", 33))))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status %d for an oversized upload, got %d", http.StatusRequestEntityTooLarge, rec.Code)
+	}
+
+	postEvent := func(userID string, payload map[string]any) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(map[string]any{"agent_id": "a0000000-0000-4000-8000-000000000001", "type": "agent.trace", "payload": payload})
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, asUser(httptest.NewRequest(http.MethodPost, "/v1/events", bytes.NewReader(body)), userID))
+		return rec
+	}
+
+	if rec := postEvent("user-1", map[string]any{"artifact_id": id}); rec.Code != http.StatusCreated {
+		t.Fatalf("event with artifact: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if rec := postEvent("user-2", map[string]any{"artifact_ids": []string{id}}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("event with another user's artifact: expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := postEvent("user-1", map[string]any{"artifact_id": "nope"}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("event with invalid artifact: expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	refs, err := server.store.ListEventArtifactIDs(context.Background())
+	if err != nil || len(refs) != 1 || refs[0] != id {
+		t.Fatalf("expected the event to reference %s, got %v (%v)", id, refs, err)
+	}
+}
@@ -1,8 +1,18 @@
 package httpapi
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
 )
 
 const (
@@ -10,30 +20,98 @@ const (
 	EventTasks        = "tasks"
 	EventChannels     = "channels"
 	EventChains       = "chains"
+	EventSchedules    = "schedules"
 	EventInputs       = "inputs"
 	EventEvents       = "events"
 	EventUpdate       = "update"
 	EventNotification = "notification"
 )
 
+// busReplayLimit is how many recent events the bus keeps per user for Last-Event-ID replay.
+const busReplayLimit = 256
+
+// busRelayQueue is how many events may wait to be relayed to other replicas.
+const busRelayQueue = 1024
+
+// busEvent is one change notification. ID is assigned by the replica that published the
+// event (Origin) and kept when the event is relayed, so the pair names the same event on
+// every replica.
 type busEvent struct {
+	ID      uint64         `json:"id"`
+	Origin  string         `json:"origin,omitempty"`
 	Type    string         `json:"type"`
 	Time    time.Time      `json:"time"`
 	Payload map[string]any `json:"payload,omitempty"`
+
+	pos uint64 // order in which this replica delivered the event, for replay
+}
+
+// streamID is the SSE id of ev: its origin and ID.
+func (ev busEvent) streamID() string {
+	return ev.Origin + "-" + strconv.FormatUint(ev.ID, 10)
+}
+
+// busCursor names the last event a stream client saw, parsed from its Last-Event-ID.
+type busCursor struct {
+	origin string
+	id     uint64
+}
+
+// parseBusCursor parses an SSE id written by streamID.
+func parseBusCursor(s string) (busCursor, bool) {
+	origin, id, ok := strings.Cut(s, "-")
+	if !ok || origin == "" {
+		return busCursor{}, false
+	}
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return busCursor{}, false
+	}
+	return busCursor{origin: origin, id: n}, true
 }
 
 type subscriber struct {
 	ch     chan busEvent
 	userID string
+	topics map[string]bool // nil = every event type
+	// closeOnOverflow closes ch instead of dropping events when the subscriber falls
+	// behind, so a stream client reconnects and replays what it missed.
+	closeOnOverflow bool
+}
+
+// busHistory is the replay buffer of one user ("" holds events published to everyone).
+type busHistory struct {
+	events  []busEvent
+	evicted uint64 // pos of the newest event pushed out of events, 0 if none
 }
 
 type eventBus struct {
-	mu   sync.Mutex
-	subs map[chan busEvent]subscriber
+	mu      sync.Mutex
+	subs    map[chan busEvent]subscriber
+	history map[string]*busHistory
+	// IDs of the events published here follow the clock in microseconds, so they keep
+	// increasing across restarts and stay roughly aligned between replicas.
+	lastID    uint64
+	delivered uint64 // pos of the last event delivered here, local or relayed
+
+	origin string            // identifies this replica in relayed events
+	relay  chan relayMessage // events waiting to be relayed; nil without a relay
+
+	// observers see every event published on this replica (not relayed ones), by name. They
+	// are called with b.mu held and must not block.
+	observers map[string]func(userID string, ev busEvent)
 }
 
 func newEventBus() *eventBus {
-	return &eventBus{subs: make(map[chan busEvent]subscriber)}
+	start := uint64(time.Now().UnixMicro())
+	var origin [8]byte
+	_, _ = rand.Read(origin[:])
+	return &eventBus{
+		subs:    make(map[chan busEvent]subscriber),
+		history: make(map[string]*busHistory),
+		lastID:  start,
+		origin:  hex.EncodeToString(origin[:]),
+	}
 }
 
 func (b *eventBus) Subscribe(userID string) chan busEvent {
@@ -44,36 +122,206 @@ func (b *eventBus) Subscribe(userID string) chan busEvent {
 	return ch
 }
 
+// SubscribeFrom subscribes a stream client to the given topics (nil = all) and returns the
+// events it missed: those this replica delivered after the one named by after (zero = none).
+// The event may have come from any replica, so a client can reconnect anywhere. complete
+// is false when some missed events are no longer buffered (or the event is unknown here,
+// e.g. from an earlier run), in which case the client must refetch.
+// The channel is closed if the client falls behind instead of silently dropping events.
+func (b *eventBus) SubscribeFrom(userID string, topics map[string]bool, after busCursor) (ch chan busEvent, backlog []busEvent, complete bool) {
+	ch = make(chan busEvent, 32)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete = true
+	if after != (busCursor{}) {
+		backlog, complete = b.replayLocked(userID, topics, after)
+	}
+	b.subs[ch] = subscriber{ch: ch, userID: userID, topics: topics, closeOnOverflow: true}
+	return ch, backlog, complete
+}
+
+// Must be called with b.mu held.
+func (b *eventBus) replayLocked(userID string, topics map[string]bool, after busCursor) ([]busEvent, bool) {
+	var last uint64
+	for owner, h := range b.history {
+		if !visibleTo(userID, owner) {
+			continue
+		}
+		for _, ev := range h.events {
+			if ev.Origin == after.origin && ev.ID == after.id {
+				last = ev.pos
+			}
+		}
+	}
+	if last == 0 {
+		return nil, false
+	}
+
+	complete := true
+	var out []busEvent
+	for owner, h := range b.history {
+		if !visibleTo(userID, owner) {
+			continue
+		}
+		if h.evicted > last {
+			complete = false
+		}
+		for _, ev := range h.events {
+			if ev.pos > last && (topics == nil || topics[ev.Type]) {
+				out = append(out, ev)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].pos < out[j].pos })
+	return out, complete
+}
+
 func (b *eventBus) Unsubscribe(ch chan busEvent) {
 	if ch == nil {
 		return
 	}
 	b.mu.Lock()
-	delete(b.subs, ch)
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
 	b.mu.Unlock()
-	close(ch)
 }
 
 func (b *eventBus) Publish(typ string, userID string) {
 	b.PublishWithPayload(typ, userID, nil)
 }
 
+// PublishIDs publishes typ with the IDs of the changed entities as payload "ids", so
+// clients can refetch just those. Empty and repeated IDs are skipped.
+func (b *eventBus) PublishIDs(typ string, userID string, ids ...string) {
+	var nonEmpty []string
+	for _, id := range ids {
+		if id != "" && !slices.Contains(nonEmpty, id) {
+			nonEmpty = append(nonEmpty, id)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		b.PublishWithPayload(typ, userID, nil)
+		return
+	}
+	b.PublishWithPayload(typ, userID, map[string]any{"ids": nonEmpty})
+}
+
 func (b *eventBus) PublishWithPayload(typ string, userID string, payload map[string]any) {
 	if typ == "" {
 		typ = EventUpdate
 	}
-	ev := busEvent{Type: typ, Time: time.Now().UTC(), Payload: payload}
 
 	b.mu.Lock()
-	for _, sub := range b.subs {
-		// Send to subscriber if: no userID filter on event, or subscriber has no userID, or they match
-		if userID == "" || sub.userID == "" || sub.userID == userID {
-			select {
-			case sub.ch <- ev:
-			default:
-				// drop if subscriber is slow
+	defer b.mu.Unlock()
+
+	ev := busEvent{ID: b.nextIDLocked(uint64(time.Now().UnixMicro())), Origin: b.origin, Type: typ, Time: time.Now().UTC(), Payload: payload}
+	ev = b.deliverLocked(userID, ev)
+	for _, observe := range b.observers {
+		observe(userID, ev)
+	}
+
+	if b.relay != nil {
+		select {
+		case b.relay <- relayMessage{Origin: b.origin, UserID: userID, Event: ev}:
+		default:
+			log.Printf("bus relay: queue full, event %s not relayed to other replicas", typ)
+		}
+	}
+}
+
+// setObserver installs (or with nil removes) the named observer of locally published events.
+func (b *eventBus) setObserver(name string, fn func(userID string, ev busEvent)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if fn == nil {
+		delete(b.observers, name)
+		return
+	}
+	if b.observers == nil {
+		b.observers = make(map[string]func(userID string, ev busEvent))
+	}
+	b.observers[name] = fn
+}
+
+// receive delivers an event relayed by another replica to the local subscribers.
+func (b *eventBus) receive(payload string) {
+	var msg relayMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		log.Printf("bus relay: bad message: %v", err)
+		return
+	}
+	if msg.Origin == b.origin {
+		return // already delivered locally when it was published
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// The event keeps its origin's ID, so a Last-Event-ID names it on every replica.
+	ev := msg.Event
+	ev.Origin = msg.Origin
+	b.deliverLocked(msg.UserID, ev)
+}
+
+// nextIDLocked returns want, or the next ID after the last one if want would not increase.
+// Must be called with b.mu held.
+func (b *eventBus) nextIDLocked(want uint64) uint64 {
+	if want <= b.lastID {
+		want = b.lastID + 1
+	}
+	b.lastID = want
+	return want
+}
+
+// deliverLocked records ev in userID's replay buffer and hands it to the subscribers that
+// may see it. It returns ev with its delivery position set.
+// Must be called with b.mu held.
+func (b *eventBus) deliverLocked(userID string, ev busEvent) busEvent {
+	b.delivered++
+	ev.pos = b.delivered
+
+	h := b.history[userID]
+	if h == nil {
+		h = &busHistory{}
+		b.history[userID] = h
+	}
+	h.events = append(h.events, ev)
+	if over := len(h.events) - busReplayLimit; over > 0 {
+		h.evicted = h.events[over-1].pos
+		h.events = append([]busEvent(nil), h.events[over:]...)
+	}
+
+	for ch, sub := range b.subs {
+		if !visibleTo(sub.userID, userID) || (sub.topics != nil && !sub.topics[ev.Type]) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			if sub.closeOnOverflow {
+				delete(b.subs, ch)
+				close(ch)
 			}
+			// Otherwise drop: the subscriber only needs to know that something changed.
 		}
 	}
-	b.mu.Unlock()
+	return ev
+}
+
+// visibleTo reports whether a subscriber of subscriberID sees events published for
+// ownerID: events without a user go to everyone, and subscribers without a user see all.
+func visibleTo(subscriberID, ownerID string) bool {
+	return ownerID == "" || subscriberID == "" || subscriberID == ownerID
+}
+
+// taskIDs returns the IDs of tasks, for PublishIDs.
+func taskIDs(tasks []model.Task) []string {
+	ids := make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		ids = append(ids, t.ID)
+	}
+	return ids
 }
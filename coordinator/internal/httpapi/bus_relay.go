@@ -0,0 +1,95 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// busNotifyChannel is the Postgres NOTIFY channel the replicas share bus events on.
+const busNotifyChannel = "coordinator_bus"
+
+// maxRelayPayload keeps relayed events under the 8000-byte NOTIFY limit.
+const maxRelayPayload = 7900
+
+// busRelay fans bus events out to every coordinator replica. postgres.Store implements it
+// with NOTIFY/LISTEN.
+type busRelay interface {
+	Notify(ctx context.Context, channel, payload string) error
+	Listen(ctx context.Context, channel string, listening func(), handle func(payload string)) error
+}
+
+// relayMessage is a bus event on its way between replicas. userID keeps the per-user
+// filtering on the receiving side.
+type relayMessage struct {
+	Origin string   `json:"origin"`
+	UserID string   `json:"user_id,omitempty"`
+	Event  busEvent `json:"event"`
+}
+
+// RunBusRelay connects the event bus to the other replicas when the store supports it, so
+// an SSE client on one replica sees changes made on another. Local subscribers are always
+// served directly; the relay only adds the other replicas' events. It returns when ctx ends.
+func (s *Server) RunBusRelay(ctx context.Context) {
+	relay, ok := s.store.(busRelay)
+	if !ok {
+		return
+	}
+	out := make(chan relayMessage, busRelayQueue)
+	s.bus.mu.Lock()
+	s.bus.relay = out
+	s.bus.mu.Unlock()
+	log.Printf("bus relay: sharing events over postgres channel %s", busNotifyChannel)
+
+	go s.sendRelayed(ctx, relay, out)
+
+	backoff := time.Second
+	connected := false
+	for {
+		err := relay.Listen(ctx, busNotifyChannel, func() {
+			if connected {
+				// Events of other replicas may have been missed while reconnecting.
+				s.bus.Publish(EventUpdate, "")
+			}
+			connected = true
+			backoff = time.Second
+		}, s.bus.receive)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("bus relay: listen failed: %v (retrying in %v)", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func (s *Server) sendRelayed(ctx context.Context, relay busRelay, out <-chan relayMessage) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-out:
+			raw, err := json.Marshal(msg)
+			if err != nil {
+				continue
+			}
+			if len(raw) > maxRelayPayload {
+				// Too big for NOTIFY: other replicas still learn that something changed.
+				msg.Event.Payload = nil
+				raw, _ = json.Marshal(msg)
+			}
+			notifyCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			if err := relay.Notify(notifyCtx, busNotifyChannel, string(raw)); err != nil {
+				log.Printf("bus relay: notify failed: %v", err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package httpapi
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/config"
+	"clwclw-monitor/coordinator/internal/store"
+	"clwclw-monitor/coordinator/internal/store/memory"
+)
+
+// fakeNotify stands in for Postgres NOTIFY/LISTEN: every listener gets every payload.
+type fakeNotify struct {
+	mu        sync.Mutex
+	listeners []chan string
+}
+
+func (f *fakeNotify) Notify(_ context.Context, _ string, payload string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, l := range f.listeners {
+		l <- payload
+	}
+	return nil
+}
+
+func (f *fakeNotify) Listen(ctx context.Context, _ string, listening func(), handle func(string)) error {
+	l := make(chan string, 64)
+	f.mu.Lock()
+	f.listeners = append(f.listeners, l)
+	f.mu.Unlock()
+	listening()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case p := <-l:
+			handle(p)
+		}
+	}
+}
+
+type relayedStore struct {
+	store.Store
+	*fakeNotify
+}
+
+func TestBusRelayAcrossReplicas(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	notify := &fakeNotify{}
+	st := relayedStore{Store: memory.NewStore(), fakeNotify: notify}
+	replicaA := NewServer(config.Config{AuthToken: "test-token"}, st)
+	replicaB := NewServer(config.Config{AuthToken: "test-token"}, st)
+	go replicaA.RunBusRelay(ctx)
+	go replicaB.RunBusRelay(ctx)
+	for deadline := time.Now().Add(2 * time.Second); ; {
+		notify.mu.Lock()
+		n := len(notify.listeners)
+		notify.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("replicas did not start listening")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	aliceOnA := replicaA.bus.Subscribe("alice")
+	aliceOnB := replicaB.bus.Subscribe("alice")
+	bobOnB := replicaB.bus.Subscribe("bob")
+
+	replicaA.bus.PublishIDs(EventTasks, "alice", "t1")
+
+	for name, ch := range map[string]chan busEvent{"replica A": aliceOnA, "replica B": aliceOnB} {
+		select {
+		case ev := <-ch:
+			if ev.Type != EventTasks {
+				t.Fatalf("%s: expected a tasks event, got %+v", name, ev)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s: alice did not get the event", name)
+		}
+	}
+	select {
+	case ev := <-aliceOnA:
+		t.Fatalf("replica A delivered its own event twice: %+v", ev)
+	case ev := <-bobOnB:
+		t.Fatalf("bob received alice's event: %+v", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,143 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventBusReplay(t *testing.T) {
+	b := newEventBus()
+	b.PublishIDs(EventTasks, "alice", "t1")
+	first := busCursor{origin: b.origin, id: b.lastID}
+	b.PublishIDs(EventChains, "alice", "c1")
+	b.Publish(EventTasks, "bob")
+	b.PublishIDs(EventTasks, "alice", "t2")
+
+	ch, backlog, complete := b.SubscribeFrom("alice", map[string]bool{EventTasks: true}, first)
+	defer b.Unsubscribe(ch)
+	if !complete {
+		t.Fatalf("expected a complete replay")
+	}
+	if len(backlog) != 1 || backlog[0].Payload["ids"].([]string)[0] != "t2" {
+		t.Fatalf("expected only alice's later task event, got %+v", backlog)
+	}
+
+	// An event this replica does not know (e.g. from an earlier run) cannot be replayed.
+	if _, _, complete := b.SubscribeFrom("alice", nil, busCursor{origin: b.origin, id: 42}); complete {
+		t.Fatalf("expected a stale Last-Event-ID to report an incomplete replay")
+	}
+
+	for i := 0; i < busReplayLimit+1; i++ {
+		b.Publish(EventTasks, "alice")
+	}
+	if _, _, complete := b.SubscribeFrom("alice", nil, first); complete {
+		t.Fatalf("expected evicted events to report an incomplete replay")
+	}
+}
+
+func TestEventBusReplayAcrossReplicas(t *testing.T) {
+	a, b := newEventBus(), newEventBus()
+	a.relay = make(chan relayMessage, 8)
+	relay := func() {
+		for len(a.relay) > 0 {
+			raw, _ := json.Marshal(<-a.relay)
+			b.receive(string(raw))
+		}
+	}
+
+	a.PublishIDs(EventTasks, "alice", "t1")
+	a.PublishIDs(EventTasks, "alice", "t2")
+	relay()
+	seen := busCursor{origin: a.origin, id: a.lastID}
+	b.PublishIDs(EventChains, "alice", "c1")
+	a.PublishIDs(EventTasks, "alice", "t3")
+	relay()
+
+	// A client that saw t2 on replica A reconnects to replica B: B knows t2 by its origin ID
+	// and replays what it delivered after it.
+	ch, backlog, complete := b.SubscribeFrom("alice", nil, seen)
+	defer b.Unsubscribe(ch)
+	if !complete {
+		t.Fatalf("expected a complete replay on the other replica")
+	}
+	var got []string
+	for _, ev := range backlog {
+		got = append(got, fmt.Sprint(ev.Payload["ids"]))
+	}
+	if strings.Join(got, ",") != "[c1],[t3]" {
+		t.Fatalf("expected c1 and t3 to be replayed, got %v", got)
+	}
+	if backlog[1].Origin != a.origin || backlog[1].ID != a.lastID {
+		t.Fatalf("expected the relayed event to keep its origin ID, got %s", backlog[1].streamID())
+	}
+}
+
+func TestEventBusClosesSlowStream(t *testing.T) {
+	b := newEventBus()
+	ch, _, _ := b.SubscribeFrom("", nil, busCursor{})
+	for i := 0; i < 64; i++ {
+		b.Publish(EventTasks, "")
+	}
+	n := 0
+	for range ch {
+		n++
+	}
+	if n != 32 {
+		t.Fatalf("expected the buffered events and then a closed channel, got %d events", n)
+	}
+	b.Unsubscribe(ch) // already removed: must not panic
+}
+
+func TestHandleStreamLastEventID(t *testing.T) {
+	server := newTestServer(t)
+	server.bus.PublishIDs(EventTasks, "", "t1")
+	seen := server.bus.origin + "-" + strconv.FormatUint(server.bus.lastID, 10)
+	server.bus.PublishIDs(EventAgents, "", "a1")
+	server.bus.PublishIDs(EventTasks, "", "t2")
+
+	httpServer := httptest.NewServer(server.mux)
+	defer httpServer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL+"/v1/stream?topics=tasks", nil)
+	req.Header.Set("Last-Event-ID", seen)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	sc := bufio.NewScanner(resp.Body)
+	var ids []string
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "data: ") && strings.Contains(line, `"ids"`) {
+			ids = append(ids, line)
+			if len(ids) == 1 {
+				cancel()
+			}
+		}
+	}
+	if len(ids) != 1 || !strings.Contains(ids[0], `"t2"`) {
+		t.Fatalf("expected only the missed task event to be replayed, got %v", ids)
+	}
+
+	rec := httptest.NewRecorder()
+	server.handleStream(rec, httptest.NewRequest(http.MethodGet, "/v1/stream?topics=bogus", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an unknown topic, got %d", http.StatusBadRequest, rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	server.handleStream(rec, httptest.NewRequest(http.MethodGet, "/v1/stream?last_event_id=42", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an id without origin, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
@@ -0,0 +1,98 @@
+package httpapi
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// Edge kinds in a chain graph.
+const (
+	edgeKindDependsOn = "depends_on" // explicit Task.DependsOn edge
+	edgeKindSequence  = "sequence"   // implicit edge from the previous sequence (tasks without depends_on)
+)
+
+type chainGraphEdge struct {
+	From string `json:"from"` // task that must finish first
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// chainGraphEdges derives the gating edges of a chain. Tasks with depends_on get one
+// edge per dependency. A task without depends_on is gated by every lower sequence, so
+// it gets edges from the lower-sequence tasks nothing else (below it) already waits on;
+// the rest of its blockers are reachable through those.
+func chainGraphEdges(tasks []model.Task) []chainGraphEdge {
+	// gates returns the IDs a task waits on, following the same rule as ClaimTask.
+	gates := func(t model.Task) []string {
+		if len(t.DependsOn) > 0 {
+			return t.DependsOn
+		}
+		var ids []string
+		for _, o := range tasks {
+			if o.Sequence < t.Sequence {
+				ids = append(ids, o.ID)
+			}
+		}
+		return ids
+	}
+
+	edges := []chainGraphEdge{}
+	for _, t := range tasks {
+		if len(t.DependsOn) > 0 {
+			for _, dep := range t.DependsOn {
+				edges = append(edges, chainGraphEdge{From: dep, To: t.ID, Kind: edgeKindDependsOn})
+			}
+			continue
+		}
+
+		waitedOn := make(map[string]bool)
+		for _, o := range tasks {
+			if o.Sequence < t.Sequence {
+				for _, id := range gates(o) {
+					waitedOn[id] = true
+				}
+			}
+		}
+		for _, o := range tasks {
+			if o.Sequence < t.Sequence && !waitedOn[o.ID] {
+				edges = append(edges, chainGraphEdge{From: o.ID, To: t.ID, Kind: edgeKindSequence})
+			}
+		}
+	}
+	return edges
+}
+
+func (s *Server) handleChainGraph(w http.ResponseWriter, r *http.Request) {
+	chainID := strings.TrimSpace(r.PathValue("id"))
+	if chainID == "" {
+		writeError(w, http.StatusBadRequest, "chain_id_required", "chain ID is required")
+		return
+	}
+
+	chain, err := s.store.GetChain(r.Context(), chainID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			writeError(w, http.StatusNotFound, "not_found", "chain not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "internal", "failed to get chain")
+		return
+	}
+
+	tasks, err := s.store.ListTasks(r.Context(), store.TaskFilter{ChainID: chainID})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to list chain tasks")
+		return
+	}
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].Sequence < tasks[j].Sequence })
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"chain": chain,
+		"nodes": tasks,
+		"edges": chainGraphEdges(tasks),
+	})
+}
@@ -0,0 +1,118 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// maxClaimWait caps the wait of a long-poll claim so proxies and agents never see a
+// request hang for minutes.
+const maxClaimWait = 60 * time.Second
+
+// claimWaitFromRequest returns how long a claim may wait for work: the body's wait
+// (seconds) or, when that is 0, the ?wait= query parameter, capped at maxClaimWait.
+func claimWaitFromRequest(r *http.Request, bodyWait int) time.Duration {
+	seconds := bodyWait
+	if seconds == 0 {
+		if v := strings.TrimSpace(r.URL.Query().Get("wait")); v != "" {
+			seconds, _ = strconv.Atoi(v)
+		}
+	}
+	if seconds <= 0 {
+		return 0
+	}
+	wait := time.Duration(seconds) * time.Second
+	if wait > maxClaimWait {
+		wait = maxClaimWait
+	}
+	return wait
+}
+
+// wakesClaim reports whether a bus event may have made a task claimable: a task was
+// created, requeued or finished (releasing its successors or an in-flight slot), or a
+// chain or channel was resumed.
+func wakesClaim(typ string) bool {
+	switch typ {
+	case EventTasks, EventChains, EventChannels:
+		return true
+	}
+	return false
+}
+
+// claimWithWait is ClaimTask with long polling. While nothing can be claimed (no task, or
+// an in-flight limit is reached) it retries whenever the event bus reports a change that
+// may release work, or a task's not_before/backoff passes, until wait expires. It then
+// returns the last claim error, or ctx.Err() if the client went away first.
+func (s *Server) claimWithWait(ctx context.Context, userID string, req store.ClaimTaskRequest, wait time.Duration) (*model.Task, error) {
+	if wait <= 0 {
+		return s.store.ClaimTask(ctx, req)
+	}
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	return s.claimUntil(ctx, userID, []store.ClaimTaskRequest{req}, deadline.C)
+}
+
+// claimUntil tries reqs in order until one of them claims a task, retrying the way
+// claimWithWait does until deadline fires (a nil deadline waits for ctx alone).
+func (s *Server) claimUntil(ctx context.Context, userID string, reqs []store.ClaimTaskRequest, deadline <-chan time.Time) (*model.Task, error) {
+	// Subscribe before the first attempt so a task created in between still wakes us.
+	events := s.bus.Subscribe(userID)
+	defer s.bus.Unsubscribe(events)
+
+	var retryTimer *time.Timer
+	defer func() {
+		if retryTimer != nil {
+			retryTimer.Stop()
+		}
+	}()
+
+	for {
+		err := store.ErrNoQueuedTasks
+		var next *time.Time
+		for _, req := range reqs {
+			var t *model.Task
+			t, err = s.store.ClaimTask(ctx, req)
+			if !errors.Is(err, store.ErrNoQueuedTasks) && !errors.Is(err, store.ErrConcurrencyLimited) {
+				return t, err
+			}
+			if at, _ := s.store.NextClaimableAt(ctx, req); at != nil && (next == nil || at.Before(*next)) {
+				next = at
+			}
+		}
+
+		var retry <-chan time.Time
+		if retryTimer != nil {
+			retryTimer.Stop()
+		}
+		if next != nil {
+			retryTimer = time.NewTimer(time.Until(*next))
+			retry = retryTimer.C
+		}
+
+	waitForChange:
+		for {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-deadline:
+				return nil, err
+			case <-retry:
+				break waitForChange
+			case ev, ok := <-events:
+				if !ok {
+					return nil, err
+				}
+				if wakesClaim(ev.Type) {
+					break waitForChange
+				}
+			}
+		}
+	}
+}
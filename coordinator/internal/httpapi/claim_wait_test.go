@@ -0,0 +1,81 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+func TestHandleTasksClaimWait(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "long-poll"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	claimReq := func(ctx context.Context, wait int) *http.Request {
+		raw, _ := json.Marshal(map[string]any{"agent_id": "55555555-5555-4555-8555-555555555555", "channel_id": ch.ID, "wait": wait})
+		return httptest.NewRequest(http.MethodPost, "/v1/tasks/claim", bytes.NewReader(raw)).WithContext(ctx)
+	}
+
+	t.Run("WakesOnNewTask", func(t *testing.T) {
+		done := make(chan *httptest.ResponseRecorder, 1)
+		go func() {
+			rec := httptest.NewRecorder()
+			server.handleTasksClaim(rec, claimReq(ctx, 10))
+			done <- rec
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		raw, _ := json.Marshal(map[string]any{"channel_id": ch.ID, "title": "late task"})
+		rec := httptest.NewRecorder()
+		server.handleTasks(rec, httptest.NewRequest(http.MethodPost, "/v1/tasks", bytes.NewReader(raw)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create task: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+
+		select {
+		case rec := <-done:
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected the waiting claim to get the task, got %d: %s", rec.Code, rec.Body.String())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("waiting claim was not woken by the new task")
+		}
+	})
+
+	t.Run("TimesOut", func(t *testing.T) {
+		start := time.Now()
+		rec := httptest.NewRecorder()
+		server.handleTasksClaim(rec, claimReq(ctx, 1))
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusNotFound, rec.Code, rec.Body.String())
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Fatalf("expected the claim to wait about a second, returned after %v", elapsed)
+		}
+	})
+
+	t.Run("StopsOnDisconnect", func(t *testing.T) {
+		reqCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			server.handleTasksClaim(httptest.NewRecorder(), claimReq(reqCtx, 30))
+			close(done)
+		}()
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("waiting claim did not return after the client went away")
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+type matchingAgent struct {
+	ID     string            `json:"id"`
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// handleTaskClaimability serves GET /v1/tasks/{id}/claimability. It lists every reason the
+// task cannot be claimed right now (for ?agent_id= when given, otherwise for any agent) and
+// the subscribed agents whose labels satisfy the task's requires.
+func (s *Server) handleTaskClaimability(w http.ResponseWriter, r *http.Request) {
+	taskID := strings.TrimSpace(r.PathValue("id"))
+	if taskID == "" {
+		writeError(w, http.StatusBadRequest, "task_id_required", "task ID is required")
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+
+	tasks, err := s.store.ListTasks(r.Context(), store.TaskFilter{IDs: []string{taskID}, UserID: userID})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to list tasks")
+		return
+	}
+	if len(tasks) == 0 {
+		writeError(w, http.StatusNotFound, "not_found", "task not found")
+		return
+	}
+	task := &tasks[0]
+
+	chain, err := s.store.GetChain(r.Context(), task.ChainID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to get chain")
+		return
+	}
+	channel, err := s.store.GetChannel(r.Context(), task.ChannelID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to get channel")
+		return
+	}
+	chainTasks, err := s.store.ListTasks(r.Context(), store.TaskFilter{ChainID: task.ChainID})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to list chain tasks")
+		return
+	}
+
+	check := store.ClaimCheck{
+		Task:       *task,
+		Chain:      chain,
+		Channel:    channel,
+		ChainTasks: chainTasks,
+		Now:        time.Now().UTC(),
+	}
+
+	var notSubscribed *store.ClaimBlocker
+	if agentID := strings.TrimSpace(r.URL.Query().Get("agent_id")); agentID != "" {
+		agent, err := s.store.GetAgent(r.Context(), agentID)
+		if err != nil {
+			if err == store.ErrNotFound {
+				writeError(w, http.StatusNotFound, "not_found", "agent not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal", "failed to get agent")
+			return
+		}
+		if agent.UserID != userID {
+			writeError(w, http.StatusNotFound, "not_found", "agent not found")
+			return
+		}
+		chains, err := s.store.ListChains(r.Context(), userID, "")
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", "failed to list chains")
+			return
+		}
+		for _, c := range chains {
+			if c.OwnerAgentID == agent.ID {
+				check.AgentOwnedChainID = c.ID
+				break
+			}
+		}
+		check.Agent = agent
+		if !hasChannelSubscription(agent, channel.Name) {
+			notSubscribed = &store.ClaimBlocker{Code: "not_subscribed", Message: fmt.Sprintf("agent %s is not subscribed to channel %s", agent.ID, channel.Name)}
+		}
+	}
+
+	reasons := store.ClaimBlockers(check)
+	if notSubscribed != nil {
+		reasons = append(reasons, *notSubscribed)
+	}
+	if task.Status == model.TaskStatusQueued {
+		limited, err := s.concurrencyBlocker(r, channel)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", "failed to count tasks in progress")
+			return
+		}
+		if limited != nil {
+			reasons = append(reasons, *limited)
+		}
+	}
+	if reasons == nil {
+		reasons = []store.ClaimBlocker{}
+	}
+
+	agents, err := s.store.ListAgents(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to list agents")
+		return
+	}
+	matching := []matchingAgent{}
+	for i := range agents {
+		a := &agents[i]
+		if hasChannelSubscription(a, channel.Name) && store.LabelsMatch(a, *task) {
+			matching = append(matching, matchingAgent{ID: a.ID, Name: a.Name, Labels: a.Labels})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"task_id":         task.ID,
+		"claimable":       len(reasons) == 0,
+		"reasons":         reasons,
+		"matching_agents": matching,
+	})
+}
+
+// concurrencyBlocker reports the channel or user in-flight limit that would turn a claim
+// on channel away right now, if any.
+func (s *Server) concurrencyBlocker(r *http.Request, channel model.Channel) (*store.ClaimBlocker, error) {
+	userLimit := s.cfg.UserMaxInFlight
+	if channel.UserID != "" {
+		owner, err := s.store.GetUserByID(r.Context(), channel.UserID)
+		if err != nil && err != store.ErrNotFound {
+			return nil, err
+		}
+		userLimit = store.UserMaxInFlight(owner, userLimit)
+	}
+	if channel.MaxInFlight <= 0 && (userLimit <= 0 || channel.UserID == "") {
+		return nil, nil
+	}
+	inChannel, err := s.store.ListTasks(r.Context(), store.TaskFilter{ChannelID: channel.ID, Status: model.TaskStatusInProgress})
+	if err != nil {
+		return nil, err
+	}
+	var ofUser []model.Task
+	if userLimit > 0 && channel.UserID != "" {
+		if ofUser, err = s.store.ListTasks(r.Context(), store.TaskFilter{UserID: channel.UserID, Status: model.TaskStatusInProgress}); err != nil {
+			return nil, err
+		}
+	}
+	err = store.CheckConcurrency(channel, len(inChannel), userLimit, len(ofUser))
+	if err == nil {
+		return nil, nil
+	}
+	return &store.ClaimBlocker{Code: "concurrency_limited", Message: err.Error()}, nil
+}
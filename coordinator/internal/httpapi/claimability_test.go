@@ -0,0 +1,95 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+func TestHandleTaskClaimability(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "gpu-jobs"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	chain, tasks, err := server.store.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "train"}, []store.NewChainTask{
+		{Task: model.Task{Title: "train model", Requires: map[string]string{"gpu": "true"}}},
+	})
+	if err != nil {
+		t.Fatalf("create chain: %v", err)
+	}
+	subscribed := map[string]any{"subscriptions": []string{"gpu-jobs"}}
+	laptop := "11111111-1111-4111-8111-111111111111"
+	buildBox := "22222222-2222-4222-8222-222222222222"
+	if _, err := server.store.UpsertAgent(ctx, model.Agent{ID: laptop, Name: "laptop", Meta: subscribed}); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+	if _, err := server.store.UpsertAgent(ctx, model.Agent{ID: buildBox, Name: "build-box", Meta: subscribed, Labels: map[string]string{"gpu": "true"}}); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+
+	type claimability struct {
+		Claimable bool                 `json:"claimable"`
+		Reasons   []store.ClaimBlocker `json:"reasons"`
+		Matching  []matchingAgent      `json:"matching_agents"`
+	}
+	get := func(query string) claimability {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/tasks/"+tasks[0].ID+"/claimability"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("claimability: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var out claimability
+		if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return out
+	}
+
+	if got := get(""); !got.Claimable || len(got.Matching) != 1 || got.Matching[0].ID != buildBox {
+		t.Fatalf("expected the task to be claimable by the build box only, got %+v", got)
+	}
+	if got := get("?agent_id=" + laptop); got.Claimable || len(got.Reasons) != 1 || got.Reasons[0].Code != "labels_missing" {
+		t.Fatalf("expected labels_missing for the laptop, got %+v", got)
+	}
+
+	// Other users' agents and tasks are not found.
+	otherUser := "99999999-9999-4999-8999-999999999999"
+	foreign := "33333333-3333-4333-8333-333333333333"
+	if _, err := server.store.UpsertAgent(ctx, model.Agent{ID: foreign, Name: "foreign", UserID: otherUser, Meta: subscribed}); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/tasks/"+tasks[0].ID+"/claimability?agent_id="+foreign, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("another user's agent: expected status %d, got %d: %s", http.StatusNotFound, rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/tasks/"+tasks[0].ID+"/claimability", nil)
+	server.mux.ServeHTTP(rec, req.WithContext(context.WithValue(req.Context(), ctxUserID, otherUser)))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("another user's task: expected status %d, got %d: %s", http.StatusNotFound, rec.Code, rec.Body.String())
+	}
+
+	assign := func(agentID string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"agent_id": agentID})
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/chains/"+chain.ID+"/assign-agent", bytes.NewReader(body)))
+		return rec
+	}
+	if rec := assign(laptop); rec.Code != http.StatusConflict || !bytes.Contains(rec.Body.Bytes(), []byte("agent_labels_mismatch")) {
+		t.Fatalf("assigning the laptop: expected agent_labels_mismatch, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := assign(buildBox); rec.Code != http.StatusOK {
+		t.Fatalf("assigning the build box: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+}
@@ -29,8 +29,8 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Compute worker_status for each agent (30 second threshold = 2x heartbeat interval)
-	threshold := 30 * time.Second
+	// Compute worker_status for each agent (default 30 second threshold = 2x heartbeat interval)
+	threshold := s.cfg.AgentOfflineThreshold()
 	agentResponses := make([]agentResponse, len(agents))
 	for i, a := range agents {
 		agentResponses[i] = agentResponse{
@@ -69,6 +69,7 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 		"chains":   chains,
 		"tasks":    tasks,
 		"events":   events,
+		"paused":   pauseSummary(channels, chains, tasks),
 	}
 
 	b, err := json.Marshal(resp)
@@ -4,7 +4,9 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
@@ -18,10 +20,15 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{
+	res := map[string]any{
 		"ok":   true,
 		"time": time.Now().UTC().Format(time.RFC3339Nano),
-	})
+	}
+	if s.elector != nil {
+		// Whether this replica runs the singleton background jobs.
+		res["leader"] = s.elector.IsLeader()
+	}
+	writeJSON(w, http.StatusOK, res)
 }
 
 type agentsHeartbeatRequest struct {
@@ -31,6 +38,7 @@ type agentsHeartbeatRequest struct {
 	ClaudeStatus  model.ClaudeStatus `json:"claude_status"`
 	CurrentTaskID string             `json:"current_task_id"`
 	Meta          map[string]any     `json:"meta"`
+	Labels        map[string]string  `json:"labels"` // Omitted: keep the labels reported before
 }
 
 func (s *Server) handleAgentsHeartbeat(w http.ResponseWriter, r *http.Request) {
@@ -51,6 +59,12 @@ func (s *Server) handleAgentsHeartbeat(w http.ResponseWriter, r *http.Request) {
 		claudeStatus = model.ClaudeStatus(req.Status)
 	}
 
+	labels, err := store.NormalizeLabels(req.Labels)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "label_invalid", err.Error())
+		return
+	}
+
 	userID := userIDFromContext(r.Context())
 
 	a := model.Agent{
@@ -61,6 +75,7 @@ func (s *Server) handleAgentsHeartbeat(w http.ResponseWriter, r *http.Request) {
 		ClaudeStatus:  claudeStatus, // New field
 		CurrentTaskID: strings.TrimSpace(req.CurrentTaskID),
 		Meta:          req.Meta,
+		Labels:        labels,
 	}
 
 	agent, err := s.store.UpsertAgent(r.Context(), a)
@@ -69,12 +84,22 @@ func (s *Server) handleAgentsHeartbeat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.bus.Publish(EventAgents, userID)
+	// Heartbeats for an in-flight task keep its claim lease alive (best-effort:
+	// the task may already be done or requeued by the lease reaper).
+	if s.cfg.TaskLeaseSeconds > 0 && a.CurrentTaskID != "" {
+		_, _ = s.store.RenewTaskLease(r.Context(), store.RenewTaskLeaseRequest{
+			TaskID:       a.CurrentTaskID,
+			AgentID:      agent.ID,
+			LeaseSeconds: s.cfg.TaskLeaseSeconds,
+		})
+	}
+
+	s.bus.PublishIDs(EventAgents, userID, agent.ID)
 	s.invalidateDashboardCache()
 
 	// Detect setup_waiting state and store + publish notification
 	metaState, _ := a.Meta["state"].(string)
-	if metaState == "setup_waiting" {
+	if metaState == notificationTypeSetupWaiting {
 		firstChan := ""
 		subs, _ := a.Meta["subscriptions"].([]any)
 		if len(subs) > 0 {
@@ -87,29 +112,18 @@ func (s *Server) handleAgentsHeartbeat(w http.ResponseWriter, r *http.Request) {
 			msg += " Start one?"
 		}
 
-		notif := Notification{
-			Key:       a.ID + ":setup_waiting",
+		s.raiseNotification(r.Context(), model.Notification{
+			Key:       notificationKey(a.ID, notificationTypeSetupWaiting),
 			UserID:    userID,
+			Type:      notificationTypeSetupWaiting,
+			Severity:  model.NotificationSeverityInfo,
+			Message:   msg,
 			AgentID:   a.ID,
 			AgentName: a.Name,
-			Type:      "setup_waiting",
 			Channel:   firstChan,
-			Message:   msg,
-			CreatedAt: time.Now().UTC(),
-		}
-		s.notifTk.Add(notif)
-
-		if s.notifTk.ShouldNotify(a.ID, "setup_waiting") {
-			s.bus.PublishWithPayload(EventNotification, userID, map[string]any{
-				"notification_type": "setup_waiting",
-				"agent_id":          a.ID,
-				"agent_name":        a.Name,
-				"channel":           firstChan,
-				"message":           msg,
-			})
-		}
+		}, notificationCooldown)
 	} else {
-		s.notifTk.ClearByAgent(a.ID, "setup_waiting")
+		s.resolveNotification(r.Context(), "", notificationKey(a.ID, notificationTypeSetupWaiting))
 	}
 
 	writeJSON(w, http.StatusOK, map[string]any{"agent": agent})
@@ -133,8 +147,8 @@ func (s *Server) handleAgentsList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Compute worker_status for each agent (30 second threshold = 2x heartbeat interval)
-	threshold := 30 * time.Second
+	// Compute worker_status for each agent (default 30 second threshold = 2x heartbeat interval)
+	threshold := s.cfg.AgentOfflineThreshold()
 	response := make([]agentResponse, len(agents))
 	for i, a := range agents {
 		response[i] = agentResponse{
@@ -217,7 +231,7 @@ func (s *Server) handleAgentsRequestSession(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	s.bus.Publish(EventTasks, userID)
+	s.bus.PublishIDs(EventTasks, userID, task.ID)
 	s.invalidateDashboardCache()
 	writeJSON(w, http.StatusCreated, map[string]any{"task": task})
 }
@@ -311,7 +325,7 @@ func (s *Server) handleAgentUpdateChannels(w http.ResponseWriter, r *http.Reques
 	}
 
 	userID := userIDFromContext(r.Context())
-	s.bus.Publish(EventAgents, userID)
+	s.bus.PublishIDs(EventAgents, userID, updated.ID)
 	s.invalidateDashboardCache()
 	writeJSON(w, http.StatusOK, map[string]any{"agent": updated})
 }
@@ -377,8 +391,19 @@ func (s *Server) handleAgentCurrentTask(w http.ResponseWriter, r *http.Request)
 }
 
 type createChannelRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name                string `json:"name"`
+	Description         string `json:"description"`
+	RetryMaxAttempts    int    `json:"retry_max_attempts"`
+	RetryBackoffSeconds int    `json:"retry_backoff_seconds"`
+	MaxInFlight         int    `json:"max_in_flight"` // 0 = no limit
+}
+
+// updateChannelRequest uses pointers so omitted fields keep their current value.
+type updateChannelRequest struct {
+	Description         *string `json:"description"`
+	RetryMaxAttempts    *int    `json:"retry_max_attempts"`
+	RetryBackoffSeconds *int    `json:"retry_backoff_seconds"`
+	MaxInFlight         *int    `json:"max_in_flight"`
 }
 
 func (s *Server) handleChannels(w http.ResponseWriter, r *http.Request) {
@@ -402,9 +427,12 @@ func (s *Server) handleChannels(w http.ResponseWriter, r *http.Request) {
 		}
 
 		ch, err := s.store.CreateChannel(r.Context(), model.Channel{
-			UserID:      userID,
-			Name:        strings.TrimSpace(req.Name),
-			Description: strings.TrimSpace(req.Description),
+			UserID:              userID,
+			Name:                strings.TrimSpace(req.Name),
+			Description:         strings.TrimSpace(req.Description),
+			RetryMaxAttempts:    req.RetryMaxAttempts,
+			RetryBackoffSeconds: req.RetryBackoffSeconds,
+			MaxInFlight:         req.MaxInFlight,
 		})
 		if err != nil {
 			status := http.StatusBadRequest
@@ -415,7 +443,7 @@ func (s *Server) handleChannels(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		s.bus.Publish(EventChannels, userID)
+		s.bus.PublishIDs(EventChannels, userID, ch.ID)
 		s.invalidateDashboardCache()
 		writeJSON(w, http.StatusCreated, map[string]any{"channel": ch})
 		return
@@ -426,6 +454,84 @@ func (s *Server) handleChannels(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) handleChannel(w http.ResponseWriter, r *http.Request) {
+	channelID := strings.TrimSpace(r.PathValue("id"))
+	if channelID == "" {
+		writeError(w, http.StatusBadRequest, "channel_id_required", "channel ID is required")
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		ch, err := s.store.GetChannel(r.Context(), channelID)
+		if err != nil {
+			if err == store.ErrNotFound {
+				writeError(w, http.StatusNotFound, "not_found", "channel not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal", "failed to get channel")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"channel": ch})
+		return
+
+	case http.MethodPatch:
+		var req updateChannelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+			return
+		}
+
+		ch, err := s.store.GetChannel(r.Context(), channelID)
+		if err != nil {
+			if err == store.ErrNotFound {
+				writeError(w, http.StatusNotFound, "not_found", "channel not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal", "failed to get channel")
+			return
+		}
+		// Only the owner may change a channel, its in-flight limit included.
+		if userID != "" && ch.UserID != userID {
+			writeError(w, http.StatusNotFound, "not_found", "channel not found")
+			return
+		}
+		if req.Description != nil {
+			ch.Description = strings.TrimSpace(*req.Description)
+		}
+		if req.RetryMaxAttempts != nil {
+			ch.RetryMaxAttempts = *req.RetryMaxAttempts
+		}
+		if req.RetryBackoffSeconds != nil {
+			ch.RetryBackoffSeconds = *req.RetryBackoffSeconds
+		}
+		if req.MaxInFlight != nil {
+			ch.MaxInFlight = *req.MaxInFlight
+		}
+
+		ch, err = s.store.UpdateChannel(r.Context(), ch)
+		if err != nil {
+			status := http.StatusBadRequest
+			if err == store.ErrNotFound {
+				status = http.StatusNotFound
+			}
+			writeError(w, status, "invalid_request", err.Error())
+			return
+		}
+
+		s.bus.PublishIDs(EventChannels, userID, ch.ID)
+		s.invalidateDashboardCache()
+		writeJSON(w, http.StatusOK, map[string]any{"channel": ch})
+		return
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+}
+
 func (s *Server) handleGetChannelByName(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
@@ -452,16 +558,18 @@ func (s *Server) handleGetChannelByName(w http.ResponseWriter, r *http.Request)
 }
 
 type createChainRequest struct {
-	ChannelID   string            `json:"channel_id"`
-	Name        string            `json:"name"`
-	Description string            `json:"description"`
-	Status      model.ChainStatus `json:"status"`
+	ChannelID   string              `json:"channel_id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Status      model.ChainStatus   `json:"status"`
+	OnFailure   model.FailurePolicy `json:"on_failure,omitempty"` // halt (default) | continue | compensate
 }
 
 type updateChainRequest struct {
-	Name        string            `json:"name"`
-	Description string            `json:"description"`
-	Status      model.ChainStatus `json:"status"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Status      model.ChainStatus   `json:"status"`
+	OnFailure   model.FailurePolicy `json:"on_failure,omitempty"` // Empty keeps the current policy
 }
 
 func (s *Server) handleChains(w http.ResponseWriter, r *http.Request) {
@@ -491,6 +599,7 @@ func (s *Server) handleChains(w http.ResponseWriter, r *http.Request) {
 			Name:        strings.TrimSpace(req.Name),
 			Description: strings.TrimSpace(req.Description),
 			Status:      req.Status,
+			OnFailure:   req.OnFailure,
 		})
 		if err != nil {
 			status := http.StatusBadRequest
@@ -503,7 +612,7 @@ func (s *Server) handleChains(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		s.bus.Publish(EventChains, userID)
+		s.bus.PublishIDs(EventChains, userID, chain.ID)
 		s.invalidateDashboardCache()
 		writeJSON(w, http.StatusCreated, map[string]any{"chain": chain})
 		return
@@ -514,6 +623,92 @@ func (s *Server) handleChains(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type bulkChainTaskRequest struct {
+	Title               string              `json:"title"`
+	Description         string              `json:"description"`
+	Type                string              `json:"type,omitempty"` // "" or "approval"
+	Compensation        bool                `json:"compensation"`   // Runs only after a task of the chain failed
+	Requires            map[string]string   `json:"requires"`       // Agent labels needed to claim the task
+	Priority            int                 `json:"priority"`
+	ExecutionMode       model.ExecutionMode `json:"execution_mode,omitempty"`
+	MaxAttempts         int                 `json:"max_attempts"`
+	RetryBackoffSeconds int                 `json:"retry_backoff_seconds"`
+	DependsOn           []int               `json:"depends_on"` // Indexes into tasks; empty = sequence order
+	NotBefore           *time.Time          `json:"not_before"`
+}
+
+type bulkChainRequest struct {
+	ChannelID   string                 `json:"channel_id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	OnFailure   model.FailurePolicy    `json:"on_failure,omitempty"`
+	Tasks       []bulkChainTaskRequest `json:"tasks"`
+}
+
+// handleChainsBulk creates a chain and all of its tasks in one store call. Sequences are
+// assigned from the order of tasks, so nothing is left behind if any task is invalid.
+func (s *Server) handleChainsBulk(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	var req bulkChainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+		return
+	}
+
+	tasks := make([]store.NewChainTask, len(req.Tasks))
+	for i, t := range req.Tasks {
+		typ := strings.TrimSpace(t.Type)
+		if !store.ValidTaskType(typ) {
+			writeError(w, http.StatusBadRequest, "invalid_request", "task type must be empty or \"approval\"")
+			return
+		}
+		requires, err := store.NormalizeLabels(t.Requires)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "label_invalid", err.Error())
+			return
+		}
+		tasks[i] = store.NewChainTask{
+			Task: model.Task{
+				Title:               strings.TrimSpace(t.Title),
+				Description:         strings.TrimSpace(t.Description),
+				Type:                typ,
+				Compensation:        t.Compensation,
+				Requires:            requires,
+				Priority:            t.Priority,
+				ExecutionMode:       t.ExecutionMode,
+				MaxAttempts:         t.MaxAttempts,
+				RetryBackoffSeconds: t.RetryBackoffSeconds,
+				NotBefore:           t.NotBefore,
+			},
+			DependsOn: t.DependsOn,
+		}
+	}
+
+	chain, created, err := s.store.CreateChainWithTasks(r.Context(), model.Chain{
+		UserID:      userID,
+		ChannelID:   strings.TrimSpace(req.ChannelID),
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		Status:      model.ChainStatusQueued,
+		OnFailure:   req.OnFailure,
+	}, tasks)
+	if err != nil {
+		status := http.StatusBadRequest
+		if err == store.ErrNotFound { // channel_id not found
+			status = http.StatusNotFound
+		}
+		writeError(w, status, "invalid_request", err.Error())
+		return
+	}
+
+	// One notification for the whole batch; the stream relays it as a single update.
+	s.bus.PublishWithPayload(EventChains, userID, map[string]any{"ids": []string{chain.ID}, "chain_id": chain.ID, "tasks": len(created)})
+	s.invalidateDashboardCache()
+	s.notifyApprovalGates(r.Context(), chain.ID)
+	writeJSON(w, http.StatusCreated, map[string]any{"chain": chain, "tasks": created})
+}
+
 func (s *Server) handleChain(w http.ResponseWriter, r *http.Request) {
 	chainID := strings.TrimSpace(r.PathValue("id"))
 	if chainID == "" {
@@ -549,6 +744,7 @@ func (s *Server) handleChain(w http.ResponseWriter, r *http.Request) {
 			Name:        strings.TrimSpace(req.Name),
 			Description: strings.TrimSpace(req.Description),
 			Status:      req.Status,
+			OnFailure:   req.OnFailure,
 		})
 		if err != nil {
 			status := http.StatusBadRequest
@@ -561,7 +757,7 @@ func (s *Server) handleChain(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		s.bus.Publish(EventChains, userID)
+		s.bus.PublishIDs(EventChains, userID, chain.ID)
 		s.invalidateDashboardCache()
 		writeJSON(w, http.StatusOK, map[string]any{"chain": chain})
 		return
@@ -577,7 +773,7 @@ func (s *Server) handleChain(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		s.bus.Publish(EventChains, userID)
+		s.bus.PublishIDs(EventChains, userID, chainID)
 		s.invalidateDashboardCache()
 		w.WriteHeader(http.StatusNoContent)
 		return
@@ -608,7 +804,20 @@ func (s *Server) handleChainDetach(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := s.store.DetachAgentFromChain(r.Context(), store.DetachAgentFromChainRequest{
+	// The agent's in-flight task is locked by the detach; remember it for the event.
+	inFlight, err := s.store.ListTasks(r.Context(), store.TaskFilter{ChainID: chainID, Status: model.TaskStatusInProgress})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to list chain tasks")
+		return
+	}
+	var taskIDs []string
+	for _, t := range inFlight {
+		if t.AssignedAgentID == strings.TrimSpace(req.AgentID) {
+			taskIDs = append(taskIDs, t.ID)
+		}
+	}
+
+	err = s.store.DetachAgentFromChain(r.Context(), store.DetachAgentFromChainRequest{
 		ChainID: chainID,
 		AgentID: req.AgentID,
 	})
@@ -625,23 +834,32 @@ func (s *Server) handleChainDetach(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.notifyChainDetached(strings.TrimSpace(req.AgentID), chainID, "detached")
+
 	userID := userIDFromContext(r.Context())
-	s.bus.Publish(EventChains, userID)
-	s.bus.Publish(EventTasks, userID)
-	s.bus.Publish(EventAgents, userID)
+	s.bus.PublishIDs(EventChains, userID, chainID)
+	s.bus.PublishIDs(EventTasks, userID, taskIDs...)
+	s.bus.PublishIDs(EventAgents, userID, req.AgentID)
 	s.invalidateDashboardCache()
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
 type createTaskRequest struct {
-	ChannelID     string              `json:"channel_id"`
-	ChainID       string              `json:"chain_id"` // New field for chain association
-	Sequence      int                 `json:"sequence"` // New field for order within a chain
-	Title         string              `json:"title"`
-	Description   string              `json:"description"`
-	Priority      int                 `json:"priority"`
-	Status        model.TaskStatus    `json:"status"`
-	ExecutionMode model.ExecutionMode `json:"execution_mode,omitempty"` // Claude Code execution mode
+	ChannelID           string              `json:"channel_id"`
+	ChainID             string              `json:"chain_id"` // New field for chain association
+	Sequence            int                 `json:"sequence"` // New field for order within a chain
+	Title               string              `json:"title"`
+	Description         string              `json:"description"`
+	Type                string              `json:"type,omitempty"` // "" or "approval" (human approval gate)
+	Compensation        bool                `json:"compensation"`   // Runs only after a task of the chain failed
+	Requires            map[string]string   `json:"requires"`       // Agent labels needed to claim the task
+	Priority            int                 `json:"priority"`
+	Status              model.TaskStatus    `json:"status"`
+	ExecutionMode       model.ExecutionMode `json:"execution_mode,omitempty"` // Claude Code execution mode
+	MaxAttempts         int                 `json:"max_attempts"`             // 0 = use channel retry policy
+	RetryBackoffSeconds int                 `json:"retry_backoff_seconds"`    // 0 = use channel retry policy
+	DependsOn           []string            `json:"depends_on"`               // Task IDs in the same chain; empty = sequence order
+	NotBefore           *time.Time          `json:"not_before"`               // Scheduled start (RFC 3339); nil = claimable now
 }
 
 func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
@@ -673,6 +891,15 @@ func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
 			return
 		}
+		if !store.ValidTaskType(strings.TrimSpace(req.Type)) {
+			writeError(w, http.StatusBadRequest, "invalid_request", "task type must be empty or \"approval\"")
+			return
+		}
+		requires, err := store.NormalizeLabels(req.Requires)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "label_invalid", err.Error())
+			return
+		}
 
 		// Check if ChainID is empty, if so, create a new chain for this task
 		if strings.TrimSpace(req.ChainID) == "" {
@@ -708,15 +935,22 @@ func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
 		}
 
 		t, err := s.store.CreateTask(r.Context(), model.Task{
-			UserID:        userID,
-			ChannelID:     strings.TrimSpace(req.ChannelID),
-			ChainID:       strings.TrimSpace(req.ChainID),
-			Sequence:      req.Sequence,
-			Title:         strings.TrimSpace(req.Title),
-			Description:   strings.TrimSpace(req.Description),
-			Priority:      req.Priority,
-			Status:        req.Status,
-			ExecutionMode: req.ExecutionMode,
+			UserID:              userID,
+			ChannelID:           strings.TrimSpace(req.ChannelID),
+			ChainID:             strings.TrimSpace(req.ChainID),
+			Sequence:            req.Sequence,
+			Title:               strings.TrimSpace(req.Title),
+			Description:         strings.TrimSpace(req.Description),
+			Type:                strings.TrimSpace(req.Type),
+			Compensation:        req.Compensation,
+			Requires:            requires,
+			Priority:            req.Priority,
+			Status:              req.Status,
+			ExecutionMode:       req.ExecutionMode,
+			MaxAttempts:         req.MaxAttempts,
+			RetryBackoffSeconds: req.RetryBackoffSeconds,
+			DependsOn:           req.DependsOn,
+			NotBefore:           req.NotBefore,
 		})
 		if err != nil {
 			status := http.StatusBadRequest
@@ -728,8 +962,9 @@ func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
 			writeError(w, status, "invalid_request", err.Error())
 			return
 		}
-		s.bus.Publish(EventTasks, userID)
+		s.bus.PublishIDs(EventTasks, userID, t.ID)
 		s.invalidateDashboardCache()
+		s.notifyApprovalGates(r.Context(), t.ChainID)
 		writeJSON(w, http.StatusCreated, map[string]any{"task": t})
 		return
 
@@ -744,6 +979,7 @@ type claimTaskRequest struct {
 	ChannelID      string `json:"channel_id"`
 	Channel        string `json:"channel"`
 	IdempotencyKey string `json:"idempotency_key"`
+	Wait           int    `json:"wait"` // Seconds to hold the request open until a task is claimable (also ?wait=)
 }
 
 func (s *Server) handleTasksClaim(w http.ResponseWriter, r *http.Request) {
@@ -760,16 +996,31 @@ func (s *Server) handleTasksClaim(w http.ResponseWriter, r *http.Request) {
 
 	userID := userIDFromContext(r.Context())
 
-	t, err := s.store.ClaimTask(r.Context(), store.ClaimTaskRequest{
-		AgentID:        strings.TrimSpace(req.AgentID),
-		ChannelID:      strings.TrimSpace(req.ChannelID),
-		Channel:        strings.TrimSpace(req.Channel),
-		IdempotencyKey: strings.TrimSpace(req.IdempotencyKey),
-	})
+	claimReq := store.ClaimTaskRequest{
+		AgentID:              strings.TrimSpace(req.AgentID),
+		ChannelID:            strings.TrimSpace(req.ChannelID),
+		Channel:              strings.TrimSpace(req.Channel),
+		IdempotencyKey:       strings.TrimSpace(req.IdempotencyKey),
+		LeaseSeconds:         s.cfg.TaskLeaseSeconds,
+		PriorityAgingSeconds: s.cfg.PriorityAgingSec,
+		UserMaxInFlight:      s.cfg.UserMaxInFlight,
+	}
+	t, err := s.claimWithWait(r.Context(), userID, claimReq, claimWaitFromRequest(r, req.Wait))
+	if err != nil && r.Context().Err() != nil {
+		// The agent hung up while waiting; there is no one left to answer.
+		return
+	}
 	if err != nil {
+		var limited *store.ConcurrencyLimitError
+		if errors.As(err, &limited) {
+			writeConcurrencyLimited(w, limited)
+			return
+		}
 		switch err {
 		case store.ErrNoQueuedTasks:
-			writeError(w, http.StatusNotFound, "no_tasks", "no queued tasks")
+			// Best effort: a failed hint lookup still reports no_tasks.
+			next, _ := s.store.NextClaimableAt(r.Context(), claimReq)
+			writeNoTasks(w, next)
 		case store.ErrConflict:
 			writeError(w, http.StatusConflict, "conflict", "duplicate claim (idempotency)")
 		default:
@@ -778,12 +1029,45 @@ func (s *Server) handleTasksClaim(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.bus.Publish(EventTasks, userID)
-	s.bus.Publish(EventAgents, userID)
+	s.bus.PublishIDs(EventTasks, userID, t.ID)
+	s.bus.PublishIDs(EventAgents, userID, t.AssignedAgentID)
 	s.invalidateDashboardCache()
 	writeJSON(w, http.StatusOK, map[string]any{"task": t})
 }
 
+// writeNoTasks writes the no_tasks error. When a task is only waiting for its not_before
+// or retry backoff, next_eligible_at (and Retry-After) tell the agent how long to sleep.
+func writeNoTasks(w http.ResponseWriter, next *time.Time) {
+	var res struct {
+		errorResponse
+		NextEligibleAt *time.Time `json:"next_eligible_at,omitempty"`
+	}
+	res.Error.Code = "no_tasks"
+	res.Error.Message = "no queued tasks"
+	if next != nil {
+		res.NextEligibleAt = next
+		wait := int(time.Until(*next).Seconds()) + 1
+		if wait < 1 {
+			wait = 1
+		}
+		w.Header().Set("Retry-After", fmt.Sprint(wait))
+	}
+	writeJSON(w, http.StatusNotFound, res)
+}
+
+// writeConcurrencyLimited writes the 429 concurrency_limited error with the occupancy of
+// the channel or user whose limit was reached.
+func writeConcurrencyLimited(w http.ResponseWriter, limited *store.ConcurrencyLimitError) {
+	var res struct {
+		errorResponse
+		Occupancy *store.ConcurrencyLimitError `json:"occupancy"`
+	}
+	res.Error.Code = "concurrency_limited"
+	res.Error.Message = fmt.Sprintf("%s has %d of %d tasks in progress", limited.Scope, limited.InFlight, limited.Limit)
+	res.Occupancy = limited
+	writeJSON(w, http.StatusTooManyRequests, res)
+}
+
 type assignTaskRequest struct {
 	TaskID         string `json:"task_id"`
 	AgentID        string `json:"agent_id"`
@@ -808,6 +1092,7 @@ func (s *Server) handleTasksAssign(w http.ResponseWriter, r *http.Request) {
 		TaskID:         strings.TrimSpace(req.TaskID),
 		AgentID:        strings.TrimSpace(req.AgentID),
 		IdempotencyKey: strings.TrimSpace(req.IdempotencyKey),
+		LeaseSeconds:   s.cfg.TaskLeaseSeconds,
 	})
 	if err != nil {
 		switch err {
@@ -821,16 +1106,20 @@ func (s *Server) handleTasksAssign(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.bus.Publish(EventTasks, userID)
-	s.bus.Publish(EventAgents, userID)
+	s.notifyTaskAssigned(t)
+
+	s.bus.PublishIDs(EventTasks, userID, t.ID)
+	s.bus.PublishIDs(EventAgents, userID, t.AssignedAgentID)
 	s.invalidateDashboardCache()
 	writeJSON(w, http.StatusOK, map[string]any{"task": t})
 }
 
 type completeTaskRequest struct {
-	TaskID         string `json:"task_id"`
-	AgentID        string `json:"agent_id"`
-	IdempotencyKey string `json:"idempotency_key"`
+	TaskID         string               `json:"task_id"`
+	AgentID        string               `json:"agent_id"`
+	IdempotencyKey string               `json:"idempotency_key"`
+	Result         map[string]any       `json:"result"`    // optional structured outcome (summary, diff stats, PR URL, ...)
+	Artifacts      []model.TaskArtifact `json:"artifacts"` // optional named outputs
 }
 
 func (s *Server) handleTasksComplete(w http.ResponseWriter, r *http.Request) {
@@ -851,6 +1140,8 @@ func (s *Server) handleTasksComplete(w http.ResponseWriter, r *http.Request) {
 		TaskID:         strings.TrimSpace(req.TaskID),
 		AgentID:        strings.TrimSpace(req.AgentID),
 		IdempotencyKey: strings.TrimSpace(req.IdempotencyKey),
+		Result:         req.Result,
+		Artifacts:      req.Artifacts,
 	})
 	if err != nil {
 		switch err {
@@ -864,17 +1155,20 @@ func (s *Server) handleTasksComplete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.bus.Publish(EventTasks, userID)
-	s.bus.Publish(EventAgents, userID)
+	s.bus.PublishIDs(EventTasks, userID, t.ID)
+	s.bus.PublishIDs(EventAgents, userID, strings.TrimSpace(req.AgentID), t.AssignedAgentID)
 	s.invalidateDashboardCache()
+	s.notifyApprovalGates(r.Context(), t.ChainID)
 	writeJSON(w, http.StatusOK, map[string]any{"task": t})
 }
 
 type failTaskRequest struct {
-	TaskID         string `json:"task_id"`
-	AgentID        string `json:"agent_id"`
-	Reason         string `json:"reason"`
-	IdempotencyKey string `json:"idempotency_key"`
+	TaskID         string               `json:"task_id"`
+	AgentID        string               `json:"agent_id"`
+	Reason         string               `json:"reason"`
+	IdempotencyKey string               `json:"idempotency_key"`
+	Result         map[string]any       `json:"result"`
+	Artifacts      []model.TaskArtifact `json:"artifacts"`
 }
 
 func (s *Server) handleTasksFail(w http.ResponseWriter, r *http.Request) {
@@ -896,6 +1190,8 @@ func (s *Server) handleTasksFail(w http.ResponseWriter, r *http.Request) {
 		AgentID:        strings.TrimSpace(req.AgentID),
 		Reason:         strings.TrimSpace(req.Reason),
 		IdempotencyKey: strings.TrimSpace(req.IdempotencyKey),
+		Result:         req.Result,
+		Artifacts:      req.Artifacts,
 	})
 	if err != nil {
 		switch err {
@@ -909,9 +1205,11 @@ func (s *Server) handleTasksFail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.bus.Publish(EventTasks, userID)
-	s.bus.Publish(EventAgents, userID)
+	s.bus.PublishIDs(EventTasks, userID, t.ID)
+	s.bus.PublishIDs(EventAgents, userID, strings.TrimSpace(req.AgentID), t.AssignedAgentID)
 	s.invalidateDashboardCache()
+	// A failure that does not halt the chain (on_failure=continue) can unblock a gate.
+	s.notifyApprovalGates(r.Context(), t.ChainID)
 	writeJSON(w, http.StatusOK, map[string]any{"task": t})
 }
 
@@ -956,8 +1254,9 @@ func (s *Server) handleTaskInputs(w http.ResponseWriter, r *http.Request) {
 		writeError(w, status, "invalid_request", err.Error())
 		return
 	}
+	s.pushTaskInputs(r.Context(), in.TaskID, in.AgentID)
 
-	s.bus.Publish(EventInputs, userID)
+	s.bus.PublishIDs(EventInputs, userID, in.ID)
 	s.invalidateDashboardCache()
 	writeJSON(w, http.StatusCreated, map[string]any{"input": in})
 }
@@ -997,7 +1296,7 @@ func (s *Server) handleTaskInputsClaim(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.bus.Publish(EventInputs, userID)
+	s.bus.PublishIDs(EventInputs, userID, in.ID)
 	s.invalidateDashboardCache()
 	writeJSON(w, http.StatusOK, map[string]any{"input": in})
 }
@@ -1046,6 +1345,11 @@ func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if err := s.checkEventArtifacts(r.Context(), userID, req.Payload); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_artifact", err.Error())
+			return
+		}
+
 		eventTaskID := strings.TrimSpace(req.TaskID)
 
 		if strings.EqualFold(strings.TrimSpace(req.Type), "agent.automation.session_request.completed") {
@@ -1130,8 +1434,8 @@ func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
 					writeError(w, http.StatusConflict, "conflict", fmt.Sprintf("failed to complete session request task: %v", err))
 					return
 				}
-				s.bus.Publish(EventTasks, userID)
-				s.bus.Publish(EventChains, userID)
+				s.bus.PublishIDs(EventTasks, userID, target.ID)
+				s.bus.PublishIDs(EventChains, userID, target.ChainID)
 			default:
 				writeError(w, http.StatusConflict, "conflict", "session request task is not in progress")
 				return
@@ -1155,7 +1459,7 @@ func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		s.bus.Publish(EventEvents, userID)
+		s.bus.PublishIDs(EventEvents, userID, e.ID)
 		s.invalidateDashboardCache()
 		writeJSON(w, http.StatusCreated, map[string]any{"event": e})
 		return
@@ -1248,12 +1552,233 @@ func (s *Server) handleTaskUpdateStatus(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	s.bus.Publish(EventTasks, userID)
-	s.bus.Publish(EventChains, userID)
+	s.bus.PublishIDs(EventTasks, userID, t.ID)
+	s.bus.PublishIDs(EventChains, userID, t.ChainID)
 	s.invalidateDashboardCache()
+	s.notifyApprovalGates(r.Context(), t.ChainID)
 	writeJSON(w, http.StatusOK, map[string]any{"task": t})
 }
 
+type taskRenewLeaseRequest struct {
+	AgentID string `json:"agent_id"`
+}
+
+func (s *Server) handleTaskRenewLease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+
+	taskID := strings.TrimSpace(r.PathValue("id"))
+	if taskID == "" {
+		writeError(w, http.StatusBadRequest, "task_id_required", "task ID is required")
+		return
+	}
+
+	var req taskRenewLeaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+		return
+	}
+
+	if s.cfg.TaskLeaseSeconds <= 0 {
+		writeError(w, http.StatusBadRequest, "leases_disabled", "task leases are disabled")
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+
+	t, err := s.store.RenewTaskLease(r.Context(), store.RenewTaskLeaseRequest{
+		TaskID:       taskID,
+		AgentID:      strings.TrimSpace(req.AgentID),
+		LeaseSeconds: s.cfg.TaskLeaseSeconds,
+	})
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			writeError(w, http.StatusNotFound, "not_found", "task not found")
+		case store.ErrConflict:
+			writeError(w, http.StatusConflict, "conflict", "task is not in progress for this agent")
+		default:
+			writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		}
+		return
+	}
+
+	s.bus.PublishIDs(EventTasks, userID, t.ID)
+	writeJSON(w, http.StatusOK, map[string]any{"task": t})
+}
+
+func (s *Server) handleTaskResubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+
+	taskID := strings.TrimSpace(r.PathValue("id"))
+	if taskID == "" {
+		writeError(w, http.StatusBadRequest, "task_id_required", "task ID is required")
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+
+	t, err := s.store.ResubmitTask(r.Context(), taskID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			writeError(w, http.StatusNotFound, "not_found", "task not found")
+		case store.ErrConflict:
+			writeError(w, http.StatusConflict, "conflict", "only dead_letter or failed tasks can be resubmitted")
+		default:
+			writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		}
+		return
+	}
+
+	s.bus.PublishIDs(EventTasks, userID, t.ID)
+	s.bus.PublishIDs(EventChains, userID, t.ChainID)
+	s.invalidateDashboardCache()
+	s.notifyApprovalGates(r.Context(), t.ChainID)
+	writeJSON(w, http.StatusOK, map[string]any{"task": t})
+}
+
+// handleTaskResult returns the result and artifacts reported for a task, plus the results
+// of the tasks it waits on (depends_on, or lower sequences) so a later task in the chain
+// can build on them. result is null until the task reports one.
+func (s *Server) handleTaskResult(w http.ResponseWriter, r *http.Request) {
+	taskID := strings.TrimSpace(r.PathValue("id"))
+	if taskID == "" {
+		writeError(w, http.StatusBadRequest, "task_id_required", "task ID is required")
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+
+	tasks, err := s.store.ListTasks(r.Context(), store.TaskFilter{IDs: []string{taskID}, UserID: userID})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to list tasks")
+		return
+	}
+	if len(tasks) == 0 {
+		writeError(w, http.StatusNotFound, "not_found", "task not found")
+		return
+	}
+	task := &tasks[0]
+
+	var result *model.TaskResult
+	if res, err := s.store.GetTaskResult(r.Context(), taskID); err == nil {
+		result = &res
+	} else if err != store.ErrNotFound {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to get task result")
+		return
+	}
+
+	predecessors := []model.TaskResult{}
+	if task.ChainID != "" {
+		chainTasks, err := s.store.ListTasks(r.Context(), store.TaskFilter{ChainID: task.ChainID})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", "failed to list chain tasks")
+			return
+		}
+		upstream := map[string]bool{}
+		for _, t := range store.Upstream(chainTasks, taskID) {
+			upstream[t.ID] = true
+		}
+		if len(upstream) > 0 {
+			results, err := s.store.ListTaskResults(r.Context(), task.ChainID)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "internal", "failed to list task results")
+				return
+			}
+			for _, res := range results {
+				if upstream[res.TaskID] {
+					predecessors = append(predecessors, res)
+				}
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"task_id":      taskID,
+		"result":       result,
+		"predecessors": predecessors,
+	})
+}
+
+type cancelTaskRequest struct {
+	Reason           string `json:"reason"`
+	CancelledBy      string `json:"cancelled_by"`      // Only used for shared-token access (no JWT username)
+	CancelDownstream bool   `json:"cancel_downstream"` // Also cancel waiting dependents instead of letting them run
+}
+
+// handleTaskCancel cancels a task. An agent working on it is sent a control input so it
+// interrupts the run; dependents are either released or cancelled as well.
+func (s *Server) handleTaskCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+
+	taskID := strings.TrimSpace(r.PathValue("id"))
+	if taskID == "" {
+		writeError(w, http.StatusBadRequest, "task_id_required", "task ID is required")
+		return
+	}
+
+	var req cancelTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+	t, downstream, err := s.store.CancelTask(r.Context(), store.CancelTaskRequest{
+		TaskID:           taskID,
+		CancelledBy:      auditActor(r.Context(), req.CancelledBy),
+		Reason:           strings.TrimSpace(req.Reason),
+		CancelDownstream: req.CancelDownstream,
+	})
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			writeError(w, http.StatusNotFound, "not_found", "task not found")
+		case store.ErrConflict:
+			writeError(w, http.StatusConflict, "conflict", "only queued, in_progress or locked tasks can be cancelled")
+		default:
+			writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		}
+		return
+	}
+
+	resp := map[string]any{"task": t, "downstream": downstream}
+	if t.AssignedAgentID != "" {
+		// The task was running: tell its agent to stop. The idempotency key makes a
+		// repeated cancel queue the control input only once.
+		in, err := s.store.CreateTaskInput(r.Context(), store.CreateTaskInputRequest{
+			TaskID:         t.ID,
+			AgentID:        t.AssignedAgentID,
+			Kind:           store.TaskInputKindControl,
+			Text:           store.TaskControlCancel,
+			IdempotencyKey: "cancel:" + t.ID,
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", "task cancelled but failed to notify agent")
+			return
+		}
+		resp["input"] = in
+		s.pushTaskInputs(r.Context(), t.ID, t.AssignedAgentID)
+		s.bus.PublishIDs(EventInputs, userID, in.ID)
+	}
+
+	s.bus.PublishIDs(EventTasks, userID, t.ID)
+	s.bus.PublishIDs(EventChains, userID, t.ChainID)
+	s.bus.PublishIDs(EventAgents, userID, t.AssignedAgentID)
+	s.invalidateDashboardCache()
+	s.notifyApprovalGates(r.Context(), t.ChainID)
+	writeJSON(w, http.StatusOK, resp)
+}
+
 type chainAssignAgentRequest struct {
 	AgentID string `json:"agent_id"`
 }
@@ -1381,6 +1906,24 @@ func (s *Server) handleChainAssignAgent(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// The owner claims every remaining task of the chain, so it must satisfy all of them.
+	chainTasks, err := s.store.ListTasks(r.Context(), store.TaskFilter{ChainID: chainID})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to list chain tasks")
+		return
+	}
+	for _, t := range chainTasks {
+		if t.Status != model.TaskStatusQueued {
+			continue
+		}
+		if missing := store.MissingLabels(agent.Labels, t.Requires); len(missing) > 0 {
+			writeError(w, http.StatusConflict, "agent_labels_mismatch",
+				fmt.Sprintf("agent '%s' lacks labels %s required by task '%s'", agentID, strings.Join(missing, ", "), t.Title))
+			return
+		}
+	}
+
+	previousOwner := existing.OwnerAgentID
 	existing.OwnerAgentID = agentID
 
 	chain, err := s.store.UpdateChain(r.Context(), existing)
@@ -1394,12 +1937,27 @@ func (s *Server) handleChainAssignAgent(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	s.bus.Publish(EventChains, userID)
-	s.bus.Publish(EventAgents, userID)
+	s.bus.PublishIDs(EventChains, userID, chain.ID)
+	s.bus.PublishIDs(EventAgents, userID, agentID, previousOwner)
 	s.invalidateDashboardCache()
 	writeJSON(w, http.StatusOK, map[string]any{"chain": chain})
 }
 
+// streamTopics maps the topics= values of /v1/stream to bus event types.
+var streamTopics = map[string]string{
+	"agents":        EventAgents,
+	"tasks":         EventTasks,
+	"channels":      EventChannels,
+	"chains":        EventChains,
+	"schedules":     EventSchedules,
+	"inputs":        EventInputs,
+	"events":        EventEvents,
+	"notifications": EventNotification,
+}
+
+// handleStream serves GET /v1/stream (SSE). Every event carries its origin replica and bus
+// event ID; a client reconnecting to any replica with Last-Event-ID (or ?last_event_id=)
+// first gets the events it missed, or a "reset" event when they are no longer buffered. ?topics= limits the event types.
 func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
@@ -1412,17 +1970,52 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var topics map[string]bool
+	if v := strings.TrimSpace(r.URL.Query().Get("topics")); v != "" {
+		topics = make(map[string]bool)
+		for _, name := range strings.Split(v, ",") {
+			typ, ok := streamTopics[strings.TrimSpace(name)]
+			if !ok {
+				writeError(w, http.StatusBadRequest, "topic_invalid", fmt.Sprintf("unknown topic %q", strings.TrimSpace(name)))
+				return
+			}
+			topics[typ] = true
+		}
+	}
+
+	lastEventID := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if lastEventID == "" {
+		lastEventID = strings.TrimSpace(r.URL.Query().Get("last_event_id"))
+	}
+	var after busCursor
+	if lastEventID != "" {
+		c, ok := parseBusCursor(lastEventID)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "last_event_id_invalid", "Last-Event-ID must be an event id")
+			return
+		}
+		after = c
+	}
+
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("X-Accel-Buffering", "no")
 
 	userID := userIDFromContext(r.Context())
-	ch := s.bus.Subscribe(userID)
+	ch, backlog, complete := s.bus.SubscribeFrom(userID, topics, after)
 	defer s.bus.Unsubscribe(ch)
 
-	// Initial event so the client knows the stream is up.
+	// Initial event so the client knows the stream is up. It has no id, so the
+	// client's Last-Event-ID stays where it was.
 	_, _ = fmt.Fprintf(w, "event: hello\ndata: {}\n\n")
+	if !complete {
+		// Some missed events are gone; the client has to reload everything.
+		_, _ = fmt.Fprintf(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, ev := range backlog {
+		writeStreamEvent(w, ev)
+	}
 	flusher.Flush()
 
 	keepAlive := time.NewTicker(15 * time.Second)
@@ -1437,45 +2030,20 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 			flusher.Flush()
 		case ev, ok := <-ch:
 			if !ok {
+				// We fell behind; the client reconnects with Last-Event-ID and replays.
 				return
 			}
-			b, _ := json.Marshal(ev)
-			eventName := "update"
-			if ev.Type == EventNotification {
-				eventName = "notification"
-			}
-			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventName, string(b))
+			writeStreamEvent(w, ev)
 			flusher.Flush()
 		}
 	}
 }
 
-func (s *Server) handleNotificationsList(w http.ResponseWriter, r *http.Request) {
-	userID := userIDFromContext(r.Context())
-	list := s.notifTk.List(userID)
-	writeJSON(w, http.StatusOK, map[string]any{"notifications": list})
-}
-
-type dismissNotificationRequest struct {
-	AgentID string `json:"agent_id"`
-	Type    string `json:"type"`
-}
-
-func (s *Server) handleNotificationDismiss(w http.ResponseWriter, r *http.Request) {
-	var req dismissNotificationRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
-		return
-	}
-
-	agentID := strings.TrimSpace(req.AgentID)
-	typ := strings.TrimSpace(req.Type)
-	if agentID == "" || typ == "" {
-		writeError(w, http.StatusBadRequest, "invalid_request", "agent_id and type are required")
-		return
+func writeStreamEvent(w http.ResponseWriter, ev busEvent) {
+	b, _ := json.Marshal(ev)
+	eventName := "update"
+	if ev.Type == EventNotification {
+		eventName = "notification"
 	}
-
-	userID := userIDFromContext(r.Context())
-	s.notifTk.Dismiss(userID, agentID, typ)
-	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+	_, _ = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", ev.streamID(), eventName, string(b))
 }
@@ -9,9 +9,12 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"clwclw-monitor/coordinator/internal/config" // Import config
+	"clwclw-monitor/coordinator/internal/leader"
 	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
 	"clwclw-monitor/coordinator/internal/store/memory" // Corrected import
 )
 
@@ -411,3 +414,571 @@ func TestHandleChainAssignAgent_AllowsWhenAgentSubscribed(t *testing.T) {
 		t.Fatalf("expected owner to be assigned, got %q", updated.OwnerAgentID)
 	}
 }
+
+func TestHandleTaskRenewLease(t *testing.T) {
+	server := newTestServer(t)
+	server.cfg.TaskLeaseSeconds = 60
+	ctx := context.Background()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "lease-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	chain, err := server.store.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: "lease-chain", Status: model.ChainStatusQueued})
+	if err != nil {
+		t.Fatalf("create chain: %v", err)
+	}
+	task, err := server.store.CreateTask(ctx, model.Task{ChannelID: ch.ID, ChainID: chain.ID, Sequence: 1, Title: "lease task"})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	agentID := "33333333-3333-4333-8333-333333333333"
+	if _, err := server.store.UpsertAgent(ctx, model.Agent{ID: agentID, Name: "agent-c"}); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+
+	renew := func(agent string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"agent_id": agent})
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/tasks/"+task.ID+"/renew", bytes.NewReader(body))
+		req.SetPathValue("id", task.ID)
+		server.handleTaskRenewLease(rec, req)
+		return rec
+	}
+
+	// Not claimed yet: nothing to renew.
+	if rec := renew(agentID); rec.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusConflict, rec.Code, rec.Body.String())
+	}
+
+	claimBody, _ := json.Marshal(map[string]string{"agent_id": agentID, "channel_id": ch.ID})
+	claimRec := httptest.NewRecorder()
+	server.handleTasksClaim(claimRec, httptest.NewRequest(http.MethodPost, "/v1/tasks/claim", bytes.NewReader(claimBody)))
+	if claimRec.Code != http.StatusOK {
+		t.Fatalf("claim: expected status %d, got %d: %s", http.StatusOK, claimRec.Code, claimRec.Body.String())
+	}
+	var claimResp map[string]model.Task
+	if err := json.NewDecoder(claimRec.Body).Decode(&claimResp); err != nil {
+		t.Fatalf("decode claim response: %v", err)
+	}
+	if claimResp["task"].LeaseExpiresAt == nil {
+		t.Fatalf("expected claimed task to carry a lease")
+	}
+
+	rec := renew(agentID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var renewResp map[string]model.Task
+	if err := json.NewDecoder(rec.Body).Decode(&renewResp); err != nil {
+		t.Fatalf("decode renew response: %v", err)
+	}
+	if renewResp["task"].LeaseExpiresAt == nil || renewResp["task"].LeaseExpiresAt.Before(*claimResp["task"].LeaseExpiresAt) {
+		t.Fatalf("expected lease to be extended, got %v", renewResp["task"].LeaseExpiresAt)
+	}
+}
+
+func TestHandleTaskResubmit(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "dlq-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	// Give the channel a one-attempt budget so the first failure dead-letters the task.
+	patchBody, _ := json.Marshal(map[string]int{"retry_max_attempts": 1})
+	patchRec := httptest.NewRecorder()
+	patchReq := httptest.NewRequest(http.MethodPatch, "/v1/channels/"+ch.ID, bytes.NewReader(patchBody))
+	patchReq.SetPathValue("id", ch.ID)
+	server.handleChannel(patchRec, patchReq)
+	if patchRec.Code != http.StatusOK {
+		t.Fatalf("patch channel: expected status %d, got %d: %s", http.StatusOK, patchRec.Code, patchRec.Body.String())
+	}
+
+	chain, err := server.store.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: "dlq-chain", Status: model.ChainStatusQueued})
+	if err != nil {
+		t.Fatalf("create chain: %v", err)
+	}
+	task, err := server.store.CreateTask(ctx, model.Task{ChannelID: ch.ID, ChainID: chain.ID, Sequence: 1, Title: "dlq task"})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	resubmit := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/tasks/"+task.ID+"/resubmit", nil)
+		req.SetPathValue("id", task.ID)
+		server.handleTaskResubmit(rec, req)
+		return rec
+	}
+
+	// Queued tasks cannot be resubmitted.
+	if rec := resubmit(); rec.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusConflict, rec.Code, rec.Body.String())
+	}
+
+	agentID := "55555555-5555-4555-8555-555555555555"
+	if _, err := server.store.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentID, ChannelID: ch.ID}); err != nil {
+		t.Fatalf("claim task: %v", err)
+	}
+	dead, err := server.store.FailTask(ctx, store.FailTaskRequest{TaskID: task.ID, AgentID: agentID, Reason: "crashed"})
+	if err != nil {
+		t.Fatalf("fail task: %v", err)
+	}
+	if dead.Status != model.TaskStatusDeadLetter {
+		t.Fatalf("expected dead_letter, got %s", dead.Status)
+	}
+
+	rec := resubmit()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp map[string]model.Task
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp["task"].Status != model.TaskStatusQueued || resp["task"].Attempts != 0 {
+		t.Fatalf("expected queued task with reset attempts, got status=%s attempts=%d", resp["task"].Status, resp["task"].Attempts)
+	}
+}
+
+func TestHandleChainGraph(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "graph-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	chain, err := server.store.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: "graph-chain", Status: model.ChainStatusQueued})
+	if err != nil {
+		t.Fatalf("create chain: %v", err)
+	}
+
+	createTask := func(body map[string]any) model.Task {
+		t.Helper()
+		body["channel_id"] = ch.ID
+		body["chain_id"] = chain.ID
+		raw, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		server.handleTasks(rec, httptest.NewRequest(http.MethodPost, "/v1/tasks", bytes.NewReader(raw)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create task: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+		var resp map[string]model.Task
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode task: %v", err)
+		}
+		return resp["task"]
+	}
+
+	root := createTask(map[string]any{"title": "root"})
+	left := createTask(map[string]any{"title": "left", "depends_on": []string{root.ID}})
+	right := createTask(map[string]any{"title": "right", "depends_on": []string{root.ID}})
+	tail := createTask(map[string]any{"title": "tail"}) // no depends_on: gated by sequence
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/chains/"+chain.ID+"/graph", nil)
+	req.SetPathValue("id", chain.ID)
+	server.handleChainGraph(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Nodes []model.Task     `json:"nodes"`
+		Edges []chainGraphEdge `json:"edges"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode graph: %v", err)
+	}
+	if len(resp.Nodes) != 4 {
+		t.Fatalf("expected 4 nodes, got %d", len(resp.Nodes))
+	}
+
+	want := map[chainGraphEdge]bool{
+		{From: root.ID, To: left.ID, Kind: edgeKindDependsOn}:  true,
+		{From: root.ID, To: right.ID, Kind: edgeKindDependsOn}: true,
+		{From: left.ID, To: tail.ID, Kind: edgeKindSequence}:   true,
+		{From: right.ID, To: tail.ID, Kind: edgeKindSequence}:  true,
+	}
+	if len(resp.Edges) != len(want) {
+		t.Fatalf("expected %d edges, got %+v", len(want), resp.Edges)
+	}
+	for _, e := range resp.Edges {
+		if !want[e] {
+			t.Fatalf("unexpected edge %+v", e)
+		}
+	}
+
+	// A dependency on an unknown task is rejected.
+	raw, _ := json.Marshal(map[string]any{"channel_id": ch.ID, "chain_id": chain.ID, "title": "bad", "depends_on": []string{"00000000-0000-4000-8000-000000000000"}})
+	badRec := httptest.NewRecorder()
+	server.handleTasks(badRec, httptest.NewRequest(http.MethodPost, "/v1/tasks", bytes.NewReader(raw)))
+	if badRec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, badRec.Code, badRec.Body.String())
+	}
+}
+
+func TestHandleTasksClaimNoTasksHint(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "scheduled-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	notBefore := time.Now().UTC().Add(90 * time.Minute).Truncate(time.Second)
+	raw, _ := json.Marshal(map[string]any{"channel_id": ch.ID, "title": "after deploy", "not_before": notBefore})
+	rec := httptest.NewRecorder()
+	server.handleTasks(rec, httptest.NewRequest(http.MethodPost, "/v1/tasks", bytes.NewReader(raw)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create task: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	raw, _ = json.Marshal(map[string]any{"agent_id": "55555555-5555-4555-8555-555555555555", "channel_id": ch.ID})
+	rec = httptest.NewRecorder()
+	server.handleTasksClaim(rec, httptest.NewRequest(http.MethodPost, "/v1/tasks/claim", bytes.NewReader(raw)))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNotFound, rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+		NextEligibleAt *time.Time `json:"next_eligible_at"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Error.Code != "no_tasks" {
+		t.Fatalf("expected no_tasks, got %q", resp.Error.Code)
+	}
+	if resp.NextEligibleAt == nil || !resp.NextEligibleAt.Equal(notBefore) {
+		t.Fatalf("expected next_eligible_at %v, got %v", notBefore, resp.NextEligibleAt)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected a Retry-After header")
+	}
+}
+
+func TestHandleTasksClaimConcurrencyLimited(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "limited-channel", MaxInFlight: 1})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	for _, title := range []string{"first", "second"} {
+		raw, _ := json.Marshal(map[string]any{"channel_id": ch.ID, "title": title})
+		rec := httptest.NewRecorder()
+		server.handleTasks(rec, httptest.NewRequest(http.MethodPost, "/v1/tasks", bytes.NewReader(raw)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create task: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+	}
+
+	claim := func(agentID string) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(map[string]any{"agent_id": agentID, "channel_id": ch.ID})
+		rec := httptest.NewRecorder()
+		server.handleTasksClaim(rec, httptest.NewRequest(http.MethodPost, "/v1/tasks/claim", bytes.NewReader(raw)))
+		return rec
+	}
+	if rec := claim("55555555-5555-4555-8555-555555555555"); rec.Code != http.StatusOK {
+		t.Fatalf("first claim: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	rec := claim("66666666-6666-4666-8666-666666666666")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusTooManyRequests, rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+		Occupancy struct {
+			Scope    string `json:"scope"`
+			Limit    int    `json:"limit"`
+			InFlight int    `json:"in_flight"`
+		} `json:"occupancy"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Error.Code != "concurrency_limited" || resp.Occupancy.Scope != "channel" || resp.Occupancy.Limit != 1 || resp.Occupancy.InFlight != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestHandleChainsBulk(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "bulk-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	events := server.bus.Subscribe("")
+	defer server.bus.Unsubscribe(events)
+
+	post := func(body map[string]any) *httptest.ResponseRecorder {
+		t.Helper()
+		raw, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/chains:bulk", bytes.NewReader(raw)))
+		return rec
+	}
+
+	rec := post(map[string]any{
+		"channel_id": ch.ID,
+		"name":       "release",
+		"tasks": []map[string]any{
+			{"title": "build"},
+			{"title": "test", "priority": 2},
+			{"title": "deploy", "depends_on": []int{0, 1}},
+		},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Chain model.Chain  `json:"chain"`
+		Tasks []model.Task `json:"tasks"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Tasks) != 3 {
+		t.Fatalf("expected 3 tasks, got %d", len(resp.Tasks))
+	}
+	for i, task := range resp.Tasks {
+		if task.ChainID != resp.Chain.ID || task.Sequence != i+1 {
+			t.Fatalf("task %d: expected chain %s sequence %d, got %+v", i, resp.Chain.ID, i+1, task)
+		}
+	}
+	if deps := resp.Tasks[2].DependsOn; len(deps) != 2 || deps[0] != resp.Tasks[0].ID || deps[1] != resp.Tasks[1].ID {
+		t.Fatalf("expected deploy to depend on build and test, got %v", deps)
+	}
+	if got := len(events); got != 1 {
+		t.Fatalf("expected a single bus event, got %d", got)
+	}
+
+	// An invalid task leaves nothing behind.
+	rec = post(map[string]any{
+		"channel_id": ch.ID,
+		"name":       "broken",
+		"tasks":      []map[string]any{{"title": "ok"}, {"title": ""}},
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+	chains, err := server.store.ListChains(ctx, "", ch.ID)
+	if err != nil || len(chains) != 1 {
+		t.Fatalf("expected only the first chain to exist, got %d (%v)", len(chains), err)
+	}
+}
+
+func TestHandleTaskCancel(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "cancel-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	_, tasks, err := server.store.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "cancel-chain"}, []store.NewChainTask{
+		{Task: model.Task{Title: "long job"}},
+		{Task: model.Task{Title: "follow-up"}},
+	})
+	if err != nil {
+		t.Fatalf("create chain: %v", err)
+	}
+	agentID := "66666666-6666-4666-8666-666666666666"
+	if _, err := server.store.UpsertAgent(ctx, model.Agent{ID: agentID, Name: "agent-f"}); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+	if _, err := server.store.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentID, ChannelID: ch.ID}); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	cancel := func(taskID string, body map[string]any) *httptest.ResponseRecorder {
+		t.Helper()
+		raw, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/tasks/"+taskID+"/cancel", bytes.NewReader(raw))
+		req.SetPathValue("id", taskID)
+		server.handleTaskCancel(rec, req)
+		return rec
+	}
+
+	rec := cancel(tasks[0].ID, map[string]any{"reason": "wrong branch", "cancelled_by": "alice", "cancel_downstream": true})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Task       model.Task       `json:"task"`
+		Downstream []model.Task     `json:"downstream"`
+		Input      *model.TaskInput `json:"input"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Task.Status != model.TaskStatusCancelled || resp.Task.CancelledBy != "alice" || resp.Task.CancelReason != "wrong branch" {
+		t.Fatalf("unexpected task: %+v", resp.Task)
+	}
+	if len(resp.Downstream) != 1 || resp.Downstream[0].ID != tasks[1].ID {
+		t.Fatalf("expected the follow-up to be cancelled, got %+v", resp.Downstream)
+	}
+	if resp.Input == nil || resp.Input.Kind != store.TaskInputKindControl || resp.Input.Text != store.TaskControlCancel {
+		t.Fatalf("expected a cancel control input, got %+v", resp.Input)
+	}
+
+	// The agent picks the control input up through the regular input queue.
+	in, err := server.store.ClaimTaskInput(ctx, store.ClaimTaskInputRequest{TaskID: tasks[0].ID, AgentID: agentID})
+	if err != nil || in.ID != resp.Input.ID {
+		t.Fatalf("expected the agent to claim the control input, got %+v (%v)", in, err)
+	}
+
+	// A queued task has nobody to notify.
+	_, queued, err := server.store.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "idle-chain"}, []store.NewChainTask{{Task: model.Task{Title: "idle"}}})
+	if err != nil {
+		t.Fatalf("create chain: %v", err)
+	}
+	// A logged-in caller is recorded by username, like an approval reviewer.
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/tasks/"+queued[0].ID+"/cancel", strings.NewReader(`{"cancelled_by": "mallory"}`))
+	req.SetPathValue("id", queued[0].ID)
+	ctxUser := context.WithValue(req.Context(), ctxUserID, "u0000000-0000-4000-8000-000000000001")
+	server.handleTaskCancel(rec, req.WithContext(context.WithValue(ctxUser, ctxUsername, "bob")))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"input"`) {
+		t.Fatalf("expected cancel without a control input, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got, err := server.store.ListTasks(ctx, store.TaskFilter{IDs: []string{queued[0].ID}}); err != nil || len(got) != 1 || got[0].CancelledBy != "bob" {
+		t.Fatalf("expected the cancel to be recorded by username, got %+v (%v)", got, err)
+	}
+
+	if rec := cancel("00000000-0000-4000-8000-000000000000", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestHandleTaskResult(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "result-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	_, tasks, err := server.store.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "migration"}, []store.NewChainTask{
+		{Task: model.Task{Title: "write migration"}},
+		{Task: model.Task{Title: "apply migration"}},
+	})
+	if err != nil {
+		t.Fatalf("create chain: %v", err)
+	}
+	agentID := "a0000000-0000-4000-8000-000000000001"
+	if _, err := server.store.UpsertAgent(ctx, model.Agent{ID: agentID, Name: "result-agent"}); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+	if _, err := server.store.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentID, ChannelID: ch.ID}); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	raw, _ := json.Marshal(map[string]any{
+		"task_id":   tasks[0].ID,
+		"agent_id":  agentID,
+		"result":    map[string]any{"summary": "wrote 0042_users.sql", "files_touched": []string{"migrations/0042_users.sql"}},
+		"artifacts": []map[string]any{{"name": "diff", "content_type": "text/x-diff", "content": "+create table users"}},
+	})
+	rec := httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/tasks/complete", bytes.NewReader(raw)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("complete: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	get := func(taskID string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/tasks/"+taskID+"/result", nil))
+		return rec
+	}
+	var resp struct {
+		Result       *model.TaskResult  `json:"result"`
+		Predecessors []model.TaskResult `json:"predecessors"`
+	}
+
+	rec = get(tasks[0].ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get result: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Result == nil || resp.Result.Result["summary"] != "wrote 0042_users.sql" || len(resp.Result.Artifacts) != 1 {
+		t.Fatalf("unexpected result: %+v", resp.Result)
+	}
+
+	// The next task has no result yet but sees its predecessor's.
+	resp.Result, resp.Predecessors = nil, nil
+	rec = get(tasks[1].ID)
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Result != nil || len(resp.Predecessors) != 1 || resp.Predecessors[0].TaskID != tasks[0].ID {
+		t.Fatalf("expected the predecessor result only, got %+v / %+v", resp.Result, resp.Predecessors)
+	}
+
+	if rec := get("c0000000-0000-4000-8000-000000000000"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	// Another user does not see the result.
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/tasks/"+tasks[0].ID+"/result", nil)
+	server.mux.ServeHTTP(rec, req.WithContext(context.WithValue(req.Context(), ctxUserID, "99999999-9999-4999-8999-999999999999")))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("another user: expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestHandleHealthLeader(t *testing.T) {
+	server := newTestServer(t)
+	health := func() map[string]any {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		var res map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return res
+	}
+
+	if _, ok := health()["leader"]; ok {
+		t.Fatalf("expected no leader field without an elector")
+	}
+
+	elector := &leader.Always{}
+	server.SetLeaderElector(elector)
+	if res := health(); res["leader"] != false {
+		t.Fatalf("expected leader=false before the elector runs, got %v", res["leader"])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	leading := make(chan struct{})
+	go elector.Run(ctx, func(ctx context.Context) {
+		close(leading)
+		<-ctx.Done()
+	})
+	<-leading
+	if res := health(); res["leader"] != true {
+		t.Fatalf("expected leader=true while leading, got %v", res["leader"])
+	}
+}
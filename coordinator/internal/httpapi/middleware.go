@@ -24,6 +24,21 @@ func userIDFromContext(ctx context.Context) string {
 	return v
 }
 
+// usernameFromContext returns the JWT username claim, or "" for token/anonymous access.
+func usernameFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(ctxUsername).(string)
+	return v
+}
+
+// auditActor is the name recorded as the actor of an audited action (cancel, review): the
+// JWT username, or the name given in the request body for shared-token access.
+func auditActor(ctx context.Context, fromBody string) string {
+	if username := usernameFromContext(ctx); username != "" {
+		return username
+	}
+	return strings.TrimSpace(fromBody)
+}
+
 func requestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Header.Get(requestIDHeader) == "" {
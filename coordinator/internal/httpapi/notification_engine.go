@@ -0,0 +1,377 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+const notificationTypeRule = "rule"
+
+const (
+	// ruleCacheTTL bounds how long rule changes made on another replica take to apply here.
+	ruleCacheTTL = 10 * time.Second
+	// ruleQueueSize is how many published events may wait for evaluation before new ones are dropped.
+	ruleQueueSize = 1024
+	// ruleStatusCacheSize bounds the remembered task statuses; past it they are forgotten,
+	// which only costs one more evaluation per task.
+	ruleStatusCacheSize = 10000
+)
+
+// ruleEvent is a task, chain or agent event published on this replica, waiting for the
+// notification rules to be evaluated against it.
+type ruleEvent struct {
+	userID string
+	typ    string
+	ids    []string
+}
+
+// ruleEngine queues the events that notification rules react to and caches the enabled rules.
+type ruleEngine struct {
+	queue chan ruleEvent
+
+	mu       sync.Mutex
+	rules    []model.NotificationRule // enabled rules; nil when not loaded
+	loadedAt time.Time
+	// taskStatus is the status the task rules last saw per task, so that task events that
+	// changed something else (a lease renewal, a progress update) are not matched again.
+	taskStatus map[string]model.TaskStatus
+}
+
+func newRuleEngine() *ruleEngine {
+	return &ruleEngine{queue: make(chan ruleEvent, ruleQueueSize), taskStatus: map[string]model.TaskStatus{}}
+}
+
+// statusChanged records the task's status and reports whether it differs from the one the
+// task rules last saw. A task seen for the first time counts as changed.
+func (e *ruleEngine) statusChanged(t model.Task) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	prev, seen := e.taskStatus[t.ID]
+	if seen && prev == t.Status {
+		return false
+	}
+	if len(e.taskStatus) >= ruleStatusCacheSize {
+		e.taskStatus = map[string]model.TaskStatus{}
+	}
+	e.taskStatus[t.ID] = t.Status
+	return true
+}
+
+// observe is the event bus observer. It runs with the bus lock held, so it only queues.
+func (e *ruleEngine) observe(userID string, ev busEvent) {
+	switch ev.Type {
+	case EventTasks, EventChains, EventEvents:
+	default:
+		return
+	}
+	ids, _ := ev.Payload["ids"].([]string)
+	if len(ids) == 0 {
+		return
+	}
+	select {
+	case e.queue <- ruleEvent{userID: userID, typ: ev.Type, ids: ids}:
+	default:
+		log.Printf("notification rules: queue full, dropping %s event", ev.Type)
+	}
+}
+
+// invalidate makes the next evaluation reload the rules from the store. The task statuses
+// are forgotten too, so a new or changed rule is matched against the next event of a task.
+func (e *ruleEngine) invalidate() {
+	e.mu.Lock()
+	e.rules = nil
+	e.taskStatus = map[string]model.TaskStatus{}
+	e.mu.Unlock()
+}
+
+// enabledRules returns the enabled rules with one of the given triggers.
+func (s *Server) enabledRules(ctx context.Context, triggers ...model.NotificationTrigger) ([]model.NotificationRule, error) {
+	e := s.rules
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.rules == nil || time.Since(e.loadedAt) >= ruleCacheTTL {
+		all, err := s.store.ListNotificationRules(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		e.rules = make([]model.NotificationRule, 0, len(all))
+		for _, r := range all {
+			if r.Enabled {
+				e.rules = append(e.rules, r)
+			}
+		}
+		e.loadedAt = time.Now()
+	}
+
+	var out []model.NotificationRule
+	for _, r := range e.rules {
+		for _, t := range triggers {
+			if r.Trigger == t {
+				out = append(out, r)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// RunNotificationRules evaluates the notification rules against the task, chain and agent
+// events published on this replica until ctx is cancelled. It runs on every replica, as
+// each one only sees the changes it made itself.
+func (s *Server) RunNotificationRules(ctx context.Context) {
+	s.bus.setObserver("notification-rules", s.rules.observe)
+	defer s.bus.setObserver("notification-rules", nil)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-s.rules.queue:
+			s.evaluateRules(ctx, ev)
+		}
+	}
+}
+
+func (s *Server) evaluateRules(ctx context.Context, ev ruleEvent) {
+	switch ev.typ {
+	case EventTasks:
+		s.evaluateTaskRules(ctx, ev.ids)
+	case EventChains:
+		s.evaluateChainRules(ctx, ev.ids)
+	case EventEvents:
+		s.evaluateEventRules(ctx, ev.userID, ev.ids)
+	}
+}
+
+// ruleApplies reports whether r watches subjects of userID in channelID. Rules without a
+// user (shared-token access) watch every user.
+func ruleApplies(r model.NotificationRule, userID, channelID string) bool {
+	if r.UserID != "" && r.UserID != userID {
+		return false
+	}
+	return r.ChannelID == "" || r.ChannelID == channelID
+}
+
+// ruleNotificationKey deduplicates the notifications of one rule about one subject.
+func ruleNotificationKey(r model.NotificationRule, subjectID string) string {
+	return notificationKey(subjectID, "rule:"+r.ID)
+}
+
+func (s *Server) evaluateTaskRules(ctx context.Context, ids []string) {
+	rules, err := s.enabledRules(ctx, model.NotificationTriggerTaskStatus)
+	if err != nil || len(rules) == 0 {
+		if err != nil {
+			log.Printf("notification rules: list rules failed: %v", err)
+		}
+		return
+	}
+	tasks, err := s.store.ListTasks(ctx, store.TaskFilter{IDs: ids})
+	if err != nil {
+		log.Printf("notification rules: list tasks failed: %v", err)
+		return
+	}
+
+	for _, t := range tasks {
+		if !s.rules.statusChanged(t) {
+			continue
+		}
+		for _, r := range rules {
+			if !ruleApplies(r, t.UserID, t.ChannelID) {
+				continue
+			}
+			key := ruleNotificationKey(r, t.ID)
+			// Leaving the status re-arms the rule for the next time the task reaches it.
+			if string(t.Status) != r.Status {
+				s.resolveNotification(ctx, r.UserID, key)
+				continue
+			}
+			s.fireRule(ctx, r, model.Notification{
+				Key:     key,
+				Message: fmt.Sprintf("Task '%s' is %s.", t.Title, t.Status),
+				TaskID:  t.ID,
+				ChainID: t.ChainID,
+			})
+		}
+	}
+}
+
+func (s *Server) evaluateChainRules(ctx context.Context, ids []string) {
+	rules, err := s.enabledRules(ctx, model.NotificationTriggerChainStatus)
+	if err != nil || len(rules) == 0 {
+		if err != nil {
+			log.Printf("notification rules: list rules failed: %v", err)
+		}
+		return
+	}
+
+	for _, id := range ids {
+		c, err := s.store.GetChain(ctx, id)
+		if err != nil {
+			if err != store.ErrNotFound {
+				log.Printf("notification rules: get chain %s failed: %v", id, err)
+			}
+			continue
+		}
+		for _, r := range rules {
+			if !ruleApplies(r, c.UserID, c.ChannelID) {
+				continue
+			}
+			key := ruleNotificationKey(r, c.ID)
+			if string(c.Status) != r.Status {
+				s.resolveNotification(ctx, r.UserID, key)
+				continue
+			}
+			s.fireRule(ctx, r, model.Notification{
+				Key:     key,
+				Message: fmt.Sprintf("Chain '%s' is %s.", c.Name, c.Status),
+				ChainID: c.ID,
+			})
+		}
+	}
+}
+
+// evaluateEventRules fires the event_type rules once per matching agent event; the events
+// are published with the user of their agent.
+func (s *Server) evaluateEventRules(ctx context.Context, userID string, ids []string) {
+	rules, err := s.enabledRules(ctx, model.NotificationTriggerEventType)
+	if err != nil || len(rules) == 0 {
+		if err != nil {
+			log.Printf("notification rules: list rules failed: %v", err)
+		}
+		return
+	}
+	events, err := s.store.ListEvents(ctx, store.EventFilter{IDs: ids})
+	if err != nil {
+		log.Printf("notification rules: list events failed: %v", err)
+		return
+	}
+
+	for _, e := range events {
+		channelID := ""
+		if e.TaskID != "" {
+			if tasks, err := s.store.ListTasks(ctx, store.TaskFilter{IDs: []string{e.TaskID}}); err == nil && len(tasks) == 1 {
+				channelID = tasks[0].ChannelID
+			}
+		}
+		agentName := ""
+		if a, err := s.store.GetAgent(ctx, e.AgentID); err == nil && a != nil {
+			agentName = a.Name
+		}
+
+		for _, r := range rules {
+			if !ruleApplies(r, userID, channelID) || !store.MatchEventType(r.EventPattern, e.Type) {
+				continue
+			}
+			s.fireRule(ctx, r, model.Notification{
+				Key:       ruleNotificationKey(r, e.ID),
+				Message:   fmt.Sprintf("Event '%s' from agent '%s'.", e.Type, agentName),
+				AgentID:   e.AgentID,
+				AgentName: agentName,
+				TaskID:    e.TaskID,
+				Extra:     map[string]any{"event_id": e.ID, "event_type": e.Type},
+			})
+		}
+	}
+}
+
+// evaluateAgentRules checks the agent_offline and claude_status rules, which match once a
+// state has lasted for the rule's duration. It runs with the agent watcher, on the leader.
+func (s *Server) evaluateAgentRules(ctx context.Context, agents []model.Agent, threshold time.Duration) {
+	rules, err := s.enabledRules(ctx, model.NotificationTriggerAgentOffline, model.NotificationTriggerClaudeStatus)
+	if err != nil {
+		log.Printf("notification rules: list rules failed: %v", err)
+		return
+	}
+
+	for _, r := range rules {
+		wait := time.Duration(r.ForSeconds) * time.Second
+		for _, a := range agents {
+			if !ruleApplies(r, a.UserID, "") {
+				continue
+			}
+			online := a.DerivedWorkerStatus(threshold) == model.WorkerStatusOnline
+
+			var match bool
+			var msg string
+			switch r.Trigger {
+			case model.NotificationTriggerAgentOffline:
+				// An agent counts as offline from the moment its heartbeats are overdue.
+				offlineFor := time.Since(a.LastSeen) - threshold
+				match = !online && offlineFor >= wait
+				msg = fmt.Sprintf("Agent '%s' has been offline for %s.", a.Name, offlineFor.Round(time.Second))
+			case model.NotificationTriggerClaudeStatus:
+				statusFor := time.Since(a.ClaudeStatusSince)
+				match = online && string(a.ClaudeStatus) == r.Status && !a.ClaudeStatusSince.IsZero() && statusFor >= wait
+				msg = fmt.Sprintf("Agent '%s' has been %s for %s.", a.Name, a.ClaudeStatus, statusFor.Round(time.Second))
+			}
+
+			key := ruleNotificationKey(r, a.ID)
+			if !match {
+				s.resolveNotification(ctx, r.UserID, key)
+				continue
+			}
+			s.fireRule(ctx, r, model.Notification{
+				Key:       key,
+				Message:   msg,
+				AgentID:   a.ID,
+				AgentName: a.Name,
+				TaskID:    a.CurrentTaskID,
+			})
+		}
+	}
+}
+
+// fireRule stores the notification of a matching rule and delivers it to the rule's targets.
+// A notification that is still active is not raised again, and a rule delivers at most once
+// per cooldown; notifications held back by the cooldown are only stored.
+func (s *Server) fireRule(ctx context.Context, r model.NotificationRule, n model.Notification) {
+	n.UserID = r.UserID
+	n.Type = notificationTypeRule
+	n.Severity = r.Severity
+	if n.Extra == nil {
+		n.Extra = map[string]any{}
+	}
+	n.Extra["rule_id"] = r.ID
+	n.Extra["rule_name"] = r.Name
+	n.Extra["trigger"] = r.Trigger
+
+	stored, raised, err := s.store.RaiseNotification(ctx, n, 0)
+	if err != nil {
+		log.Printf("notification rules: raise %s failed: %v", n.Key, err)
+		return
+	}
+	if !raised {
+		return
+	}
+	fired, err := s.store.FireNotificationRule(ctx, r.ID, time.Duration(r.CooldownSeconds)*time.Second)
+	if err != nil {
+		if err != store.ErrNotFound {
+			log.Printf("notification rules: fire rule %s failed: %v", r.ID, err)
+		}
+		return
+	}
+	if !fired {
+		return
+	}
+
+	for _, target := range r.Targets {
+		switch target {
+		case model.NotificationTargetDashboard:
+			s.bus.PublishWithPayload(EventNotification, stored.UserID, notificationPayload(stored))
+		case model.NotificationTargetWebhook:
+			s.queueWebhookDeliveries(ctx, webhookEvent{
+				userID:  stored.UserID,
+				topic:   model.WebhookTopicNotifications,
+				payload: notificationPayload(stored),
+			})
+		}
+	}
+}
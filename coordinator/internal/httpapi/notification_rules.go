@@ -0,0 +1,188 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+type createNotificationRuleRequest struct {
+	Name            string                     `json:"name"`
+	Trigger         model.NotificationTrigger  `json:"trigger"`
+	ChannelID       string                     `json:"channel_id"`
+	Status          string                     `json:"status"`
+	EventPattern    string                     `json:"event_pattern"`
+	ForSeconds      int                        `json:"for_seconds"`
+	Severity        model.NotificationSeverity `json:"severity"` // default info
+	Targets         []model.NotificationTarget `json:"targets"`  // "dashboard" | "webhook"; default ["dashboard"]
+	CooldownSeconds int                        `json:"cooldown_seconds"`
+	Enabled         *bool                      `json:"enabled"` // default true
+}
+
+// updateNotificationRuleRequest uses pointers so omitted fields keep their current value.
+type updateNotificationRuleRequest struct {
+	Name            *string                     `json:"name"`
+	Trigger         *model.NotificationTrigger  `json:"trigger"`
+	ChannelID       *string                     `json:"channel_id"`
+	Status          *string                     `json:"status"`
+	EventPattern    *string                     `json:"event_pattern"`
+	ForSeconds      *int                        `json:"for_seconds"`
+	Severity        *model.NotificationSeverity `json:"severity"`
+	Targets         *[]model.NotificationTarget `json:"targets"`
+	CooldownSeconds *int                        `json:"cooldown_seconds"`
+	Enabled         *bool                       `json:"enabled"`
+}
+
+func (s *Server) handleNotificationRules(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		rules, err := s.store.ListNotificationRules(r.Context(), userID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", "failed to list notification rules")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"rules": rules})
+		return
+
+	case http.MethodPost:
+		var req createNotificationRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+			return
+		}
+
+		rule, err := s.store.CreateNotificationRule(r.Context(), model.NotificationRule{
+			UserID:          userID,
+			Name:            req.Name,
+			Trigger:         req.Trigger,
+			ChannelID:       req.ChannelID,
+			Status:          req.Status,
+			EventPattern:    req.EventPattern,
+			ForSeconds:      req.ForSeconds,
+			Severity:        req.Severity,
+			Targets:         req.Targets,
+			CooldownSeconds: req.CooldownSeconds,
+			Enabled:         req.Enabled == nil || *req.Enabled,
+		})
+		if err != nil {
+			status := http.StatusBadRequest
+			if err == store.ErrNotFound { // channel_id not found
+				status = http.StatusNotFound
+			}
+			writeError(w, status, "invalid_request", err.Error())
+			return
+		}
+
+		s.rules.invalidate()
+		writeJSON(w, http.StatusCreated, map[string]any{"rule": rule})
+		return
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+}
+
+func (s *Server) handleNotificationRule(w http.ResponseWriter, r *http.Request) {
+	ruleID := strings.TrimSpace(r.PathValue("id"))
+	if ruleID == "" {
+		writeError(w, http.StatusBadRequest, "rule_id_required", "rule ID is required")
+		return
+	}
+
+	// Rules of other users are reported as missing.
+	userID := userIDFromContext(r.Context())
+	rule, err := s.store.GetNotificationRule(r.Context(), ruleID)
+	if err == nil && userID != "" && rule.UserID != userID {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		if err == store.ErrNotFound {
+			writeError(w, http.StatusNotFound, "not_found", "notification rule not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "internal", "failed to get notification rule")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{"rule": rule})
+		return
+
+	case http.MethodPatch:
+		var req updateNotificationRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+			return
+		}
+
+		if req.Name != nil {
+			rule.Name = *req.Name
+		}
+		if req.Trigger != nil {
+			rule.Trigger = *req.Trigger
+		}
+		if req.ChannelID != nil {
+			rule.ChannelID = *req.ChannelID
+		}
+		if req.Status != nil {
+			rule.Status = *req.Status
+		}
+		if req.EventPattern != nil {
+			rule.EventPattern = *req.EventPattern
+		}
+		if req.ForSeconds != nil {
+			rule.ForSeconds = *req.ForSeconds
+		}
+		if req.Severity != nil {
+			rule.Severity = *req.Severity
+		}
+		if req.Targets != nil {
+			rule.Targets = *req.Targets
+		}
+		if req.CooldownSeconds != nil {
+			rule.CooldownSeconds = *req.CooldownSeconds
+		}
+		if req.Enabled != nil {
+			rule.Enabled = *req.Enabled
+		}
+
+		rule, err = s.store.UpdateNotificationRule(r.Context(), rule)
+		if err != nil {
+			status := http.StatusBadRequest
+			if err == store.ErrNotFound {
+				status = http.StatusNotFound
+			}
+			writeError(w, status, "invalid_request", err.Error())
+			return
+		}
+
+		s.rules.invalidate()
+		writeJSON(w, http.StatusOK, map[string]any{"rule": rule})
+		return
+
+	case http.MethodDelete:
+		if err := s.store.DeleteNotificationRule(r.Context(), ruleID); err != nil {
+			if err == store.ErrNotFound {
+				writeError(w, http.StatusNotFound, "not_found", "notification rule not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal", "failed to delete notification rule")
+			return
+		}
+
+		s.rules.invalidate()
+		w.WriteHeader(http.StatusNoContent)
+		return
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+}
@@ -0,0 +1,268 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/config"
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+	"clwclw-monitor/coordinator/internal/store/memory"
+)
+
+// drainRuleEvents evaluates the queued rule events the way RunNotificationRules does.
+func drainRuleEvents(server *Server) {
+	for len(server.rules.queue) > 0 {
+		server.evaluateRules(context.Background(), <-server.rules.queue)
+	}
+}
+
+func TestNotificationRuleTaskStatus(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	server.bus.setObserver("notification-rules", server.rules.observe)
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "rules-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	other, err := server.store.CreateChannel(ctx, model.Channel{Name: "other-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	body := `{"name":"done in rules-channel","trigger":"task_status","status":"done","channel_id":"` + ch.ID + `","cooldown_seconds":3600}`
+	server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/notification-rules", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create rule: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/notification-rules", strings.NewReader(`{"name":"bad","trigger":"task_status","status":"finished"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("create invalid rule: expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	events := server.bus.Subscribe("")
+	defer server.bus.Unsubscribe(events)
+
+	complete := func(channelID, title string) {
+		t.Helper()
+		chain, err := server.store.CreateChain(ctx, model.Chain{ChannelID: channelID, Name: title, Status: model.ChainStatusQueued})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+		task, err := server.store.CreateTask(ctx, model.Task{ChannelID: channelID, ChainID: chain.ID, Sequence: 1, Title: title, Status: model.TaskStatusLocked})
+		if err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/tasks/"+task.ID+"/status", strings.NewReader(`{"status":"done"}`)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("complete task: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		drainRuleEvents(server)
+	}
+	pushed := func() int {
+		n := 0
+		for len(events) > 0 {
+			if ev := <-events; ev.Type == EventNotification && ev.Payload["notification_type"] == notificationTypeRule {
+				n++
+			}
+		}
+		return n
+	}
+
+	complete(other.ID, "elsewhere")
+	if n := pushed(); n != 0 {
+		t.Fatalf("expected no notification for another channel, got %d", n)
+	}
+
+	complete(ch.ID, "first")
+	if n := pushed(); n != 1 {
+		t.Fatalf("expected one pushed notification, got %d", n)
+	}
+
+	// The cooldown holds back the delivery, but the notification is still stored.
+	complete(ch.ID, "second")
+	if n := pushed(); n != 0 {
+		t.Fatalf("expected the cooldown to hold back the push, got %d", n)
+	}
+	if got, _ := server.store.ListNotifications(ctx, store.NotificationFilter{}); len(got) != 2 {
+		t.Fatalf("expected two stored notifications, got %+v", got)
+	}
+}
+
+func TestNotificationRuleAgentOffline(t *testing.T) {
+	st := staleAgentsStore{memory.NewStore()}
+	server := NewServer(config.Config{AuthToken: "test-token"}, st)
+	ctx := context.Background()
+
+	if _, err := st.UpsertAgent(ctx, model.Agent{ID: "55555555-5555-4555-8555-555555555555", Name: "agent-e"}); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+	// The agent has been offline for about an hour: only the shorter rule matches.
+	short, err := st.CreateNotificationRule(ctx, model.NotificationRule{Name: "offline 10m", Trigger: model.NotificationTriggerAgentOffline, ForSeconds: 600, Enabled: true})
+	if err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+	if _, err := st.CreateNotificationRule(ctx, model.NotificationRule{Name: "offline 2h", Trigger: model.NotificationTriggerAgentOffline, ForSeconds: 7200, Enabled: true}); err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+
+	server.checkAgents(ctx)
+	server.checkAgents(ctx)
+
+	got, err := st.ListNotifications(ctx, store.NotificationFilter{})
+	if err != nil {
+		t.Fatalf("list notifications: %v", err)
+	}
+	if len(got) != 1 || got[0].Extra["rule_id"] != short.ID || got[0].AgentName != "agent-e" {
+		t.Fatalf("expected one notification of the 10m rule, got %+v", got)
+	}
+}
+
+func TestNotificationRuleRequeueAndDetach(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	server.bus.setObserver("notification-rules", server.rules.observe)
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "requeue-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	for _, r := range []model.NotificationRule{
+		{Name: "requeued", Trigger: model.NotificationTriggerTaskStatus, Status: string(model.TaskStatusQueued), ChannelID: ch.ID, Enabled: true},
+		{Name: "locked", Trigger: model.NotificationTriggerTaskStatus, Status: string(model.TaskStatusLocked), ChannelID: ch.ID, Enabled: true},
+		{Name: "lease expired", Trigger: model.NotificationTriggerEventType, EventPattern: store.EventTypeTaskLeaseExpired, Enabled: true},
+	} {
+		if _, err := server.store.CreateNotificationRule(ctx, r); err != nil {
+			t.Fatalf("create rule %q: %v", r.Name, err)
+		}
+	}
+	fired := func() map[string]int {
+		drainRuleEvents(server)
+		got, err := server.store.ListNotifications(ctx, store.NotificationFilter{})
+		if err != nil {
+			t.Fatalf("list notifications: %v", err)
+		}
+		out := map[string]int{}
+		for _, n := range got {
+			out[n.Extra["rule_name"].(string)]++
+		}
+		return out
+	}
+	newClaimed := func(agentID string, leaseSeconds int) model.Task {
+		t.Helper()
+		if _, err := server.store.UpsertAgent(ctx, model.Agent{ID: agentID, Name: agentID}); err != nil {
+			t.Fatalf("upsert agent: %v", err)
+		}
+		if _, _, err := server.store.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: agentID}, []store.NewChainTask{{Task: model.Task{Title: "work"}}}); err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+		task, err := server.store.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentID, ChannelID: ch.ID, LeaseSeconds: leaseSeconds})
+		if err != nil {
+			t.Fatalf("claim: %v", err)
+		}
+		return *task
+	}
+
+	// The lease reaper requeues a task: both the task rule and the event rule fire.
+	newClaimed("a0000000-0000-4000-8000-000000000001", 1)
+	requeued, err := server.store.RequeueExpiredTasks(ctx, time.Now().UTC().Add(time.Hour))
+	if err != nil || len(requeued) != 1 {
+		t.Fatalf("expected one expired lease, got %v (%v)", requeued, err)
+	}
+	server.PublishLeaseExpired(ctx, requeued)
+	if got := fired(); got["requeued"] != 1 || got["lease expired"] != 1 {
+		t.Fatalf("expected the requeue rules to fire, got %v", got)
+	}
+
+	// Detaching locks the in-flight task.
+	agentID := "a0000000-0000-4000-8000-000000000002"
+	task := newClaimed(agentID, 0)
+	rec := httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/chains/"+task.ChainID+"/detach", strings.NewReader(`{"agent_id":"`+agentID+`"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("detach: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got := fired(); got["locked"] != 1 {
+		t.Fatalf("expected the locked rule to fire on detach, got %v", got)
+	}
+}
+
+func TestNotificationRuleWebhookTargetAndRenewals(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	server.bus.setObserver("notification-rules", server.rules.observe)
+	server.bus.setObserver("webhooks", server.webhooks.observe)
+
+	hook, err := server.store.CreateWebhook(ctx, model.Webhook{Name: "pager", URL: "https://hooks.example.com/pager", Secret: "whsec_test", Topics: []string{model.WebhookTopicNotifications}, Enabled: true})
+	if err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "webhook-rules"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	rule, err := server.store.CreateNotificationRule(ctx, model.NotificationRule{
+		Name:    "running",
+		Trigger: model.NotificationTriggerTaskStatus,
+		Status:  string(model.TaskStatusInProgress),
+		Targets: []model.NotificationTarget{model.NotificationTargetWebhook},
+		Enabled: true,
+	})
+	if err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+	agentID := "a0000000-0000-4000-8000-000000000001"
+	if _, err := server.store.UpsertAgent(ctx, model.Agent{ID: agentID, Name: "renewer"}); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+	if _, _, err := server.store.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "long"}, []store.NewChainTask{{Task: model.Task{Title: "long build"}}}); err != nil {
+		t.Fatalf("create chain: %v", err)
+	}
+	task, err := server.store.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentID, ChannelID: ch.ID, LeaseSeconds: 60})
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	events := server.bus.Subscribe("")
+	defer server.bus.Unsubscribe(events)
+	publish := func() {
+		server.bus.PublishIDs(EventTasks, "", task.ID)
+		drainRuleEvents(server)
+		for len(server.webhooks.queue) > 0 {
+			server.queueWebhookDeliveries(ctx, <-server.webhooks.queue)
+		}
+	}
+
+	// The webhook target queues a delivery; nothing is pushed on the stream.
+	publish()
+	deliveries, err := server.store.ListWebhookDeliveries(ctx, hook.ID, 10)
+	if err != nil || len(deliveries) != 1 || deliveries[0].Topic != model.WebhookTopicNotifications {
+		t.Fatalf("expected one notification delivery, got %+v (%v)", deliveries, err)
+	}
+	if n, _ := deliveries[0].Payload["notification"].(map[string]any); n["rule_name"] != "running" {
+		t.Fatalf("expected the rule's notification in the delivery, got %+v", deliveries[0].Payload)
+	}
+	for len(events) > 0 {
+		if ev := <-events; ev.Type == EventNotification {
+			t.Fatalf("expected no stream push without the dashboard target, got %+v", ev)
+		}
+	}
+
+	// Dismissed, the notification is not raised again by lease renewals: the status is unchanged.
+	server.resolveNotification(ctx, "", ruleNotificationKey(rule, task.ID))
+	if _, err := server.store.RenewTaskLease(ctx, store.RenewTaskLeaseRequest{TaskID: task.ID, AgentID: agentID, LeaseSeconds: 60}); err != nil {
+		t.Fatalf("renew lease: %v", err)
+	}
+	publish()
+	if got, _ := server.store.ListNotifications(ctx, store.NotificationFilter{}); len(got) != 1 {
+		t.Fatalf("expected the renewal not to raise the rule again, got %d notifications", len(got))
+	}
+}
@@ -1,120 +1,163 @@
 package httpapi
 
 import (
-	"sync"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
 	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
 )
 
+// notificationCooldown is how long a still active notification waits before it is pushed again.
 const notificationCooldown = 5 * time.Minute
 
-// Notification represents a stored notification visible to a user.
-type Notification struct {
-	Key       string         `json:"key"` // unique: "agentID:type"
-	UserID    string         `json:"user_id"`
-	AgentID   string         `json:"agent_id"`
-	AgentName string         `json:"agent_name"`
-	Type      string         `json:"type"`    // e.g. "setup_waiting"
-	Channel   string         `json:"channel"` // first subscribed channel (may be empty)
-	Message   string         `json:"message"`
-	CreatedAt time.Time      `json:"created_at"`
-	Extra     map[string]any `json:"extra,omitempty"`
-}
+const notificationTypeSetupWaiting = "setup_waiting"
 
-// notificationTracker stores active notifications per user and prevents duplicate sends.
-type notificationTracker struct {
-	mu    sync.Mutex
-	sent  map[string]time.Time      // cooldown tracker, key: "agentID:type"
-	items map[string][]Notification // stored notifications, key: userID
+// notificationKey deduplicates notifications of one type about one agent or task.
+func notificationKey(subjectID, typ string) string {
+	return subjectID + ":" + typ
 }
 
-func newNotificationTracker() *notificationTracker {
-	return &notificationTracker{
-		sent:  make(map[string]time.Time),
-		items: make(map[string][]Notification),
+// raiseNotification stores n and, when the store says it is due (new, or active for longer
+// than cooldown), pushes it to its user as an EventNotification.
+func (s *Server) raiseNotification(ctx context.Context, n model.Notification, cooldown time.Duration) {
+	stored, push, err := s.store.RaiseNotification(ctx, n, cooldown)
+	if err != nil {
+		log.Printf("notifications: raise %s failed: %v", n.Key, err)
+		return
+	}
+	if push {
+		s.bus.PublishWithPayload(EventNotification, stored.UserID, notificationPayload(stored))
 	}
 }
 
-func (n *notificationTracker) cooldownKey(agentID, typ string) string {
-	return agentID + ":" + typ
+// resolveNotification resolves the active notifications with key (of every user when userID is empty).
+func (s *Server) resolveNotification(ctx context.Context, userID, key string) {
+	if err := s.store.ResolveNotifications(ctx, userID, key); err != nil {
+		log.Printf("notifications: resolve %s failed: %v", key, err)
+	}
 }
 
-// ShouldNotify returns true if this agent+type hasn't been notified within the cooldown.
-func (n *notificationTracker) ShouldNotify(agentID, typ string) bool {
-	n.mu.Lock()
-	defer n.mu.Unlock()
-
-	k := n.cooldownKey(agentID, typ)
-	last, ok := n.sent[k]
-	if ok && time.Since(last) < notificationCooldown {
-		return false
+// notificationPayload is the EventNotification payload of n; Extra keys are added as they are.
+func notificationPayload(n model.Notification) map[string]any {
+	payload := map[string]any{}
+	for k, v := range n.Extra {
+		payload[k] = v
+	}
+	payload["notification_id"] = n.ID
+	payload["notification_type"] = n.Type
+	payload["severity"] = n.Severity
+	payload["message"] = n.Message
+	for k, v := range map[string]string{
+		"agent_id":   n.AgentID,
+		"agent_name": n.AgentName,
+		"channel":    n.Channel,
+		"task_id":    n.TaskID,
+		"chain_id":   n.ChainID,
+	} {
+		if v != "" {
+			payload[k] = v
+		}
 	}
-	n.sent[k] = time.Now()
-	return true
+	return payload
 }
 
-// Add stores a notification. Replaces any existing notification with the same key for this user.
-func (n *notificationTracker) Add(notif Notification) {
-	n.mu.Lock()
-	defer n.mu.Unlock()
+func (s *Server) handleNotificationsList(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+	q := r.URL.Query()
+
+	filter := store.NotificationFilter{
+		UserID:     userID,
+		UnreadOnly: q.Get("unread") == "true",
+		Cursor:     strings.TrimSpace(q.Get("cursor")),
+		Limit:      50,
+	}
+	if v := strings.TrimSpace(q.Get("limit")); v != "" {
+		// Ignore parsing errors and keep the default.
+		var n int
+		_, _ = fmt.Sscanf(v, "%d", &n)
+		if n > 0 {
+			filter.Limit = min(n, 200)
+		}
+	}
 
-	list := n.items[notif.UserID]
-	// Replace if exists
-	for i, existing := range list {
-		if existing.Key == notif.Key {
-			list[i] = notif
+	list, err := s.store.ListNotifications(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusBadRequest, "cursor_invalid", "unknown cursor")
 			return
 		}
+		writeError(w, http.StatusInternalServerError, "internal", "failed to list notifications")
+		return
+	}
+	unread, err := s.store.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to count notifications")
+		return
 	}
-	n.items[notif.UserID] = append(list, notif)
+
+	res := map[string]any{"notifications": list, "unread_count": unread}
+	if len(list) == filter.Limit {
+		res["next_cursor"] = list[len(list)-1].ID
+	}
+	writeJSON(w, http.StatusOK, res)
 }
 
-// List returns all stored notifications for a user.
-func (n *notificationTracker) List(userID string) []Notification {
-	n.mu.Lock()
-	defer n.mu.Unlock()
+func (s *Server) handleNotificationRead(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+	n, err := s.store.MarkNotificationRead(r.Context(), userID, strings.TrimSpace(r.PathValue("id")))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "not_found", "notification not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "internal", "failed to mark notification read")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"notification": n})
+}
 
-	list := n.items[userID]
-	if list == nil {
-		return []Notification{}
+func (s *Server) handleNotificationsReadAll(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+	marked, err := s.store.MarkAllNotificationsRead(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to mark notifications read")
+		return
 	}
-	// Return a copy
-	out := make([]Notification, len(list))
-	copy(out, list)
-	return out
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "marked": marked})
 }
 
-// Dismiss removes a notification by agentID+type for a user and clears the cooldown.
-func (n *notificationTracker) Dismiss(userID, agentID, typ string) {
-	n.mu.Lock()
-	defer n.mu.Unlock()
+type dismissNotificationRequest struct {
+	AgentID string `json:"agent_id"`
+	Type    string `json:"type"`
+}
 
-	key := n.cooldownKey(agentID, typ)
-	delete(n.sent, key)
+// handleNotificationDismiss resolves the user's active notification of an agent, so the
+// next occurrence is pushed right away.
+func (s *Server) handleNotificationDismiss(w http.ResponseWriter, r *http.Request) {
+	var req dismissNotificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+		return
+	}
 
-	list := n.items[userID]
-	for i, item := range list {
-		if item.Key == key {
-			n.items[userID] = append(list[:i], list[i+1:]...)
-			return
-		}
+	agentID := strings.TrimSpace(req.AgentID)
+	typ := strings.TrimSpace(req.Type)
+	if agentID == "" || typ == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "agent_id and type are required")
+		return
 	}
-}
 
-// ClearByAgent removes all notifications for a specific agent+type across all users,
-// and clears the cooldown. Used when agent exits setup_waiting.
-func (n *notificationTracker) ClearByAgent(agentID, typ string) {
-	n.mu.Lock()
-	defer n.mu.Unlock()
-
-	key := n.cooldownKey(agentID, typ)
-	delete(n.sent, key)
-
-	for userID, list := range n.items {
-		for i, item := range list {
-			if item.Key == key {
-				n.items[userID] = append(list[:i], list[i+1:]...)
-				break
-			}
-		}
+	userID := userIDFromContext(r.Context())
+	if err := s.store.ResolveNotifications(r.Context(), userID, notificationKey(agentID, typ)); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to dismiss notification")
+		return
 	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
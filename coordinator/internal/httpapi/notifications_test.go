@@ -0,0 +1,114 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+func TestNotificationsSetupWaiting(t *testing.T) {
+	server := newTestServer(t)
+	agentID := "77777777-7777-4777-8777-777777777777"
+	events := server.bus.Subscribe("")
+	defer server.bus.Unsubscribe(events)
+
+	do := func(method, path string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		var raw []byte
+		if body != nil {
+			raw, _ = json.Marshal(body)
+		}
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(raw)))
+		return rec
+	}
+	heartbeat := func(state string) {
+		t.Helper()
+		rec := do(http.MethodPost, "/v1/agents/heartbeat", map[string]any{
+			"agent_id": agentID,
+			"name":     "setup-agent",
+			"meta":     map[string]any{"state": state, "subscriptions": []string{"jobs"}},
+		})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("heartbeat: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+	}
+	pushed := func() int {
+		n := 0
+		for len(events) > 0 {
+			if ev := <-events; ev.Type == EventNotification && ev.Payload["notification_type"] == notificationTypeSetupWaiting {
+				n++
+			}
+		}
+		return n
+	}
+	type listResponse struct {
+		Notifications []model.Notification `json:"notifications"`
+		UnreadCount   int                  `json:"unread_count"`
+		NextCursor    string               `json:"next_cursor"`
+	}
+	list := func(query string) listResponse {
+		t.Helper()
+		rec := do(http.MethodGet, "/v1/notifications"+query, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var res listResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return res
+	}
+
+	// Repeated setup_waiting heartbeats keep one notification and push it once per cooldown.
+	heartbeat(notificationTypeSetupWaiting)
+	heartbeat(notificationTypeSetupWaiting)
+	if n := pushed(); n != 1 {
+		t.Fatalf("expected one push, got %d", n)
+	}
+	res := list("")
+	if len(res.Notifications) != 1 || res.UnreadCount != 1 {
+		t.Fatalf("expected one unread notification, got %+v", res)
+	}
+	first := res.Notifications[0]
+	if first.AgentID != agentID || first.Channel != "jobs" || first.ReadAt != nil {
+		t.Fatalf("unexpected notification: %+v", first)
+	}
+
+	if rec := do(http.MethodPost, "/v1/notifications/"+first.ID+"/read", nil); rec.Code != http.StatusOK {
+		t.Fatalf("mark read: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if res := list("?unread=true"); len(res.Notifications) != 0 || res.UnreadCount != 0 {
+		t.Fatalf("expected no unread notifications, got %+v", res)
+	}
+
+	// Leaving setup_waiting resolves it; the next setup_waiting raises (and pushes) a new one.
+	heartbeat("idle")
+	heartbeat(notificationTypeSetupWaiting)
+	if n := pushed(); n != 1 {
+		t.Fatalf("expected a push for the new notification, got %d", n)
+	}
+	res = list("?limit=1")
+	if len(res.Notifications) != 1 || res.Notifications[0].ID == first.ID || res.NextCursor == "" {
+		t.Fatalf("expected the new notification first with a next cursor, got %+v", res)
+	}
+	older := list("?limit=1&cursor=" + res.NextCursor)
+	if len(older.Notifications) != 1 || older.Notifications[0].ID != first.ID || older.Notifications[0].ResolvedAt == nil {
+		t.Fatalf("expected the resolved notification on the next page, got %+v", older)
+	}
+
+	rec := do(http.MethodPost, "/v1/notifications/read-all", nil)
+	if rec.Code != http.StatusOK || !bytes.Contains(rec.Body.Bytes(), []byte(`"marked":1`)) {
+		t.Fatalf("read-all: expected one marked notification, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodGet, "/v1/notifications?cursor="+agentID, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an unknown cursor, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := do(http.MethodPost, "/v1/notifications/"+agentID+"/read", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for an unknown notification, got %d", http.StatusNotFound, rec.Code)
+	}
+}
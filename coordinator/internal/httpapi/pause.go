@@ -0,0 +1,124 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// pauseStatus is one paused or draining chain/channel in the dashboard. An entry with no
+// in-flight tasks is fully drained.
+type pauseStatus struct {
+	Kind     string          `json:"kind"` // "chain" | "channel"
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Pause    model.PauseMode `json:"pause"`
+	InFlight int             `json:"in_flight"`
+	Drained  bool            `json:"drained"`
+}
+
+// handleChainPause returns the handler for POST /v1/chains/{id}/pause|drain|resume.
+// Both modes block the next claim. Draining lets in-flight tasks finish; pausing stops them
+// now by requeueing them (the attempt is given back), held until resume, so an agent's later
+// complete/fail conflicts exactly as after a lease expiry.
+func (s *Server) handleChainPause(mode model.PauseMode) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chainID := strings.TrimSpace(r.PathValue("id"))
+		if chainID == "" {
+			writeError(w, http.StatusBadRequest, "chain_id_required", "chain ID is required")
+			return
+		}
+
+		chain, requeued, err := s.store.SetChainPause(r.Context(), chainID, mode)
+		if err != nil {
+			if err == store.ErrNotFound {
+				writeError(w, http.StatusNotFound, "not_found", "chain not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal", "failed to update chain")
+			return
+		}
+
+		userID := userIDFromContext(r.Context())
+		s.bus.PublishIDs(EventChains, userID, chain.ID)
+		s.publishRequeued(userID, requeued)
+		s.invalidateDashboardCache()
+		writeJSON(w, http.StatusOK, map[string]any{"chain": chain})
+	}
+}
+
+// handleChannelPause is handleChainPause for a whole channel.
+func (s *Server) handleChannelPause(mode model.PauseMode) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		channelID := strings.TrimSpace(r.PathValue("id"))
+		if channelID == "" {
+			writeError(w, http.StatusBadRequest, "channel_id_required", "channel ID is required")
+			return
+		}
+
+		ch, requeued, err := s.store.SetChannelPause(r.Context(), channelID, mode)
+		if err != nil {
+			if err == store.ErrNotFound {
+				writeError(w, http.StatusNotFound, "not_found", "channel not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal", "failed to update channel")
+			return
+		}
+
+		userID := userIDFromContext(r.Context())
+		s.bus.PublishIDs(EventChannels, userID, ch.ID)
+		s.publishRequeued(userID, requeued)
+		s.invalidateDashboardCache()
+		writeJSON(w, http.StatusOK, map[string]any{"channel": ch})
+	}
+}
+
+// publishRequeued announces the tasks a pause sent back to the queue, with their chains.
+func (s *Server) publishRequeued(userID string, tasks []model.Task) {
+	if len(tasks) == 0 {
+		return
+	}
+	var taskIDs, chainIDs []string
+	for _, t := range tasks {
+		taskIDs = append(taskIDs, t.ID)
+		chainIDs = append(chainIDs, t.ChainID)
+	}
+	s.bus.PublishIDs(EventTasks, userID, taskIDs...)
+	s.bus.PublishIDs(EventChains, userID, chainIDs...)
+}
+
+// pauseSummary lists the paused and draining channels and chains with their in-flight
+// task counts, so the dashboard can tell a drain in progress from a finished one.
+func pauseSummary(channels []model.Channel, chains []model.Chain, tasks []model.Task) []pauseStatus {
+	inFlightByChannel := map[string]int{}
+	inFlightByChain := map[string]int{}
+	for _, t := range tasks {
+		if t.Status != model.TaskStatusInProgress {
+			continue
+		}
+		inFlightByChannel[t.ChannelID]++
+		if t.ChainID != "" {
+			inFlightByChain[t.ChainID]++
+		}
+	}
+
+	out := []pauseStatus{}
+	for _, ch := range channels {
+		if ch.Pause == "" {
+			continue
+		}
+		n := inFlightByChannel[ch.ID]
+		out = append(out, pauseStatus{Kind: "channel", ID: ch.ID, Name: ch.Name, Pause: ch.Pause, InFlight: n, Drained: n == 0})
+	}
+	for _, c := range chains {
+		if c.Pause == "" {
+			continue
+		}
+		n := inFlightByChain[c.ID]
+		out = append(out, pauseStatus{Kind: "chain", ID: c.ID, Name: c.Name, Pause: c.Pause, InFlight: n, Drained: n == 0})
+	}
+	return out
+}
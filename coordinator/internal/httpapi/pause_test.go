@@ -0,0 +1,113 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+func TestHandleChainPause(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "pause-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	chain, tasks, err := server.store.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "force-push"}, []store.NewChainTask{
+		{Task: model.Task{Title: "rebase"}},
+		{Task: model.Task{Title: "push"}},
+	})
+	if err != nil {
+		t.Fatalf("create chain: %v", err)
+	}
+	agentID := "a0000000-0000-4000-8000-000000000001"
+	if _, err := server.store.UpsertAgent(ctx, model.Agent{ID: agentID, Name: "pause-agent"}); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+	if _, err := server.store.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentID, ChannelID: ch.ID}); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	post := func(path string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		return rec
+	}
+
+	// Pausing blocks claims and stops the running task: it goes back to the queue.
+	rec := post("/v1/chains/" + chain.ID + "/pause")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("pause: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Chain model.Chain `json:"chain"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Chain.Pause != model.PauseModePaused {
+		t.Fatalf("expected chain paused, got %q", resp.Chain.Pause)
+	}
+	stopped, _ := server.store.ListTasks(ctx, store.TaskFilter{IDs: []string{tasks[0].ID}})
+	if len(stopped) != 1 || stopped[0].Status != model.TaskStatusQueued || stopped[0].AssignedAgentID != "" {
+		t.Fatalf("expected %q to be requeued, got %+v", tasks[0].Title, stopped)
+	}
+	if in, err := server.store.ClaimTaskInput(ctx, store.ClaimTaskInputRequest{TaskID: tasks[0].ID, AgentID: agentID}); err == nil {
+		t.Fatalf("expected no control input for the agent, got %+v", in)
+	}
+
+	// The dashboard lists the paused chain with nothing left running.
+	rec = httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/dashboard", nil))
+	var dash struct {
+		Paused []pauseStatus `json:"paused"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&dash); err != nil {
+		t.Fatalf("decode dashboard: %v", err)
+	}
+	if len(dash.Paused) != 1 || dash.Paused[0].ID != chain.ID || dash.Paused[0].Kind != "chain" || dash.Paused[0].InFlight != 0 || !dash.Paused[0].Drained {
+		t.Fatalf("unexpected dashboard pause summary: %+v", dash.Paused)
+	}
+
+	// The agent's late completion conflicts, and nothing is handed out while paused.
+	if _, err := server.store.CompleteTask(ctx, store.CompleteTaskRequest{TaskID: tasks[0].ID, AgentID: agentID}); err == nil {
+		t.Fatalf("expected completing a paused task to fail")
+	}
+	if _, err := server.store.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentID, ChannelID: ch.ID}); err != store.ErrNoQueuedTasks {
+		t.Fatalf("expected nothing to claim while paused, got %v", err)
+	}
+
+	// Resume, claim again, then drain: the running task is left alone.
+	if rec := post("/v1/chains/" + chain.ID + "/resume"); rec.Code != http.StatusOK {
+		t.Fatalf("resume: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if _, err := server.store.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentID, ChannelID: ch.ID}); err != nil {
+		t.Fatalf("claim after resume: %v", err)
+	}
+	if rec := post("/v1/channels/" + ch.ID + "/drain"); rec.Code != http.StatusOK {
+		t.Fatalf("drain: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	running, _ := server.store.ListTasks(ctx, store.TaskFilter{ChainID: chain.ID, Status: model.TaskStatusInProgress})
+	if len(running) != 1 {
+		t.Fatalf("expected the in-flight task to keep running, got %d", len(running))
+	}
+	rec = httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/dashboard", nil))
+	if err := json.NewDecoder(rec.Body).Decode(&dash); err != nil {
+		t.Fatalf("decode dashboard: %v", err)
+	}
+	if len(dash.Paused) != 1 || dash.Paused[0].Kind != "channel" || dash.Paused[0].InFlight != 1 || dash.Paused[0].Drained {
+		t.Fatalf("expected a channel still draining, got %+v", dash.Paused)
+	}
+
+	if rec := post("/v1/chains/c0000000-0000-4000-8000-000000000000/pause"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
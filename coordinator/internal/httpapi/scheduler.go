@@ -0,0 +1,171 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/cron"
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// scheduleRunReasonActive is recorded when a run is skipped because the chain of the
+// previous run is still queued, in progress or locked.
+const scheduleRunReasonActive = "previous_run_active"
+
+// RunScheduler materializes due recurring schedules until ctx is cancelled.
+// Cron expressions have minute resolution, so the interval should stay well below a minute.
+func (s *Server) RunScheduler(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	s.runDueSchedules(ctx, time.Now().UTC())
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			s.runDueSchedules(ctx, time.Now().UTC())
+		}
+	}
+}
+
+func (s *Server) runDueSchedules(ctx context.Context, now time.Time) {
+	schedules, err := s.store.ListSchedules(ctx, "")
+	if err != nil {
+		log.Printf("scheduler: list schedules failed: %v", err)
+		return
+	}
+
+	for _, sc := range schedules {
+		if !sc.Enabled || sc.NextRunAt == nil || sc.NextRunAt.After(now) {
+			continue
+		}
+		s.runSchedule(ctx, sc, now)
+	}
+}
+
+// runSchedule performs one due run of sc. Runs missed while the coordinator was down
+// collapse into this one: the next run is computed from now, not from the missed slot.
+func (s *Server) runSchedule(ctx context.Context, sc model.Schedule, now time.Time) {
+	run := model.ScheduleRun{ScheduleID: sc.ID, ScheduledFor: *sc.NextRunAt}
+	var taskIDs []string
+
+	if active, err := s.scheduleChainActive(ctx, sc.LastChainID); err != nil {
+		run.Status = model.ScheduleRunStatusFailed
+		run.Reason = err.Error()
+	} else if active {
+		run.Status = model.ScheduleRunStatusSkipped
+		run.Reason = scheduleRunReasonActive
+	} else if chain, tasks, err := s.materializeSchedule(ctx, sc, run.ScheduledFor); err != nil {
+		run.Status = model.ScheduleRunStatusFailed
+		run.Reason = err.Error()
+	} else {
+		run.Status = model.ScheduleRunStatusCreated
+		run.ChainID = chain.ID
+		for _, t := range tasks {
+			taskIDs = append(taskIDs, t.ID)
+		}
+	}
+
+	next, err := nextScheduleRun(sc, now)
+	if err != nil {
+		// The expression was valid when saved; if it no longer is, stop firing.
+		log.Printf("scheduler: schedule %s: %v", sc.ID, err)
+		next = nil
+	}
+	if _, err := s.store.RecordScheduleRun(ctx, run, next); err != nil {
+		log.Printf("scheduler: record run of schedule %s failed: %v", sc.ID, err)
+		return
+	}
+	log.Printf("scheduler: schedule %s (%s) run %s chain=%s %s", sc.ID, sc.Name, run.Status, run.ChainID, run.Reason)
+
+	s.bus.PublishIDs(EventSchedules, sc.UserID, sc.ID)
+	if run.ChainID != "" {
+		s.bus.PublishIDs(EventChains, sc.UserID, run.ChainID)
+		s.bus.PublishIDs(EventTasks, sc.UserID, taskIDs...)
+	}
+	s.invalidateDashboardCache()
+}
+
+// scheduleChainActive reports whether the chain created by a schedule's previous run
+// still has work pending. A deleted chain does not block the next run.
+func (s *Server) scheduleChainActive(ctx context.Context, chainID string) (bool, error) {
+	if chainID == "" {
+		return false, nil
+	}
+	chain, err := s.store.GetChain(ctx, chainID)
+	if err == store.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	switch chain.Status {
+	case model.ChainStatusQueued, model.ChainStatusInProgress, model.ChainStatusLocked:
+		return true, nil
+	}
+	return false, nil
+}
+
+// materializeSchedule creates the chain of one run and its tasks in template order,
+// all-or-nothing.
+func (s *Server) materializeSchedule(ctx context.Context, sc model.Schedule, scheduledFor time.Time) (model.Chain, []model.Task, error) {
+	loc, err := time.LoadLocation(sc.Timezone)
+	if err != nil {
+		return model.Chain{}, nil, fmt.Errorf("timezone_invalid: %w", err)
+	}
+	local := scheduledFor.In(loc)
+	expand := strings.NewReplacer(
+		"{{date}}", local.Format("2006-01-02"),
+		"{{time}}", local.Format("15:04"),
+		"{{schedule}}", sc.Name,
+	).Replace
+
+	tasks := make([]store.NewChainTask, len(sc.Tasks))
+	for i, tmpl := range sc.Tasks {
+		tasks[i].Task = model.Task{
+			Title:               expand(tmpl.Title),
+			Description:         expand(tmpl.Description),
+			Priority:            tmpl.Priority,
+			ExecutionMode:       tmpl.ExecutionMode,
+			MaxAttempts:         tmpl.MaxAttempts,
+			RetryBackoffSeconds: tmpl.RetryBackoffSeconds,
+		}
+	}
+
+	return s.store.CreateChainWithTasks(ctx, model.Chain{
+		UserID:      sc.UserID,
+		ChannelID:   sc.ChannelID,
+		Name:        fmt.Sprintf("%s %s", sc.Name, local.Format("2006-01-02 15:04")),
+		Description: fmt.Sprintf("Created by schedule %s (%s)", sc.Name, sc.ID),
+		Status:      model.ChainStatusQueued,
+	}, tasks)
+}
+
+// nextScheduleRun returns the first fire time of sc strictly after after, evaluated in the
+// schedule's time zone, or nil if the expression never fires again.
+func nextScheduleRun(sc model.Schedule, after time.Time) (*time.Time, error) {
+	expr, err := cron.Parse(sc.Cron)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(sc.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("timezone_invalid: %w", err)
+	}
+	next := expr.Next(after.In(loc))
+	if next.IsZero() {
+		return nil, nil
+	}
+	next = next.UTC()
+	return &next, nil
+}
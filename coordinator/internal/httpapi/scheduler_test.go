@@ -0,0 +1,128 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+func TestRunDueSchedules(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "nightly-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	due := time.Date(2026, time.March, 14, 2, 0, 0, 0, time.UTC)
+	sc, err := server.store.CreateSchedule(ctx, model.Schedule{
+		ChannelID: ch.ID,
+		Name:      "nightly",
+		Cron:      "0 2 * * *",
+		Enabled:   true,
+		NextRunAt: &due,
+		Tasks: []model.ScheduleTaskTemplate{
+			{Title: "update deps {{date}}"},
+			{Title: "run lint fix", Priority: 3},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+
+	// First tick: the chain and its tasks are materialized in template order.
+	now := due.Add(10 * time.Second)
+	server.runDueSchedules(ctx, now)
+
+	sc, err = server.store.GetSchedule(ctx, sc.ID)
+	if err != nil {
+		t.Fatalf("get schedule: %v", err)
+	}
+	if sc.LastChainID == "" {
+		t.Fatalf("expected a chain to be created")
+	}
+	if want := due.Add(24 * time.Hour); sc.NextRunAt == nil || !sc.NextRunAt.Equal(want) {
+		t.Fatalf("expected next run %v, got %v", want, sc.NextRunAt)
+	}
+	tasks, err := server.store.ListTasks(ctx, store.TaskFilter{ChainID: sc.LastChainID})
+	if err != nil {
+		t.Fatalf("list tasks: %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Title != "update deps 2026-03-14" || tasks[1].Sequence != 2 || tasks[1].Priority != 3 {
+		t.Fatalf("unexpected tasks: %+v", tasks)
+	}
+
+	// Next tick while that chain is still queued: the run is skipped.
+	firstChainID := sc.LastChainID
+	now = sc.NextRunAt.Add(time.Second)
+	server.runDueSchedules(ctx, now)
+	runs, err := server.store.ListScheduleRuns(ctx, sc.ID, 0)
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	if len(runs) != 2 || runs[0].Status != model.ScheduleRunStatusSkipped || runs[0].Reason != scheduleRunReasonActive {
+		t.Fatalf("expected a skipped run, got %+v", runs)
+	}
+
+	// Once the previous chain is done, the following tick creates a new one.
+	chain, err := server.store.GetChain(ctx, firstChainID)
+	if err != nil {
+		t.Fatalf("get chain: %v", err)
+	}
+	chain.Status = model.ChainStatusDone
+	if _, err := server.store.UpdateChain(ctx, chain); err != nil {
+		t.Fatalf("finish chain: %v", err)
+	}
+	sc, _ = server.store.GetSchedule(ctx, sc.ID)
+	server.runDueSchedules(ctx, sc.NextRunAt.Add(time.Second))
+	sc, _ = server.store.GetSchedule(ctx, sc.ID)
+	if sc.LastChainID == "" || sc.LastChainID == firstChainID {
+		t.Fatalf("expected a new chain, got %q", sc.LastChainID)
+	}
+}
+
+func TestHandleSchedules_Validation(t *testing.T) {
+	server := newTestServer(t)
+	ch, err := server.store.CreateChannel(context.Background(), model.Channel{Name: "schedule-api"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		body map[string]any
+		code int
+	}{
+		{"BadCron", map[string]any{"channel_id": ch.ID, "name": "x", "cron": "61 * * * *", "tasks": []map[string]any{{"title": "t"}}}, http.StatusBadRequest},
+		{"BadTimezone", map[string]any{"channel_id": ch.ID, "name": "x", "cron": "@daily", "timezone": "Mars/Olympus", "tasks": []map[string]any{{"title": "t"}}}, http.StatusBadRequest},
+		{"NoTasks", map[string]any{"channel_id": ch.ID, "name": "x", "cron": "@daily"}, http.StatusBadRequest},
+		{"Valid", map[string]any{"channel_id": ch.ID, "name": "x", "cron": "@daily", "tasks": []map[string]any{{"title": "t"}}}, http.StatusCreated},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, _ := json.Marshal(tc.body)
+			rec := httptest.NewRecorder()
+			server.handleSchedules(rec, httptest.NewRequest(http.MethodPost, "/v1/schedules", bytes.NewReader(raw)))
+			if rec.Code != tc.code {
+				t.Fatalf("expected status %d, got %d: %s", tc.code, rec.Code, rec.Body.String())
+			}
+			if tc.code != http.StatusCreated {
+				return
+			}
+			var resp map[string]model.Schedule
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if sc := resp["schedule"]; !sc.Enabled || sc.NextRunAt == nil {
+				t.Fatalf("expected an enabled schedule with next_run_at, got %+v", sc)
+			}
+		})
+	}
+}
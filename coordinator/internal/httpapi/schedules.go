@@ -0,0 +1,251 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+type createScheduleRequest struct {
+	ChannelID   string                       `json:"channel_id"`
+	Name        string                       `json:"name"`
+	Description string                       `json:"description"`
+	Cron        string                       `json:"cron"`
+	Timezone    string                       `json:"timezone"` // IANA zone, default UTC
+	Tasks       []model.ScheduleTaskTemplate `json:"tasks"`
+	Enabled     *bool                        `json:"enabled"` // default true
+}
+
+// updateScheduleRequest uses pointers so omitted fields keep their current value.
+type updateScheduleRequest struct {
+	Name        *string                       `json:"name"`
+	Description *string                       `json:"description"`
+	Cron        *string                       `json:"cron"`
+	Timezone    *string                       `json:"timezone"`
+	Tasks       *[]model.ScheduleTaskTemplate `json:"tasks"`
+	Enabled     *bool                         `json:"enabled"`
+}
+
+// scheduleNextRun validates the cron expression and time zone of sc and returns its next
+// run after now (nil while the schedule is disabled). It returns an error code and message
+// suitable for writeError.
+func scheduleNextRun(sc model.Schedule, now time.Time) (*time.Time, string, string) {
+	if strings.TrimSpace(sc.Timezone) != "" {
+		if _, err := time.LoadLocation(sc.Timezone); err != nil {
+			return nil, "timezone_invalid", fmt.Sprintf("unknown time zone %q", sc.Timezone)
+		}
+	}
+	next, err := nextScheduleRun(sc, now)
+	if err != nil {
+		return nil, "cron_invalid", err.Error()
+	}
+	if next == nil {
+		return nil, "cron_never_fires", "cron expression never fires"
+	}
+	if !sc.Enabled {
+		return nil, "", ""
+	}
+	return next, "", ""
+}
+
+func (s *Server) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		schedules, err := s.store.ListSchedules(r.Context(), userID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", "failed to list schedules")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"schedules": schedules})
+		return
+
+	case http.MethodPost:
+		var req createScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+			return
+		}
+
+		sc := model.Schedule{
+			UserID:      userID,
+			ChannelID:   strings.TrimSpace(req.ChannelID),
+			Name:        strings.TrimSpace(req.Name),
+			Description: strings.TrimSpace(req.Description),
+			Cron:        strings.TrimSpace(req.Cron),
+			Timezone:    strings.TrimSpace(req.Timezone),
+			Tasks:       req.Tasks,
+			Enabled:     req.Enabled == nil || *req.Enabled,
+		}
+		if sc.Cron == "" {
+			writeError(w, http.StatusBadRequest, "invalid_request", "cron_required")
+			return
+		}
+		next, code, msg := scheduleNextRun(sc, time.Now().UTC())
+		if code != "" {
+			writeError(w, http.StatusBadRequest, code, msg)
+			return
+		}
+		sc.NextRunAt = next
+
+		sc, err := s.store.CreateSchedule(r.Context(), sc)
+		if err != nil {
+			status := http.StatusBadRequest
+			if err == store.ErrNotFound { // channel_id not found
+				status = http.StatusNotFound
+			}
+			writeError(w, status, "invalid_request", err.Error())
+			return
+		}
+
+		s.bus.PublishIDs(EventSchedules, userID, sc.ID)
+		writeJSON(w, http.StatusCreated, map[string]any{"schedule": sc})
+		return
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+}
+
+func (s *Server) handleSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleID := strings.TrimSpace(r.PathValue("id"))
+	if scheduleID == "" {
+		writeError(w, http.StatusBadRequest, "schedule_id_required", "schedule ID is required")
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		sc, err := s.store.GetSchedule(r.Context(), scheduleID)
+		if err != nil {
+			if err == store.ErrNotFound {
+				writeError(w, http.StatusNotFound, "not_found", "schedule not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal", "failed to get schedule")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"schedule": sc})
+		return
+
+	case http.MethodPatch:
+		var req updateScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+			return
+		}
+
+		sc, err := s.store.GetSchedule(r.Context(), scheduleID)
+		if err != nil {
+			if err == store.ErrNotFound {
+				writeError(w, http.StatusNotFound, "not_found", "schedule not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal", "failed to get schedule")
+			return
+		}
+
+		wasEnabled := sc.Enabled
+		timingChanged := false
+		if req.Name != nil {
+			sc.Name = strings.TrimSpace(*req.Name)
+		}
+		if req.Description != nil {
+			sc.Description = strings.TrimSpace(*req.Description)
+		}
+		if req.Cron != nil {
+			sc.Cron = strings.TrimSpace(*req.Cron)
+			timingChanged = true
+		}
+		if req.Timezone != nil {
+			sc.Timezone = strings.TrimSpace(*req.Timezone)
+			timingChanged = true
+		}
+		if req.Tasks != nil {
+			sc.Tasks = *req.Tasks
+		}
+		if req.Enabled != nil {
+			sc.Enabled = *req.Enabled
+		}
+
+		// Keep a pending next_run_at unless the timing changed or the schedule was re-enabled.
+		if timingChanged || sc.Enabled != wasEnabled || sc.NextRunAt == nil {
+			next, code, msg := scheduleNextRun(sc, time.Now().UTC())
+			if code != "" {
+				writeError(w, http.StatusBadRequest, code, msg)
+				return
+			}
+			sc.NextRunAt = next
+		}
+
+		sc, err = s.store.UpdateSchedule(r.Context(), sc)
+		if err != nil {
+			status := http.StatusBadRequest
+			if err == store.ErrNotFound {
+				status = http.StatusNotFound
+			}
+			writeError(w, status, "invalid_request", err.Error())
+			return
+		}
+
+		s.bus.PublishIDs(EventSchedules, userID, sc.ID)
+		writeJSON(w, http.StatusOK, map[string]any{"schedule": sc})
+		return
+
+	case http.MethodDelete:
+		if err := s.store.DeleteSchedule(r.Context(), scheduleID); err != nil {
+			if err == store.ErrNotFound {
+				writeError(w, http.StatusNotFound, "not_found", "schedule not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal", "failed to delete schedule")
+			return
+		}
+
+		s.bus.PublishIDs(EventSchedules, userID, scheduleID)
+		w.WriteHeader(http.StatusNoContent)
+		return
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+}
+
+func (s *Server) handleScheduleRuns(w http.ResponseWriter, r *http.Request) {
+	scheduleID := strings.TrimSpace(r.PathValue("id"))
+	if _, err := s.store.GetSchedule(r.Context(), scheduleID); err != nil {
+		if err == store.ErrNotFound {
+			writeError(w, http.StatusNotFound, "not_found", "schedule not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "internal", "failed to get schedule")
+		return
+	}
+
+	limit := 50
+	if v := strings.TrimSpace(r.URL.Query().Get("limit")); v != "" {
+		// Ignore parsing errors and keep the default.
+		var n int
+		_, _ = fmt.Sscanf(v, "%d", &n)
+		if n > 0 {
+			limit = n
+		}
+	}
+
+	runs, err := s.store.ListScheduleRuns(r.Context(), scheduleID, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to list schedule runs")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"runs": runs})
+}
@@ -1,33 +1,51 @@
 package httpapi
 
 import (
+	"context"
+	"log"
 	"net/http"
 
+	"clwclw-monitor/coordinator/internal/artifact"
 	"clwclw-monitor/coordinator/internal/config"
+	"clwclw-monitor/coordinator/internal/leader"
+	"clwclw-monitor/coordinator/internal/model"
 	"clwclw-monitor/coordinator/internal/store"
 )
 
 type Server struct {
-	cfg     config.Config
-	store   store.Store
-	mux     *http.ServeMux
-	bus     *eventBus
-	notifTk *notificationTracker
+	cfg        config.Config
+	store      store.Store
+	mux        *http.ServeMux
+	bus        *eventBus
+	agentWatch *agentWatcher
+	agents     *agentHub
+	artifacts  artifact.Store
+	elector    leader.Elector
+	rules      *ruleEngine
+	webhooks   *webhookWorker
 }
 
 func NewServer(cfg config.Config, st store.Store) *Server {
 	initJWTKey(cfg.JWTSecret)
 	s := &Server{
-		cfg:     cfg,
-		store:   st,
-		mux:     http.NewServeMux(),
-		bus:     newEventBus(),
-		notifTk: newNotificationTracker(),
+		cfg:        cfg,
+		store:      st,
+		mux:        http.NewServeMux(),
+		bus:        newEventBus(),
+		agentWatch: newAgentWatcher(),
+		agents:     newAgentHub(),
+		rules:      newRuleEngine(),
+		webhooks:   newWebhookWorker(cfg.WebhookAllowPrivate),
 	}
 	s.registerRoutes()
 	return s
 }
 
+// SetLeaderElector makes /health report whether this replica runs the background jobs.
+func (s *Server) SetLeaderElector(e leader.Elector) {
+	s.elector = e
+}
+
 func (s *Server) Handler() http.Handler {
 	var h http.Handler = s.mux
 	h = recoverMiddleware(h)
@@ -37,6 +55,53 @@ func (s *Server) Handler() http.Handler {
 	return h
 }
 
+// Publish notifies stream subscribers of userID that resources of the given
+// type changed. Background jobs (e.g. the lease reaper) use it to keep
+// dashboards live without going through an HTTP handler.
+func (s *Server) Publish(typ string, userID string) {
+	s.bus.Publish(typ, userID)
+	s.invalidateDashboardCache()
+}
+
+// PublishLeaseExpired publishes the tasks the lease reaper requeued with their IDs, so
+// webhooks and notification rules see them: the tasks, their chains, and the agents that
+// held them with their task.lease_expired events.
+func (s *Server) PublishLeaseExpired(ctx context.Context, tasks []model.Task) {
+	type changed struct{ tasks, chains, agents, events []string }
+	byUser := map[string]*changed{}
+	for _, t := range tasks {
+		c := byUser[t.UserID]
+		if c == nil {
+			c = &changed{}
+			byUser[t.UserID] = c
+		}
+		c.tasks = append(c.tasks, t.ID)
+		c.chains = append(c.chains, t.ChainID)
+		s.notifyApprovalGates(ctx, t.ChainID)
+
+		// The requeue cleared the assignment; the event it recorded names the agent.
+		events, err := s.store.ListEvents(ctx, store.EventFilter{TaskID: t.ID, Limit: 1})
+		if err != nil {
+			log.Printf("lease reaper: list events of task %s failed: %v", t.ID, err)
+			continue
+		}
+		for _, e := range events {
+			if e.Type == store.EventTypeTaskLeaseExpired {
+				c.agents = append(c.agents, e.AgentID)
+				c.events = append(c.events, e.ID)
+			}
+		}
+	}
+
+	for userID, c := range byUser {
+		s.bus.PublishIDs(EventTasks, userID, c.tasks...)
+		s.bus.PublishIDs(EventChains, userID, c.chains...)
+		s.bus.PublishIDs(EventAgents, userID, c.agents...)
+		s.bus.PublishIDs(EventEvents, userID, c.events...)
+	}
+	s.invalidateDashboardCache()
+}
+
 func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("/health", s.handleHealth)
 
@@ -46,9 +111,11 @@ func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("GET /v1/auth/verify", s.handleAuthVerify)
 	s.mux.HandleFunc("POST /v1/auth/agent-token", s.handleAgentToken)
 	s.mux.HandleFunc("POST /v1/auth/debug-token", s.handleDebugToken)
+	s.mux.HandleFunc("PUT /v1/users/{id}/max-in-flight", s.handleUserMaxInFlight)
 
 	s.mux.HandleFunc("POST /v1/agents/heartbeat", s.handleAgentsHeartbeat)
 	s.mux.HandleFunc("POST /v1/agents/request-session", s.handleAgentsRequestSession)
+	s.mux.HandleFunc("GET /v1/agents/socket", s.handleAgentSocket)
 	s.mux.HandleFunc("GET /v1/agents/{id}/current-task", s.handleAgentCurrentTask)
 	s.mux.HandleFunc("GET /v1/agents/{id}", s.handleGetAgent)
 	s.mux.HandleFunc("PATCH /v1/agents/{id}/channels", s.handleAgentUpdateChannels)
@@ -56,11 +123,27 @@ func (s *Server) registerRoutes() {
 
 	s.mux.HandleFunc("/v1/channels", s.handleChannels)
 	s.mux.HandleFunc("GET /v1/channels/by-name/{name}", s.handleGetChannelByName)
+	s.mux.HandleFunc("/v1/channels/{id}", s.handleChannel)
+	s.mux.HandleFunc("POST /v1/channels/{id}/pause", s.handleChannelPause(model.PauseModePaused))
+	s.mux.HandleFunc("POST /v1/channels/{id}/drain", s.handleChannelPause(model.PauseModeDraining))
+	s.mux.HandleFunc("POST /v1/channels/{id}/resume", s.handleChannelPause(""))
 	s.mux.HandleFunc("/v1/chains", s.handleChains)
+	s.mux.HandleFunc("POST /v1/chains:bulk", s.handleChainsBulk)
 	s.mux.HandleFunc("POST /v1/chains/{id}/detach", s.handleChainDetach)
 	s.mux.HandleFunc("POST /v1/chains/{id}/assign-agent", s.handleChainAssignAgent)
+	s.mux.HandleFunc("POST /v1/chains/{id}/pause", s.handleChainPause(model.PauseModePaused))
+	s.mux.HandleFunc("POST /v1/chains/{id}/drain", s.handleChainPause(model.PauseModeDraining))
+	s.mux.HandleFunc("POST /v1/chains/{id}/resume", s.handleChainPause(""))
 	s.mux.HandleFunc("/v1/chains/{id}", s.handleChain)
+	s.mux.HandleFunc("GET /v1/chains/{id}/graph", s.handleChainGraph)
 	s.mux.HandleFunc("POST /v1/tasks/{id}/status", s.handleTaskUpdateStatus)
+	s.mux.HandleFunc("POST /v1/tasks/{id}/renew", s.handleTaskRenewLease)
+	s.mux.HandleFunc("POST /v1/tasks/{id}/resubmit", s.handleTaskResubmit)
+	s.mux.HandleFunc("POST /v1/tasks/{id}/cancel", s.handleTaskCancel)
+	s.mux.HandleFunc("POST /v1/tasks/{id}/approve", s.handleTaskReview(true))
+	s.mux.HandleFunc("POST /v1/tasks/{id}/reject", s.handleTaskReview(false))
+	s.mux.HandleFunc("GET /v1/tasks/{id}/result", s.handleTaskResult)
+	s.mux.HandleFunc("GET /v1/tasks/{id}/claimability", s.handleTaskClaimability)
 	s.mux.HandleFunc("/v1/tasks", s.handleTasks)
 	s.mux.HandleFunc("/v1/tasks/claim", s.handleTasksClaim)
 	s.mux.HandleFunc("/v1/tasks/assign", s.handleTasksAssign)
@@ -69,8 +152,26 @@ func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("/v1/tasks/inputs", s.handleTaskInputs)
 	s.mux.HandleFunc("/v1/tasks/inputs/claim", s.handleTaskInputsClaim)
 
+	s.mux.HandleFunc("/v1/templates", s.handleTemplates)
+	s.mux.HandleFunc("/v1/templates/{id}", s.handleTemplate)
+	s.mux.HandleFunc("POST /v1/templates/{id}/instantiate", s.handleTemplateInstantiate)
+	s.mux.HandleFunc("/v1/schedules", s.handleSchedules)
+	s.mux.HandleFunc("/v1/schedules/{id}", s.handleSchedule)
+	s.mux.HandleFunc("GET /v1/schedules/{id}/runs", s.handleScheduleRuns)
+
 	s.mux.HandleFunc("GET /v1/notifications", s.handleNotificationsList)
+	s.mux.HandleFunc("POST /v1/notifications/{id}/read", s.handleNotificationRead)
+	s.mux.HandleFunc("POST /v1/notifications/read-all", s.handleNotificationsReadAll)
 	s.mux.HandleFunc("POST /v1/notifications/dismiss", s.handleNotificationDismiss)
+	s.mux.HandleFunc("/v1/notification-rules", s.handleNotificationRules)
+	s.mux.HandleFunc("/v1/notification-rules/{id}", s.handleNotificationRule)
+	s.mux.HandleFunc("/v1/webhooks", s.handleWebhooks)
+	s.mux.HandleFunc("/v1/webhooks/{id}", s.handleWebhook)
+	s.mux.HandleFunc("GET /v1/webhooks/{id}/deliveries", s.handleWebhookDeliveries)
+	s.mux.HandleFunc("POST /v1/webhooks/{id}/deliveries/{delivery_id}/replay", s.handleWebhookDeliveryReplay)
+
+	s.mux.HandleFunc("/v1/artifacts", s.handleArtifacts)
+	s.mux.HandleFunc("GET /v1/artifacts/{id}", s.handleArtifactDownload)
 
 	s.mux.HandleFunc("/v1/events", s.handleEvents)
 	s.mux.HandleFunc("/v1/stream", s.handleStream)
@@ -0,0 +1,180 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+type templateRequest struct {
+	Name             string                `json:"name"`
+	Description      string                `json:"description"`
+	ChainName        string                `json:"chain_name"`
+	ChainDescription string                `json:"chain_description"`
+	Params           []model.TemplateParam `json:"params"`
+	Tasks            []model.TemplateTask  `json:"tasks"`
+}
+
+func (req templateRequest) template(userID string) model.Template {
+	return model.Template{
+		UserID:           userID,
+		Name:             strings.TrimSpace(req.Name),
+		Description:      strings.TrimSpace(req.Description),
+		ChainName:        strings.TrimSpace(req.ChainName),
+		ChainDescription: strings.TrimSpace(req.ChainDescription),
+		Params:           req.Params,
+		Tasks:            req.Tasks,
+	}
+}
+
+type instantiateTemplateRequest struct {
+	ChannelID string            `json:"channel_id"`
+	Params    map[string]string `json:"params"`
+}
+
+func (s *Server) handleTemplates(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		templates, err := s.store.ListTemplates(r.Context(), userID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", "failed to list templates")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"templates": templates})
+		return
+
+	case http.MethodPost:
+		var req templateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+			return
+		}
+
+		tpl, err := s.store.CreateTemplate(r.Context(), req.template(userID))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"template": tpl})
+		return
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+}
+
+func (s *Server) handleTemplate(w http.ResponseWriter, r *http.Request) {
+	templateID := strings.TrimSpace(r.PathValue("id"))
+	if templateID == "" {
+		writeError(w, http.StatusBadRequest, "template_id_required", "template ID is required")
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		tpl, err := s.store.GetTemplate(r.Context(), templateID)
+		if err != nil {
+			if err == store.ErrNotFound {
+				writeError(w, http.StatusNotFound, "not_found", "template not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal", "failed to get template")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"template": tpl})
+		return
+
+	case http.MethodPut:
+		var req templateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+			return
+		}
+
+		tpl := req.template(userID)
+		tpl.ID = templateID
+		tpl, err := s.store.UpdateTemplate(r.Context(), tpl)
+		if err != nil {
+			status := http.StatusBadRequest
+			if err == store.ErrNotFound {
+				status = http.StatusNotFound
+			}
+			writeError(w, status, "invalid_request", err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"template": tpl})
+		return
+
+	case http.MethodDelete:
+		if err := s.store.DeleteTemplate(r.Context(), templateID); err != nil {
+			if err == store.ErrNotFound {
+				writeError(w, http.StatusNotFound, "not_found", "template not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal", "failed to delete template")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+}
+
+// handleTemplateInstantiate renders a template with the given parameters and creates the
+// chain and all of its tasks atomically.
+func (s *Server) handleTemplateInstantiate(w http.ResponseWriter, r *http.Request) {
+	templateID := strings.TrimSpace(r.PathValue("id"))
+	userID := userIDFromContext(r.Context())
+
+	var req instantiateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+		return
+	}
+
+	tpl, err := s.store.GetTemplate(r.Context(), templateID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			writeError(w, http.StatusNotFound, "not_found", "template not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "internal", "failed to get template")
+		return
+	}
+
+	chain, tasks, err := store.InstantiateTemplate(tpl, req.Params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_params", err.Error())
+		return
+	}
+	chain.UserID = userID
+	chain.ChannelID = strings.TrimSpace(req.ChannelID)
+
+	chain, created, err := s.store.CreateChainWithTasks(r.Context(), chain, tasks)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, store.ErrNotFound) { // channel_id not found
+			status = http.StatusNotFound
+		}
+		writeError(w, status, "invalid_request", err.Error())
+		return
+	}
+
+	s.bus.PublishIDs(EventChains, userID, chain.ID)
+	s.bus.PublishIDs(EventTasks, userID, taskIDs(created)...)
+	s.invalidateDashboardCache()
+	s.notifyApprovalGates(r.Context(), chain.ID)
+	writeJSON(w, http.StatusCreated, map[string]any{"chain": chain, "tasks": created})
+}
@@ -0,0 +1,97 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+func TestHandleTemplateInstantiate(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "template-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	raw, _ := json.Marshal(map[string]any{
+		"name":       "release",
+		"chain_name": "release {{version}}",
+		"params":     []map[string]any{{"name": "version"}, {"name": "env", "default": "staging"}},
+		"tasks": []map[string]any{
+			{"key": "build", "title": "build {{version}}"},
+			{"key": "test", "title": "test {{ version }}"},
+			{"key": "deploy", "title": "deploy to {{env}}", "depends_on": []string{"build", "test"}},
+		},
+	})
+	rec := httptest.NewRecorder()
+	server.handleTemplates(rec, httptest.NewRequest(http.MethodPost, "/v1/templates", bytes.NewReader(raw)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create template: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created map[string]model.Template
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode template: %v", err)
+	}
+	tpl := created["template"]
+
+	instantiate := func(body map[string]any) *httptest.ResponseRecorder {
+		t.Helper()
+		raw, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/templates/"+tpl.ID+"/instantiate", bytes.NewReader(raw))
+		req.SetPathValue("id", tpl.ID)
+		server.handleTemplateInstantiate(rec, req)
+		return rec
+	}
+
+	// A missing required parameter creates nothing.
+	rec = instantiate(map[string]any{"channel_id": ch.ID})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+	if chains, _ := server.store.ListChains(ctx, "", ch.ID); len(chains) != 0 {
+		t.Fatalf("expected no chain after a failed instantiate, got %d", len(chains))
+	}
+
+	rec = instantiate(map[string]any{"channel_id": ch.ID, "params": map[string]string{"version": "1.4.0"}})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("instantiate: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Chain model.Chain  `json:"chain"`
+		Tasks []model.Task `json:"tasks"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode instantiate: %v", err)
+	}
+	if resp.Chain.Name != "release 1.4.0" || resp.Chain.ChannelID != ch.ID {
+		t.Fatalf("unexpected chain: %+v", resp.Chain)
+	}
+	if len(resp.Tasks) != 3 {
+		t.Fatalf("expected 3 tasks, got %d", len(resp.Tasks))
+	}
+	if resp.Tasks[0].Title != "build 1.4.0" || resp.Tasks[1].Title != "test 1.4.0" || resp.Tasks[2].Title != "deploy to staging" {
+		t.Fatalf("unexpected titles: %q %q %q", resp.Tasks[0].Title, resp.Tasks[1].Title, resp.Tasks[2].Title)
+	}
+	if deps := resp.Tasks[2].DependsOn; len(deps) != 2 || deps[0] != resp.Tasks[0].ID || deps[1] != resp.Tasks[1].ID {
+		t.Fatalf("expected deploy to depend on build and test, got %v", deps)
+	}
+	stored, err := server.store.ListTasks(ctx, store.TaskFilter{ChainID: resp.Chain.ID})
+	if err != nil || len(stored) != 3 {
+		t.Fatalf("expected 3 stored tasks, got %d (%v)", len(stored), err)
+	}
+
+	// Unknown channel.
+	rec = instantiate(map[string]any{"channel_id": "c0000000-0000-4000-8000-000000000000", "params": map[string]string{"version": "1"}})
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNotFound, rec.Code, rec.Body.String())
+	}
+}
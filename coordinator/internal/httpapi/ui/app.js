@@ -119,6 +119,8 @@ function startStream() {
   stream = new EventSource(url);
   stream.addEventListener('update', scheduleRefresh);
   stream.addEventListener('notification', handleNotificationEvent);
+  // Missed events were no longer buffered on reconnect: reload everything.
+  stream.addEventListener('reset', scheduleRefresh);
   stream.addEventListener('hello', () => {});
   stream.onerror = () => {
     // Keep the UI usable even if SSE is unavailable; polling remains as fallback.
@@ -174,7 +176,7 @@ function claudeStatusBadge(claudeStatus) {
     cls += ' ok';
   } else if (s === 'done') {
     cls += ' success';
-  } else if (s === 'failed') {
+  } else if (s === 'failed' || s === 'dead_letter') {
     cls += ' err';
   } else {
     cls += ' muted-badge';
@@ -324,6 +326,7 @@ function renderAgents(agents) {
       const agentState = a?.meta?.state || '';
       const isSetupWaiting = agentState === 'setup_waiting' && !tmux;
       const hasSubs = Array.isArray(a?.meta?.subscriptions) && a.meta.subscriptions.length > 0;
+      const labels = fmtLabels(a.labels);
 
       // Tmux cell: show state + start session button if applicable
       let tmuxCell;
@@ -342,7 +345,7 @@ function renderAgents(agents) {
       }
 
       return `<tr>
-        <td>${escapeHtml(name)}</td>
+        <td>${escapeHtml(name)}${labels ? `<div class="muted" style="font-size:11px;">${escapeHtml(labels)}</div>` : ''}</td>
         <td>${workerStatusBadge(a.last_seen)}</td>
         <td>${claudeStatusBadge(claudeStatus)}</td>
         <td class="muted subs-cell" data-agent-id="${escapeHtml(a.id)}" data-subs="${escapeHtml(subs)}" title="Click to edit"
@@ -355,6 +358,29 @@ function renderAgents(agents) {
     .join('');
 }
 
+// Agent labels / task requires as "key=value, ...".
+function fmtLabels(labels) {
+  if (!labels || typeof labels !== 'object') return '';
+  return Object.keys(labels).sort().map((k) => `${k}=${labels[k]}`).join(', ');
+}
+
+// Pause state pill plus pause / drain / resume buttons for a chain or channel.
+// inFlight is the number of its tasks still running; a drain lets them finish (done at 0),
+// a pause sends them back to the queue.
+function pauseControls(kind, item, inFlight) {
+  const id = escapeHtml(item.id);
+  const btn = (mode, label) =>
+    `<button class="btn" data-action="pause-control" data-kind="${kind}" data-id="${id}" data-mode="${mode}">${label}</button>`;
+  if (item.pause === 'paused') {
+    return `<span class="pill warn">Paused</span>${btn('resume', 'Resume')}`;
+  }
+  if (item.pause === 'draining') {
+    const label = inFlight > 0 ? `Draining (${inFlight} running)` : 'Drained';
+    return `<span class="pill warn">${label}</span>${btn('resume', 'Resume')}`;
+  }
+  return `${btn('drain', 'Drain')}${btn('pause', 'Pause')}`;
+}
+
 function renderTaskBoard(channels, chains, tasks, agents) {
   if (!channels.length && !chains.length && !tasks.length) {
     els.taskBoard.innerHTML = `<div class="muted">No channels, chains, or tasks yet.</div>`;
@@ -392,8 +418,8 @@ function renderTaskBoard(channels, chains, tasks, agents) {
       const queued = list.filter((t) => t.status === 'queued').sort((a, b) => a.sequence - b.sequence);
       const locked = list.filter((t) => t.status === 'locked').sort((a, b) => a.sequence - b.sequence);
       const prog = list.filter((t) => t.status === 'in_progress' || t.status === 'locked').sort((a, b) => a.sequence - b.sequence);
-      const done = list.filter((t) => t.status === 'done').sort((a, b) => a.sequence - b.sequence);
-      const failed = list.filter((t) => t.status === 'failed').sort((a, b) => a.sequence - b.sequence);
+      const done = list.filter((t) => t.status === 'done' || t.status === 'cancelled').sort((a, b) => a.sequence - b.sequence);
+      const failed = list.filter((t) => t.status === 'failed' || t.status === 'dead_letter').sort((a, b) => a.sequence - b.sequence);
 
       // Owner agent info
       const ownerAgent = ch.owner_agent_id ? lastAgentsById.get(ch.owner_agent_id) : null;
@@ -433,8 +459,8 @@ function renderTaskBoard(channels, chains, tasks, agents) {
       inner += `
         <div class="${chainClass}">
           <div class="chain-title">
-            <div class="chain-badge"><strong>Chain</strong>: ${escapeHtml(ch.name)} ${claudeStatusBadge(ch.status)} ${ownerHtml}</div>
-            <div style="display:flex;align-items:center;gap:8px;">${assignDropdown}<span class="pill">${list.length} tasks</span><button class="btn chain-toggle-btn" data-action="toggle-chain-board" data-chain-id="${escapeHtml(ch.id)}">${toggleLabel}</button></div>
+            <div class="chain-badge"><strong>Chain</strong>: ${escapeHtml(ch.name)} ${claudeStatusBadge(ch.status)} ${ch.on_failure && ch.on_failure !== 'halt' ? `<span class="pill" title="on_failure policy">on failure: ${escapeHtml(ch.on_failure)}</span>` : ''} ${ownerHtml}</div>
+            <div style="display:flex;align-items:center;gap:8px;">${assignDropdown}${pauseControls('chains', ch, prog.length - locked.length)}<span class="pill">${list.length} tasks</span><button class="btn chain-toggle-btn" data-action="toggle-chain-board" data-chain-id="${escapeHtml(ch.id)}">${toggleLabel}</button></div>
           </div>
           <div class="${boardClass}">
             ${renderTaskCol('Queued', queued, { variant: 'queued', channelId: channel.id, chainId: ch.id })}
@@ -455,6 +481,8 @@ function renderTaskBoard(channels, chains, tasks, agents) {
         <div class="channel-header">
           <div class="channel-label">Channel: ${escapeHtml(channel.name)}</div>
           <div class="channel-actions">
+            ${pauseControls('channels', channel, tasks.filter((t) => t.channel_id === channel.id && t.status === 'in_progress').length)}
+            ${channel.max_in_flight ? `<span class="pill" title="max in-flight tasks">${tasks.filter((t) => t.channel_id === channel.id && t.status === 'in_progress').length}/${channel.max_in_flight} running</span>` : ''}
             <span class="pill">${channelChains.length} chains</span>
             <div class="chain-create-wrap">
               <button class="btn chain-create-btn" data-action="toggle-chain-popover" data-channel-id="${escapeHtml(channel.id)}">New Chain</button>
@@ -498,9 +526,16 @@ function renderTaskCol(title, tasks, opts = {}) {
             <button class="btn" data-action="task-status" data-task-id="${escapeHtml(t.id)}" data-status="done">→ Done</button>
             <div class="muted" style="font-size:11px;">locked</div>
           </div>`;
+        } else if (variant === 'queued' && t.type === 'approval') {
+          actions = `<div style="margin-top:10px;display:flex;gap:10px;align-items:center;">
+            <button class="btn" data-action="approve" data-task-id="${escapeHtml(t.id)}">Approve</button>
+            <button class="btn danger" data-action="reject" data-task-id="${escapeHtml(t.id)}">Reject</button>
+            <div class="muted" style="font-size:11px;">approval gate</div>
+          </div>`;
         } else if (variant === 'queued') {
           actions = `<div style="margin-top:10px;display:flex;gap:10px;align-items:center;">
             <button class="btn" data-action="assign" data-task-id="${escapeHtml(t.id)}">Assign…</button>
+            <button class="btn danger" data-action="cancel" data-task-id="${escapeHtml(t.id)}">Cancel</button>
           </div>`;
         } else if (variant === 'in_progress') {
           actions = `<div style="margin-top:10px;display:flex;gap:10px;align-items:center;">
@@ -509,17 +544,39 @@ function renderTaskCol(title, tasks, opts = {}) {
               : ''}
             <button class="btn" data-action="complete" data-task-id="${escapeHtml(t.id)}">Mark Done</button>
             <button class="btn danger" data-action="fail" data-task-id="${escapeHtml(t.id)}">Fail</button>
+            <button class="btn danger" data-action="cancel" data-task-id="${escapeHtml(t.id)}">Cancel</button>
             <div class="muted" style="font-size:11px;">agent: ${escapeHtml(t.assigned_agent_id || '')}</div>
           </div>`;
+        } else if (variant === 'failed') {
+          actions = `<div style="margin-top:10px;display:flex;gap:10px;align-items:center;">
+            <button class="btn" data-action="resubmit" data-task-id="${escapeHtml(t.id)}">Resubmit</button>
+            <button class="btn" data-action="result" data-task-id="${escapeHtml(t.id)}">Result</button>
+            ${t.status === 'dead_letter' ? claudeStatusBadge(t.status) : ''}
+            <div class="muted" style="font-size:11px;">attempts: ${t.attempts || 0}</div>
+          </div>`;
+        } else if (t.status === 'cancelled') {
+          actions = `<div style="margin-top:10px;display:flex;gap:10px;align-items:center;">
+            ${claudeStatusBadge(t.status)}
+            <div class="muted" style="font-size:11px;">${escapeHtml(t.cancel_reason || '')}</div>
+          </div>`;
+        } else if (t.type === 'approval' && t.reviewed_at) {
+          actions = `<div style="margin-top:10px;display:flex;gap:10px;align-items:center;">
+            <div class="muted" style="font-size:11px;">${t.status === 'done' ? 'approved' : 'rejected'} by ${escapeHtml(t.reviewed_by || '—')}${t.review_comment ? `: ${escapeHtml(t.review_comment)}` : ''}</div>
+          </div>`;
+        } else if (variant === 'done') {
+          actions = `<div style="margin-top:10px;display:flex;gap:10px;align-items:center;">
+            <button class="btn" data-action="result" data-task-id="${escapeHtml(t.id)}">Result</button>
+          </div>`;
         }
 
         return `
         <div class="${taskClass}">
-          <div class="task-title">${escapeHtml(t.title)}</div>
+          <div class="task-title">${escapeHtml(t.title)}${t.compensation ? ' <span class="muted" style="font-size:11px;">(compensation)</span>' : ''}</div>
           <div class="task-desc">${escapeHtml(t.description || '')}</div>
           ${actions}
           <div class="muted" style="margin-top:6px;font-size:11px;">
             ${t.chain_id ? `chain: ${escapeHtml(t.chain_id)} seq: ${t.sequence}<br>` : ''}
+            ${t.requires ? `requires: ${escapeHtml(fmtLabels(t.requires))}<br>` : ''}
             ${escapeHtml(t.id)}
           </div>
         </div>
@@ -553,6 +610,26 @@ function renderTaskCol(title, tasks, opts = {}) {
   `;
 }
 
+// Blob IDs linked from an event payload (artifact_id / artifact_ids).
+function eventArtifactIds(payload) {
+  if (!payload) return [];
+  const ids = [];
+  if (typeof payload.artifact_id === 'string') ids.push(payload.artifact_id.trim());
+  if (Array.isArray(payload.artifact_ids)) {
+    for (const id of payload.artifact_ids) {
+      if (typeof id === 'string') ids.push(id.trim());
+    }
+  }
+  return [...new Set(ids.filter(Boolean))];
+}
+
+// Links can't send the Authorization header, so the token goes in the query like /v1/stream.
+function artifactUrl(id) {
+  const token = getAuthToken();
+  const path = `/v1/artifacts/${encodeURIComponent(id)}`;
+  return token ? `${path}?token=${encodeURIComponent(token)}` : path;
+}
+
 function renderEvents(events) {
   if (!events.length) {
     els.eventsList.innerHTML = `<div class="muted">No events yet.</div>`;
@@ -563,12 +640,16 @@ function renderEvents(events) {
     .slice(0, 80)
     .map((e) => {
       const payload = e.payload ? JSON.stringify(e.payload, null, 2) : '';
+      const links = eventArtifactIds(e.payload)
+        .map((id) => `<a class="event-artifact" href="${escapeHtml(artifactUrl(id))}" target="_blank" rel="noopener">artifact ${escapeHtml(id.slice(0, 12))}</a>`)
+        .join(' ');
       return `
       <div class="event">
         <div class="event-top">
           <div class="event-type">${escapeHtml(e.type)}</div>
           <div class="event-time">${escapeHtml(fmtTime(e.created_at))}</div>
         </div>
+        ${links ? `<div class="event-artifacts">${links}</div>` : ''}
         <div class="event-meta">${escapeHtml(payload)}</div>
       </div>
     `;
@@ -775,13 +856,19 @@ async function refresh() {
 async function fetchNotifications() {
   try {
     const data = await api('/v1/notifications');
+    // Newest first; the panel shows the latest page.
     notifications = (data.notifications || []).map(n => ({
+      id: n.id,
       key: n.key,
-      agentId: n.agent_id,
-      agentName: n.agent_name || n.agent_id,
+      agentId: n.agent_id || '',
+      agentName: n.agent_name || n.agent_id || '',
       type: n.type,
+      ruleName: (n.extra && n.extra.rule_name) || '',
+      severity: n.severity || 'info',
       channel: n.channel || '',
       message: n.message || '',
+      read: !!n.read_at,
+      resolved: !!n.resolved_at,
       time: new Date(n.created_at),
     }));
     renderNotifPanel();
@@ -793,15 +880,20 @@ function handleNotificationEvent(e) {
   try {
     const data = JSON.parse(e.data);
     const payload = data.payload || {};
+    // Approval gates are not tied to an agent; they carry the gate's task_id instead.
+    // Rule notifications may only carry a chain_id.
     const agentId = payload.agent_id || '';
+    const taskId = payload.task_id || '';
+    const chainId = payload.chain_id || '';
     const type = payload.notification_type || '';
-    if (!agentId || !type) return;
+    if ((!agentId && !taskId && !chainId) || !type) return;
 
     // Show toast
     addToast({
       agentId,
-      agentName: payload.agent_name || agentId,
+      agentName: payload.agent_name || agentId || payload.title || taskId,
       type,
+      ruleName: payload.rule_name || '',
       channel: payload.channel || '',
       message: payload.message || '',
     });
@@ -812,6 +904,14 @@ function handleNotificationEvent(e) {
   } catch { /* ignore */ }
 }
 
+function notificationTitle(type, ruleName) {
+  if (type === 'rule') return ruleName || 'Notification Rule';
+  if (type === 'agent_offline') return 'Agent Offline';
+  if (type === 'setup_waiting') return 'Agent Setup Required';
+  if (type === 'approval_required') return 'Approval Required';
+  return 'Notification';
+}
+
 // --- Toast (floating popup, top-right) ---
 
 function addToast(data) {
@@ -833,15 +933,18 @@ function renderToasts() {
 
   container.innerHTML = toasts.map(t => {
     const hasChannel = !!t.channel;
-    const actionBtn = hasChannel
-      ? `<button class="btn primary toast-action-btn" data-toast-action="start"
-           data-agent-id="${escapeHtml(t.agentId)}" data-channel="${escapeHtml(t.channel)}"
-           data-toast-id="${t.id}">Start Session</button>`
-      : `<span class="muted" style="font-size:11px;">Assign channel first</span>`;
+    let actionBtn = '';
+    if (t.type === 'setup_waiting') {
+      actionBtn = hasChannel
+        ? `<button class="btn primary toast-action-btn" data-toast-action="start"
+             data-agent-id="${escapeHtml(t.agentId)}" data-channel="${escapeHtml(t.channel)}"
+             data-toast-id="${t.id}">Start Session</button>`
+        : `<span class="muted" style="font-size:11px;">Assign channel first</span>`;
+    }
     return `
       <div class="toast-item" data-toast-id="${t.id}">
         <div class="toast-content">
-          <div class="toast-title">Agent Setup Required</div>
+          <div class="toast-title">${escapeHtml(notificationTitle(t.type, t.ruleName))}</div>
           <div class="toast-msg">${escapeHtml(t.message)}</div>
           <div class="toast-actions">${actionBtn}</div>
         </div>
@@ -873,14 +976,13 @@ function renderNotifPanel() {
     return;
   }
 
-  // Show newest first
-  const sorted = [...notifications].reverse();
-  list.innerHTML = sorted.map(n => {
+  list.innerHTML = notifications.map(n => {
     const timeStr = fmtTime(n.time.toISOString());
+    const cls = ['notification-item', n.read ? '' : 'unread', n.resolved ? 'resolved' : ''].filter(Boolean).join(' ');
     return `
-      <div class="notification-item">
+      <div class="${cls}">
         <div class="notification-item-header">
-          <span class="notification-item-title">Agent Setup Required</span>
+          <span class="notification-item-title">${escapeHtml(notificationTitle(n.type, n.ruleName))}</span>
           <span class="notification-item-time">${escapeHtml(timeStr)}</span>
         </div>
         <div class="notification-item-msg">${escapeHtml(n.message)}</div>
@@ -894,10 +996,10 @@ function toggleNotifPanel() {
   if (panel) panel.classList.toggle('hidden', !isNotifPanelOpen);
 
   if (isNotifPanelOpen) {
-    // Mark all as seen
+    // Show the list with unread highlighted, then mark everything read.
     unseenCount = 0;
     renderBellBadge();
-    fetchNotifications();
+    fetchNotifications().then(() => api('/v1/notifications/read-all', { method: 'POST' })).catch(() => {});
   }
 }
 
@@ -1005,6 +1107,20 @@ async function main() {
         openPromptModal(task_id);
         return;
       }
+      if (action === 'result') {
+        const res = await api(`/v1/tasks/${encodeURIComponent(task_id)}/result`);
+        if (!res.result) {
+          alert('No result reported for this task.');
+          return;
+        }
+        const lines = Object.entries(res.result.result || {})
+          .map(([k, v]) => `${k}: ${typeof v === 'string' ? v : JSON.stringify(v)}`);
+        for (const a of res.result.artifacts || []) {
+          lines.push(`artifact ${a.name}${a.url ? ` → ${a.url}` : ` (${(a.content || '').length} bytes)`}`);
+        }
+        alert(lines.join('\n') || '(empty result)');
+        return;
+      }
       if (action === 'detach') {
         const chainId = btn.getAttribute('data-chain-id');
         const agentId = btn.getAttribute('data-agent-id');
@@ -1013,6 +1129,12 @@ async function main() {
           method: 'POST',
           body: JSON.stringify({ agent_id: agentId }),
         });
+      } else if (action === 'pause-control') {
+        const kind = btn.getAttribute('data-kind');
+        const id = btn.getAttribute('data-id');
+        const mode = btn.getAttribute('data-mode');
+        if (!kind || !id || !mode) return;
+        await api(`/v1/${kind}/${encodeURIComponent(id)}/${mode}`, { method: 'POST' });
       } else if (action === 'task-status') {
         const status = btn.getAttribute('data-status');
         if (!task_id || !status) return;
@@ -1030,6 +1152,23 @@ async function main() {
           method: 'POST',
           body: JSON.stringify({ task_id }),
         });
+      } else if (action === 'resubmit') {
+        await api(`/v1/tasks/${encodeURIComponent(task_id)}/resubmit`, { method: 'POST' });
+      } else if (action === 'cancel') {
+        const reason = prompt('Cancel reason (optional)');
+        if (reason === null) return;
+        const cancel_downstream = confirm('Also cancel the tasks waiting on this one?\n(Cancel = let them run)');
+        await api(`/v1/tasks/${encodeURIComponent(task_id)}/cancel`, {
+          method: 'POST',
+          body: JSON.stringify({ reason, cancel_downstream }),
+        });
+      } else if (action === 'approve' || action === 'reject') {
+        const comment = prompt(action === 'approve' ? 'Approval comment (optional)' : 'Rejection reason (optional)');
+        if (comment === null) return;
+        await api(`/v1/tasks/${encodeURIComponent(task_id)}/${action}`, {
+          method: 'POST',
+          body: JSON.stringify({ comment }),
+        });
       } else if (action === 'assign') {
         const agent_id = prompt('Assign to agent_id (uuid)');
         if (!agent_id) return;
@@ -1502,9 +1641,8 @@ async function main() {
   await refresh();
   // Load initial unseen count from server
   try {
-    const data = await api('/v1/notifications');
-    const list = data.notifications || [];
-    unseenCount = list.length;
+    const data = await api('/v1/notifications?limit=1');
+    unseenCount = data.unread_count || 0;
     renderBellBadge();
   } catch { /* ignore */ }
   startStream();
@@ -0,0 +1,50 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// setUserMaxInFlightRequest is the body of PUT /v1/users/{id}/max-in-flight. A null limit
+// goes back to COORDINATOR_USER_MAX_IN_FLIGHT.
+type setUserMaxInFlightRequest struct {
+	MaxInFlight *int `json:"max_in_flight"`
+}
+
+// handleUserMaxInFlight serves PUT /v1/users/{id}/max-in-flight: the operator's override of
+// the per-user in-flight limit. Only the API token may call it; a user raising their own
+// limit would defeat it.
+func (s *Server) handleUserMaxInFlight(w http.ResponseWriter, r *http.Request) {
+	if userIDFromContext(r.Context()) != "" {
+		writeError(w, http.StatusForbidden, "forbidden", "only the API token may change user limits")
+		return
+	}
+	userID := strings.TrimSpace(r.PathValue("id"))
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, "user_id_required", "user ID is required")
+		return
+	}
+	var req setUserMaxInFlightRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+		return
+	}
+	if req.MaxInFlight != nil && *req.MaxInFlight < 0 {
+		writeError(w, http.StatusBadRequest, "max_in_flight_invalid", "max_in_flight must be 0 or more")
+		return
+	}
+
+	u, err := s.store.SetUserMaxInFlight(r.Context(), userID, req.MaxInFlight)
+	if err != nil {
+		if err == store.ErrNotFound {
+			writeError(w, http.StatusNotFound, "not_found", "user not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "internal", "failed to update user")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"user": u})
+}
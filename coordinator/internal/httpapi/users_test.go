@@ -0,0 +1,133 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/config"
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+	"clwclw-monitor/coordinator/internal/store/memory"
+)
+
+func TestUserMaxInFlight(t *testing.T) {
+	server := NewServer(config.Config{AuthToken: "test-token", UserMaxInFlight: 1}, memory.NewStore())
+	ctx := context.Background()
+	user, err := server.store.CreateUser(ctx, model.User{Username: "busy", PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "busy-jobs", UserID: user.ID})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	put := func(asUser string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/v1/users/"+user.ID+"/max-in-flight", bytes.NewBufferString(body))
+		if asUser != "" {
+			req = req.WithContext(context.WithValue(req.Context(), ctxUserID, asUser))
+		}
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, req)
+		return rec
+	}
+	claim := func(agentID string) int {
+		raw, _ := json.Marshal(map[string]any{"agent_id": agentID, "channel_id": ch.ID})
+		req := httptest.NewRequest(http.MethodPost, "/v1/tasks/claim", bytes.NewReader(raw))
+		req = req.WithContext(context.WithValue(req.Context(), ctxUserID, user.ID))
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A user may not raise their own limit.
+	if rec := put(user.ID, `{"max_in_flight": 0}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d for a user token, got %d: %s", http.StatusForbidden, rec.Code, rec.Body.String())
+	}
+	if rec := put("", `{"max_in_flight": -1}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for a negative limit, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+	rec := put("", `{"max_in_flight": 2}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var got struct {
+		User model.User `json:"user"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || got.User.MaxInFlight == nil || *got.User.MaxInFlight != 2 {
+		t.Fatalf("expected max_in_flight 2, got %s (%v)", rec.Body.String(), err)
+	}
+
+	for _, title := range []string{"one", "two", "three"} {
+		if _, _, err := server.store.CreateChainWithTasks(ctx, model.Chain{UserID: user.ID, ChannelID: ch.ID, Name: title}, []store.NewChainTask{
+			{Task: model.Task{Title: title}},
+		}); err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+	}
+	// The user's limit of 2 replaces the coordinator default of 1.
+	for _, agentID := range []string{"a1000000-0000-4000-8000-000000000001", "a1000000-0000-4000-8000-000000000002"} {
+		if code := claim(agentID); code != http.StatusOK {
+			t.Fatalf("expected the claim under the user's limit to succeed, got %d", code)
+		}
+	}
+	if code := claim("a1000000-0000-4000-8000-000000000003"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d at the user's limit, got %d", http.StatusTooManyRequests, code)
+	}
+
+	if rec := put("", `{"max_in_flight": null}`); rec.Code != http.StatusOK || bytes.Contains(rec.Body.Bytes(), []byte("max_in_flight")) {
+		t.Fatalf("expected the limit to be cleared, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// failingUserStore fails every user limit update like an unreachable database.
+type failingUserStore struct {
+	*memory.Store
+}
+
+func (failingUserStore) SetUserMaxInFlight(context.Context, string, *int) (*model.User, error) {
+	return nil, errors.New("connection reset by peer")
+}
+
+func TestUserMaxInFlightErrors(t *testing.T) {
+	server := NewServer(config.Config{AuthToken: "test-token"}, failingUserStore{memory.NewStore()})
+	req := httptest.NewRequest(http.MethodPut, "/v1/users/u0000000-0000-4000-8000-000000000001/max-in-flight", bytes.NewBufferString(`{"max_in_flight": 3}`))
+	rec := httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError || bytes.Contains(rec.Body.Bytes(), []byte("connection reset")) {
+		t.Fatalf("expected a generic %d, got %d: %s", http.StatusInternalServerError, rec.Code, rec.Body.String())
+	}
+}
+
+func TestChannelPatchOwner(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	owner, other := "u0000000-0000-4000-8000-000000000001", "u0000000-0000-4000-8000-000000000002"
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "owned-jobs", UserID: owner})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	patch := func(asUser string) int {
+		req := httptest.NewRequest(http.MethodPatch, "/v1/channels/"+ch.ID, bytes.NewBufferString(`{"max_in_flight": 50}`))
+		req = req.WithContext(context.WithValue(req.Context(), ctxUserID, asUser))
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := patch(other); code != http.StatusNotFound {
+		t.Fatalf("expected status %d for another user, got %d", http.StatusNotFound, code)
+	}
+	if got, _ := server.store.GetChannel(ctx, ch.ID); got.MaxInFlight != 0 {
+		t.Fatalf("expected the limit to be unchanged, got %d", got.MaxInFlight)
+	}
+	if code := patch(owner); code != http.StatusOK {
+		t.Fatalf("expected status %d for the owner, got %d", http.StatusOK, code)
+	}
+}
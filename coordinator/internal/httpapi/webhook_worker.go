@@ -0,0 +1,439 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+const (
+	// webhookQueueSize is how many published events may wait to become deliveries before new ones are dropped.
+	webhookQueueSize = 1024
+	// webhookCacheTTL bounds how long webhook changes made on another replica take to apply here.
+	webhookCacheTTL = 10 * time.Second
+	// webhookTimeout bounds one delivery attempt; webhookClaimLease must stay above it so that
+	// no other worker retries a delivery that is still being sent. Deliveries are claimed one
+	// at a time, so the lease only has to cover a single attempt.
+	webhookTimeout    = 10 * time.Second
+	webhookClaimLease = time.Minute
+	// webhookBatchSize is how many deliveries one round sends at most before waiting for the next tick.
+	webhookBatchSize = 50
+	// Retries wait webhookBackoffBase, then twice as long each time, up to webhookBackoffMax.
+	webhookBackoffBase = 10 * time.Second
+	webhookBackoffMax  = time.Hour
+)
+
+// errWebhookAddrBlocked is returned for webhook URLs resolving to an address the
+// coordinator may not send to; see webhookAddrBlocked.
+var errWebhookAddrBlocked = errors.New("webhook address not allowed")
+
+// webhookBlockedPrefixes are the ranges not covered by the netip.Addr predicates in
+// webhookAddrBlocked: "this network" and the carrier-grade NAT shared space.
+var webhookBlockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// webhookTopics maps the bus event types webhooks can subscribe to to their topic.
+var webhookTopics = map[string]string{
+	EventTasks:        model.WebhookTopicTasks,
+	EventChains:       model.WebhookTopicChains,
+	EventAgents:       model.WebhookTopicAgents,
+	EventEvents:       model.WebhookTopicEvents,
+	EventNotification: model.WebhookTopicNotifications,
+}
+
+// webhookEvent is an event published on this replica, waiting to become deliveries.
+type webhookEvent struct {
+	userID  string
+	topic   string
+	payload map[string]any
+}
+
+// webhookWorker queues the events for webhooks, caches the webhooks and sends deliveries.
+type webhookWorker struct {
+	queue  chan webhookEvent
+	wake   chan struct{} // signalled when deliveries were queued
+	client *http.Client
+	// allowPrivate lets webhooks reach loopback and private addresses (COORDINATOR_WEBHOOK_ALLOW_PRIVATE).
+	allowPrivate bool
+
+	mu       sync.Mutex
+	hooks    []model.Webhook // enabled webhooks; nil when not loaded
+	loadedAt time.Time
+}
+
+func newWebhookWorker(allowPrivate bool) *webhookWorker {
+	dialer := &net.Dialer{Timeout: webhookTimeout}
+	if !allowPrivate {
+		// Checked on the address actually dialed, so a DNS answer that changed since the
+		// webhook was saved cannot reach an internal service either.
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil || webhookAddrBlocked(ap.Addr()) {
+				return errWebhookAddrBlocked
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // a proxy would dial the receiver on our behalf, past the check
+	transport.DialContext = dialer.DialContext
+
+	return &webhookWorker{
+		queue:        make(chan webhookEvent, webhookQueueSize),
+		wake:         make(chan struct{}, 1),
+		allowPrivate: allowPrivate,
+		client: &http.Client{
+			Timeout:   webhookTimeout,
+			Transport: transport,
+			// A redirect is an answer like any other non-2xx: the receiver's URL should be fixed.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// webhookAddrBlocked reports whether webhooks may not be sent to addr: loopback, private,
+// link-local (which includes the cloud metadata address 169.254.169.254), multicast and
+// unspecified addresses, and the ranges in webhookBlockedPrefixes.
+func webhookAddrBlocked(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+	for _, p := range webhookBlockedPrefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkURL resolves the host of a webhook URL and rejects it when any of its addresses is
+// blocked. Deliveries check again when dialing.
+func (w *webhookWorker) checkURL(ctx context.Context, rawURL string) error {
+	if w.allowPrivate {
+		return nil
+	}
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Hostname() == "" {
+		return nil // left to store.NormalizeWebhook
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("url_unresolvable: %s", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if webhookAddrBlocked(addr) {
+			return fmt.Errorf("url_not_allowed: %s resolves to %s", u.Hostname(), addr.Unmap())
+		}
+	}
+	return nil
+}
+
+// observe is the event bus observer. It runs with the bus lock held, so it only queues.
+// Rule notifications reach webhooks through the rule's webhook target, not the bus.
+func (w *webhookWorker) observe(userID string, ev busEvent) {
+	topic, ok := webhookTopics[ev.Type]
+	if !ok || len(ev.Payload) == 0 {
+		return
+	}
+	if ev.Type == EventNotification && ev.Payload["notification_type"] == notificationTypeRule {
+		return
+	}
+	select {
+	case w.queue <- webhookEvent{userID: userID, topic: topic, payload: ev.Payload}:
+	default:
+		log.Printf("webhooks: queue full, dropping %s event", ev.Type)
+	}
+}
+
+// invalidate makes the next event reload the webhooks from the store.
+func (w *webhookWorker) invalidate() {
+	w.mu.Lock()
+	w.hooks = nil
+	w.mu.Unlock()
+}
+
+func (w *webhookWorker) notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// enabledWebhooks returns the enabled webhooks.
+func (s *Server) enabledWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	w := s.webhooks
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.hooks == nil || time.Since(w.loadedAt) >= webhookCacheTTL {
+		all, err := s.store.ListWebhooks(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		w.hooks = make([]model.Webhook, 0, len(all))
+		for _, h := range all {
+			if h.Enabled {
+				w.hooks = append(w.hooks, h)
+			}
+		}
+		w.loadedAt = time.Now()
+	}
+	return w.hooks, nil
+}
+
+// RunWebhooks turns the events published on this replica into webhook deliveries and sends
+// the due deliveries until ctx is cancelled. It runs on every replica: each one queues its
+// own changes, and deliveries are claimed under a lease so that only one replica sends each.
+func (s *Server) RunWebhooks(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	s.bus.setObserver("webhooks", s.webhooks.observe)
+	defer s.bus.setObserver("webhooks", nil)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-s.webhooks.queue:
+				s.queueWebhookDeliveries(ctx, ev)
+			}
+		}
+	}()
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		case <-s.webhooks.wake:
+		}
+		s.deliverDueWebhooks(ctx, time.Now().UTC())
+	}
+}
+
+// queueWebhookDeliveries stores one delivery of ev per webhook subscribed to it. The
+// delivery carries the changed entities as they are now, not just their IDs.
+func (s *Server) queueWebhookDeliveries(ctx context.Context, ev webhookEvent) {
+	hooks, err := s.enabledWebhooks(ctx)
+	if err != nil {
+		log.Printf("webhooks: list webhooks failed: %v", err)
+		return
+	}
+
+	var payload map[string]any
+	queued := false
+	for _, h := range hooks {
+		if !visibleTo(h.UserID, ev.userID) || !slices.Contains(h.Topics, ev.topic) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = s.webhookPayload(ctx, ev); err != nil {
+				log.Printf("webhooks: load %s payload failed: %v", ev.topic, err)
+				return
+			}
+		}
+		if _, err := s.store.CreateWebhookDelivery(ctx, model.WebhookDelivery{WebhookID: h.ID, Topic: ev.topic, Payload: payload}); err != nil {
+			if err != store.ErrNotFound { // deleted meanwhile
+				log.Printf("webhooks: queue delivery for %s failed: %v", h.ID, err)
+			}
+			continue
+		}
+		queued = true
+	}
+	if queued {
+		s.webhooks.notify()
+	}
+}
+
+// webhookPayload is the data of a delivery: the IDs of the bus event and the entities they
+// refer to (entities deleted meanwhile are left out), or the notification as pushed.
+func (s *Server) webhookPayload(ctx context.Context, ev webhookEvent) (map[string]any, error) {
+	if ev.topic == model.WebhookTopicNotifications {
+		return map[string]any{"notification": ev.payload}, nil
+	}
+
+	ids, _ := ev.payload["ids"].([]string)
+	payload := map[string]any{"ids": ids}
+	switch ev.topic {
+	case model.WebhookTopicTasks:
+		tasks, err := s.store.ListTasks(ctx, store.TaskFilter{IDs: ids})
+		if err != nil {
+			return nil, err
+		}
+		payload["tasks"] = tasks
+	case model.WebhookTopicChains:
+		chains := make([]model.Chain, 0, len(ids))
+		for _, id := range ids {
+			c, err := s.store.GetChain(ctx, id)
+			if err == store.ErrNotFound {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			chains = append(chains, c)
+		}
+		payload["chains"] = chains
+	case model.WebhookTopicAgents:
+		agents := make([]model.Agent, 0, len(ids))
+		for _, id := range ids {
+			a, err := s.store.GetAgent(ctx, id)
+			if err == store.ErrNotFound || (err == nil && a == nil) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			agents = append(agents, *a)
+		}
+		payload["agents"] = agents
+	case model.WebhookTopicEvents:
+		events, err := s.store.ListEvents(ctx, store.EventFilter{IDs: ids})
+		if err != nil {
+			return nil, err
+		}
+		payload["events"] = events
+	}
+	return payload, nil
+}
+
+// deliverDueWebhooks sends the deliveries due at now. Each one is claimed right before it is
+// sent, so its lease never runs while earlier deliveries of the round are still being sent.
+func (s *Server) deliverDueWebhooks(ctx context.Context, now time.Time) {
+	for i := 0; i < webhookBatchSize && ctx.Err() == nil; i++ {
+		due, err := s.store.ClaimWebhookDeliveries(ctx, now, webhookClaimLease, 1)
+		if err != nil {
+			log.Printf("webhooks: claim deliveries failed: %v", err)
+			return
+		}
+		if len(due) == 0 {
+			return
+		}
+		s.deliverWebhook(ctx, due[0], now)
+		if i == webhookBatchSize-1 {
+			s.webhooks.notify() // more may be due: start the next round right away
+		}
+	}
+}
+
+// deliverWebhook makes one attempt of d and records its outcome. A non-2xx answer or a
+// transport error is retried with exponential backoff until the attempts run out.
+func (s *Server) deliverWebhook(ctx context.Context, d model.WebhookDelivery, now time.Time) {
+	attempt := store.RecordWebhookAttemptRequest{DeliveryID: d.ID}
+
+	h, err := s.store.GetWebhook(ctx, d.WebhookID)
+	switch {
+	case err == store.ErrNotFound:
+		return // deleted with its deliveries
+	case err != nil:
+		log.Printf("webhooks: get webhook %s failed: %v", d.WebhookID, err)
+		return // retried when the lease expires
+	case !h.Enabled:
+		attempt.Error = "webhook disabled"
+	default:
+		attempt = s.sendWebhook(ctx, h, d)
+	}
+
+	maxAttempts := s.cfg.WebhookMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 8
+	}
+	if !attempt.Succeeded && h.Enabled && d.Attempts+1 < maxAttempts {
+		retryAt := now.Add(webhookBackoff(d.Attempts + 1))
+		attempt.RetryAt = &retryAt
+	}
+
+	if _, err := s.store.RecordWebhookAttempt(ctx, attempt); err != nil && err != store.ErrNotFound {
+		log.Printf("webhooks: record attempt of %s failed: %v", d.ID, err)
+	}
+}
+
+// sendWebhook posts d to h and returns the outcome. The body is signed as described in
+// webhookSignature.
+func (s *Server) sendWebhook(ctx context.Context, h model.Webhook, d model.WebhookDelivery) store.RecordWebhookAttemptRequest {
+	attempt := store.RecordWebhookAttemptRequest{DeliveryID: d.ID}
+
+	body, err := json.Marshal(map[string]any{
+		"id":         d.ID,
+		"webhook_id": h.ID,
+		"topic":      d.Topic,
+		"created_at": d.CreatedAt,
+		"data":       d.Payload,
+	})
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "clwclw-coordinator-webhook")
+	req.Header.Set("X-Webhook-Id", h.ID)
+	req.Header.Set("X-Webhook-Delivery", d.ID)
+	req.Header.Set("X-Webhook-Topic", d.Topic)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+webhookSignature(h.Secret, timestamp, body))
+
+	resp, err := s.webhooks.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	// Only the status is kept: the body is the receiver's and might come from an internal service.
+	resp.Body.Close()
+
+	attempt.ResponseCode = resp.StatusCode
+	attempt.Succeeded = resp.StatusCode >= 200 && resp.StatusCode < 300
+	return attempt
+}
+
+// webhookSignature is the hex HMAC-SHA256, keyed with the webhook secret, of the timestamp,
+// a dot and the body. Receivers recompute it and reject old timestamps to stop replays.
+func webhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff is how long a delivery waits after its attempt-th failed attempt.
+func webhookBackoff(attempt int) time.Duration {
+	d := webhookBackoffBase
+	for i := 1; i < attempt && d < webhookBackoffMax; i++ {
+		d *= 2
+	}
+	return min(d, webhookBackoffMax)
+}
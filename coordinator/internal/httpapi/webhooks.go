@@ -0,0 +1,264 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+type createWebhookRequest struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url"`
+	Topics  []string `json:"topics"`
+	Secret  string   `json:"secret"`  // generated when empty
+	Enabled *bool    `json:"enabled"` // default true
+}
+
+// updateWebhookRequest uses pointers so omitted fields keep their current value.
+type updateWebhookRequest struct {
+	Name    *string   `json:"name"`
+	URL     *string   `json:"url"`
+	Topics  *[]string `json:"topics"`
+	Secret  *string   `json:"secret"` // "" generates a new secret
+	Enabled *bool     `json:"enabled"`
+}
+
+func newWebhookSecret() string {
+	var b [32]byte
+	_, _ = rand.Read(b[:])
+	return "whsec_" + hex.EncodeToString(b[:])
+}
+
+// withoutSecret hides the secret, which is only returned when it is set.
+func withoutSecret(w model.Webhook) model.Webhook {
+	w.Secret = ""
+	return w
+}
+
+func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		hooks, err := s.store.ListWebhooks(r.Context(), userID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", "failed to list webhooks")
+			return
+		}
+		for i := range hooks {
+			hooks[i] = withoutSecret(hooks[i])
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"webhooks": hooks})
+		return
+
+	case http.MethodPost:
+		var req createWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+			return
+		}
+
+		if err := s.webhooks.checkURL(r.Context(), req.URL); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+
+		secret := strings.TrimSpace(req.Secret)
+		if secret == "" {
+			secret = newWebhookSecret()
+		}
+		hook, err := s.store.CreateWebhook(r.Context(), model.Webhook{
+			UserID:  userID,
+			Name:    req.Name,
+			URL:     req.URL,
+			Topics:  req.Topics,
+			Secret:  secret,
+			Enabled: req.Enabled == nil || *req.Enabled,
+		})
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+
+		s.webhooks.invalidate()
+		writeJSON(w, http.StatusCreated, map[string]any{"webhook": hook})
+		return
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+}
+
+// webhookForRequest loads the webhook {id} of the request's user; webhooks of other users
+// are reported as missing. It writes the error response and returns false on failure.
+func (s *Server) webhookForRequest(w http.ResponseWriter, r *http.Request) (model.Webhook, bool) {
+	webhookID := strings.TrimSpace(r.PathValue("id"))
+	if webhookID == "" {
+		writeError(w, http.StatusBadRequest, "webhook_id_required", "webhook ID is required")
+		return model.Webhook{}, false
+	}
+
+	userID := userIDFromContext(r.Context())
+	hook, err := s.store.GetWebhook(r.Context(), webhookID)
+	if err == nil && userID != "" && hook.UserID != userID {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		if err == store.ErrNotFound {
+			writeError(w, http.StatusNotFound, "not_found", "webhook not found")
+			return model.Webhook{}, false
+		}
+		writeError(w, http.StatusInternalServerError, "internal", "failed to get webhook")
+		return model.Webhook{}, false
+	}
+	return hook, true
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, ok := s.webhookForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{"webhook": withoutSecret(hook)})
+		return
+
+	case http.MethodPatch:
+		var req updateWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_json", "invalid json")
+			return
+		}
+
+		if req.Name != nil {
+			hook.Name = *req.Name
+		}
+		if req.URL != nil {
+			if err := s.webhooks.checkURL(r.Context(), *req.URL); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+				return
+			}
+			hook.URL = *req.URL
+		}
+		if req.Topics != nil {
+			hook.Topics = *req.Topics
+		}
+		if req.Secret != nil {
+			hook.Secret = strings.TrimSpace(*req.Secret)
+			if hook.Secret == "" {
+				hook.Secret = newWebhookSecret()
+			}
+		}
+		if req.Enabled != nil {
+			hook.Enabled = *req.Enabled
+		}
+
+		hook, err := s.store.UpdateWebhook(r.Context(), hook)
+		if err != nil {
+			status := http.StatusBadRequest
+			if err == store.ErrNotFound {
+				status = http.StatusNotFound
+			}
+			writeError(w, status, "invalid_request", err.Error())
+			return
+		}
+
+		s.webhooks.invalidate()
+		if req.Secret == nil {
+			hook = withoutSecret(hook)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"webhook": hook})
+		return
+
+	case http.MethodDelete:
+		if err := s.store.DeleteWebhook(r.Context(), hook.ID); err != nil {
+			if err == store.ErrNotFound {
+				writeError(w, http.StatusNotFound, "not_found", "webhook not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal", "failed to delete webhook")
+			return
+		}
+
+		s.webhooks.invalidate()
+		w.WriteHeader(http.StatusNoContent)
+		return
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+}
+
+func (s *Server) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	hook, ok := s.webhookForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 50
+	if v := strings.TrimSpace(r.URL.Query().Get("limit")); v != "" {
+		// Ignore parsing errors and keep the default.
+		var n int
+		_, _ = fmt.Sscanf(v, "%d", &n)
+		if n > 0 {
+			limit = min(n, 200)
+		}
+	}
+
+	deliveries, err := s.store.ListWebhookDeliveries(r.Context(), hook.ID, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to list webhook deliveries")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"deliveries": deliveries})
+}
+
+// handleWebhookDeliveryReplay queues a new delivery with the payload of an earlier one,
+// whatever its outcome. The new delivery records which one it replays.
+func (s *Server) handleWebhookDeliveryReplay(w http.ResponseWriter, r *http.Request) {
+	hook, ok := s.webhookForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	d, err := s.store.GetWebhookDelivery(r.Context(), strings.TrimSpace(r.PathValue("delivery_id")))
+	if err == nil && d.WebhookID != hook.ID {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		if err == store.ErrNotFound {
+			writeError(w, http.StatusNotFound, "not_found", "webhook delivery not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "internal", "failed to get webhook delivery")
+		return
+	}
+
+	replay, err := s.store.CreateWebhookDelivery(r.Context(), model.WebhookDelivery{
+		WebhookID: hook.ID,
+		Topic:     d.Topic,
+		Payload:   d.Payload,
+		ReplayOf:  d.ID,
+	})
+	if err != nil {
+		if err == store.ErrNotFound {
+			writeError(w, http.StatusNotFound, "not_found", "webhook not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "internal", "failed to queue webhook delivery")
+		return
+	}
+
+	s.webhooks.notify()
+	writeJSON(w, http.StatusAccepted, map[string]any{"delivery": replay})
+}
@@ -0,0 +1,374 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/config"
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+	"clwclw-monitor/coordinator/internal/store/memory"
+)
+
+func TestWebhookDeliveries(t *testing.T) {
+	// The receiver listens on loopback.
+	server := NewServer(config.Config{AuthToken: "test-token", WebhookAllowPrivate: true}, memory.NewStore())
+	ctx := context.Background()
+	server.bus.setObserver("webhooks", server.webhooks.observe)
+
+	// The receiver fails the first request and accepts the others.
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies [][]byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r)
+		bodies = append(bodies, body)
+		if len(received) == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	rec := httptest.NewRecorder()
+	body := `{"name":"ci","url":"` + receiver.URL + `","topics":["tasks"]}`
+	server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/webhooks", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create webhook: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Webhook model.Webhook `json:"webhook"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	hook := created.Webhook
+	if !strings.HasPrefix(hook.Secret, "whsec_") {
+		t.Fatalf("expected a generated secret, got %q", hook.Secret)
+	}
+	rec = httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/webhooks/"+hook.ID, nil))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), hook.Secret) {
+		t.Fatalf("expected the secret to be hidden, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// A task change becomes one delivery carrying the task.
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "webhook-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	chain, err := server.store.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: "webhook-chain", Status: model.ChainStatusQueued})
+	if err != nil {
+		t.Fatalf("create chain: %v", err)
+	}
+	task, err := server.store.CreateTask(ctx, model.Task{ChannelID: ch.ID, ChainID: chain.ID, Sequence: 1, Title: "ship", Status: model.TaskStatusLocked})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	server.bus.PublishIDs(EventTasks, "", task.ID)
+	server.bus.PublishIDs(EventChains, "", chain.ID) // not subscribed
+	for len(server.webhooks.queue) > 0 {
+		server.queueWebhookDeliveries(ctx, <-server.webhooks.queue)
+	}
+
+	now := time.Now().UTC().Add(time.Second)
+	server.deliverDueWebhooks(ctx, now)
+	deliveries, err := server.store.ListWebhookDeliveries(ctx, hook.ID, 10)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("expected one delivery, got %v (%v)", deliveries, err)
+	}
+	d := deliveries[0]
+	if d.Status != model.WebhookDeliveryPending || d.Attempts != 1 || d.ResponseCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected a failed first attempt waiting for a retry, got %+v", d)
+	}
+
+	// Not due again before the backoff.
+	server.deliverDueWebhooks(ctx, now.Add(webhookBackoff(1)/2))
+	if len(received) != 1 {
+		t.Fatalf("expected no retry before the backoff, got %d requests", len(received))
+	}
+	server.deliverDueWebhooks(ctx, now.Add(webhookBackoff(1)+time.Second))
+	if d, _ = server.store.GetWebhookDelivery(ctx, d.ID); d.Status != model.WebhookDeliverySucceeded || d.Attempts != 2 {
+		t.Fatalf("expected the retry to succeed, got %+v", d)
+	}
+
+	r := received[1]
+	if want := "sha256=" + webhookSignature(hook.Secret, r.Header.Get("X-Webhook-Timestamp"), bodies[1]); r.Header.Get("X-Webhook-Signature") != want {
+		t.Fatalf("unexpected signature %q, want %q", r.Header.Get("X-Webhook-Signature"), want)
+	}
+	var sent struct {
+		ID    string `json:"id"`
+		Topic string `json:"topic"`
+		Data  struct {
+			Tasks []model.Task `json:"tasks"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(bodies[1], &sent); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if sent.ID != d.ID || sent.Topic != model.WebhookTopicTasks || len(sent.Data.Tasks) != 1 || sent.Data.Tasks[0].ID != task.ID {
+		t.Fatalf("unexpected body: %s", bodies[1])
+	}
+
+	// Replaying queues a new delivery with the same payload.
+	rec = httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/webhooks/"+hook.ID+"/deliveries/"+d.ID+"/replay", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("replay: expected status %d, got %d: %s", http.StatusAccepted, rec.Code, rec.Body.String())
+	}
+	server.deliverDueWebhooks(ctx, time.Now().UTC().Add(time.Second))
+	if len(received) != 3 || received[2].Header.Get("X-Webhook-Delivery") == d.ID {
+		t.Fatalf("expected the replay to be sent as a new delivery, got %d requests", len(received))
+	}
+
+	rec = httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/webhooks/"+hook.ID+"/deliveries", nil))
+	var deliveryLog struct {
+		Deliveries []model.WebhookDelivery `json:"deliveries"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&deliveryLog); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(deliveryLog.Deliveries) != 2 || deliveryLog.Deliveries[0].ReplayOf != d.ID || deliveryLog.Deliveries[0].Status != model.WebhookDeliverySucceeded {
+		t.Fatalf("unexpected delivery log: %+v", deliveryLog.Deliveries)
+	}
+}
+
+func TestWebhookClaimsOneAtATime(t *testing.T) {
+	server := NewServer(config.Config{AuthToken: "test-token", WebhookAllowPrivate: true}, memory.NewStore())
+	ctx := context.Background()
+	now := time.Now().UTC().Add(time.Second)
+
+	// While one delivery is being sent, the others are not leased yet.
+	var hookID string
+	var leased []int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries, _ := server.store.ListWebhookDeliveries(r.Context(), hookID, 10)
+		n := 0
+		for _, d := range deliveries {
+			if d.Status == model.WebhookDeliveryPending && d.NextAttemptAt.After(now) {
+				n++
+			}
+		}
+		leased = append(leased, n)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	hook, err := server.store.CreateWebhook(ctx, model.Webhook{Name: "ci", URL: receiver.URL, Secret: "x", Topics: []string{model.WebhookTopicEvents}, Enabled: true})
+	if err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+	hookID = hook.ID
+	for range 3 {
+		if _, err := server.store.CreateWebhookDelivery(ctx, model.WebhookDelivery{WebhookID: hook.ID, Topic: model.WebhookTopicEvents}); err != nil {
+			t.Fatalf("create delivery: %v", err)
+		}
+	}
+
+	server.deliverDueWebhooks(ctx, now)
+	if len(leased) != 3 || leased[0] != 1 || leased[1] != 1 || leased[2] != 1 {
+		t.Fatalf("expected each delivery to be leased only while it is sent, got %v", leased)
+	}
+}
+
+// TestWebhookEventsCarryIDs checks that the changes made outside the task and chain CRUD
+// handlers are published with the IDs webhooks need to build a delivery.
+func TestWebhookEventsCarryIDs(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	server.bus.setObserver("webhooks", server.webhooks.observe)
+
+	ch, err := server.store.CreateChannel(ctx, model.Channel{Name: "ids-channel"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	// Each step gets its own agent and chain so that chain ownership does not carry over.
+	n := 0
+	newRun := func() (string, model.Chain, model.Task) {
+		t.Helper()
+		n++
+		agentID := fmt.Sprintf("a0000000-0000-4000-8000-%012d", n)
+		if _, err := server.store.UpsertAgent(ctx, model.Agent{ID: agentID, Name: agentID, Meta: map[string]any{"subscriptions": []any{"ids-channel"}}}); err != nil {
+			t.Fatalf("upsert agent: %v", err)
+		}
+		chain, tasks, err := server.store.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: agentID}, []store.NewChainTask{{Task: model.Task{Title: "work"}}})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+		return agentID, chain, tasks[0]
+	}
+	claim := func(agentID string, leaseSeconds int) {
+		t.Helper()
+		if _, err := server.store.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentID, ChannelID: ch.ID, LeaseSeconds: leaseSeconds}); err != nil {
+			t.Fatalf("claim: %v", err)
+		}
+	}
+	post := func(path, body string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", path, http.StatusOK, rec.Code, rec.Body.String())
+		}
+	}
+	// published drains the webhook queue into the IDs published per topic.
+	published := func() map[string][]string {
+		out := map[string][]string{}
+		for len(server.webhooks.queue) > 0 {
+			ev := <-server.webhooks.queue
+			ids, _ := ev.payload["ids"].([]string)
+			out[ev.topic] = append(out[ev.topic], ids...)
+		}
+		return out
+	}
+	expect := func(step string, got map[string][]string, want map[string][]string) {
+		t.Helper()
+		for topic, ids := range want {
+			if !slices.Equal(got[topic], ids) {
+				t.Fatalf("%s: expected %s %v, got %v", step, topic, ids, got)
+			}
+		}
+	}
+
+	agentID, chain, task := newRun()
+	claim(agentID, 0)
+	published()
+	post("/v1/tasks/complete", `{"task_id":"`+task.ID+`","agent_id":"`+agentID+`"}`)
+	expect("complete", published(), map[string][]string{model.WebhookTopicTasks: {task.ID}, model.WebhookTopicAgents: {agentID}})
+
+	agentID, chain, task = newRun()
+	claim(agentID, 0)
+	published()
+	post("/v1/tasks/fail", `{"task_id":"`+task.ID+`","agent_id":"`+agentID+`"}`)
+	expect("fail", published(), map[string][]string{model.WebhookTopicTasks: {task.ID}, model.WebhookTopicAgents: {agentID}})
+
+	agentID, chain, task = newRun()
+	claim(agentID, 0)
+	published()
+	post("/v1/chains/"+chain.ID+"/detach", `{"agent_id":"`+agentID+`"}`)
+	expect("detach", published(), map[string][]string{model.WebhookTopicTasks: {task.ID}, model.WebhookTopicChains: {chain.ID}, model.WebhookTopicAgents: {agentID}})
+
+	agentID, chain, task = newRun()
+	claim(agentID, 1)
+	published()
+	requeued, err := server.store.RequeueExpiredTasks(ctx, time.Now().UTC().Add(time.Hour))
+	if err != nil || len(requeued) != 1 {
+		t.Fatalf("expected one expired lease, got %v (%v)", requeued, err)
+	}
+	server.PublishLeaseExpired(ctx, requeued)
+	got := published()
+	expect("lease expired", got, map[string][]string{model.WebhookTopicTasks: {task.ID}, model.WebhookTopicChains: {chain.ID}, model.WebhookTopicAgents: {agentID}})
+	if len(got[model.WebhookTopicEvents]) != 1 {
+		t.Fatalf("lease expired: expected the task.lease_expired event, got %v", got)
+	}
+
+	for _, action := range []string{"pause", "drain", "resume"} {
+		post("/v1/chains/"+chain.ID+"/"+action, "")
+		expect(action, published(), map[string][]string{model.WebhookTopicChains: {chain.ID}})
+	}
+
+	agentID, chain, _ = newRun()
+	post("/v1/chains/"+chain.ID+"/assign-agent", `{"agent_id":"`+agentID+`"}`)
+	expect("assign agent", published(), map[string][]string{model.WebhookTopicChains: {chain.ID}, model.WebhookTopicAgents: {agentID}})
+
+	due := time.Now().UTC().Add(-time.Minute)
+	sc, err := server.store.CreateSchedule(ctx, model.Schedule{ChannelID: ch.ID, Name: "nightly", Cron: "0 2 * * *", Enabled: true, NextRunAt: &due, Tasks: []model.ScheduleTaskTemplate{{Title: "a"}, {Title: "b"}}})
+	if err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+	server.runDueSchedules(ctx, time.Now().UTC())
+	sc, _ = server.store.GetSchedule(ctx, sc.ID)
+	tasks, _ := server.store.ListTasks(ctx, store.TaskFilter{ChainID: sc.LastChainID})
+	if len(tasks) != 2 {
+		t.Fatalf("expected the schedule to create two tasks, got %+v", tasks)
+	}
+	expect("schedule", published(), map[string][]string{model.WebhookTopicChains: {sc.LastChainID}, model.WebhookTopicTasks: {tasks[0].ID, tasks[1].ID}})
+}
+
+func TestWebhookPrivateAddresses(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	for _, url := range []string{"http://127.0.0.1:8080/hook", "http://localhost/hook", "http://10.1.2.3/hook", "http://169.254.169.254/latest/meta-data", "http://[::1]/hook"} {
+		rec := httptest.NewRecorder()
+		body := `{"name":"internal","url":"` + url + `","topics":["tasks"]}`
+		server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/webhooks", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("create %s: expected status %d, got %d: %s", url, http.StatusBadRequest, rec.Code, rec.Body.String())
+		}
+	}
+
+	// A webhook whose host resolved to a public address when it was saved is still checked
+	// when dialing.
+	var requests int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+	hook, err := server.store.CreateWebhook(ctx, model.Webhook{Name: "rebound", URL: receiver.URL, Secret: "x", Topics: []string{model.WebhookTopicTasks}, Enabled: true})
+	if err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	server.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/v1/webhooks/"+hook.ID, strings.NewReader(`{"url":"http://169.254.169.254/"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("update: expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+
+	attempt := server.sendWebhook(ctx, hook, model.WebhookDelivery{ID: "d1", Topic: model.WebhookTopicTasks})
+	if attempt.Succeeded || !strings.Contains(attempt.Error, errWebhookAddrBlocked.Error()) || requests != 0 {
+		t.Fatalf("expected the dial to be refused, got %+v and %d requests", attempt, requests)
+	}
+}
+
+func TestWebhookAddrBlocked(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1":        true,
+		"10.0.0.1":         true,
+		"172.16.5.4":       true,
+		"192.168.1.1":      true,
+		"169.254.169.254":  true,
+		"100.64.0.1":       true,
+		"0.0.0.0":          true,
+		"::1":              true,
+		"fd00::1":          true,
+		"fe80::1":          true,
+		"::ffff:127.0.0.1": true,
+		"93.184.216.34":    false,
+		"2606:4700::1111":  false,
+	} {
+		if got := webhookAddrBlocked(netip.MustParseAddr(addr)); got != want {
+			t.Fatalf("webhookAddrBlocked(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{
+		1:  webhookBackoffBase,
+		2:  2 * webhookBackoffBase,
+		4:  8 * webhookBackoffBase,
+		30: webhookBackoffMax,
+	} {
+		if got := webhookBackoff(attempt); got != want {
+			t.Fatalf("webhookBackoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// Package leader decides which coordinator replica runs the singleton background jobs
+// (retention, lease reaper, agent watcher, scheduler). Every replica campaigns; only the
+// leader runs the jobs, and another replica takes over when it goes away.
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Elector campaigns for leadership on behalf of one replica.
+type Elector interface {
+	// Run campaigns until ctx ends. Each time this replica becomes the leader it calls lead
+	// with a context that is cancelled when leadership is lost, and waits for it to return
+	// before campaigning again.
+	Run(ctx context.Context, lead func(ctx context.Context))
+	// IsLeader reports whether this replica leads right now.
+	IsLeader() bool
+}
+
+// Always is the Elector of a single-instance coordinator (memory store): it leads from
+// the moment Run starts.
+type Always struct {
+	leading atomic.Bool
+}
+
+func (a *Always) Run(ctx context.Context, lead func(ctx context.Context)) {
+	a.leading.Store(true)
+	defer a.leading.Store(false)
+	lead(ctx)
+}
+
+func (a *Always) IsLeader() bool { return a.leading.Load() }
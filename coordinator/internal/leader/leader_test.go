@@ -0,0 +1,24 @@
+package leader
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAlwaysLeads(t *testing.T) {
+	var a Always
+	if a.IsLeader() {
+		t.Fatalf("expected no leadership before Run")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.Run(ctx, func(ctx context.Context) {
+		if !a.IsLeader() {
+			t.Errorf("expected leadership while leading")
+		}
+		cancel()
+		<-ctx.Done()
+	})
+	if a.IsLeader() {
+		t.Fatalf("expected leadership to end with Run")
+	}
+}
@@ -35,8 +35,14 @@ const (
 	TaskStatusDone       TaskStatus = "done"
 	TaskStatusFailed     TaskStatus = "failed"
 	TaskStatusLocked     TaskStatus = "locked"
+	TaskStatusDeadLetter TaskStatus = "dead_letter" // Retry budget exhausted; resubmit to run again
+	TaskStatusCancelled  TaskStatus = "cancelled"   // Stopped by a user; does not hold back dependents
 )
 
+// TaskTypeApproval marks a human approval gate: agents never claim it, and it holds back
+// its successors until a user approves (done) or rejects (failed) it.
+const TaskTypeApproval = "approval"
+
 type ExecutionMode string
 
 const (
@@ -53,19 +59,40 @@ const (
 	ChainStatusDone       ChainStatus = "done"
 	ChainStatusFailed     ChainStatus = "failed"
 	ChainStatusLocked     ChainStatus = "locked"
+	ChainStatusCancelled  ChainStatus = "cancelled" // Every task was cancelled
+)
+
+// PauseMode stops new claims from a chain or channel. Empty means claims run normally.
+type PauseMode string
+
+const (
+	PauseModePaused   PauseMode = "paused"   // No claims; in-flight tasks are requeued and held until resumed
+	PauseModeDraining PauseMode = "draining" // No claims; in-flight tasks finish (e.g. before a deploy)
+)
+
+// FailurePolicy decides what a chain does once one of its tasks fails for good. Empty
+// means FailurePolicyHalt.
+type FailurePolicy string
+
+const (
+	FailurePolicyHalt       FailurePolicy = "halt"       // The chain is marked failed right away
+	FailurePolicyContinue   FailurePolicy = "continue"   // The remaining tasks still run; failed once all finished
+	FailurePolicyCompensate FailurePolicy = "compensate" // Tasks after the failure are skipped; compensation tasks run instead
 )
 
 type Agent struct {
-	ID            string         `json:"id"`
-	UserID        string         `json:"user_id,omitempty"`
-	Name          string         `json:"name"`
-	Status        AgentStatus    `json:"status"` // DEPRECATED: Use ClaudeStatus instead
-	ClaudeStatus  ClaudeStatus   `json:"claude_status"`
-	CurrentTaskID string         `json:"current_task_id,omitempty"`
-	LastSeen      time.Time      `json:"last_seen"`
-	Meta          map[string]any `json:"meta,omitempty"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
+	ID                string            `json:"id"`
+	UserID            string            `json:"user_id,omitempty"`
+	Name              string            `json:"name"`
+	Status            AgentStatus       `json:"status"` // DEPRECATED: Use ClaudeStatus instead
+	ClaudeStatus      ClaudeStatus      `json:"claude_status"`
+	ClaudeStatusSince time.Time         `json:"claude_status_since"` // When ClaudeStatus last changed
+	CurrentTaskID     string            `json:"current_task_id,omitempty"`
+	LastSeen          time.Time         `json:"last_seen"`
+	Meta              map[string]any    `json:"meta,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"` // Capabilities reported in the heartbeat (e.g. gpu=true, repo=web)
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
 }
 
 // DerivedWorkerStatus computes worker status from last_seen timestamp
@@ -77,43 +104,90 @@ func (a Agent) DerivedWorkerStatus(threshold time.Duration) WorkerStatus {
 }
 
 type Channel struct {
-	ID          string    `json:"id"`
-	UserID      string    `json:"user_id,omitempty"`
-	Name        string    `json:"name"`
-	Description string    `json:"description,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID                  string     `json:"id"`
+	UserID              string     `json:"user_id,omitempty"`
+	Name                string     `json:"name"`
+	Description         string     `json:"description,omitempty"`
+	RetryMaxAttempts    int        `json:"retry_max_attempts,omitempty"`    // Default retry budget for tasks in this channel (0 = no retries)
+	RetryBackoffSeconds int        `json:"retry_backoff_seconds,omitempty"` // Default base backoff between attempts
+	MaxInFlight         int        `json:"max_in_flight,omitempty"`         // Most tasks of this channel in progress at once (0 = no limit)
+	Pause               PauseMode  `json:"pause,omitempty"`                 // Claims from this channel are blocked while set
+	PausedAt            *time.Time `json:"paused_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
 }
 
 type Chain struct {
-	ID           string      `json:"id"`
-	UserID       string      `json:"user_id,omitempty"`
-	ChannelID    string      `json:"channel_id"`
-	Name         string      `json:"name"`
-	Description  string      `json:"description,omitempty"`
-	Status       ChainStatus `json:"status"`
-	OwnerAgentID string      `json:"owner_agent_id,omitempty"` // Agent that owns this chain
-	CreatedAt    time.Time   `json:"created_at"`
-	UpdatedAt    time.Time   `json:"updated_at"`
+	ID           string        `json:"id"`
+	UserID       string        `json:"user_id,omitempty"`
+	ChannelID    string        `json:"channel_id"`
+	Name         string        `json:"name"`
+	Description  string        `json:"description,omitempty"`
+	Status       ChainStatus   `json:"status"`
+	OwnerAgentID string        `json:"owner_agent_id,omitempty"` // Agent that owns this chain
+	Pause        PauseMode     `json:"pause,omitempty"`          // Claims from this chain are blocked while set
+	PausedAt     *time.Time    `json:"paused_at,omitempty"`
+	OnFailure    FailurePolicy `json:"on_failure,omitempty"` // Empty = halt
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
 }
 
 type Task struct {
-	ID                       string        `json:"id"`
-	UserID                   string        `json:"user_id,omitempty"`
-	ChainID                  string        `json:"chain_id,omitempty"` // New field to link to a chain
-	Sequence                 int           `json:"sequence,omitempty"` // New field for order within a chain
-	ChannelID                string        `json:"channel_id"`
-	Title                    string        `json:"title"`
-	Description              string        `json:"description,omitempty"`
-	Type                     string        `json:"type,omitempty"`
-	AgentSessionRequestToken string        `json:"agent_session_request_token,omitempty"`
-	Status                   TaskStatus    `json:"status"`
-	Priority                 int           `json:"priority"`
-	AssignedAgentID          string        `json:"assigned_agent_id,omitempty"`
-	ExecutionMode            ExecutionMode `json:"execution_mode,omitempty"` // Claude Code execution mode
-	CreatedAt                time.Time     `json:"created_at"`
-	ClaimedAt                *time.Time    `json:"claimed_at,omitempty"`
-	DoneAt                   *time.Time    `json:"done_at,omitempty"`
-	UpdatedAt                time.Time     `json:"updated_at"`
+	ID                       string            `json:"id"`
+	UserID                   string            `json:"user_id,omitempty"`
+	ChainID                  string            `json:"chain_id,omitempty"`   // New field to link to a chain
+	Sequence                 int               `json:"sequence,omitempty"`   // New field for order within a chain
+	DependsOn                []string          `json:"depends_on,omitempty"` // Task IDs (same chain) that must finish first; empty = sequence order
+	ChannelID                string            `json:"channel_id"`
+	Title                    string            `json:"title"`
+	Description              string            `json:"description,omitempty"`
+	Type                     string            `json:"type,omitempty"`
+	AgentSessionRequestToken string            `json:"agent_session_request_token,omitempty"`
+	Status                   TaskStatus        `json:"status"`
+	Priority                 int               `json:"priority"`
+	AssignedAgentID          string            `json:"assigned_agent_id,omitempty"`
+	ExecutionMode            ExecutionMode     `json:"execution_mode,omitempty"` // Claude Code execution mode
+	CreatedAt                time.Time         `json:"created_at"`
+	ClaimedAt                *time.Time        `json:"claimed_at,omitempty"`
+	DoneAt                   *time.Time        `json:"done_at,omitempty"`
+	UpdatedAt                time.Time         `json:"updated_at"`
+	LeaseExpiresAt           *time.Time        `json:"lease_expires_at,omitempty"`      // Claim lease; requeued by the reaper once passed
+	MaxAttempts              int               `json:"max_attempts,omitempty"`          // Retry budget (0 = use channel policy)
+	RetryBackoffSeconds      int               `json:"retry_backoff_seconds,omitempty"` // Base backoff (0 = use channel policy)
+	Attempts                 int               `json:"attempts"`                        // Claims/assignments so far; requeues (lease expiry, offline agent) give theirs back
+	LastFailureReason        string            `json:"last_failure_reason,omitempty"`
+	NextEligibleAt           *time.Time        `json:"next_eligible_at,omitempty"` // Not claimable before this time (retry backoff)
+	NotBefore                *time.Time        `json:"not_before,omitempty"`       // Scheduled start: not claimable before this time
+	CancelledAt              *time.Time        `json:"cancelled_at,omitempty"`
+	CancelledBy              string            `json:"cancelled_by,omitempty"`
+	CancelReason             string            `json:"cancel_reason,omitempty"`
+	ReviewedAt               *time.Time        `json:"reviewed_at,omitempty"` // Approval gates: when a user approved/rejected
+	ReviewedBy               string            `json:"reviewed_by,omitempty"`
+	ReviewComment            string            `json:"review_comment,omitempty"`
+	Compensation             bool              `json:"compensation,omitempty"` // Runs only after a task of its chain failed; skipped otherwise
+	Requires                 map[string]string `json:"requires,omitempty"`     // Agent labels a claimer must have, value for value
+}
+
+// TaskArtifact is a named output attached to a task result. Small text (a diff, a log
+// excerpt) is stored inline in Content; anything larger is referenced by URL.
+type TaskArtifact struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type,omitempty"`
+	Content     string `json:"content,omitempty"`
+	URL         string `json:"url,omitempty"`
+}
+
+// TaskResult is the structured outcome an agent reports when it completes or fails a task
+// (summary, diff stats, PR URL, files touched, ...). A task keeps one result; a later
+// attempt replaces it.
+type TaskResult struct {
+	TaskID    string         `json:"task_id"`
+	ChainID   string         `json:"chain_id,omitempty"`
+	AgentID   string         `json:"agent_id,omitempty"`
+	Status    TaskStatus     `json:"status"`  // Task status the result was recorded with
+	Attempt   int            `json:"attempt"` // Task attempt that produced the result
+	Result    map[string]any `json:"result,omitempty"`
+	Artifacts []TaskArtifact `json:"artifacts,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
 }
 
 type Event struct {
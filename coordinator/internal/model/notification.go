@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+type NotificationSeverity string
+
+const (
+	NotificationSeverityInfo     NotificationSeverity = "info"
+	NotificationSeverityWarning  NotificationSeverity = "warning"
+	NotificationSeverityCritical NotificationSeverity = "critical"
+)
+
+// Notification tells a user about an agent, task or chain that needs attention.
+// A user has at most one active (unresolved) notification per Key.
+type Notification struct {
+	ID         string               `json:"id"`
+	UserID     string               `json:"user_id,omitempty"`
+	Key        string               `json:"key"`  // e.g. "agentID:setup_waiting"
+	Type       string               `json:"type"` // e.g. "setup_waiting"
+	Severity   NotificationSeverity `json:"severity"`
+	Message    string               `json:"message"`
+	AgentID    string               `json:"agent_id,omitempty"`
+	AgentName  string               `json:"agent_name,omitempty"`
+	TaskID     string               `json:"task_id,omitempty"`
+	ChainID    string               `json:"chain_id,omitempty"`
+	Channel    string               `json:"channel,omitempty"` // first subscribed channel of the agent (may be empty)
+	Extra      map[string]any       `json:"extra,omitempty"`
+	ReadAt     *time.Time           `json:"read_at,omitempty"`
+	ResolvedAt *time.Time           `json:"resolved_at,omitempty"` // condition went away or dismissed
+	PushedAt   *time.Time           `json:"pushed_at,omitempty"`   // last push over the event stream
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+}
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+type NotificationTrigger string
+
+const (
+	NotificationTriggerTaskStatus   NotificationTrigger = "task_status"   // A task reaches Status
+	NotificationTriggerChainStatus  NotificationTrigger = "chain_status"  // A chain reaches Status
+	NotificationTriggerAgentOffline NotificationTrigger = "agent_offline" // An agent stays offline for ForSeconds
+	NotificationTriggerClaudeStatus NotificationTrigger = "claude_status" // An online agent stays in claude_status Status for ForSeconds
+	NotificationTriggerEventType    NotificationTrigger = "event_type"    // An agent event whose type matches EventPattern
+)
+
+// NotificationTarget is where a rule delivers its notifications. Every notification is
+// stored (GET /v1/notifications) whatever its targets.
+type NotificationTarget string
+
+const (
+	NotificationTargetDashboard NotificationTarget = "dashboard" // Pushed on /v1/stream (dashboard toast)
+	NotificationTargetWebhook   NotificationTarget = "webhook"   // Sent to the user's webhooks subscribed to "notifications"
+)
+
+// NotificationRule raises a notification for its user whenever its trigger matches.
+type NotificationRule struct {
+	ID              string               `json:"id"`
+	UserID          string               `json:"user_id,omitempty"`
+	Name            string               `json:"name"`
+	Trigger         NotificationTrigger  `json:"trigger"`
+	ChannelID       string               `json:"channel_id,omitempty"`    // task_status, chain_status, event_type: only this channel
+	Status          string               `json:"status,omitempty"`        // task_status, chain_status, claude_status
+	EventPattern    string               `json:"event_pattern,omitempty"` // event_type: glob such as "tool.*"
+	ForSeconds      int                  `json:"for_seconds,omitempty"`   // agent_offline, claude_status
+	Severity        NotificationSeverity `json:"severity"`
+	Targets         []NotificationTarget `json:"targets"`
+	CooldownSeconds int                  `json:"cooldown_seconds,omitempty"` // Minimum time between two deliveries of the rule
+	Enabled         bool                 `json:"enabled"`
+	LastFiredAt     *time.Time           `json:"last_fired_at,omitempty"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+}
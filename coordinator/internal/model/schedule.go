@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+type ScheduleRunStatus string
+
+const (
+	ScheduleRunStatusCreated ScheduleRunStatus = "created" // Chain materialized
+	ScheduleRunStatusSkipped ScheduleRunStatus = "skipped" // Previous instance still running
+	ScheduleRunStatusFailed  ScheduleRunStatus = "failed"  // Chain/task creation failed
+)
+
+// ScheduleTaskTemplate is one task of the chain a schedule creates on every run.
+// Title and description may use {{date}}, {{time}} and {{schedule}} placeholders.
+type ScheduleTaskTemplate struct {
+	Title               string        `json:"title"`
+	Description         string        `json:"description,omitempty"`
+	Priority            int           `json:"priority,omitempty"`
+	ExecutionMode       ExecutionMode `json:"execution_mode,omitempty"`
+	MaxAttempts         int           `json:"max_attempts,omitempty"`
+	RetryBackoffSeconds int           `json:"retry_backoff_seconds,omitempty"`
+}
+
+// Schedule creates a new chain in ChannelID from Tasks whenever Cron fires.
+type Schedule struct {
+	ID          string                 `json:"id"`
+	UserID      string                 `json:"user_id,omitempty"`
+	ChannelID   string                 `json:"channel_id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Cron        string                 `json:"cron"`     // Five-field cron expression or @daily etc.
+	Timezone    string                 `json:"timezone"` // IANA zone the cron expression is evaluated in
+	Tasks       []ScheduleTaskTemplate `json:"tasks"`
+	Enabled     bool                   `json:"enabled"`
+	LastRunAt   *time.Time             `json:"last_run_at,omitempty"`
+	LastChainID string                 `json:"last_chain_id,omitempty"` // Chain created by the latest run
+	NextRunAt   *time.Time             `json:"next_run_at,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// ScheduleRun is one entry of a schedule's run history.
+type ScheduleRun struct {
+	ID           string            `json:"id"`
+	ScheduleID   string            `json:"schedule_id"`
+	Status       ScheduleRunStatus `json:"status"`
+	ChainID      string            `json:"chain_id,omitempty"`
+	Reason       string            `json:"reason,omitempty"`
+	ScheduledFor time.Time         `json:"scheduled_for"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// TemplateParam is a {{name}} placeholder a template accepts. A parameter without a
+// default must be supplied on instantiate.
+type TemplateParam struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Default     *string `json:"default,omitempty"`
+}
+
+// TemplateTask is one task of a template. Title and description may contain {{param}}
+// placeholders. Tasks without depends_on run in list order (chain sequence).
+type TemplateTask struct {
+	Key                 string            `json:"key,omitempty"` // Local name referenced by depends_on
+	Title               string            `json:"title"`
+	Description         string            `json:"description,omitempty"`
+	Type                string            `json:"type,omitempty"` // "" or TaskTypeApproval
+	Compensation        bool              `json:"compensation,omitempty"`
+	Requires            map[string]string `json:"requires,omitempty"`
+	Priority            int               `json:"priority,omitempty"`
+	ExecutionMode       ExecutionMode     `json:"execution_mode,omitempty"`
+	MaxAttempts         int               `json:"max_attempts,omitempty"`
+	RetryBackoffSeconds int               `json:"retry_backoff_seconds,omitempty"`
+	DependsOn           []string          `json:"depends_on,omitempty"` // Keys of other tasks in this template
+}
+
+// Template describes a reusable chain; instantiating it creates the chain and every task at once.
+type Template struct {
+	ID               string          `json:"id"`
+	UserID           string          `json:"user_id,omitempty"`
+	Name             string          `json:"name"`
+	Description      string          `json:"description,omitempty"`
+	ChainName        string          `json:"chain_name,omitempty"` // Name of created chains ({{param}} allowed); default: template name
+	ChainDescription string          `json:"chain_description,omitempty"`
+	ChainOnFailure   FailurePolicy   `json:"chain_on_failure,omitempty"`
+	Params           []TemplateParam `json:"params,omitempty"`
+	Tasks            []TemplateTask  `json:"tasks"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
@@ -6,6 +6,7 @@ type User struct {
 	ID           string    `json:"id"`
 	Username     string    `json:"username"`
 	PasswordHash string    `json:"-"`
+	MaxInFlight  *int      `json:"max_in_flight,omitempty"` // Most tasks of the user's channels in progress at once (0 = no limit); nil = COORDINATOR_USER_MAX_IN_FLIGHT
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// Webhook topics are the /v1/stream event types a webhook can subscribe to.
+const (
+	WebhookTopicTasks         = "tasks"
+	WebhookTopicChains        = "chains"
+	WebhookTopicAgents        = "agents" // Includes heartbeats
+	WebhookTopicEvents        = "events"
+	WebhookTopicNotifications = "notifications"
+)
+
+// Webhook posts the changes of its user's tasks, chains, agents, events or notifications
+// to URL, signed with Secret.
+type Webhook struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id,omitempty"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // Only returned when the webhook is created or its secret changes
+	Topics    []string  `json:"topics"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"   // Waiting for its (next) attempt
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded" // The receiver answered 2xx
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"    // Out of attempts
+)
+
+// WebhookDelivery is one message to a webhook and the outcome of its latest attempt.
+type WebhookDelivery struct {
+	ID            string                `json:"id"`
+	WebhookID     string                `json:"webhook_id"`
+	Topic         string                `json:"topic"`
+	Payload       map[string]any        `json:"payload"`
+	Status        WebhookDeliveryStatus `json:"status"`
+	Attempts      int                   `json:"attempts"`
+	NextAttemptAt *time.Time            `json:"next_attempt_at,omitempty"` // While pending
+	ResponseCode  int                   `json:"response_code,omitempty"`
+	Error         string                `json:"error,omitempty"`     // Transport error of the latest attempt
+	ReplayOf      string                `json:"replay_of,omitempty"` // Delivery this one replays
+	DeliveredAt   *time.Time            `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+}
@@ -0,0 +1,40 @@
+package store
+
+import "clwclw-monitor/coordinator/internal/model"
+
+// ValidTaskType reports whether a client may create a task of this type: a regular task
+// ("") or an approval gate. Other types are set by the coordinator itself.
+func ValidTaskType(typ string) bool {
+	return typ == "" || typ == model.TaskTypeApproval
+}
+
+// IsApprovalGate reports whether t is a human approval gate (never claimed by agents).
+func IsApprovalGate(t model.Task) bool {
+	return t.Type == model.TaskTypeApproval
+}
+
+// GateReached reports whether nothing t waits on directly still holds it back, i.e. an
+// agent could claim t now if it were a regular task. For an approval gate this is the
+// point where a user has to decide.
+func GateReached(chainTasks []model.Task, t model.Task) bool {
+	for _, up := range chainTasks {
+		if up.ID == t.ID || !gatedBy(t, up) {
+			continue
+		}
+		if BlocksDependents(up.Status) {
+			return false
+		}
+	}
+	return true
+}
+
+// AwaitingApproval returns the approval gates of chainTasks that are queued and reached.
+func AwaitingApproval(chainTasks []model.Task) []model.Task {
+	var out []model.Task
+	for _, t := range chainTasks {
+		if IsApprovalGate(t) && t.Status == model.TaskStatusQueued && GateReached(chainTasks, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+// taskArtifactBlobPath is the path of the artifact download endpoint. A task result artifact
+// whose URL ends in it plus a blob ID links to that blob.
+const taskArtifactBlobPath = "/v1/artifacts/"
+
+// Event payload keys that link an event to blobs in the artifact store. Blobs referenced
+// by a stored event are kept by artifact garbage collection.
+const (
+	EventPayloadArtifactID  = "artifact_id"  // a single blob ID
+	EventPayloadArtifactIDs = "artifact_ids" // a list of blob IDs
+)
+
+// EventArtifactIDs returns the blob IDs referenced by an event payload, deduplicated and
+// in payload order. Non-string values are ignored.
+func EventArtifactIDs(payload map[string]any) []string {
+	var out []string
+	seen := map[string]bool{}
+	add := func(v any) {
+		s, ok := v.(string)
+		if !ok {
+			return
+		}
+		s = strings.TrimSpace(s)
+		if s == "" || seen[s] {
+			return
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+
+	add(payload[EventPayloadArtifactID])
+	switch ids := payload[EventPayloadArtifactIDs].(type) {
+	case []any:
+		for _, v := range ids {
+			add(v)
+		}
+	case []string:
+		for _, v := range ids {
+			add(v)
+		}
+	}
+	return out
+}
+
+// TaskArtifactBlobID returns the blob a task result artifact links to: the ID at the end of a
+// relative or absolute URL ending in /v1/artifacts/{id}, ignoring any query or fragment.
+// It returns "" for inline artifacts and other URLs.
+func TaskArtifactBlobID(a model.TaskArtifact) string {
+	u := strings.TrimSpace(a.URL)
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		u = u[:i]
+	}
+	i := strings.LastIndex(u, taskArtifactBlobPath)
+	if i < 0 {
+		return ""
+	}
+	id := u[i+len(taskArtifactBlobPath):]
+	if id == "" || strings.Contains(id, "/") {
+		return ""
+	}
+	return id
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+// ClaimBlocker is one reason a queued task cannot be claimed right now.
+type ClaimBlocker struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ClaimCheck is what ClaimBlockers needs to know about a task. Agent is optional: without
+// one, only the reasons that hold for every agent are reported.
+type ClaimCheck struct {
+	Task       model.Task
+	Chain      model.Chain
+	Channel    model.Channel
+	ChainTasks []model.Task
+	Now        time.Time
+
+	Agent             *model.Agent
+	AgentOwnedChainID string // Chain the agent owns, if any
+}
+
+// ClaimBlockers explains why c.Task cannot be claimed, following the same rules as
+// ClaimTask in both stores. An empty result means the task is claimable.
+func ClaimBlockers(c ClaimCheck) []ClaimBlocker {
+	t := c.Task
+	var out []ClaimBlocker
+	add := func(code, format string, args ...any) {
+		out = append(out, ClaimBlocker{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if t.Status != model.TaskStatusQueued {
+		add("not_queued", "task is %s", t.Status)
+		return out
+	}
+	if IsApprovalGate(t) {
+		add("approval_gate", "approval gates are approved by a user, not claimed")
+	}
+	if c.Channel.Pause != "" {
+		add("channel_paused", "channel %s is %s", c.Channel.Name, c.Channel.Pause)
+	}
+	if c.Chain.Pause != "" {
+		add("chain_paused", "chain %s is %s", c.Chain.Name, c.Chain.Pause)
+	}
+
+	for _, ct := range c.ChainTasks {
+		if ct.Status == model.TaskStatusLocked {
+			add("chain_locked", "task %q of the chain is locked", ct.Title)
+			break
+		}
+	}
+
+	var waiting []string
+	for _, up := range c.ChainTasks {
+		if up.ID != t.ID && gatedBy(t, up) && BlocksDependents(up.Status) {
+			waiting = append(waiting, fmt.Sprintf("%q (%s)", up.Title, up.Status))
+		}
+	}
+	if len(waiting) > 0 {
+		add("waiting_on_predecessors", "waiting on %s", strings.Join(waiting, ", "))
+	}
+	if !CompensationReady(c.ChainTasks, t) {
+		add("compensation_not_needed", "compensation tasks run only after a task of the chain failed")
+	}
+
+	if t.NotBefore != nil && t.NotBefore.After(c.Now) {
+		add("not_before", "scheduled to start at %s", t.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if t.NextEligibleAt != nil && t.NextEligibleAt.After(c.Now) {
+		add("retry_backoff", "retry backoff until %s", t.NextEligibleAt.UTC().Format(time.RFC3339))
+	}
+
+	if c.Agent == nil {
+		// Ownership decides which agent gets the chain; without an agent only its status matters.
+		if c.Chain.OwnerAgentID == "" && c.Chain.Status != model.ChainStatusQueued && c.Chain.Status != model.ChainStatusInProgress {
+			add("chain_status", "chain is %s", c.Chain.Status)
+		}
+		return out
+	}
+
+	switch {
+	case c.AgentOwnedChainID == c.Chain.ID:
+		if c.Chain.Status == model.ChainStatusLocked {
+			add("chain_status", "chain is %s", c.Chain.Status)
+		}
+	case c.AgentOwnedChainID != "":
+		add("agent_owns_other_chain", "agent %s is still attached to chain %s", c.Agent.ID, c.AgentOwnedChainID)
+	case c.Chain.OwnerAgentID != "":
+		add("chain_owned", "chain is owned by agent %s", c.Chain.OwnerAgentID)
+	case c.Chain.Status != model.ChainStatusQueued && c.Chain.Status != model.ChainStatusInProgress:
+		add("chain_status", "chain is %s", c.Chain.Status)
+	}
+	if missing := MissingLabels(c.Agent.Labels, t.Requires); len(missing) > 0 {
+		add("labels_missing", "agent %s lacks required labels %s", c.Agent.ID, strings.Join(missing, ", "))
+	}
+	return out
+}
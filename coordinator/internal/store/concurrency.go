@@ -0,0 +1,50 @@
+package store
+
+import (
+	"fmt"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+// Scopes of a ConcurrencyLimitError.
+const (
+	ConcurrencyScopeChannel = "channel"
+	ConcurrencyScopeUser    = "user"
+)
+
+// ConcurrencyLimitError is returned by ClaimTask when handing out another task would take a
+// channel or user past its max in-flight count. It wraps ErrConcurrencyLimited.
+type ConcurrencyLimitError struct {
+	Scope    string `json:"scope"` // ConcurrencyScopeChannel or ConcurrencyScopeUser
+	ID       string `json:"id"`    // Channel or user ID
+	Limit    int    `json:"limit"`
+	InFlight int    `json:"in_flight"` // Tasks in progress before this claim
+}
+
+func (e *ConcurrencyLimitError) Error() string {
+	return fmt.Sprintf("concurrency_limited: %s %s has %d of %d tasks in progress", e.Scope, e.ID, e.InFlight, e.Limit)
+}
+
+func (e *ConcurrencyLimitError) Unwrap() error { return ErrConcurrencyLimited }
+
+// UserMaxInFlight returns the in-flight limit of u's channels: u's own MaxInFlight when set,
+// or else def (ClaimTaskRequest.UserMaxInFlight, the coordinator-wide default).
+func UserMaxInFlight(u *model.User, def int) int {
+	if u != nil && u.MaxInFlight != nil {
+		return *u.MaxInFlight
+	}
+	return def
+}
+
+// CheckConcurrency returns a *ConcurrencyLimitError when ch or its owner already runs as
+// many tasks as allowed. userLimit is the owner's limit (see UserMaxInFlight); a limit of 0
+// never blocks, and the user limit only applies to channels that have an owner.
+func CheckConcurrency(ch model.Channel, channelInFlight, userLimit, userInFlight int) error {
+	if ch.MaxInFlight > 0 && channelInFlight >= ch.MaxInFlight {
+		return &ConcurrencyLimitError{Scope: ConcurrencyScopeChannel, ID: ch.ID, Limit: ch.MaxInFlight, InFlight: channelInFlight}
+	}
+	if userLimit > 0 && ch.UserID != "" && userInFlight >= userLimit {
+		return &ConcurrencyLimitError{Scope: ConcurrencyScopeUser, ID: ch.UserID, Limit: userLimit, InFlight: userInFlight}
+	}
+	return nil
+}
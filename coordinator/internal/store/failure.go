@@ -0,0 +1,99 @@
+package store
+
+import "clwclw-monitor/coordinator/internal/model"
+
+// Cancel reasons recorded on tasks the coordinator skips because of the chain's
+// on_failure policy.
+const (
+	SkipReasonUpstreamFailed = "skipped: upstream task failed"
+	SkipReasonNoFailure      = "skipped: nothing to compensate"
+)
+
+// ValidFailurePolicy reports whether p can be stored on a chain; empty means halt.
+func ValidFailurePolicy(p model.FailurePolicy) bool {
+	switch p {
+	case "", model.FailurePolicyHalt, model.FailurePolicyContinue, model.FailurePolicyCompensate:
+		return true
+	}
+	return false
+}
+
+// HaltsOnFailure reports whether a failed task marks chain failed immediately (the default)
+// instead of letting the rest of the chain run.
+func HaltsOnFailure(chain model.Chain) bool {
+	return chain.OnFailure == "" || chain.OnFailure == model.FailurePolicyHalt
+}
+
+// HasFailure reports whether any task of chainTasks failed for good.
+func HasFailure(chainTasks []model.Task) bool {
+	for _, t := range chainTasks {
+		if isFailed(t.Status) {
+			return true
+		}
+	}
+	return false
+}
+
+// CompensationReady reports whether t may be claimed as far as compensation goes: regular
+// tasks always, compensation tasks only once a task of their chain failed.
+func CompensationReady(chainTasks []model.Task, t model.Task) bool {
+	return !t.Compensation || HasFailure(chainTasks)
+}
+
+// SkippedTask is a queued task that can no longer run, with the cancel reason to record.
+type SkippedTask struct {
+	Task   model.Task
+	Reason string
+}
+
+// SkippedTasks returns the queued tasks of chain that will never run and should be
+// cancelled:
+//   - a compensation task whose predecessors all finished without any task of the chain
+//     failing;
+//   - under FailurePolicyCompensate, a regular task that waits (directly or not) on a
+//     failed task, so the compensation tasks take over.
+//
+// Skipping a task can release the next one, so the result covers every step until the
+// chain settles.
+func SkippedTasks(chain model.Chain, chainTasks []model.Task) []SkippedTask {
+	tasks := make([]model.Task, len(chainTasks))
+	copy(tasks, chainTasks)
+
+	var out []SkippedTask
+	for {
+		failed := HasFailure(tasks)
+		skipped := false
+		for i, t := range tasks {
+			if t.Status != model.TaskStatusQueued {
+				continue
+			}
+			reason := ""
+			switch {
+			case t.Compensation:
+				if !failed && GateReached(tasks, t) {
+					reason = SkipReasonNoFailure
+				}
+			case chain.OnFailure == model.FailurePolicyCompensate:
+				for _, up := range Upstream(tasks, t.ID) {
+					if isFailed(up.Status) {
+						reason = SkipReasonUpstreamFailed
+						break
+					}
+				}
+			}
+			if reason == "" {
+				continue
+			}
+			out = append(out, SkippedTask{Task: t, Reason: reason})
+			tasks[i].Status = model.TaskStatusCancelled
+			skipped = true
+		}
+		if !skipped {
+			return out
+		}
+	}
+}
+
+func isFailed(status model.TaskStatus) bool {
+	return status == model.TaskStatusFailed || status == model.TaskStatusDeadLetter
+}
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+// Task dependencies: a task with depends_on is gated by the graph (claimable once
+// none of its dependencies is queued or in_progress); a task without depends_on keeps
+// the linear rule (no queued/in_progress task with a lower sequence in the chain).
+
+// NormalizeDependsOn trims and de-duplicates dependency IDs, preserving order.
+func NormalizeDependsOn(ids []string) []string {
+	out := make([]string, 0, len(ids))
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if id == "" {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	return out
+}
+
+// ValidateDependencies checks t.DependsOn against the other tasks of its chain:
+// every dependency must be a task of the same chain and the graph must stay acyclic.
+func ValidateDependencies(t model.Task, chainTasks []model.Task) error {
+	byID := make(map[string]struct{}, len(chainTasks))
+	graph := make([]model.Task, 0, len(chainTasks)+1)
+	for _, ct := range chainTasks {
+		if ct.ID == t.ID {
+			continue
+		}
+		byID[ct.ID] = struct{}{}
+		graph = append(graph, ct)
+	}
+	for _, dep := range t.DependsOn {
+		if dep == t.ID {
+			return ErrDependencyCycle
+		}
+		if _, ok := byID[dep]; !ok {
+			return errors.New("depends_on_not_in_chain")
+		}
+	}
+	if HasDependencyCycle(append(graph, t)) {
+		return ErrDependencyCycle
+	}
+	return nil
+}
+
+// HasDependencyCycle reports whether the depends_on edges among tasks form a cycle.
+// Edges to tasks outside the slice are ignored.
+func HasDependencyCycle(tasks []model.Task) bool {
+	indegree := make(map[string]int, len(tasks))
+	dependents := make(map[string][]string, len(tasks))
+	for _, t := range tasks {
+		indegree[t.ID] += 0
+	}
+	for _, t := range tasks {
+		for _, dep := range t.DependsOn {
+			if _, ok := indegree[dep]; !ok {
+				continue
+			}
+			indegree[t.ID]++
+			dependents[dep] = append(dependents[dep], t.ID)
+		}
+	}
+
+	// Kahn's algorithm: whatever cannot be peeled off in topological order sits on a cycle.
+	queue := make([]string, 0, len(tasks))
+	for id, n := range indegree {
+		if n == 0 {
+			queue = append(queue, id)
+		}
+	}
+	visited := 0
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, next := range dependents[id] {
+			indegree[next]--
+			if indegree[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+	return visited < len(indegree)
+}
+
+// BlocksDependents reports whether a task in this status still holds back the tasks
+// that come after it (by depends_on or by sequence).
+func BlocksDependents(status model.TaskStatus) bool {
+	return status == model.TaskStatusQueued || status == model.TaskStatusInProgress
+}
+
+// gatedBy reports whether t waits on upstream: by depends_on, or by sequence for tasks
+// without depends_on.
+func gatedBy(t, upstream model.Task) bool {
+	if len(t.DependsOn) > 0 {
+		for _, dep := range t.DependsOn {
+			if dep == upstream.ID {
+				return true
+			}
+		}
+		return false
+	}
+	return t.Sequence > upstream.Sequence
+}
+
+// Downstream returns the tasks of chainTasks that wait on taskID, directly or through
+// other tasks: by depends_on, or by sequence for tasks without depends_on. The result is
+// in sequence order and does not include taskID itself.
+func Downstream(chainTasks []model.Task, taskID string) []model.Task {
+	return walkGates(chainTasks, taskID, func(next, cur model.Task) bool { return gatedBy(next, cur) })
+}
+
+// Upstream returns the tasks of chainTasks that taskID waits on, directly or through other
+// tasks; the mirror of Downstream. The result is in sequence order.
+func Upstream(chainTasks []model.Task, taskID string) []model.Task {
+	return walkGates(chainTasks, taskID, func(next, cur model.Task) bool { return gatedBy(cur, next) })
+}
+
+// walkGates collects every task reachable from taskID where linked(next, cur) holds for
+// each step, sorted by sequence.
+func walkGates(chainTasks []model.Task, taskID string, linked func(next, cur model.Task) bool) []model.Task {
+	var root *model.Task
+	for i := range chainTasks {
+		if chainTasks[i].ID == taskID {
+			root = &chainTasks[i]
+			break
+		}
+	}
+	if root == nil {
+		return nil
+	}
+
+	reached := map[string]bool{taskID: true}
+	queue := []model.Task{*root}
+	var out []model.Task
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, t := range chainTasks {
+			if reached[t.ID] || !linked(t, cur) {
+				continue
+			}
+			reached[t.ID] = true
+			queue = append(queue, t)
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Sequence < out[j].Sequence })
+	return out
+}
+
+// NewChainTask is one task of a CreateChainWithTasks call. DependsOn holds indexes into
+// the same call's task list, because task IDs are only assigned by the store.
+type NewChainTask struct {
+	Task      model.Task
+	DependsOn []int
+}
+
+// ValidateNewChainTasks checks the task list of CreateChainWithTasks: at least one task,
+// every task titled, dependency indexes in range, and an acyclic graph.
+func ValidateNewChainTasks(tasks []NewChainTask) error {
+	if len(tasks) == 0 {
+		return errors.New("tasks_required")
+	}
+	graph := make([]model.Task, len(tasks))
+	for i, nt := range tasks {
+		if strings.TrimSpace(nt.Task.Title) == "" {
+			return errors.New("title_required")
+		}
+		if nt.Task.MaxAttempts < 0 || nt.Task.RetryBackoffSeconds < 0 {
+			return errors.New("retry_policy_invalid")
+		}
+		if _, err := NormalizeLabels(nt.Task.Requires); err != nil {
+			return err
+		}
+		graph[i].ID = strconv.Itoa(i)
+		for _, dep := range nt.DependsOn {
+			if dep < 0 || dep >= len(tasks) {
+				return errors.New("depends_on_not_in_chain")
+			}
+			if dep == i {
+				return ErrDependencyCycle
+			}
+			graph[i].DependsOn = append(graph[i].DependsOn, strconv.Itoa(dep))
+		}
+	}
+	if HasDependencyCycle(graph) {
+		return ErrDependencyCycle
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+func TestHasDependencyCycle(t *testing.T) {
+	diamond := []model.Task{
+		{ID: "a"},
+		{ID: "b", DependsOn: []string{"a"}},
+		{ID: "c", DependsOn: []string{"a"}},
+		{ID: "d", DependsOn: []string{"b", "c"}},
+	}
+	if HasDependencyCycle(diamond) {
+		t.Fatalf("diamond must be acyclic")
+	}
+
+	cyclic := []model.Task{
+		{ID: "a", DependsOn: []string{"c"}},
+		{ID: "b", DependsOn: []string{"a"}},
+		{ID: "c", DependsOn: []string{"b"}},
+	}
+	if !HasDependencyCycle(cyclic) {
+		t.Fatalf("expected a cycle")
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	chain := []model.Task{{ID: "a"}, {ID: "b", DependsOn: []string{"a"}}}
+
+	if err := ValidateDependencies(model.Task{ID: "c", DependsOn: []string{"a", "b"}}, chain); err != nil {
+		t.Fatalf("expected valid fan-in, got %v", err)
+	}
+	if err := ValidateDependencies(model.Task{ID: "c", DependsOn: []string{"x"}}, chain); err == nil || err.Error() != "depends_on_not_in_chain" {
+		t.Fatalf("expected depends_on_not_in_chain, got %v", err)
+	}
+	if err := ValidateDependencies(model.Task{ID: "c", DependsOn: []string{"c"}}, chain); err != ErrDependencyCycle {
+		t.Fatalf("expected self-dependency to be a cycle, got %v", err)
+	}
+	// Rewiring "a" to depend on "b" closes a loop.
+	if err := ValidateDependencies(model.Task{ID: "a", DependsOn: []string{"b"}}, chain); err != ErrDependencyCycle {
+		t.Fatalf("expected cycle, got %v", err)
+	}
+}
+
+func TestDownstream(t *testing.T) {
+	// a → b, a → c (depends_on); d has no depends_on, so it waits on everything before it.
+	chain := []model.Task{
+		{ID: "a", Sequence: 1},
+		{ID: "b", Sequence: 2, DependsOn: []string{"a"}},
+		{ID: "c", Sequence: 3, DependsOn: []string{"a"}},
+		{ID: "d", Sequence: 4},
+		{ID: "e", Sequence: 5, DependsOn: []string{"b"}},
+	}
+
+	ids := func(tasks []model.Task) []string {
+		out := make([]string, len(tasks))
+		for i, t := range tasks {
+			out[i] = t.ID
+		}
+		return out
+	}
+	cases := map[string][]string{
+		"a": {"b", "c", "d", "e"},
+		"b": {"d", "e"},
+		"c": {"d"},
+		"d": {},
+		"e": {},
+	}
+	for id, want := range cases {
+		got := ids(Downstream(chain, id))
+		if len(got) != len(want) {
+			t.Fatalf("Downstream(%s) = %v, want %v", id, got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("Downstream(%s) = %v, want %v", id, got, want)
+			}
+		}
+	}
+}
+
+func TestUpstream(t *testing.T) {
+	// Same chain as TestDownstream.
+	chain := []model.Task{
+		{ID: "a", Sequence: 1},
+		{ID: "b", Sequence: 2, DependsOn: []string{"a"}},
+		{ID: "c", Sequence: 3, DependsOn: []string{"a"}},
+		{ID: "d", Sequence: 4},
+		{ID: "e", Sequence: 5, DependsOn: []string{"b"}},
+	}
+
+	cases := map[string][]string{
+		"a": {},
+		"b": {"a"},
+		"c": {"a"},
+		"d": {"a", "b", "c"},
+		"e": {"a", "b"},
+	}
+	for id, want := range cases {
+		got := Upstream(chain, id)
+		if len(got) != len(want) {
+			t.Fatalf("Upstream(%s) = %v, want %v", id, got, want)
+		}
+		for i := range want {
+			if got[i].ID != want[i] {
+				t.Fatalf("Upstream(%s)[%d] = %s, want %s", id, i, got[i].ID, want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+// Agent labels describe what a machine can do (gpu=true, repo=web, size=large). A task's
+// requires lists the labels a claimer must have, each with the same value.
+
+var labelKeyRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]{0,62}$`)
+
+const maxLabelValueLen = 128
+
+// NormalizeLabels trims keys and values and checks them; nil stays nil so callers can tell
+// "not sent" from "cleared".
+func NormalizeLabels(labels map[string]string) (map[string]string, error) {
+	if labels == nil {
+		return nil, nil
+	}
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !labelKeyRe.MatchString(k) {
+			return nil, fmt.Errorf("label_invalid: %q", k)
+		}
+		if len(v) > maxLabelValueLen {
+			return nil, fmt.Errorf("label_invalid: value of %s is longer than %d", k, maxLabelValueLen)
+		}
+		out[k] = v
+	}
+	return out, nil
+}
+
+// MissingLabels returns the requirements agentLabels does not satisfy as sorted
+// "key=value" strings; empty means the agent matches.
+func MissingLabels(agentLabels, requires map[string]string) []string {
+	var missing []string
+	for k, want := range requires {
+		if got, ok := agentLabels[k]; !ok || got != want {
+			missing = append(missing, k+"="+want)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// LabelsMatch reports whether agent has every label t requires.
+func LabelsMatch(agent *model.Agent, t model.Task) bool {
+	if len(t.Requires) == 0 {
+		return true
+	}
+	if agent == nil {
+		return false
+	}
+	return len(MissingLabels(agent.Labels, t.Requires)) == 0
+}
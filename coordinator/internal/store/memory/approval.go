@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+func (s *Store) ReviewApprovalTask(_ context.Context, req store.ReviewTaskRequest) (*model.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.TrimSpace(req.TaskID) == "" {
+		return nil, errWithCode("task_id_required")
+	}
+
+	t, ok := s.tasks[req.TaskID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	if !store.IsApprovalGate(t) {
+		return nil, store.ErrConflict
+	}
+
+	decided := model.TaskStatusFailed
+	if req.Approve {
+		decided = model.TaskStatusDone
+	}
+	switch t.Status {
+	case decided:
+		return &t, nil // idempotent
+	case model.TaskStatusQueued:
+	default:
+		return nil, store.ErrConflict
+	}
+
+	if !store.GateReached(s.chainTasks(t.ChainID), t) {
+		return nil, store.ErrConflict
+	}
+
+	now := time.Now().UTC()
+	t.Status = decided
+	t.ReviewedAt = &now
+	t.ReviewedBy = req.ReviewedBy
+	t.ReviewComment = req.Comment
+	t.DoneAt = &now
+	if !req.Approve {
+		t.LastFailureReason = "rejected"
+		if req.Comment != "" {
+			t.LastFailureReason = "rejected: " + req.Comment
+		}
+	}
+	t.UpdatedAt = now
+	s.tasks[t.ID] = t
+
+	// A rejection counts as a failed task for the chain's on_failure policy.
+	if t.ChainID != "" {
+		s.updateChainStatus(t.ChainID, now)
+	}
+	return &t, nil
+}
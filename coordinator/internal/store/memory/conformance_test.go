@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/store"
+	"clwclw-monitor/coordinator/internal/store/storetest"
+)
+
+func TestClaimOrder(t *testing.T) {
+	storetest.RunClaimOrderTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestDependencies(t *testing.T) {
+	storetest.RunDependencyTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestSchedule(t *testing.T) {
+	storetest.RunScheduleTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestRecurringSchedules(t *testing.T) {
+	storetest.RunRecurringScheduleTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestChainWithTasks(t *testing.T) {
+	storetest.RunChainWithTasksTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestCancelTask(t *testing.T) {
+	storetest.RunCancelTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestPause(t *testing.T) {
+	storetest.RunPauseTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestTaskResults(t *testing.T) {
+	storetest.RunTaskResultTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestEventArtifacts(t *testing.T) {
+	storetest.RunEventArtifactTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestTaskResultArtifacts(t *testing.T) {
+	storetest.RunTaskResultArtifactTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestApprovalGates(t *testing.T) {
+	storetest.RunApprovalTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestFailurePolicies(t *testing.T) {
+	storetest.RunFailurePolicyTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestAgentLabels(t *testing.T) {
+	storetest.RunLabelTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestConcurrencyLimits(t *testing.T) {
+	storetest.RunConcurrencyTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestNotifications(t *testing.T) {
+	storetest.RunNotificationTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestNotificationRules(t *testing.T) {
+	storetest.RunNotificationRuleTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestWebhooks(t *testing.T) {
+	storetest.RunWebhookTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestRetry(t *testing.T) {
+	storetest.RunRetryTests(t, func(t *testing.T) store.Store { return NewStore() })
+}
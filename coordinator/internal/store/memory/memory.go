@@ -3,6 +3,7 @@ package memory
 import (
 	"context"
 	"log"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -24,6 +25,17 @@ type Store struct {
 	users     map[string]model.User
 	authCodes map[string]model.AuthCode
 
+	templates    map[string]model.Template
+	schedules    map[string]model.Schedule
+	scheduleRuns map[string][]model.ScheduleRun // by schedule ID, oldest first
+	results      map[string]model.TaskResult    // by task ID
+
+	notifications     map[string]model.Notification
+	notificationRules map[string]model.NotificationRule
+
+	webhooks          map[string]model.Webhook
+	webhookDeliveries map[string]model.WebhookDelivery
+
 	claimIdem map[string]string
 	inputIdem map[string]string
 
@@ -33,17 +45,25 @@ type Store struct {
 
 func NewStore() *Store {
 	return &Store{
-		agents:    make(map[string]model.Agent),
-		channels:  make(map[string]model.Channel),
-		chains:    make(map[string]model.Chain),
-		tasks:     make(map[string]model.Task),
-		events:    make(map[string]model.Event),
-		inputs:    make(map[string]model.TaskInput),
-		users:     make(map[string]model.User),
-		authCodes: make(map[string]model.AuthCode),
-		claimIdem: make(map[string]string),
-		inputIdem: make(map[string]string),
-		idem:      make(map[string]struct{}),
+		agents:            make(map[string]model.Agent),
+		channels:          make(map[string]model.Channel),
+		chains:            make(map[string]model.Chain),
+		tasks:             make(map[string]model.Task),
+		events:            make(map[string]model.Event),
+		inputs:            make(map[string]model.TaskInput),
+		users:             make(map[string]model.User),
+		authCodes:         make(map[string]model.AuthCode),
+		templates:         make(map[string]model.Template),
+		schedules:         make(map[string]model.Schedule),
+		scheduleRuns:      make(map[string][]model.ScheduleRun),
+		results:           make(map[string]model.TaskResult),
+		notifications:     make(map[string]model.Notification),
+		notificationRules: make(map[string]model.NotificationRule),
+		webhooks:          make(map[string]model.Webhook),
+		webhookDeliveries: make(map[string]model.WebhookDelivery),
+		claimIdem:         make(map[string]string),
+		inputIdem:         make(map[string]string),
+		idem:              make(map[string]struct{}),
 	}
 }
 
@@ -64,8 +84,9 @@ func (s *Store) UpsertAgent(_ context.Context, a model.Agent) (model.Agent, erro
 		if a.Status != "" {
 			existing.Status = a.Status
 		}
-		if a.ClaudeStatus != "" {
+		if a.ClaudeStatus != "" && a.ClaudeStatus != existing.ClaudeStatus {
 			existing.ClaudeStatus = a.ClaudeStatus
+			existing.ClaudeStatusSince = now
 		}
 		if a.CurrentTaskID != "" {
 			existing.CurrentTaskID = a.CurrentTaskID
@@ -73,6 +94,10 @@ func (s *Store) UpsertAgent(_ context.Context, a model.Agent) (model.Agent, erro
 		if a.Meta != nil {
 			existing.Meta = a.Meta
 		}
+		// Agents that do not report labels keep the ones they had.
+		if a.Labels != nil {
+			existing.Labels = a.Labels
+		}
 		existing.LastSeen = now
 		existing.UpdatedAt = now
 		s.agents[a.ID] = existing
@@ -85,6 +110,7 @@ func (s *Store) UpsertAgent(_ context.Context, a model.Agent) (model.Agent, erro
 	if a.ClaudeStatus == "" {
 		a.ClaudeStatus = model.ClaudeStatusIdle
 	}
+	a.ClaudeStatusSince = now
 	a.LastSeen = now
 	a.CreatedAt = now
 	a.UpdatedAt = now
@@ -138,6 +164,13 @@ func (s *Store) CreateChannel(_ context.Context, ch model.Channel) (model.Channe
 		}
 	}
 
+	if ch.RetryMaxAttempts < 0 || ch.RetryBackoffSeconds < 0 {
+		return model.Channel{}, errWithCode("retry_policy_invalid")
+	}
+	if ch.MaxInFlight < 0 {
+		return model.Channel{}, errWithCode("max_in_flight_invalid")
+	}
+
 	ch.ID = newID()
 	ch.CreatedAt = time.Now().UTC()
 	s.channels[ch.ID] = ch
@@ -173,6 +206,93 @@ func (s *Store) GetChannelByName(_ context.Context, name string) (model.Channel,
 	return model.Channel{}, store.ErrNotFound
 }
 
+func (s *Store) GetChannel(_ context.Context, id string) (model.Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch, ok := s.channels[id]
+	if !ok {
+		return model.Channel{}, store.ErrNotFound
+	}
+	return ch, nil
+}
+
+func (s *Store) UpdateChannel(_ context.Context, ch model.Channel) (model.Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.channels[ch.ID]
+	if !ok {
+		return model.Channel{}, store.ErrNotFound
+	}
+	if ch.RetryMaxAttempts < 0 || ch.RetryBackoffSeconds < 0 {
+		return model.Channel{}, errWithCode("retry_policy_invalid")
+	}
+	if ch.MaxInFlight < 0 {
+		return model.Channel{}, errWithCode("max_in_flight_invalid")
+	}
+
+	existing.Description = ch.Description
+	existing.RetryMaxAttempts = ch.RetryMaxAttempts
+	existing.RetryBackoffSeconds = ch.RetryBackoffSeconds
+	existing.MaxInFlight = ch.MaxInFlight
+	s.channels[existing.ID] = existing
+	return existing, nil
+}
+
+func (s *Store) SetChannelPause(_ context.Context, channelID string, mode model.PauseMode) (model.Channel, []model.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !store.ValidPauseMode(mode) {
+		return model.Channel{}, nil, errWithCode("pause_mode_invalid")
+	}
+	ch, ok := s.channels[channelID]
+	if !ok {
+		return model.Channel{}, nil, store.ErrNotFound
+	}
+	now := time.Now().UTC()
+	ch.Pause, ch.PausedAt = mode, pausedAt(ch.Pause, ch.PausedAt, mode, now)
+	s.channels[channelID] = ch
+
+	var requeued []model.Task
+	if mode == model.PauseModePaused {
+		requeued = s.requeuePaused(func(t model.Task) bool { return t.ChannelID == channelID }, now)
+	}
+	return ch, requeued, nil
+}
+
+// requeuePaused returns the in_progress tasks matched by inScope to the queue when their
+// chain or channel is paused, so the work stops now instead of finishing as with a drain.
+// Must be called with s.mu held.
+func (s *Store) requeuePaused(inScope func(model.Task) bool, now time.Time) []model.Task {
+	var out []model.Task
+	for _, t := range s.tasks {
+		if t.Status != model.TaskStatusInProgress || !inScope(t) {
+			continue
+		}
+		agentID := t.AssignedAgentID
+		t = s.requeueTask(t, now)
+		s.recordEvent(agentID, t.ID, store.EventTypeTaskRequeued, map[string]any{
+			"reason": "paused",
+		}, now)
+		out = append(out, t)
+	}
+	return out
+}
+
+// pausedAt returns the paused_at to store when the pause mode changes from prev to next:
+// it keeps the original time while still paused in some mode and clears it on resume.
+func pausedAt(prev model.PauseMode, prevAt *time.Time, next model.PauseMode, now time.Time) *time.Time {
+	if next == "" {
+		return nil
+	}
+	if prev != "" && prevAt != nil {
+		return prevAt
+	}
+	return &now
+}
+
 func (s *Store) CreateChain(_ context.Context, c model.Chain) (model.Chain, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -186,6 +306,9 @@ func (s *Store) CreateChain(_ context.Context, c model.Chain) (model.Chain, erro
 	if _, ok := s.channels[c.ChannelID]; !ok {
 		return model.Chain{}, store.ErrNotFound
 	}
+	if !store.ValidFailurePolicy(c.OnFailure) {
+		return model.Chain{}, errWithCode("on_failure_invalid")
+	}
 
 	// Removed: chain name uniqueness check - allow duplicate names within same channel
 
@@ -200,6 +323,66 @@ func (s *Store) CreateChain(_ context.Context, c model.Chain) (model.Chain, erro
 	return c, nil
 }
 
+func (s *Store) CreateChainWithTasks(_ context.Context, c model.Chain, tasks []store.NewChainTask) (model.Chain, []model.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.TrimSpace(c.ChannelID) == "" {
+		return model.Chain{}, nil, errWithCode("channel_id_required")
+	}
+	if strings.TrimSpace(c.Name) == "" {
+		return model.Chain{}, nil, errWithCode("name_required")
+	}
+	if _, ok := s.channels[c.ChannelID]; !ok {
+		return model.Chain{}, nil, store.ErrNotFound
+	}
+	if !store.ValidFailurePolicy(c.OnFailure) {
+		return model.Chain{}, nil, errWithCode("on_failure_invalid")
+	}
+	if err := store.ValidateNewChainTasks(tasks); err != nil {
+		return model.Chain{}, nil, err
+	}
+
+	// Everything is validated before the first write, so the lock makes this all-or-nothing.
+	now := time.Now().UTC()
+	c.ID = newID()
+	if c.Status == "" {
+		c.Status = model.ChainStatusQueued
+	}
+	c.CreatedAt = now
+	c.UpdatedAt = now
+
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = newID()
+	}
+	out := make([]model.Task, len(tasks))
+	for i, nt := range tasks {
+		t := nt.Task
+		t.ID = ids[i]
+		t.UserID = c.UserID
+		t.ChannelID = c.ChannelID
+		t.ChainID = c.ID
+		t.Sequence = i + 1
+		t.DependsOn = nil
+		for _, dep := range nt.DependsOn {
+			t.DependsOn = append(t.DependsOn, ids[dep])
+		}
+		if t.Status == "" {
+			t.Status = model.TaskStatusQueued
+		}
+		t.CreatedAt = now
+		t.UpdatedAt = now
+		out[i] = t
+	}
+
+	s.chains[c.ID] = c
+	for _, t := range out {
+		s.tasks[t.ID] = t
+	}
+	return c, out, nil
+}
+
 func (s *Store) GetChain(_ context.Context, id string) (model.Chain, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -240,6 +423,9 @@ func (s *Store) UpdateChain(_ context.Context, c model.Chain) (model.Chain, erro
 	if !ok {
 		return model.Chain{}, store.ErrNotFound
 	}
+	if !store.ValidFailurePolicy(c.OnFailure) {
+		return model.Chain{}, errWithCode("on_failure_invalid")
+	}
 
 	// Removed: chain name uniqueness check - allow duplicate names within same channel
 	if strings.TrimSpace(c.Name) != "" {
@@ -252,6 +438,9 @@ func (s *Store) UpdateChain(_ context.Context, c model.Chain) (model.Chain, erro
 	if c.Status != "" {
 		existing.Status = c.Status
 	}
+	if c.OnFailure != "" {
+		existing.OnFailure = c.OnFailure
+	}
 	// Allow updating OwnerAgentID (including setting to empty string to release ownership)
 	if c.OwnerAgentID != existing.OwnerAgentID {
 		existing.OwnerAgentID = c.OwnerAgentID
@@ -262,6 +451,31 @@ func (s *Store) UpdateChain(_ context.Context, c model.Chain) (model.Chain, erro
 	return existing, nil
 }
 
+func (s *Store) SetChainPause(_ context.Context, chainID string, mode model.PauseMode) (model.Chain, []model.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !store.ValidPauseMode(mode) {
+		return model.Chain{}, nil, errWithCode("pause_mode_invalid")
+	}
+	chain, ok := s.chains[chainID]
+	if !ok {
+		return model.Chain{}, nil, store.ErrNotFound
+	}
+	now := time.Now().UTC()
+	chain.Pause, chain.PausedAt = mode, pausedAt(chain.Pause, chain.PausedAt, mode, now)
+	chain.UpdatedAt = now
+	s.chains[chainID] = chain
+
+	var requeued []model.Task
+	if mode == model.PauseModePaused {
+		requeued = s.requeuePaused(func(t model.Task) bool { return t.ChainID == chainID }, now)
+		// The requeue re-evaluated the chain status and released its owner.
+		chain = s.chains[chainID]
+	}
+	return chain, requeued, nil
+}
+
 func (s *Store) DeleteChain(_ context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -302,6 +516,7 @@ func (s *Store) DetachAgentFromChain(_ context.Context, req store.DetachAgentFro
 	for id, t := range s.tasks {
 		if t.ChainID == chainID && t.Status == model.TaskStatusInProgress {
 			t.Status = model.TaskStatusLocked
+			t.LeaseExpiresAt = nil
 			t.UpdatedAt = now
 			s.tasks[id] = t
 		}
@@ -353,6 +568,7 @@ func (s *Store) UpdateTaskStatus(_ context.Context, taskID string, newStatus mod
 		t.Status = model.TaskStatusQueued
 		t.AssignedAgentID = ""
 		t.ClaimedAt = nil
+		t.LeaseExpiresAt = nil
 		t.UpdatedAt = now
 	} else { // done
 		t.Status = model.TaskStatusDone
@@ -369,6 +585,82 @@ func (s *Store) UpdateTaskStatus(_ context.Context, taskID string, newStatus mod
 	return &t, nil
 }
 
+func (s *Store) CancelTask(_ context.Context, req store.CancelTaskRequest) (*model.Task, []model.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.TrimSpace(req.TaskID) == "" {
+		return nil, nil, errWithCode("task_id_required")
+	}
+
+	t, ok := s.tasks[req.TaskID]
+	if !ok {
+		return nil, nil, store.ErrNotFound
+	}
+	switch t.Status {
+	case model.TaskStatusCancelled:
+		return &t, nil, nil // idempotent
+	case model.TaskStatusQueued, model.TaskStatusInProgress, model.TaskStatusLocked:
+	default:
+		return nil, nil, store.ErrConflict
+	}
+
+	now := time.Now().UTC()
+	running := t.Status == model.TaskStatusInProgress
+	agentID := t.AssignedAgentID
+	t = s.cancelTask(t, req, now)
+	if running {
+		if agent, ok := s.agents[agentID]; ok && agent.CurrentTaskID == t.ID {
+			agent.CurrentTaskID = ""
+			agent.UpdatedAt = now
+			s.agents[agentID] = agent
+		}
+		s.recordEvent(agentID, t.ID, store.EventTypeTaskCancelled, map[string]any{
+			"cancelled_by": req.CancelledBy,
+			"reason":       req.Reason,
+		}, now)
+	}
+
+	var downstream []model.Task
+	if t.ChainID != "" {
+		if req.CancelDownstream {
+			var chainTasks []model.Task
+			for _, ct := range s.tasks {
+				if ct.ChainID == t.ChainID {
+					chainTasks = append(chainTasks, ct)
+				}
+			}
+			for _, dt := range store.Downstream(chainTasks, t.ID) {
+				if dt.Status != model.TaskStatusQueued && dt.Status != model.TaskStatusLocked {
+					continue
+				}
+				downstream = append(downstream, s.cancelTask(dt, req, now))
+			}
+		}
+		s.reevaluateChainStatus(t.ChainID, now)
+	}
+
+	return &t, downstream, nil
+}
+
+// cancelTask marks t cancelled and releases its claim. The assignee is kept for a task
+// that was in progress so the agent can be told to stop.
+// Must be called with s.mu held.
+func (s *Store) cancelTask(t model.Task, req store.CancelTaskRequest, now time.Time) model.Task {
+	if t.Status != model.TaskStatusInProgress {
+		t.AssignedAgentID = ""
+	}
+	t.Status = model.TaskStatusCancelled
+	t.CancelledAt = &now
+	t.CancelledBy = req.CancelledBy
+	t.CancelReason = req.Reason
+	t.LeaseExpiresAt = nil
+	t.NextEligibleAt = nil
+	t.UpdatedAt = now
+	s.tasks[t.ID] = t
+	return t
+}
+
 // reevaluateChainStatus checks chain tasks and updates chain status accordingly.
 // Must be called with s.mu held.
 func (s *Store) reevaluateChainStatus(chainID string, now time.Time) {
@@ -376,17 +668,25 @@ func (s *Store) reevaluateChainStatus(chainID string, now time.Time) {
 	if !ok {
 		return
 	}
+	s.settleChainTasks(chain, now)
 
 	hasLocked := false
 	hasInProgress := false
 	hasQueued := false
 	allDoneOrFailed := true
 	hasFailed := false
+	// A chain without tasks is done, not cancelled: only tasks can be cancelled.
+	hasTasks := false
+	allCancelled := true
 
 	for _, t := range s.tasks {
 		if t.ChainID != chainID {
 			continue
 		}
+		hasTasks = true
+		if t.Status != model.TaskStatusCancelled {
+			allCancelled = false
+		}
 		switch t.Status {
 		case model.TaskStatusLocked:
 			hasLocked = true
@@ -397,7 +697,7 @@ func (s *Store) reevaluateChainStatus(chainID string, now time.Time) {
 		case model.TaskStatusQueued:
 			hasQueued = true
 			allDoneOrFailed = false
-		case model.TaskStatusFailed:
+		case model.TaskStatusFailed, model.TaskStatusDeadLetter:
 			hasFailed = true
 		case model.TaskStatusDone:
 			// fine
@@ -408,6 +708,8 @@ func (s *Store) reevaluateChainStatus(chainID string, now time.Time) {
 		// Ownership persists until explicit detach (matching Postgres behavior)
 		if hasFailed {
 			chain.Status = model.ChainStatusFailed
+		} else if hasTasks && allCancelled {
+			chain.Status = model.ChainStatusCancelled
 		} else {
 			chain.Status = model.ChainStatusDone
 		}
@@ -442,9 +744,30 @@ func (s *Store) CreateTask(_ context.Context, t model.Task) (model.Task, error)
 	if _, ok := s.chains[t.ChainID]; !ok {
 		return model.Task{}, errWithCode("chain_id_not_found")
 	}
+	if t.MaxAttempts < 0 || t.RetryBackoffSeconds < 0 {
+		return model.Task{}, errWithCode("retry_policy_invalid")
+	}
+	if _, err := store.NormalizeLabels(t.Requires); err != nil {
+		return model.Task{}, err
+	}
 
-	now := time.Now().UTC()
 	t.ID = newID()
+	t.DependsOn = store.NormalizeDependsOn(t.DependsOn)
+	if len(t.DependsOn) > 0 {
+		var chainTasks []model.Task
+		for _, ct := range s.tasks {
+			if ct.ChainID == t.ChainID {
+				chainTasks = append(chainTasks, ct)
+			}
+		}
+		if err := store.ValidateDependencies(t, chainTasks); err != nil {
+			return model.Task{}, err
+		}
+	} else {
+		t.DependsOn = nil
+	}
+
+	now := time.Now().UTC()
 	if t.Status == "" {
 		t.Status = model.TaskStatusQueued
 	}
@@ -476,11 +799,18 @@ func (s *Store) ListTasks(_ context.Context, f store.TaskFilter) ([]model.Task,
 		if f.Status != "" && t.Status != f.Status {
 			continue
 		}
+		if len(f.IDs) > 0 && !slices.Contains(f.IDs, t.ID) {
+			continue
+		}
 		out = append(out, t)
 	}
 
+	// Tasks created together (CreateChainWithTasks) share created_at; keep them in sequence order.
 	sort.Slice(out, func(i, j int) bool {
-		return out[i].CreatedAt.Before(out[j].CreatedAt)
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].Sequence < out[j].Sequence
 	})
 
 	if f.Limit > 0 && len(out) > f.Limit {
@@ -509,6 +839,94 @@ func (s *Store) ClaimTask(_ context.Context, req store.ClaimTaskRequest) (*model
 		}
 	}
 
+	channelID := s.resolveClaimChannel(req)
+	if channelID == "" {
+		return nil, errWithCode("channel_id_or_channel_required")
+	}
+
+	now := time.Now().UTC()
+	eligibleChainTasks, _ := s.claimCandidates(channelID, req.AgentID, now)
+
+	var taskToClaim *model.Task
+	if len(eligibleChainTasks) > 0 {
+		// Sort eligible chain tasks in claim order: effective priority, chain age, sequence
+		sort.Slice(eligibleChainTasks, func(i, j int) bool {
+			a, b := eligibleChainTasks[i], eligibleChainTasks[j]
+			return store.ClaimsBefore(a, s.chains[a.ChainID].CreatedAt, b, s.chains[b.ChainID].CreatedAt, now, req.PriorityAgingSeconds)
+		})
+		taskToClaim = &eligibleChainTasks[0]
+	}
+
+	if taskToClaim == nil {
+		return nil, store.ErrNoQueuedTasks
+	}
+	if err := s.checkConcurrency(channelID, req.UserMaxInFlight); err != nil {
+		return nil, err
+	}
+
+	taskToClaim.Status = model.TaskStatusInProgress
+	taskToClaim.AssignedAgentID = req.AgentID
+	taskToClaim.ClaimedAt = &now
+	taskToClaim.Attempts++
+	taskToClaim.NextEligibleAt = nil
+	taskToClaim.LeaseExpiresAt = leaseUntil(now, req.LeaseSeconds)
+	taskToClaim.UpdatedAt = now
+	s.tasks[taskToClaim.ID] = *taskToClaim
+
+	// Update chain status and ownership if this is the first task of a chain
+	chain := s.chains[taskToClaim.ChainID]
+	if chain.Status == model.ChainStatusQueued {
+		chain.Status = model.ChainStatusInProgress
+		chain.OwnerAgentID = req.AgentID // Set chain ownership
+		chain.UpdatedAt = now
+		s.chains[chain.ID] = chain
+	}
+
+	if idemKey != "" {
+		key := req.AgentID + ":" + idemKey
+		s.claimIdem[key] = taskToClaim.ID
+	}
+
+	// Update agent's current_task_id (task claimed)
+	// NOTE: Do NOT update claude_status - heartbeat is sole source of truth
+	if agent, ok := s.agents[req.AgentID]; ok {
+		agent.CurrentTaskID = taskToClaim.ID
+		agent.UpdatedAt = now
+		s.agents[req.AgentID] = agent
+	}
+
+	return taskToClaim, nil
+}
+
+// checkConcurrency reports whether the channel or its owner already has as many tasks in
+// progress as allowed. userLimit applies unless the owner has a limit of their own.
+// Must be called with s.mu held.
+func (s *Store) checkConcurrency(channelID string, userLimit int) error {
+	ch := s.channels[channelID]
+	if owner, ok := s.users[ch.UserID]; ok {
+		userLimit = store.UserMaxInFlight(&owner, userLimit)
+	}
+	if ch.MaxInFlight <= 0 && (userLimit <= 0 || ch.UserID == "") {
+		return nil
+	}
+	channelInFlight, userInFlight := 0, 0
+	for _, t := range s.tasks {
+		if t.Status != model.TaskStatusInProgress {
+			continue
+		}
+		if t.ChannelID == channelID {
+			channelInFlight++
+		}
+		if ch.UserID != "" && t.UserID == ch.UserID {
+			userInFlight++
+		}
+	}
+	return store.CheckConcurrency(ch, channelInFlight, userLimit, userInFlight)
+}
+
+// resolveClaimChannel returns the channel ID of a claim request, looking the channel up
+// by name when only req.Channel is given. Empty means no channel matched.
+func (s *Store) resolveClaimChannel(req store.ClaimTaskRequest) string {
 	channelID := strings.TrimSpace(req.ChannelID)
 	if channelID == "" && strings.TrimSpace(req.Channel) != "" {
 		for _, ch := range s.channels {
@@ -518,30 +936,44 @@ func (s *Store) ClaimTask(_ context.Context, req store.ClaimTaskRequest) (*model
 			}
 		}
 	}
-	if channelID == "" {
-		return nil, errWithCode("channel_id_or_channel_required")
+	return channelID
+}
+
+// claimCandidates returns the queued tasks of a channel the agent may claim at now, and the
+// earliest time a task that is eligible apart from its not_before/backoff becomes claimable.
+// Callers must hold s.mu.
+func (s *Store) claimCandidates(channelID, agentID string, now time.Time) (eligibleChainTasks []model.Task, nextClaimableAt *time.Time) {
+	var agent *model.Agent
+	if a, ok := s.agents[agentID]; ok {
+		agent = &a
 	}
 
 	// Check if agent already owns a chain
 	var ownedChainID string
 	for _, chain := range s.chains {
-		if chain.OwnerAgentID == req.AgentID {
+		if chain.OwnerAgentID == agentID {
 			ownedChainID = chain.ID
 			break
 		}
 	}
 
 	// Find eligible tasks within active chains (all tasks must belong to a chain)
-	var eligibleChainTasks []model.Task
 	for _, t := range s.tasks {
 		if t.ChannelID != channelID || t.Status != model.TaskStatusQueued || t.ChainID == "" {
 			continue
 		}
-
+		// Approval gates wait for a user, never for an agent.
+		if store.IsApprovalGate(t) {
+			continue
+		}
 		chain, ok := s.chains[t.ChainID]
 		if !ok {
 			continue
 		}
+		// Paused and draining chains/channels hand out nothing, and are not counted in the hint.
+		if chain.Pause != "" || s.channels[channelID].Pause != "" {
+			continue
+		}
 		if ownedChainID != "" && t.ChainID == ownedChainID {
 			// Owned chain: allow unless locked
 			if chain.Status == model.ChainStatusLocked {
@@ -576,73 +1008,64 @@ func (s *Store) ClaimTask(_ context.Context, req store.ClaimTaskRequest) (*model
 			continue
 		}
 
-		// A task is eligible when no predecessor in the chain is queued or in_progress.
+		// A task is eligible when no predecessor is queued or in_progress: its depends_on
+		// tasks if it has any, otherwise every lower sequence in the chain.
 		hasBlockingPredecessor := false
-		for _, otherTask := range s.tasks {
-			if otherTask.ChainID != t.ChainID || otherTask.Sequence >= t.Sequence {
-				continue
+		if len(t.DependsOn) > 0 {
+			for _, depID := range t.DependsOn {
+				if dep, ok := s.tasks[depID]; ok && store.BlocksDependents(dep.Status) {
+					hasBlockingPredecessor = true
+					break
+				}
 			}
-			if otherTask.Status == model.TaskStatusQueued || otherTask.Status == model.TaskStatusInProgress {
-				hasBlockingPredecessor = true
-				break
+		} else {
+			for _, otherTask := range s.tasks {
+				if otherTask.ChainID != t.ChainID || otherTask.Sequence >= t.Sequence {
+					continue
+				}
+				if store.BlocksDependents(otherTask.Status) {
+					hasBlockingPredecessor = true
+					break
+				}
 			}
 		}
-		if !hasBlockingPredecessor {
-			eligibleChainTasks = append(eligibleChainTasks, t)
+		if hasBlockingPredecessor {
+			continue
+		}
+		// Compensation tasks only run once something in their chain failed.
+		if !store.CompensationReady(s.chainTasks(t.ChainID), t) {
+			continue
+		}
+		// The agent must have every label the task requires.
+		if !store.LabelsMatch(agent, t) {
+			continue
 		}
-	}
 
-	var taskToClaim *model.Task
-	if len(eligibleChainTasks) > 0 {
-		// Sort eligible chain tasks: by chain creation time (oldest first), then by task sequence
-		sort.Slice(eligibleChainTasks, func(i, j int) bool {
-			chainI := s.chains[eligibleChainTasks[i].ChainID]
-			chainJ := s.chains[eligibleChainTasks[j].ChainID]
-			if chainI.CreatedAt.Before(chainJ.CreatedAt) {
-				return true
+		// Time gates last, so only tasks held back by their schedule or retry backoff count towards the hint.
+		if at := store.ClaimableAt(t); at != nil && at.After(now) {
+			if nextClaimableAt == nil || at.Before(*nextClaimableAt) {
+				nextClaimableAt = at
 			}
-			if chainI.CreatedAt.After(chainJ.CreatedAt) {
-				return false
-			}
-			return eligibleChainTasks[i].Sequence < eligibleChainTasks[j].Sequence
-		})
-		taskToClaim = &eligibleChainTasks[0]
-	}
-
-	if taskToClaim == nil {
-		return nil, store.ErrNoQueuedTasks
+			continue
+		}
+		eligibleChainTasks = append(eligibleChainTasks, t)
 	}
+	return eligibleChainTasks, nextClaimableAt
 
-	now := time.Now().UTC()
-	taskToClaim.Status = model.TaskStatusInProgress
-	taskToClaim.AssignedAgentID = req.AgentID
-	taskToClaim.ClaimedAt = &now
-	taskToClaim.UpdatedAt = now
-	s.tasks[taskToClaim.ID] = *taskToClaim
-
-	// Update chain status and ownership if this is the first task of a chain
-	chain := s.chains[taskToClaim.ChainID]
-	if chain.Status == model.ChainStatusQueued {
-		chain.Status = model.ChainStatusInProgress
-		chain.OwnerAgentID = req.AgentID // Set chain ownership
-		chain.UpdatedAt = now
-		s.chains[chain.ID] = chain
-	}
+}
 
-	if idemKey != "" {
-		key := req.AgentID + ":" + idemKey
-		s.claimIdem[key] = taskToClaim.ID
-	}
+// NextClaimableAt reports when the next task held back only by its not_before schedule
+// or retry backoff becomes claimable for the request, or nil if none is waiting.
+func (s *Store) NextClaimableAt(_ context.Context, req store.ClaimTaskRequest) (*time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Update agent's current_task_id (task claimed)
-	// NOTE: Do NOT update claude_status - heartbeat is sole source of truth
-	if agent, ok := s.agents[req.AgentID]; ok {
-		agent.CurrentTaskID = taskToClaim.ID
-		agent.UpdatedAt = now
-		s.agents[req.AgentID] = agent
+	channelID := s.resolveClaimChannel(req)
+	if channelID == "" {
+		return nil, errWithCode("channel_id_or_channel_required")
 	}
-
-	return taskToClaim, nil
+	_, next := s.claimCandidates(channelID, req.AgentID, time.Now().UTC())
+	return next, nil
 }
 
 func (s *Store) AssignTask(_ context.Context, req store.AssignTaskRequest) (*model.Task, error) {
@@ -660,6 +1083,9 @@ func (s *Store) AssignTask(_ context.Context, req store.AssignTaskRequest) (*mod
 	if !ok {
 		return nil, store.ErrNotFound
 	}
+	if store.IsApprovalGate(t) {
+		return nil, store.ErrConflict
+	}
 
 	switch t.Status {
 	case model.TaskStatusQueued:
@@ -679,6 +1105,9 @@ func (s *Store) AssignTask(_ context.Context, req store.AssignTaskRequest) (*mod
 	if t.ClaimedAt == nil {
 		t.ClaimedAt = &now
 	}
+	t.Attempts++
+	t.NextEligibleAt = nil
+	t.LeaseExpiresAt = leaseUntil(now, req.LeaseSeconds)
 	t.UpdatedAt = now
 	s.tasks[t.ID] = t
 
@@ -700,6 +1129,9 @@ func (s *Store) CompleteTask(_ context.Context, req store.CompleteTaskRequest) (
 	if strings.TrimSpace(req.TaskID) == "" {
 		return nil, errWithCode("task_id_required")
 	}
+	if err := store.ValidateTaskResult(req.Artifacts); err != nil {
+		return nil, err
+	}
 
 	t, ok := s.tasks[req.TaskID]
 	if !ok {
@@ -734,8 +1166,10 @@ func (s *Store) CompleteTask(_ context.Context, req store.CompleteTaskRequest) (
 		if t.DoneAt == nil {
 			t.DoneAt = &now
 		}
+		t.LeaseExpiresAt = nil
 		t.UpdatedAt = now
 		s.tasks[t.ID] = t
+		s.recordTaskResult(t, t.AssignedAgentID, req.Result, req.Artifacts, now)
 	default:
 		return nil, store.ErrConflict
 	}
@@ -762,6 +1196,27 @@ func (s *Store) CompleteTask(_ context.Context, req store.CompleteTaskRequest) (
 	return &t, nil
 }
 
+// settleChainTasks cancels the queued tasks of chain that can no longer run under its
+// on_failure policy (see store.SkippedTasks).
+// Must be called with s.mu held.
+func (s *Store) settleChainTasks(chain model.Chain, now time.Time) {
+	for _, sk := range store.SkippedTasks(chain, s.chainTasks(chain.ID)) {
+		s.cancelTask(sk.Task, store.CancelTaskRequest{Reason: sk.Reason}, now)
+	}
+}
+
+// chainTasks returns the tasks of chainID in no particular order.
+// Must be called with s.mu held.
+func (s *Store) chainTasks(chainID string) []model.Task {
+	var out []model.Task
+	for _, t := range s.tasks {
+		if t.ChainID == chainID {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
 // updateChainStatus updates chain status based on task completion
 // Does NOT release ownership - ownership persists until explicit detach.
 // Must be called with s.mu held.
@@ -770,6 +1225,13 @@ func (s *Store) updateChainStatus(chainID string, now time.Time) {
 	if !ok {
 		return
 	}
+	// Only the halt policy stops the chain at the first failure; otherwise the chain keeps
+	// its running status until every task has finished.
+	if !store.HaltsOnFailure(chain) {
+		s.reevaluateChainStatus(chainID, now)
+		return
+	}
+	s.settleChainTasks(chain, now)
 
 	allDone := true
 	hasFailed := false
@@ -777,10 +1239,10 @@ func (s *Store) updateChainStatus(chainID string, now time.Time) {
 		if t.ChainID != chainID {
 			continue
 		}
-		if t.Status == model.TaskStatusFailed {
+		if t.Status == model.TaskStatusFailed || t.Status == model.TaskStatusDeadLetter {
 			hasFailed = true
 		}
-		if t.Status != model.TaskStatusDone && t.Status != model.TaskStatusFailed {
+		if t.Status != model.TaskStatusDone && t.Status != model.TaskStatusFailed && t.Status != model.TaskStatusDeadLetter && t.Status != model.TaskStatusCancelled {
 			allDone = false
 		}
 	}
@@ -808,6 +1270,9 @@ func (s *Store) FailTask(_ context.Context, req store.FailTaskRequest) (*model.T
 	if strings.TrimSpace(req.TaskID) == "" {
 		return nil, errWithCode("task_id_required")
 	}
+	if err := store.ValidateTaskResult(req.Artifacts); err != nil {
+		return nil, err
+	}
 
 	t, ok := s.tasks[req.TaskID]
 	if !ok {
@@ -819,14 +1284,38 @@ func (s *Store) FailTask(_ context.Context, req store.FailTaskRequest) (*model.T
 	}
 
 	now := time.Now().UTC()
+	assignedAgentID := t.AssignedAgentID
 	switch t.Status {
-	case model.TaskStatusFailed:
+	case model.TaskStatusFailed, model.TaskStatusDeadLetter:
 		// idempotent: already failed; no state changes.
 	case model.TaskStatusInProgress:
-		t.Status = model.TaskStatusFailed
+		maxAttempts, backoffSeconds := store.RetryPolicy(t, s.channels[t.ChannelID])
+		t.Status, t.NextEligibleAt = store.RetryOutcome(t.Attempts, maxAttempts, backoffSeconds, now)
+		t.LastFailureReason = req.Reason
 		t.DoneAt = nil
+		t.LeaseExpiresAt = nil
+		if t.Status == model.TaskStatusQueued {
+			// Retry: hand the task back to the queue for any agent.
+			t.AssignedAgentID = ""
+			t.ClaimedAt = nil
+		}
 		t.UpdatedAt = now
 		s.tasks[t.ID] = t
+		s.recordTaskResult(t, assignedAgentID, req.Result, req.Artifacts, now)
+
+		switch t.Status {
+		case model.TaskStatusQueued:
+			payload := map[string]any{"reason": req.Reason, "attempts": t.Attempts, "max_attempts": maxAttempts}
+			if t.NextEligibleAt != nil {
+				payload["next_eligible_at"] = t.NextEligibleAt.Format(time.RFC3339Nano)
+			}
+			s.recordEvent(assignedAgentID, t.ID, store.EventTypeTaskRetryScheduled, payload, now)
+		case model.TaskStatusDeadLetter:
+			s.recordEvent(assignedAgentID, t.ID, store.EventTypeTaskDeadLettered, map[string]any{
+				"reason":   req.Reason,
+				"attempts": t.Attempts,
+			}, now)
+		}
 	default:
 		return nil, store.ErrConflict
 	}
@@ -835,7 +1324,7 @@ func (s *Store) FailTask(_ context.Context, req store.FailTaskRequest) (*model.T
 	// NOTE: Do NOT update claude_status - heartbeat is sole source of truth
 	agentID := strings.TrimSpace(req.AgentID)
 	if agentID == "" {
-		agentID = strings.TrimSpace(t.AssignedAgentID)
+		agentID = strings.TrimSpace(assignedAgentID)
 	}
 	if agentID != "" {
 		if agent, ok := s.agents[agentID]; ok {
@@ -853,6 +1342,185 @@ func (s *Store) FailTask(_ context.Context, req store.FailTaskRequest) (*model.T
 	return &t, nil
 }
 
+func (s *Store) ResubmitTask(_ context.Context, taskID string) (*model.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.TrimSpace(taskID) == "" {
+		return nil, errWithCode("task_id_required")
+	}
+
+	t, ok := s.tasks[taskID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	if t.Status != model.TaskStatusDeadLetter && t.Status != model.TaskStatusFailed {
+		return nil, store.ErrConflict
+	}
+
+	now := time.Now().UTC()
+	t.Status = model.TaskStatusQueued
+	t.Attempts = 0
+	t.NextEligibleAt = nil
+	t.AssignedAgentID = ""
+	t.ClaimedAt = nil
+	t.DoneAt = nil
+	t.UpdatedAt = now
+	s.tasks[t.ID] = t
+
+	// Chain leaves failed once no failed/dead_letter task remains.
+	if t.ChainID != "" {
+		s.reevaluateChainStatus(t.ChainID, now)
+	}
+
+	return &t, nil
+}
+
+func (s *Store) RenewTaskLease(_ context.Context, req store.RenewTaskLeaseRequest) (*model.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.TrimSpace(req.TaskID) == "" {
+		return nil, errWithCode("task_id_required")
+	}
+	if strings.TrimSpace(req.AgentID) == "" {
+		return nil, errWithCode("agent_id_required")
+	}
+	if req.LeaseSeconds <= 0 {
+		return nil, errWithCode("lease_seconds_required")
+	}
+
+	t, ok := s.tasks[req.TaskID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	// Only the agent currently holding the claim may extend it.
+	if t.Status != model.TaskStatusInProgress || t.AssignedAgentID != req.AgentID {
+		return nil, store.ErrConflict
+	}
+
+	now := time.Now().UTC()
+	t.LeaseExpiresAt = leaseUntil(now, req.LeaseSeconds)
+	t.UpdatedAt = now
+	s.tasks[t.ID] = t
+
+	return &t, nil
+}
+
+func (s *Store) RequeueExpiredTasks(_ context.Context, now time.Time) ([]model.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []model.Task
+	for _, t := range s.tasks {
+		if t.Status != model.TaskStatusInProgress || t.LeaseExpiresAt == nil || !t.LeaseExpiresAt.Before(now) {
+			continue
+		}
+
+		agentID := t.AssignedAgentID
+		expiredAt := *t.LeaseExpiresAt
+		t = s.requeueTask(t, now)
+		s.recordEvent(agentID, t.ID, store.EventTypeTaskLeaseExpired, map[string]any{
+			"reason":           "lease_expired",
+			"lease_expires_at": expiredAt.Format(time.RFC3339Nano),
+		}, now)
+
+		out = append(out, t)
+	}
+
+	return out, nil
+}
+
+func (s *Store) RequeueTask(_ context.Context, req store.RequeueTaskRequest) (*model.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.TrimSpace(req.TaskID) == "" {
+		return nil, errWithCode("task_id_required")
+	}
+
+	t, ok := s.tasks[req.TaskID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	if t.Status != model.TaskStatusInProgress {
+		return nil, store.ErrConflict
+	}
+	if strings.TrimSpace(req.AgentID) != "" && t.AssignedAgentID != req.AgentID {
+		return nil, store.ErrConflict
+	}
+
+	now := time.Now().UTC()
+	agentID := t.AssignedAgentID
+	t = s.requeueTask(t, now)
+	s.recordEvent(agentID, t.ID, store.EventTypeTaskRequeued, map[string]any{
+		"reason": req.Reason,
+	}, now)
+
+	return &t, nil
+}
+
+// requeueTask returns an in_progress task to the queue, clears the assigned agent's
+// current_task_id and releases that agent's ownership of the task's chain so another
+// agent can pick it up. The claim is given back to the retry budget: the task did not fail.
+// Must be called with s.mu held.
+func (s *Store) requeueTask(t model.Task, now time.Time) model.Task {
+	agentID := t.AssignedAgentID
+
+	t.Status = model.TaskStatusQueued
+	if t.Attempts > 0 {
+		t.Attempts--
+	}
+	t.AssignedAgentID = ""
+	t.ClaimedAt = nil
+	t.LeaseExpiresAt = nil
+	t.UpdatedAt = now
+	s.tasks[t.ID] = t
+
+	if agent, ok := s.agents[agentID]; ok && agent.CurrentTaskID == t.ID {
+		agent.CurrentTaskID = ""
+		agent.UpdatedAt = now
+		s.agents[agentID] = agent
+	}
+
+	if t.ChainID != "" {
+		if chain, ok := s.chains[t.ChainID]; ok && chain.OwnerAgentID == agentID {
+			chain.OwnerAgentID = ""
+			s.chains[t.ChainID] = chain
+		}
+		s.reevaluateChainStatus(t.ChainID, now)
+	}
+
+	return t
+}
+
+// recordEvent appends a coordinator-generated event attributed to agentID.
+// Must be called with s.mu held.
+func (s *Store) recordEvent(agentID, taskID, typ string, payload map[string]any, now time.Time) {
+	if agentID == "" {
+		return
+	}
+	e := model.Event{
+		ID:        newID(),
+		AgentID:   agentID,
+		TaskID:    taskID,
+		Type:      typ,
+		Payload:   payload,
+		CreatedAt: now,
+	}
+	s.events[e.ID] = e
+}
+
+// leaseUntil returns the lease deadline for a claim made at now, or nil when leases are disabled.
+func leaseUntil(now time.Time, seconds int) *time.Time {
+	if seconds <= 0 {
+		return nil
+	}
+	until := now.Add(time.Duration(seconds) * time.Second)
+	return &until
+}
+
 func (s *Store) CreateEvent(_ context.Context, e model.Event) (model.Event, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -915,6 +1583,9 @@ func (s *Store) ListEvents(_ context.Context, f store.EventFilter) ([]model.Even
 		if f.TaskID != "" && e.TaskID != f.TaskID {
 			continue
 		}
+		if len(f.IDs) > 0 && !slices.Contains(f.IDs, e.ID) {
+			continue
+		}
 		out = append(out, e)
 	}
 
@@ -929,6 +1600,24 @@ func (s *Store) ListEvents(_ context.Context, f store.EventFilter) ([]model.Even
 	return out, nil
 }
 
+func (s *Store) ListEventArtifactIDs(_ context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := map[string]bool{}
+	out := []string{}
+	for _, e := range s.events {
+		for _, id := range store.EventArtifactIDs(e.Payload) {
+			if !seen[id] {
+				seen[id] = true
+				out = append(out, id)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
 func (s *Store) PurgeEventsBefore(_ context.Context, before time.Time) (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"clwclw-monitor/coordinator/internal/model"
 	"clwclw-monitor/coordinator/internal/store"
@@ -385,6 +386,24 @@ func TestCompleteChainTaskUpdatesChainStatus(t *testing.T) {
 	assert.Equal(t, model.ChainStatusDone, updatedChain.Status)
 }
 
+func TestEmptyChainIsNotCancelled(t *testing.T) {
+	s := NewStore()
+	ctx := context.Background()
+
+	ch, err := s.CreateChannel(ctx, model.Channel{Name: "empty-channel"})
+	assert.NoError(t, err)
+	chain, err := s.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: "empty-chain", Status: model.ChainStatusQueued})
+	assert.NoError(t, err)
+
+	s.mu.Lock()
+	s.reevaluateChainStatus(chain.ID, time.Now().UTC())
+	s.mu.Unlock()
+
+	updatedChain, err := s.GetChain(ctx, chain.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.ChainStatusDone, updatedChain.Status)
+}
+
 func TestFailChainTaskUpdatesChainStatus(t *testing.T) {
 	s := NewStore()
 	ctx := context.Background()
@@ -785,3 +804,177 @@ func TestClaimTaskBlockedByLockedTask(t *testing.T) {
 	_, err = s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agent.ID, ChannelID: ch.ID})
 	assert.ErrorIs(t, err, store.ErrNoQueuedTasks)
 }
+
+func TestRequeueExpiredTasks(t *testing.T) {
+	s := NewStore()
+	ctx := context.Background()
+
+	ch, err := s.CreateChannel(ctx, model.Channel{Name: "lease-channel"})
+	assert.NoError(t, err)
+
+	agent := model.Agent{ID: "agent-lease", Name: "Lease Agent"}
+	_, err = s.UpsertAgent(ctx, agent)
+	assert.NoError(t, err)
+
+	chain, err := s.CreateChain(ctx, model.Chain{
+		ChannelID: ch.ID,
+		Name:      "lease-chain",
+		Status:    model.ChainStatusQueued,
+	})
+	assert.NoError(t, err)
+	_, err = s.CreateTask(ctx, model.Task{ChannelID: ch.ID, ChainID: chain.ID, Sequence: 1, Title: "Lease Task"})
+	assert.NoError(t, err)
+
+	claimed, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agent.ID, ChannelID: ch.ID, LeaseSeconds: 60})
+	assert.NoError(t, err)
+	if assert.NotNil(t, claimed.LeaseExpiresAt) {
+		assert.WithinDuration(t, time.Now().Add(60*time.Second), *claimed.LeaseExpiresAt, 5*time.Second)
+	}
+
+	// Lease still valid: nothing to requeue.
+	requeued, err := s.RequeueExpiredTasks(ctx, time.Now().UTC())
+	assert.NoError(t, err)
+	assert.Empty(t, requeued)
+
+	// Renewal is restricted to the agent holding the claim.
+	_, err = s.RenewTaskLease(ctx, store.RenewTaskLeaseRequest{TaskID: claimed.ID, AgentID: "other-agent", LeaseSeconds: 60})
+	assert.ErrorIs(t, err, store.ErrConflict)
+	renewed, err := s.RenewTaskLease(ctx, store.RenewTaskLeaseRequest{TaskID: claimed.ID, AgentID: agent.ID, LeaseSeconds: 120})
+	assert.NoError(t, err)
+	assert.True(t, renewed.LeaseExpiresAt.After(*claimed.LeaseExpiresAt))
+
+	// Past the lease deadline the task goes back to the queue and the chain is released.
+	requeued, err = s.RequeueExpiredTasks(ctx, time.Now().UTC().Add(10*time.Minute))
+	assert.NoError(t, err)
+	if assert.Len(t, requeued, 1) {
+		assert.Equal(t, claimed.ID, requeued[0].ID)
+		assert.Equal(t, model.TaskStatusQueued, requeued[0].Status)
+		assert.Empty(t, requeued[0].AssignedAgentID)
+		assert.Nil(t, requeued[0].LeaseExpiresAt)
+	}
+
+	updatedChain, err := s.GetChain(ctx, chain.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.ChainStatusQueued, updatedChain.Status)
+	assert.Empty(t, updatedChain.OwnerAgentID)
+
+	updatedAgent, err := s.GetAgent(ctx, agent.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, updatedAgent.CurrentTaskID)
+
+	events, err := s.ListEvents(ctx, store.EventFilter{TaskID: claimed.ID})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, store.EventTypeTaskLeaseExpired, events[0].Type)
+		assert.Equal(t, agent.ID, events[0].AgentID)
+		assert.Equal(t, "lease_expired", events[0].Payload["reason"])
+	}
+
+	// The task is claimable again.
+	reclaimed, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agent.ID, ChannelID: ch.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, claimed.ID, reclaimed.ID)
+}
+
+func TestFailTaskRetryThenDeadLetter(t *testing.T) {
+	s := NewStore()
+	ctx := context.Background()
+
+	ch, err := s.CreateChannel(ctx, model.Channel{Name: "retry-channel", RetryMaxAttempts: 2, RetryBackoffSeconds: 30})
+	assert.NoError(t, err)
+
+	agent := model.Agent{ID: "agent-retry", Name: "Retry Agent"}
+	_, err = s.UpsertAgent(ctx, agent)
+	assert.NoError(t, err)
+
+	chain, err := s.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: "retry-chain", Status: model.ChainStatusQueued})
+	assert.NoError(t, err)
+	task, err := s.CreateTask(ctx, model.Task{ChannelID: ch.ID, ChainID: chain.ID, Sequence: 1, Title: "Flaky Task"})
+	assert.NoError(t, err)
+
+	claimed, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agent.ID, ChannelID: ch.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, claimed.Attempts)
+
+	// First failure is within budget: requeued with backoff, chain keeps running.
+	failed, err := s.FailTask(ctx, store.FailTaskRequest{TaskID: task.ID, AgentID: agent.ID, Reason: "exit 1"})
+	assert.NoError(t, err)
+	assert.Equal(t, model.TaskStatusQueued, failed.Status)
+	assert.Equal(t, "exit 1", failed.LastFailureReason)
+	assert.Empty(t, failed.AssignedAgentID)
+	if assert.NotNil(t, failed.NextEligibleAt) {
+		assert.WithinDuration(t, time.Now().Add(30*time.Second), *failed.NextEligibleAt, 5*time.Second)
+	}
+	updatedChain, err := s.GetChain(ctx, chain.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.ChainStatusInProgress, updatedChain.Status)
+
+	// Not claimable until the backoff has passed.
+	_, err = s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agent.ID, ChannelID: ch.ID})
+	assert.ErrorIs(t, err, store.ErrNoQueuedTasks)
+
+	past := time.Now().UTC().Add(-time.Second)
+	s.mu.Lock()
+	backedOff := s.tasks[task.ID]
+	backedOff.NextEligibleAt = &past
+	s.tasks[task.ID] = backedOff
+	s.mu.Unlock()
+
+	reclaimed, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agent.ID, ChannelID: ch.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, task.ID, reclaimed.ID)
+	assert.Equal(t, 2, reclaimed.Attempts)
+
+	// Budget exhausted: dead_letter halts the chain like failed.
+	dead, err := s.FailTask(ctx, store.FailTaskRequest{TaskID: task.ID, AgentID: agent.ID, Reason: "exit 1"})
+	assert.NoError(t, err)
+	assert.Equal(t, model.TaskStatusDeadLetter, dead.Status)
+	updatedChain, err = s.GetChain(ctx, chain.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.ChainStatusFailed, updatedChain.Status)
+
+	deadTasks, err := s.ListTasks(ctx, store.TaskFilter{Status: model.TaskStatusDeadLetter})
+	assert.NoError(t, err)
+	assert.Len(t, deadTasks, 1)
+
+	events, err := s.ListEvents(ctx, store.EventFilter{TaskID: task.ID})
+	assert.NoError(t, err)
+	var types []string
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	assert.ElementsMatch(t, []string{store.EventTypeTaskRetryScheduled, store.EventTypeTaskDeadLettered}, types)
+
+	// Resubmit resets the budget and reopens the chain.
+	resubmitted, err := s.ResubmitTask(ctx, task.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.TaskStatusQueued, resubmitted.Status)
+	assert.Equal(t, 0, resubmitted.Attempts)
+	updatedChain, err = s.GetChain(ctx, chain.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.ChainStatusQueued, updatedChain.Status)
+
+	_, err = s.ResubmitTask(ctx, task.ID)
+	assert.ErrorIs(t, err, store.ErrConflict)
+}
+
+func TestFailTaskWithoutRetryPolicyIsTerminal(t *testing.T) {
+	s := NewStore()
+	ctx := context.Background()
+
+	ch, err := s.CreateChannel(ctx, model.Channel{Name: "no-retry-channel"})
+	assert.NoError(t, err)
+	chain, err := s.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: "no-retry-chain", Status: model.ChainStatusQueued})
+	assert.NoError(t, err)
+	task, err := s.CreateTask(ctx, model.Task{ChannelID: ch.ID, ChainID: chain.ID, Sequence: 1, Title: "One Shot"})
+	assert.NoError(t, err)
+
+	_, err = s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: "agent-once", ChannelID: ch.ID})
+	assert.NoError(t, err)
+
+	failed, err := s.FailTask(ctx, store.FailTaskRequest{TaskID: task.ID, Reason: "boom"})
+	assert.NoError(t, err)
+	assert.Equal(t, model.TaskStatusFailed, failed.Status)
+	assert.Equal(t, "boom", failed.LastFailureReason)
+	assert.Nil(t, failed.NextEligibleAt)
+}
@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+func (s *Store) RaiseNotification(_ context.Context, n model.Notification, cooldown time.Duration) (model.Notification, bool, error) {
+	n, err := store.NormalizeNotification(n)
+	if err != nil {
+		return model.Notification{}, false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	for id, existing := range s.notifications {
+		if existing.UserID != n.UserID || existing.Key != n.Key || existing.ResolvedAt != nil {
+			continue
+		}
+		push := store.NotificationPushDue(existing.PushedAt, cooldown, now)
+		n.ID = existing.ID
+		n.CreatedAt = existing.CreatedAt
+		n.ReadAt = existing.ReadAt
+		n.PushedAt = existing.PushedAt
+		n.ResolvedAt = nil
+		if push {
+			n.ReadAt = nil
+			n.PushedAt = &now
+		}
+		n.UpdatedAt = now
+		s.notifications[id] = copyNotification(n)
+		return copyNotification(n), push, nil
+	}
+
+	n.ID = newID()
+	n.ReadAt = nil
+	n.ResolvedAt = nil
+	n.PushedAt = &now
+	n.CreatedAt = now
+	n.UpdatedAt = now
+	s.notifications[n.ID] = copyNotification(n)
+	return copyNotification(n), true, nil
+}
+
+func (s *Store) ResolveNotifications(_ context.Context, userID string, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	for id, n := range s.notifications {
+		if n.Key != key || n.ResolvedAt != nil || (userID != "" && n.UserID != userID) {
+			continue
+		}
+		n.ResolvedAt = &now
+		n.UpdatedAt = now
+		s.notifications[id] = n
+	}
+	return nil
+}
+
+func (s *Store) ListNotifications(_ context.Context, f store.NotificationFilter) ([]model.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var cursor *model.Notification
+	if f.Cursor != "" {
+		c, ok := s.notifications[f.Cursor]
+		if !ok || (f.UserID != "" && c.UserID != f.UserID) {
+			return nil, store.ErrNotFound
+		}
+		cursor = &c
+	}
+
+	out := make([]model.Notification, 0)
+	for _, n := range s.notifications {
+		if f.UserID != "" && n.UserID != f.UserID {
+			continue
+		}
+		if f.UnreadOnly && n.ReadAt != nil {
+			continue
+		}
+		if cursor != nil && !notificationBefore(n, *cursor) {
+			continue
+		}
+		out = append(out, copyNotification(n))
+	}
+	sort.Slice(out, func(i, j int) bool { return notificationBefore(out[j], out[i]) })
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, nil
+}
+
+func (s *Store) CountUnreadNotifications(_ context.Context, userID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, n := range s.notifications {
+		if n.ReadAt == nil && (userID == "" || n.UserID == userID) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) MarkNotificationRead(_ context.Context, userID string, id string) (model.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.notifications[id]
+	if !ok || (userID != "" && n.UserID != userID) {
+		return model.Notification{}, store.ErrNotFound
+	}
+	if n.ReadAt == nil {
+		now := time.Now().UTC()
+		n.ReadAt = &now
+		n.UpdatedAt = now
+		s.notifications[id] = n
+	}
+	return copyNotification(n), nil
+}
+
+func (s *Store) MarkAllNotificationsRead(_ context.Context, userID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	count := 0
+	for id, n := range s.notifications {
+		if n.ReadAt != nil || (userID != "" && n.UserID != userID) {
+			continue
+		}
+		n.ReadAt = &now
+		n.UpdatedAt = now
+		s.notifications[id] = n
+		count++
+	}
+	return count, nil
+}
+
+// notificationBefore reports whether a is older than b in list order (created_at, then ID).
+func notificationBefore(a, b model.Notification) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+func copyNotification(n model.Notification) model.Notification {
+	out := n
+	out.Extra = nil
+	if len(n.Extra) > 0 {
+		if b, err := json.Marshal(n.Extra); err == nil {
+			_ = json.Unmarshal(b, &out.Extra)
+		}
+	}
+	return out
+}
@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+func (s *Store) CreateNotificationRule(_ context.Context, r model.NotificationRule) (model.NotificationRule, error) {
+	r, err := store.NormalizeNotificationRule(r)
+	if err != nil {
+		return model.NotificationRule{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.ChannelID != "" {
+		if _, ok := s.channels[r.ChannelID]; !ok {
+			return model.NotificationRule{}, store.ErrNotFound
+		}
+	}
+
+	now := time.Now().UTC()
+	r.ID = newID()
+	r.LastFiredAt = nil
+	r.CreatedAt = now
+	r.UpdatedAt = now
+	s.notificationRules[r.ID] = copyNotificationRule(r)
+	return copyNotificationRule(r), nil
+}
+
+func (s *Store) GetNotificationRule(_ context.Context, id string) (model.NotificationRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.notificationRules[id]
+	if !ok {
+		return model.NotificationRule{}, store.ErrNotFound
+	}
+	return copyNotificationRule(r), nil
+}
+
+func (s *Store) ListNotificationRules(_ context.Context, userID string) ([]model.NotificationRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]model.NotificationRule, 0, len(s.notificationRules))
+	for _, r := range s.notificationRules {
+		if userID != "" && r.UserID != userID {
+			continue
+		}
+		out = append(out, copyNotificationRule(r))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+func (s *Store) UpdateNotificationRule(_ context.Context, r model.NotificationRule) (model.NotificationRule, error) {
+	r, err := store.NormalizeNotificationRule(r)
+	if err != nil {
+		return model.NotificationRule{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.notificationRules[r.ID]
+	if !ok {
+		return model.NotificationRule{}, store.ErrNotFound
+	}
+	if r.ChannelID != "" {
+		if _, ok := s.channels[r.ChannelID]; !ok {
+			return model.NotificationRule{}, store.ErrNotFound
+		}
+	}
+
+	r.UserID = existing.UserID
+	r.LastFiredAt = existing.LastFiredAt
+	r.CreatedAt = existing.CreatedAt
+	r.UpdatedAt = time.Now().UTC()
+	s.notificationRules[r.ID] = copyNotificationRule(r)
+	return copyNotificationRule(r), nil
+}
+
+func (s *Store) DeleteNotificationRule(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.notificationRules[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.notificationRules, id)
+	return nil
+}
+
+func (s *Store) FireNotificationRule(_ context.Context, id string, cooldown time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.notificationRules[id]
+	if !ok {
+		return false, store.ErrNotFound
+	}
+	now := time.Now().UTC()
+	if r.LastFiredAt != nil && now.Sub(*r.LastFiredAt) < cooldown {
+		return false, nil
+	}
+	r.LastFiredAt = &now
+	s.notificationRules[id] = r
+	return true, nil
+}
+
+func copyNotificationRule(r model.NotificationRule) model.NotificationRule {
+	r.Targets = append([]model.NotificationTarget(nil), r.Targets...)
+	return r
+}
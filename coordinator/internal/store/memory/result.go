@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+func (s *Store) GetTaskResult(_ context.Context, taskID string) (model.TaskResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.results[taskID]
+	if !ok {
+		return model.TaskResult{}, store.ErrNotFound
+	}
+	return copyTaskResult(r), nil
+}
+
+func (s *Store) ListTaskResults(_ context.Context, chainID string) ([]model.TaskResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := []model.TaskResult{}
+	for _, r := range s.results {
+		if r.ChainID == chainID {
+			out = append(out, copyTaskResult(r))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return s.tasks[out[i].TaskID].Sequence < s.tasks[out[j].TaskID].Sequence
+	})
+	return out, nil
+}
+
+func (s *Store) ListTaskResultArtifactIDs(_ context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := map[string]bool{}
+	out := []string{}
+	for _, r := range s.results {
+		for _, a := range r.Artifacts {
+			if id := store.TaskArtifactBlobID(a); id != "" && !seen[id] {
+				seen[id] = true
+				out = append(out, id)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// recordTaskResult stores the result reported for t's current attempt, replacing any
+// earlier one. Nothing is stored when the report carries no result or artifacts.
+// Must be called with s.mu held.
+func (s *Store) recordTaskResult(t model.Task, agentID string, result map[string]any, artifacts []model.TaskArtifact, now time.Time) {
+	if !store.HasTaskResult(result, artifacts) {
+		return
+	}
+	s.results[t.ID] = copyTaskResult(model.TaskResult{
+		TaskID:    t.ID,
+		ChainID:   t.ChainID,
+		AgentID:   agentID,
+		Status:    t.Status,
+		Attempt:   t.Attempts,
+		Result:    result,
+		Artifacts: artifacts,
+		CreatedAt: now,
+	})
+}
+
+// copyTaskResult deep-copies r so callers cannot mutate stored maps and slices.
+func copyTaskResult(r model.TaskResult) model.TaskResult {
+	out := r
+	out.Result = nil
+	out.Artifacts = nil
+	if b, err := json.Marshal(r.Result); err == nil {
+		_ = json.Unmarshal(b, &out.Result)
+	}
+	if len(r.Artifacts) > 0 {
+		out.Artifacts = append([]model.TaskArtifact(nil), r.Artifacts...)
+	}
+	return out
+}
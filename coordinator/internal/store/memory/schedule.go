@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+func (s *Store) CreateSchedule(_ context.Context, sc model.Schedule) (model.Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.TrimSpace(sc.ChannelID) == "" {
+		return model.Schedule{}, errWithCode("channel_id_required")
+	}
+	if strings.TrimSpace(sc.Name) == "" {
+		return model.Schedule{}, errWithCode("name_required")
+	}
+	if strings.TrimSpace(sc.Cron) == "" {
+		return model.Schedule{}, errWithCode("cron_required")
+	}
+	if err := store.ValidateScheduleTasks(sc.Tasks); err != nil {
+		return model.Schedule{}, err
+	}
+	if _, ok := s.channels[sc.ChannelID]; !ok {
+		return model.Schedule{}, store.ErrNotFound
+	}
+
+	now := time.Now().UTC()
+	sc.ID = newID()
+	if sc.Timezone == "" {
+		sc.Timezone = "UTC"
+	}
+	sc.Tasks = append([]model.ScheduleTaskTemplate(nil), sc.Tasks...)
+	sc.CreatedAt = now
+	sc.UpdatedAt = now
+	s.schedules[sc.ID] = sc
+	return copySchedule(sc), nil
+}
+
+func (s *Store) GetSchedule(_ context.Context, id string) (model.Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc, ok := s.schedules[id]
+	if !ok {
+		return model.Schedule{}, store.ErrNotFound
+	}
+	return copySchedule(sc), nil
+}
+
+func (s *Store) ListSchedules(_ context.Context, userID string) ([]model.Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]model.Schedule, 0, len(s.schedules))
+	for _, sc := range s.schedules {
+		if userID != "" && sc.UserID != userID {
+			continue
+		}
+		out = append(out, copySchedule(sc))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+func (s *Store) UpdateSchedule(_ context.Context, sc model.Schedule) (model.Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.schedules[sc.ID]
+	if !ok {
+		return model.Schedule{}, store.ErrNotFound
+	}
+	if strings.TrimSpace(sc.Name) == "" {
+		return model.Schedule{}, errWithCode("name_required")
+	}
+	if strings.TrimSpace(sc.Cron) == "" {
+		return model.Schedule{}, errWithCode("cron_required")
+	}
+	if err := store.ValidateScheduleTasks(sc.Tasks); err != nil {
+		return model.Schedule{}, err
+	}
+
+	existing.Name = sc.Name
+	existing.Description = sc.Description
+	existing.Cron = sc.Cron
+	existing.Timezone = sc.Timezone
+	if existing.Timezone == "" {
+		existing.Timezone = "UTC"
+	}
+	existing.Tasks = append([]model.ScheduleTaskTemplate(nil), sc.Tasks...)
+	existing.Enabled = sc.Enabled
+	existing.NextRunAt = sc.NextRunAt
+	existing.UpdatedAt = time.Now().UTC()
+	s.schedules[existing.ID] = existing
+	return copySchedule(existing), nil
+}
+
+func (s *Store) DeleteSchedule(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.schedules[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.schedules, id)
+	delete(s.scheduleRuns, id)
+	return nil
+}
+
+func (s *Store) RecordScheduleRun(_ context.Context, run model.ScheduleRun, nextRunAt *time.Time) (model.ScheduleRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc, ok := s.schedules[run.ScheduleID]
+	if !ok {
+		return model.ScheduleRun{}, store.ErrNotFound
+	}
+
+	now := time.Now().UTC()
+	run.ID = newID()
+	run.CreatedAt = now
+	s.scheduleRuns[sc.ID] = append(s.scheduleRuns[sc.ID], run)
+
+	scheduledFor := run.ScheduledFor
+	sc.LastRunAt = &scheduledFor
+	if run.ChainID != "" {
+		sc.LastChainID = run.ChainID
+	}
+	sc.NextRunAt = nextRunAt
+	sc.UpdatedAt = now
+	s.schedules[sc.ID] = sc
+	return run, nil
+}
+
+func (s *Store) ListScheduleRuns(_ context.Context, scheduleID string, limit int) ([]model.ScheduleRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs := s.scheduleRuns[scheduleID]
+	out := make([]model.ScheduleRun, 0, len(runs))
+	for i := len(runs) - 1; i >= 0; i-- {
+		out = append(out, runs[i])
+		if limit > 0 && len(out) == limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// copySchedule detaches the task template slice so callers cannot mutate stored state.
+func copySchedule(sc model.Schedule) model.Schedule {
+	sc.Tasks = append([]model.ScheduleTaskTemplate(nil), sc.Tasks...)
+	return sc
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+func (s *Store) CreateTemplate(_ context.Context, tpl model.Template) (model.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := store.ValidateTemplate(tpl); err != nil {
+		return model.Template{}, err
+	}
+
+	now := time.Now().UTC()
+	tpl.ID = newID()
+	tpl.CreatedAt = now
+	tpl.UpdatedAt = now
+	s.templates[tpl.ID] = copyTemplate(tpl)
+	return copyTemplate(tpl), nil
+}
+
+func (s *Store) GetTemplate(_ context.Context, id string) (model.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tpl, ok := s.templates[id]
+	if !ok {
+		return model.Template{}, store.ErrNotFound
+	}
+	return copyTemplate(tpl), nil
+}
+
+func (s *Store) ListTemplates(_ context.Context, userID string) ([]model.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]model.Template, 0, len(s.templates))
+	for _, tpl := range s.templates {
+		if userID != "" && tpl.UserID != userID {
+			continue
+		}
+		out = append(out, copyTemplate(tpl))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+func (s *Store) UpdateTemplate(_ context.Context, tpl model.Template) (model.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.templates[tpl.ID]
+	if !ok {
+		return model.Template{}, store.ErrNotFound
+	}
+	if err := store.ValidateTemplate(tpl); err != nil {
+		return model.Template{}, err
+	}
+
+	existing.Name = tpl.Name
+	existing.Description = tpl.Description
+	existing.ChainName = tpl.ChainName
+	existing.ChainDescription = tpl.ChainDescription
+	existing.Params = tpl.Params
+	existing.Tasks = tpl.Tasks
+	existing.UpdatedAt = time.Now().UTC()
+	s.templates[existing.ID] = copyTemplate(existing)
+	return copyTemplate(existing), nil
+}
+
+func (s *Store) DeleteTemplate(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.templates[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.templates, id)
+	return nil
+}
+
+// copyTemplate deep-copies the params and tasks (which hold nested slices and pointers)
+// so callers cannot mutate stored state. A JSON round trip matches what Postgres stores.
+func copyTemplate(tpl model.Template) model.Template {
+	out := tpl
+	out.Params = nil
+	out.Tasks = nil
+	if b, err := json.Marshal(tpl.Params); err == nil {
+		_ = json.Unmarshal(b, &out.Params)
+	}
+	if b, err := json.Marshal(tpl.Tasks); err == nil {
+		_ = json.Unmarshal(b, &out.Tasks)
+	}
+	return out
+}
@@ -44,3 +44,24 @@ func (s *Store) GetUserByUsername(_ context.Context, username string) (*model.Us
 	}
 	return nil, store.ErrNotFound
 }
+
+func (s *Store) SetUserMaxInFlight(_ context.Context, userID string, limit *int) (*model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if limit != nil && *limit < 0 {
+		return nil, errWithCode("max_in_flight_invalid")
+	}
+	u, ok := s.users[userID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	u.MaxInFlight = nil
+	if limit != nil {
+		n := *limit
+		u.MaxInFlight = &n
+	}
+	u.UpdatedAt = time.Now().UTC()
+	s.users[userID] = u
+	return &u, nil
+}
@@ -0,0 +1,239 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+func (s *Store) CreateWebhook(_ context.Context, w model.Webhook) (model.Webhook, error) {
+	w, err := store.NormalizeWebhook(w)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	w.ID = newID()
+	w.CreatedAt = now
+	w.UpdatedAt = now
+	s.webhooks[w.ID] = copyWebhook(w)
+	return copyWebhook(w), nil
+}
+
+func (s *Store) GetWebhook(_ context.Context, id string) (model.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.webhooks[id]
+	if !ok {
+		return model.Webhook{}, store.ErrNotFound
+	}
+	return copyWebhook(w), nil
+}
+
+func (s *Store) ListWebhooks(_ context.Context, userID string) ([]model.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]model.Webhook, 0, len(s.webhooks))
+	for _, w := range s.webhooks {
+		if userID != "" && w.UserID != userID {
+			continue
+		}
+		out = append(out, copyWebhook(w))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+func (s *Store) UpdateWebhook(_ context.Context, w model.Webhook) (model.Webhook, error) {
+	w, err := store.NormalizeWebhook(w)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.webhooks[w.ID]
+	if !ok {
+		return model.Webhook{}, store.ErrNotFound
+	}
+
+	w.UserID = existing.UserID
+	w.CreatedAt = existing.CreatedAt
+	w.UpdatedAt = time.Now().UTC()
+	s.webhooks[w.ID] = copyWebhook(w)
+	return copyWebhook(w), nil
+}
+
+func (s *Store) DeleteWebhook(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.webhooks, id)
+	for did, d := range s.webhookDeliveries {
+		if d.WebhookID == id {
+			delete(s.webhookDeliveries, did)
+		}
+	}
+	return nil
+}
+
+func (s *Store) CreateWebhookDelivery(_ context.Context, d model.WebhookDelivery) (model.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[d.WebhookID]; !ok {
+		return model.WebhookDelivery{}, store.ErrNotFound
+	}
+
+	now := time.Now().UTC()
+	d.ID = newID()
+	d.Status = model.WebhookDeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = &now
+	d.ResponseCode, d.Error = 0, ""
+	d.DeliveredAt = nil
+	d.CreatedAt = now
+	d.UpdatedAt = now
+	s.webhookDeliveries[d.ID] = copyWebhookDelivery(d)
+	return copyWebhookDelivery(d), nil
+}
+
+func (s *Store) GetWebhookDelivery(_ context.Context, id string) (model.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.webhookDeliveries[id]
+	if !ok {
+		return model.WebhookDelivery{}, store.ErrNotFound
+	}
+	return copyWebhookDelivery(d), nil
+}
+
+func (s *Store) ListWebhookDeliveries(_ context.Context, webhookID string, limit int) ([]model.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]model.WebhookDelivery, 0)
+	for _, d := range s.webhookDeliveries {
+		if d.WebhookID == webhookID {
+			out = append(out, copyWebhookDelivery(d))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *Store) ClaimWebhookDeliveries(_ context.Context, now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []model.WebhookDelivery
+	for _, d := range s.webhookDeliveries {
+		if d.Status == model.WebhookDeliveryPending && d.NextAttemptAt != nil && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	leaseUntil := now.Add(lease)
+	out := make([]model.WebhookDelivery, 0, len(due))
+	for _, d := range due {
+		d.NextAttemptAt = &leaseUntil
+		d.UpdatedAt = time.Now().UTC()
+		s.webhookDeliveries[d.ID] = d
+		out = append(out, copyWebhookDelivery(d))
+	}
+	return out, nil
+}
+
+func (s *Store) RecordWebhookAttempt(_ context.Context, req store.RecordWebhookAttemptRequest) (model.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.webhookDeliveries[req.DeliveryID]
+	if !ok {
+		return model.WebhookDelivery{}, store.ErrNotFound
+	}
+
+	now := time.Now().UTC()
+	d.Attempts++
+	d.Status = store.WebhookAttemptStatus(req)
+	d.ResponseCode = req.ResponseCode
+	d.Error = req.Error
+	d.NextAttemptAt = nil
+	if d.Status == model.WebhookDeliveryPending {
+		retryAt := req.RetryAt.UTC()
+		d.NextAttemptAt = &retryAt
+	}
+	if d.Status == model.WebhookDeliverySucceeded {
+		d.DeliveredAt = &now
+	}
+	d.UpdatedAt = now
+	s.webhookDeliveries[d.ID] = d
+	return copyWebhookDelivery(d), nil
+}
+
+func (s *Store) PurgeWebhookDeliveriesBefore(_ context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := make(map[string]struct{})
+	for id, d := range s.webhookDeliveries {
+		if d.Status != model.WebhookDeliveryPending && d.UpdatedAt.Before(before) {
+			delete(s.webhookDeliveries, id)
+			removed[id] = struct{}{}
+		}
+	}
+	// Like the replay_of foreign key (on delete set null), replays outlive their original.
+	for id, d := range s.webhookDeliveries {
+		if _, ok := removed[d.ReplayOf]; ok {
+			d.ReplayOf = ""
+			s.webhookDeliveries[id] = d
+		}
+	}
+	return len(removed), nil
+}
+
+func copyWebhook(w model.Webhook) model.Webhook {
+	w.Topics = append([]string(nil), w.Topics...)
+	return w
+}
+
+func copyWebhookDelivery(d model.WebhookDelivery) model.WebhookDelivery {
+	out := d
+	out.Payload = nil
+	if len(d.Payload) > 0 {
+		if b, err := json.Marshal(d.Payload); err == nil {
+			_ = json.Unmarshal(b, &out.Payload)
+		}
+	}
+	return out
+}
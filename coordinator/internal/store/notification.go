@@ -0,0 +1,38 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+// NormalizeNotification checks a notification about to be raised and defaults its severity.
+func NormalizeNotification(n model.Notification) (model.Notification, error) {
+	n.Key = strings.TrimSpace(n.Key)
+	n.Type = strings.TrimSpace(n.Type)
+	if n.Key == "" {
+		return n, errors.New("key_required")
+	}
+	if n.Type == "" {
+		return n, errors.New("type_required")
+	}
+	switch n.Severity {
+	case "":
+		n.Severity = model.NotificationSeverityInfo
+	case model.NotificationSeverityInfo, model.NotificationSeverityWarning, model.NotificationSeverityCritical:
+	default:
+		return n, errors.New("severity_invalid")
+	}
+	return n, nil
+}
+
+// NotificationPushDue reports whether an active notification last pushed at pushedAt is
+// pushed again when raised at now. A cooldown of 0 never pushes it again.
+func NotificationPushDue(pushedAt *time.Time, cooldown time.Duration, now time.Time) bool {
+	if pushedAt == nil {
+		return true
+	}
+	return cooldown > 0 && now.Sub(*pushedAt) >= cooldown
+}
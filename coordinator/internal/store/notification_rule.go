@@ -0,0 +1,108 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+// NormalizeNotificationRule checks a rule before it is stored and fills in its defaults
+// (info severity, dashboard target). Fields its trigger does not use are cleared.
+func NormalizeNotificationRule(r model.NotificationRule) (model.NotificationRule, error) {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Status = strings.TrimSpace(r.Status)
+	r.EventPattern = strings.TrimSpace(r.EventPattern)
+	r.ChannelID = strings.TrimSpace(r.ChannelID)
+	if r.Name == "" {
+		return r, errors.New("name_required")
+	}
+	if r.ForSeconds < 0 || r.CooldownSeconds < 0 {
+		return r, errors.New("duration_invalid")
+	}
+
+	switch r.Trigger {
+	case model.NotificationTriggerTaskStatus:
+		if !validTaskStatus(model.TaskStatus(r.Status)) {
+			return r, fmt.Errorf("status_invalid: %s", r.Status)
+		}
+		r.EventPattern, r.ForSeconds = "", 0
+	case model.NotificationTriggerChainStatus:
+		if !validChainStatus(model.ChainStatus(r.Status)) {
+			return r, fmt.Errorf("status_invalid: %s", r.Status)
+		}
+		r.EventPattern, r.ForSeconds = "", 0
+	case model.NotificationTriggerAgentOffline:
+		r.ChannelID, r.Status, r.EventPattern = "", "", ""
+	case model.NotificationTriggerClaudeStatus:
+		switch model.ClaudeStatus(r.Status) {
+		case model.ClaudeStatusIdle, model.ClaudeStatusRunning, model.ClaudeStatusWaiting:
+		default:
+			return r, fmt.Errorf("status_invalid: %s", r.Status)
+		}
+		r.ChannelID, r.EventPattern = "", ""
+	case model.NotificationTriggerEventType:
+		if r.EventPattern == "" {
+			return r, errors.New("event_pattern_required")
+		}
+		if _, err := path.Match(r.EventPattern, ""); err != nil {
+			return r, fmt.Errorf("event_pattern_invalid: %s", r.EventPattern)
+		}
+		r.Status, r.ForSeconds = "", 0
+	default:
+		return r, fmt.Errorf("trigger_invalid: %s", r.Trigger)
+	}
+
+	switch r.Severity {
+	case "":
+		r.Severity = model.NotificationSeverityInfo
+	case model.NotificationSeverityInfo, model.NotificationSeverityWarning, model.NotificationSeverityCritical:
+	default:
+		return r, fmt.Errorf("severity_invalid: %s", r.Severity)
+	}
+
+	if len(r.Targets) == 0 {
+		r.Targets = []model.NotificationTarget{model.NotificationTargetDashboard}
+	}
+	seen := make(map[model.NotificationTarget]bool, len(r.Targets))
+	targets := make([]model.NotificationTarget, 0, len(r.Targets))
+	for _, t := range r.Targets {
+		switch t {
+		case model.NotificationTargetDashboard, model.NotificationTargetWebhook:
+		default:
+			return r, fmt.Errorf("target_invalid: %s", t)
+		}
+		if !seen[t] {
+			seen[t] = true
+			targets = append(targets, t)
+		}
+	}
+	r.Targets = targets
+	return r, nil
+}
+
+// MatchEventType reports whether an event type matches a rule's glob pattern.
+func MatchEventType(pattern, typ string) bool {
+	ok, err := path.Match(pattern, typ)
+	return err == nil && ok
+}
+
+func validTaskStatus(s model.TaskStatus) bool {
+	switch s {
+	case model.TaskStatusQueued, model.TaskStatusInProgress, model.TaskStatusDone, model.TaskStatusFailed,
+		model.TaskStatusLocked, model.TaskStatusDeadLetter, model.TaskStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func validChainStatus(s model.ChainStatus) bool {
+	switch s {
+	case model.ChainStatusQueued, model.ChainStatusInProgress, model.ChainStatusDone, model.ChainStatusFailed,
+		model.ChainStatusLocked, model.ChainStatusCancelled:
+		return true
+	}
+	return false
+}
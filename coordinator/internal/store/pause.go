@@ -0,0 +1,12 @@
+package store
+
+import "clwclw-monitor/coordinator/internal/model"
+
+// ValidPauseMode reports whether mode can be stored on a chain or channel; empty resumes.
+func ValidPauseMode(mode model.PauseMode) bool {
+	switch mode {
+	case "", model.PauseModePaused, model.PauseModeDraining:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) ReviewApprovalTask(ctx context.Context, req store.ReviewTaskRequest) (*model.Task, error) {
+	if strings.TrimSpace(req.TaskID) == "" {
+		return nil, errors.New("task_id_required")
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var t model.Task
+	err = scanTask(tx.QueryRow(ctx, `
+		select `+taskColumns+`
+		from public.tasks
+		where id = $1::uuid
+		for update
+	`, req.TaskID), &t)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrNotFound
+		}
+		return nil, mapPgErr(err)
+	}
+	if !store.IsApprovalGate(t) {
+		return nil, store.ErrConflict
+	}
+
+	decided := model.TaskStatusFailed
+	if req.Approve {
+		decided = model.TaskStatusDone
+	}
+	switch t.Status {
+	case decided:
+		return &t, nil // idempotent
+	case model.TaskStatusQueued:
+	default:
+		return nil, store.ErrConflict
+	}
+
+	chainTasks, err := listChainTasksTx(ctx, tx, t.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	if !store.GateReached(chainTasks, t) {
+		return nil, store.ErrConflict
+	}
+
+	failureReason := ""
+	if !req.Approve {
+		failureReason = "rejected"
+		if req.Comment != "" {
+			failureReason = "rejected: " + req.Comment
+		}
+	}
+	err = scanTask(tx.QueryRow(ctx, `
+		update public.tasks
+		set status = $2,
+		    reviewed_at = now(),
+		    reviewed_by = nullif($3, ''),
+		    review_comment = nullif($4, ''),
+		    done_at = now(),
+		    last_failure_reason = coalesce(nullif($5, ''), last_failure_reason),
+		    updated_at = now()
+		where id = $1::uuid
+		returning `+taskColumns+`
+	`, t.ID, string(decided), req.ReviewedBy, req.Comment, failureReason), &t)
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+
+	// A rejection counts as a failed task for the chain's on_failure policy.
+	if t.ChainID != "" {
+		if req.Approve {
+			err = s.reevaluateChainStatusTx(ctx, tx, t.ChainID, false)
+		} else {
+			err = s.failChainTx(ctx, tx, t.ChainID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, mapPgErr(err)
+	}
+	return &t, nil
+}
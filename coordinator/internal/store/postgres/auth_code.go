@@ -13,10 +13,10 @@ import (
 func (s *Store) GetUserByID(ctx context.Context, id string) (*model.User, error) {
 	var u model.User
 	err := s.pool.QueryRow(ctx, `
-		select id::text, username, password_hash, created_at, updated_at
+		select id::text, username, password_hash, max_in_flight, created_at, updated_at
 		from public.users
 		where id = $1::uuid
-	`, id).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt)
+	`, id).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.MaxInFlight, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, store.ErrNotFound
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// claimLimits are the in-flight limits a claim must respect.
+type claimLimits struct {
+	channel   model.Channel
+	userLimit int
+}
+
+// lockClaimLimitsTx loads the limits of a claim on channelID (userLimit unless the channel
+// owner has a limit of their own) and, when any applies, takes
+// transaction-scoped advisory locks on the channel and its owner. Claims sharing a limit
+// then count in-progress tasks and claim one without racing each other. The channel lock
+// is always taken before the user lock, so two claims cannot wait on each other.
+func lockClaimLimitsTx(ctx context.Context, tx pgx.Tx, channelID string, userLimit int) (claimLimits, error) {
+	var l claimLimits
+	err := tx.QueryRow(ctx, `
+		select c.id::text, coalesce(c.user_id::text, ''), c.max_in_flight, coalesce(u.max_in_flight, $2)
+		from public.channels c
+		left join public.users u on u.id = c.user_id
+		where c.id = $1::uuid
+	`, channelID, userLimit).Scan(&l.channel.ID, &l.channel.UserID, &l.channel.MaxInFlight, &l.userLimit)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return claimLimits{}, store.ErrNotFound
+		}
+		return claimLimits{}, mapPgErr(err)
+	}
+
+	if l.channel.MaxInFlight > 0 {
+		if _, err := tx.Exec(ctx, `select pg_advisory_xact_lock(hashtextextended('claim_limit:channel:' || $1, 0))`, l.channel.ID); err != nil {
+			return claimLimits{}, mapPgErr(err)
+		}
+	}
+	if l.userApplies() {
+		if _, err := tx.Exec(ctx, `select pg_advisory_xact_lock(hashtextextended('claim_limit:user:' || $1, 0))`, l.channel.UserID); err != nil {
+			return claimLimits{}, mapPgErr(err)
+		}
+	}
+	return l, nil
+}
+
+func (l claimLimits) userApplies() bool {
+	return l.userLimit > 0 && l.channel.UserID != ""
+}
+
+// checkTx counts the tasks in progress besides claimedID, the task this transaction just
+// claimed, and returns a *store.ConcurrencyLimitError when a limit was already reached.
+func (l claimLimits) checkTx(ctx context.Context, tx pgx.Tx, claimedID string) error {
+	if l.channel.MaxInFlight <= 0 && !l.userApplies() {
+		return nil
+	}
+	var channelInFlight, userInFlight int
+	err := tx.QueryRow(ctx, `
+		select count(*) filter (where channel_id = $1::uuid),
+		       count(*) filter (where user_id = nullif($2, '')::uuid)
+		from public.tasks
+		where status = 'in_progress'
+		  and id <> $3::uuid
+	`, l.channel.ID, l.channel.UserID, claimedID).Scan(&channelInFlight, &userInFlight)
+	if err != nil {
+		return mapPgErr(err)
+	}
+	return store.CheckConcurrency(l.channel, channelInFlight, l.userLimit, userInFlight)
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/store"
+	"clwclw-monitor/coordinator/internal/store/storetest"
+)
+
+func newConformanceStore(t *testing.T) store.Store {
+	s, teardown := setupTestDB(t)
+	t.Cleanup(teardown)
+	return s
+}
+
+func TestClaimOrder(t *testing.T) {
+	storetest.RunClaimOrderTests(t, newConformanceStore)
+}
+
+func TestDependencies(t *testing.T) {
+	storetest.RunDependencyTests(t, newConformanceStore)
+}
+
+func TestSchedule(t *testing.T) {
+	storetest.RunScheduleTests(t, newConformanceStore)
+}
+
+func TestRecurringSchedules(t *testing.T) {
+	storetest.RunRecurringScheduleTests(t, newConformanceStore)
+}
+
+func TestChainWithTasks(t *testing.T) {
+	storetest.RunChainWithTasksTests(t, newConformanceStore)
+}
+
+func TestCancelTask(t *testing.T) {
+	storetest.RunCancelTests(t, newConformanceStore)
+}
+
+func TestPause(t *testing.T) {
+	storetest.RunPauseTests(t, newConformanceStore)
+}
+
+func TestTaskResults(t *testing.T) {
+	storetest.RunTaskResultTests(t, newConformanceStore)
+}
+
+func TestEventArtifacts(t *testing.T) {
+	storetest.RunEventArtifactTests(t, newConformanceStore)
+}
+
+func TestTaskResultArtifacts(t *testing.T) {
+	storetest.RunTaskResultArtifactTests(t, newConformanceStore)
+}
+
+func TestApprovalGates(t *testing.T) {
+	storetest.RunApprovalTests(t, newConformanceStore)
+}
+
+func TestFailurePolicies(t *testing.T) {
+	storetest.RunFailurePolicyTests(t, newConformanceStore)
+}
+
+func TestAgentLabels(t *testing.T) {
+	storetest.RunLabelTests(t, newConformanceStore)
+}
+
+func TestConcurrencyLimits(t *testing.T) {
+	storetest.RunConcurrencyTests(t, newConformanceStore)
+}
+
+func TestNotifications(t *testing.T) {
+	storetest.RunNotificationTests(t, newConformanceStore)
+}
+
+func TestNotificationRules(t *testing.T) {
+	storetest.RunNotificationRuleTests(t, newConformanceStore)
+}
+
+func TestWebhooks(t *testing.T) {
+	storetest.RunWebhookTests(t, newConformanceStore)
+}
+
+func TestRetry(t *testing.T) {
+	storetest.RunRetryTests(t, newConformanceStore)
+}
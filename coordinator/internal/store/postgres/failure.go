@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// listChainTasksTx returns every task of chainID, locked for the rest of the transaction.
+func listChainTasksTx(ctx context.Context, tx pgx.Tx, chainID string) ([]model.Task, error) {
+	rows, err := tx.Query(ctx, `
+		select `+taskColumns+`
+		from public.tasks
+		where chain_id = $1::uuid
+		for update
+	`, chainID)
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer rows.Close()
+
+	var out []model.Task
+	for rows.Next() {
+		var t model.Task
+		if err := scanTask(rows, &t); err != nil {
+			return nil, mapPgErr(err)
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, mapPgErr(err)
+	}
+	return out, nil
+}
+
+// settleChainTasksTx cancels the queued tasks of chainID that can no longer run under its
+// on_failure policy (see store.SkippedTasks) and returns the chain.
+func settleChainTasksTx(ctx context.Context, tx pgx.Tx, chainID string) (model.Chain, error) {
+	var chain model.Chain
+	err := scanChain(tx.QueryRow(ctx, `
+		select `+chainColumns+`
+		from public.chains
+		where id = $1::uuid
+	`, chainID), &chain)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Chain{}, store.ErrNotFound
+		}
+		return model.Chain{}, mapPgErr(err)
+	}
+
+	chainTasks, err := listChainTasksTx(ctx, tx, chainID)
+	if err != nil {
+		return model.Chain{}, err
+	}
+	for _, sk := range store.SkippedTasks(chain, chainTasks) {
+		if _, err := cancelTaskTx(ctx, tx, sk.Task.ID, store.CancelTaskRequest{Reason: sk.Reason}); err != nil {
+			return model.Chain{}, err
+		}
+	}
+	return chain, nil
+}
+
+// failChainTx applies the chain's on_failure policy after one of its tasks failed for good:
+// halt marks the chain failed right away, the other policies keep it running until every
+// task has finished.
+func (s *Store) failChainTx(ctx context.Context, tx pgx.Tx, chainID string) error {
+	chain, err := settleChainTasksTx(ctx, tx, chainID)
+	if err != nil {
+		return err
+	}
+	if !store.HaltsOnFailure(chain) {
+		return s.reevaluateChainStatusTx(ctx, tx, chainID, false)
+	}
+
+	_, err = tx.Exec(ctx, `
+		update public.chains
+		set status = $1, updated_at = now()
+		where id = $2::uuid
+	`, model.ChainStatusFailed, chainID)
+	if err != nil {
+		return mapPgErr(err)
+	}
+	return nil
+}
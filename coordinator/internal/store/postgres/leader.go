@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// LeaderElector elects one leader among the replicas sharing a database with a
+// session-level advisory lock. The lock lives as long as the connection holding it, so a
+// crashed or partitioned leader loses it and another replica takes over.
+type LeaderElector struct {
+	store    *Store
+	name     string
+	interval time.Duration
+	leading  atomic.Bool
+}
+
+// NewLeaderElector campaigns for the lock called name, retrying (and checking a held
+// lock's connection) every interval.
+func NewLeaderElector(s *Store, name string, interval time.Duration) *LeaderElector {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &LeaderElector{store: s, name: name, interval: interval}
+}
+
+func (e *LeaderElector) IsLeader() bool { return e.leading.Load() }
+
+func (e *LeaderElector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	t := time.NewTicker(e.interval)
+	defer t.Stop()
+	for {
+		if err := e.campaign(ctx, lead); err != nil && ctx.Err() == nil {
+			log.Printf("leader election: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// campaign tries to take the lock once. If it does, it leads until ctx ends or the lock's
+// connection stops answering.
+func (e *LeaderElector) campaign(ctx context.Context, lead func(ctx context.Context)) error {
+	conn, err := e.store.pool.Acquire(ctx)
+	if err != nil {
+		return mapPgErr(err)
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err := conn.QueryRow(ctx, `select pg_try_advisory_lock(hashtextextended($1, 0))`, e.name).Scan(&locked); err != nil {
+		return mapPgErr(err)
+	}
+	if !locked {
+		return nil
+	}
+
+	log.Printf("leader election: this replica is the leader (%s)", e.name)
+	e.leading.Store(true)
+	leadCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(leadCtx)
+	}()
+
+	t := time.NewTicker(e.interval)
+	defer t.Stop()
+	var lost error
+	for lost == nil && ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case <-done:
+			// The jobs stopped on their own; keep the lock until ctx ends.
+			done = nil
+		case <-t.C:
+			pingCtx, cancelPing := context.WithTimeout(ctx, e.interval)
+			lost = conn.Ping(pingCtx)
+			cancelPing()
+		}
+	}
+
+	// Stop the jobs before anyone else can take over.
+	cancel()
+	if done != nil {
+		<-done
+	}
+	e.leading.Store(false)
+
+	if lost != nil {
+		// The session (and with it the lock) may be gone; never hand this connection back.
+		_ = conn.Conn().Close(context.Background())
+		return lost
+	}
+	unlockCtx, cancelUnlock := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelUnlock()
+	_, _ = conn.Exec(unlockCtx, `select pg_advisory_unlock(hashtextextended($1, 0))`, e.name)
+	log.Printf("leader election: stepped down (%s)", e.name)
+	return nil
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLeaderElectorSingleLeader(t *testing.T) {
+	s, teardown := setupTestDB(t)
+	defer teardown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	a := NewLeaderElector(s, "coordinator:leader-test", 100*time.Millisecond)
+	b := NewLeaderElector(s, "coordinator:leader-test", 100*time.Millisecond)
+
+	aCtx, stopA := context.WithCancel(ctx)
+	leadingA := make(chan struct{})
+	doneA := make(chan struct{})
+	go func() {
+		defer close(doneA)
+		a.Run(aCtx, func(ctx context.Context) {
+			close(leadingA)
+			<-ctx.Done()
+		})
+	}()
+	select {
+	case <-leadingA:
+	case <-ctx.Done():
+		t.Fatalf("first elector never became leader")
+	}
+
+	leadingB := make(chan struct{})
+	go b.Run(ctx, func(ctx context.Context) {
+		close(leadingB)
+		<-ctx.Done()
+	})
+	select {
+	case <-leadingB:
+		t.Fatalf("second elector became leader while the first holds the lock")
+	case <-time.After(500 * time.Millisecond):
+	}
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("expected only the first elector to lead (a=%v b=%v)", a.IsLeader(), b.IsLeader())
+	}
+
+	// Once the leader stops, the other replica takes over.
+	stopA()
+	<-doneA
+	if a.IsLeader() {
+		t.Fatalf("expected the stopped elector to step down")
+	}
+	select {
+	case <-leadingB:
+	case <-ctx.Done():
+		t.Fatalf("second elector did not take over")
+	}
+}
@@ -0,0 +1,223 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// notificationColumns is the select list shared by every notification query; keep in sync
+// with scanNotification.
+const notificationColumns = `id::text, coalesce(user_id::text, ''), key, type, severity, message,
+		       coalesce(agent_id::text, ''), coalesce(agent_name, ''), coalesce(task_id::text, ''),
+		       coalesce(chain_id::text, ''), coalesce(channel, ''), extra, read_at, resolved_at,
+		       pushed_at, created_at, updated_at`
+
+func scanNotification(row pgx.Row, n *model.Notification) error {
+	var severity string
+	var extraJSON []byte
+	if err := row.Scan(
+		&n.ID,
+		&n.UserID,
+		&n.Key,
+		&n.Type,
+		&severity,
+		&n.Message,
+		&n.AgentID,
+		&n.AgentName,
+		&n.TaskID,
+		&n.ChainID,
+		&n.Channel,
+		&extraJSON,
+		&n.ReadAt,
+		&n.ResolvedAt,
+		&n.PushedAt,
+		&n.CreatedAt,
+		&n.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	n.Severity = model.NotificationSeverity(severity)
+	n.Extra = nil
+	if len(extraJSON) > 0 && string(extraJSON) != "{}" {
+		return json.Unmarshal(extraJSON, &n.Extra)
+	}
+	return nil
+}
+
+func (s *Store) RaiseNotification(ctx context.Context, n model.Notification, cooldown time.Duration) (model.Notification, bool, error) {
+	n, err := store.NormalizeNotification(n)
+	if err != nil {
+		return model.Notification{}, false, err
+	}
+	extraJSON := []byte("{}")
+	if len(n.Extra) > 0 {
+		if extraJSON, err = json.Marshal(n.Extra); err != nil {
+			return model.Notification{}, false, err
+		}
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return model.Notification{}, false, mapPgErr(err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Replicas raising the same notification at once must not both insert one.
+	if _, err := tx.Exec(ctx, `select pg_advisory_xact_lock(hashtextextended('notification:' || $1 || ':' || $2, 0))`, n.UserID, n.Key); err != nil {
+		return model.Notification{}, false, mapPgErr(err)
+	}
+
+	var existingID string
+	var pushedAt *time.Time
+	err = tx.QueryRow(ctx, `
+		select id::text, pushed_at
+		from public.notifications
+		where user_id is not distinct from nullif($1, '')::uuid and key = $2 and resolved_at is null
+	`, n.UserID, n.Key).Scan(&existingID, &pushedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return model.Notification{}, false, mapPgErr(err)
+	}
+
+	var out model.Notification
+	push := true
+	if existingID == "" {
+		err = scanNotification(tx.QueryRow(ctx, `
+			insert into public.notifications (user_id, key, type, severity, message, agent_id, agent_name,
+			                                  task_id, chain_id, channel, extra, pushed_at)
+			values (nullif($1, '')::uuid, $2, $3, $4, $5, nullif($6, '')::uuid, nullif($7, ''),
+			        nullif($8, '')::uuid, nullif($9, '')::uuid, nullif($10, ''), $11::jsonb, now())
+			returning `+notificationColumns+`
+		`, n.UserID, n.Key, n.Type, string(n.Severity), n.Message, n.AgentID, n.AgentName,
+			n.TaskID, n.ChainID, n.Channel, string(extraJSON)), &out)
+	} else {
+		push = store.NotificationPushDue(pushedAt, cooldown, time.Now())
+		err = scanNotification(tx.QueryRow(ctx, `
+			update public.notifications
+			set type = $2,
+			    severity = $3,
+			    message = $4,
+			    agent_id = nullif($5, '')::uuid,
+			    agent_name = nullif($6, ''),
+			    task_id = nullif($7, '')::uuid,
+			    chain_id = nullif($8, '')::uuid,
+			    channel = nullif($9, ''),
+			    extra = $10::jsonb,
+			    read_at = case when $11 then null else read_at end,
+			    pushed_at = case when $11 then now() else pushed_at end
+			where id = $1::uuid
+			returning `+notificationColumns+`
+		`, existingID, n.Type, string(n.Severity), n.Message, n.AgentID, n.AgentName,
+			n.TaskID, n.ChainID, n.Channel, string(extraJSON), push), &out)
+	}
+	if err != nil {
+		return model.Notification{}, false, mapPgErr(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return model.Notification{}, false, mapPgErr(err)
+	}
+	return out, push, nil
+}
+
+func (s *Store) ResolveNotifications(ctx context.Context, userID string, key string) error {
+	_, err := s.pool.Exec(ctx, `
+		update public.notifications
+		set resolved_at = now()
+		where key = $2 and resolved_at is null
+		  and ($1 = '' or user_id = nullif($1, '')::uuid)
+	`, userID, key)
+	return mapPgErr(err)
+}
+
+func (s *Store) ListNotifications(ctx context.Context, f store.NotificationFilter) ([]model.Notification, error) {
+	query := `
+		select ` + notificationColumns + `
+		from public.notifications n
+		where ($1 = '' or n.user_id = nullif($1, '')::uuid)
+		  and (not $2 or n.read_at is null)
+	`
+	args := []any{f.UserID, f.UnreadOnly}
+	if f.Cursor != "" {
+		var exists bool
+		if err := s.pool.QueryRow(ctx, `
+			select exists(
+				select 1 from public.notifications
+				where id = $1::uuid and ($2 = '' or user_id = nullif($2, '')::uuid)
+			)
+		`, f.Cursor, f.UserID).Scan(&exists); err != nil {
+			return nil, mapPgErr(err)
+		}
+		if !exists {
+			return nil, store.ErrNotFound
+		}
+		query += ` and (n.created_at, n.id) < (select created_at, id from public.notifications where id = $3::uuid)`
+		args = append(args, f.Cursor)
+	}
+	query += " order by n.created_at desc, n.id desc"
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		query += fmt.Sprintf(" limit $%d", len(args))
+	}
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer rows.Close()
+
+	out := make([]model.Notification, 0)
+	for rows.Next() {
+		var n model.Notification
+		if err := scanNotification(rows, &n); err != nil {
+			return nil, mapPgErr(err)
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) CountUnreadNotifications(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := s.pool.QueryRow(ctx, `
+		select count(*)
+		from public.notifications
+		where read_at is null and ($1 = '' or user_id = nullif($1, '')::uuid)
+	`, userID).Scan(&count)
+	return count, mapPgErr(err)
+}
+
+func (s *Store) MarkNotificationRead(ctx context.Context, userID string, id string) (model.Notification, error) {
+	var out model.Notification
+	err := scanNotification(s.pool.QueryRow(ctx, `
+		update public.notifications
+		set read_at = coalesce(read_at, now())
+		where id = $1::uuid and ($2 = '' or user_id = nullif($2, '')::uuid)
+		returning `+notificationColumns+`
+	`, id, userID), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Notification{}, store.ErrNotFound
+		}
+		return model.Notification{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) MarkAllNotificationsRead(ctx context.Context, userID string) (int, error) {
+	cmdTag, err := s.pool.Exec(ctx, `
+		update public.notifications
+		set read_at = now()
+		where read_at is null and ($1 = '' or user_id = nullif($1, '')::uuid)
+	`, userID)
+	if err != nil {
+		return 0, mapPgErr(err)
+	}
+	return int(cmdTag.RowsAffected()), nil
+}
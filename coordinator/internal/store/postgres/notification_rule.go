@@ -0,0 +1,187 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// notificationRuleColumns is the select list shared by every rule query; keep in sync with
+// scanNotificationRule.
+const notificationRuleColumns = `id::text, coalesce(user_id::text, ''), name, trigger, coalesce(channel_id::text, ''),
+		       coalesce(status, ''), coalesce(event_pattern, ''), for_seconds, severity, targets,
+		       cooldown_seconds, enabled, last_fired_at, created_at, updated_at`
+
+func scanNotificationRule(row pgx.Row, r *model.NotificationRule) error {
+	var trigger, severity string
+	var targetsJSON []byte
+	if err := row.Scan(
+		&r.ID,
+		&r.UserID,
+		&r.Name,
+		&trigger,
+		&r.ChannelID,
+		&r.Status,
+		&r.EventPattern,
+		&r.ForSeconds,
+		&severity,
+		&targetsJSON,
+		&r.CooldownSeconds,
+		&r.Enabled,
+		&r.LastFiredAt,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	r.Trigger = model.NotificationTrigger(trigger)
+	r.Severity = model.NotificationSeverity(severity)
+	return json.Unmarshal(targetsJSON, &r.Targets)
+}
+
+func (s *Store) CreateNotificationRule(ctx context.Context, r model.NotificationRule) (model.NotificationRule, error) {
+	r, err := store.NormalizeNotificationRule(r)
+	if err != nil {
+		return model.NotificationRule{}, err
+	}
+	targetsJSON, err := json.Marshal(r.Targets)
+	if err != nil {
+		return model.NotificationRule{}, err
+	}
+
+	var out model.NotificationRule
+	err = scanNotificationRule(s.pool.QueryRow(ctx, `
+		insert into public.notification_rules (user_id, name, trigger, channel_id, status, event_pattern,
+		                                       for_seconds, severity, targets, cooldown_seconds, enabled)
+		values (nullif($1, '')::uuid, $2, $3, nullif($4, '')::uuid, nullif($5, ''), nullif($6, ''),
+		        $7, $8, $9::jsonb, $10, $11)
+		returning `+notificationRuleColumns+`
+	`, r.UserID, r.Name, string(r.Trigger), r.ChannelID, r.Status, r.EventPattern,
+		r.ForSeconds, string(r.Severity), string(targetsJSON), r.CooldownSeconds, r.Enabled), &out)
+	if err != nil {
+		return model.NotificationRule{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) GetNotificationRule(ctx context.Context, id string) (model.NotificationRule, error) {
+	var out model.NotificationRule
+	err := scanNotificationRule(s.pool.QueryRow(ctx, `
+		select `+notificationRuleColumns+`
+		from public.notification_rules
+		where id = $1::uuid
+	`, id), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.NotificationRule{}, store.ErrNotFound
+		}
+		return model.NotificationRule{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) ListNotificationRules(ctx context.Context, userID string) ([]model.NotificationRule, error) {
+	query := `
+		select ` + notificationRuleColumns + `
+		from public.notification_rules
+	`
+	var args []any
+	if strings.TrimSpace(userID) != "" {
+		query += " where user_id = $1::uuid"
+		args = append(args, userID)
+	}
+	query += " order by created_at asc"
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer rows.Close()
+
+	out := make([]model.NotificationRule, 0)
+	for rows.Next() {
+		var r model.NotificationRule
+		if err := scanNotificationRule(rows, &r); err != nil {
+			return nil, mapPgErr(err)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) UpdateNotificationRule(ctx context.Context, r model.NotificationRule) (model.NotificationRule, error) {
+	r, err := store.NormalizeNotificationRule(r)
+	if err != nil {
+		return model.NotificationRule{}, err
+	}
+	targetsJSON, err := json.Marshal(r.Targets)
+	if err != nil {
+		return model.NotificationRule{}, err
+	}
+
+	var out model.NotificationRule
+	err = scanNotificationRule(s.pool.QueryRow(ctx, `
+		update public.notification_rules
+		set name = $2,
+		    trigger = $3,
+		    channel_id = nullif($4, '')::uuid,
+		    status = nullif($5, ''),
+		    event_pattern = nullif($6, ''),
+		    for_seconds = $7,
+		    severity = $8,
+		    targets = $9::jsonb,
+		    cooldown_seconds = $10,
+		    enabled = $11
+		where id = $1::uuid
+		returning `+notificationRuleColumns+`
+	`, r.ID, r.Name, string(r.Trigger), r.ChannelID, r.Status, r.EventPattern,
+		r.ForSeconds, string(r.Severity), string(targetsJSON), r.CooldownSeconds, r.Enabled), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.NotificationRule{}, store.ErrNotFound
+		}
+		return model.NotificationRule{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) DeleteNotificationRule(ctx context.Context, id string) error {
+	cmdTag, err := s.pool.Exec(ctx, `
+		delete from public.notification_rules
+		where id = $1::uuid
+	`, id)
+	if err != nil {
+		return mapPgErr(err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) FireNotificationRule(ctx context.Context, id string, cooldown time.Duration) (bool, error) {
+	// One conditional update, so replicas firing the same rule at once deliver only once.
+	cmdTag, err := s.pool.Exec(ctx, `
+		update public.notification_rules
+		set last_fired_at = now()
+		where id = $1::uuid
+		  and (last_fired_at is null or last_fired_at <= now() - make_interval(secs => $2))
+	`, id, cooldown.Seconds())
+	if err != nil {
+		return false, mapPgErr(err)
+	}
+	if cmdTag.RowsAffected() > 0 {
+		return true, nil
+	}
+	if _, err := s.GetNotificationRule(ctx, id); err != nil {
+		return false, err
+	}
+	return false, nil
+}
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Notify sends payload on a Postgres NOTIFY channel. Payloads must stay under 8000 bytes.
+func (s *Store) Notify(ctx context.Context, channel, payload string) error {
+	_, err := s.pool.Exec(ctx, `select pg_notify($1, $2)`, channel, payload)
+	return mapPgErr(err)
+}
+
+// Listen holds one pooled connection in LISTEN on channel, calls listening once it is
+// subscribed and then handle with every payload, in the order Postgres delivers them, until
+// ctx ends or the connection fails.
+func (s *Store) Listen(ctx context.Context, channel string, listening func(), handle func(payload string)) error {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return mapPgErr(err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "listen "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return mapPgErr(err)
+	}
+	defer func() {
+		// The connection goes back to the pool; stop listening unless it is already broken.
+		if !conn.Conn().IsClosed() {
+			_, _ = conn.Exec(context.Background(), "unlisten *")
+		}
+	}()
+	listening()
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		handle(n.Payload)
+	}
+}
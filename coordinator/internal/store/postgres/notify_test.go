@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNotifyListen(t *testing.T) {
+	s, teardown := setupTestDB(t)
+	defer teardown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ready := make(chan struct{})
+	got := make(chan string, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Listen(ctx, "coordinator_bus_test", func() { close(ready) }, func(p string) { got <- p })
+	}()
+
+	select {
+	case <-ready:
+	case err := <-done:
+		t.Fatalf("listen: %v", err)
+	}
+	if err := s.Notify(ctx, "coordinator_bus_test", "hello"); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	select {
+	case p := <-got:
+		if p != "hello" {
+			t.Fatalf("expected payload hello, got %q", p)
+		}
+	case <-ctx.Done():
+		t.Fatalf("notification not received")
+	}
+
+	cancel()
+	if err := <-done; err == nil {
+		t.Fatalf("expected listen to stop with the context")
+	}
+}
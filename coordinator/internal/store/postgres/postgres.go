@@ -52,6 +52,20 @@ func (s *Store) Close() {
 	}
 }
 
+const agentColumns = `id::text, coalesce(user_id::text, ''), name, status, claude_status, claude_status_since, coalesce(current_task_id::text, ''), last_seen, meta, labels, created_at, updated_at`
+
+func scanAgent(row pgx.Row, a *model.Agent) error {
+	var metaJSON, labelsJSON []byte
+	if err := row.Scan(&a.ID, &a.UserID, &a.Name, &a.Status, &a.ClaudeStatus, &a.ClaudeStatusSince, &a.CurrentTaskID, &a.LastSeen, &metaJSON, &labelsJSON, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return err
+	}
+	_ = json.Unmarshal(metaJSON, &a.Meta)
+	if len(labelsJSON) > 0 {
+		_ = json.Unmarshal(labelsJSON, &a.Labels)
+	}
+	return nil
+}
+
 func (s *Store) UpsertAgent(ctx context.Context, a model.Agent) (model.Agent, error) {
 	now := time.Now().UTC()
 
@@ -61,69 +75,60 @@ func (s *Store) UpsertAgent(ctx context.Context, a model.Agent) (model.Agent, er
 			metaJSON = b
 		}
 	}
+	// nil labels keep what the agent reported before.
+	var labelsJSON *string
+	if a.Labels != nil {
+		b, err := json.Marshal(a.Labels)
+		if err != nil {
+			return model.Agent{}, err
+		}
+		v := string(b)
+		labelsJSON = &v
+	}
 
 	if strings.TrimSpace(a.ID) == "" {
 		// Let DB generate UUID.
 		var out model.Agent
-		err := s.pool.QueryRow(ctx, `
-			insert into public.agents (name, status, claude_status, current_task_id, last_seen, meta, user_id)
-			values ($1, $2, $3, nullif($4, '')::uuid, $5, $6::jsonb, nullif($7, '')::uuid)
-			returning id::text, coalesce(user_id::text, ''), name, status, claude_status, coalesce(current_task_id::text, ''), last_seen, meta, created_at, updated_at
-		`, a.Name, string(a.Status), string(a.ClaudeStatus), a.CurrentTaskID, now, string(metaJSON), a.UserID).Scan(
-			&out.ID,
-			&out.UserID,
-			&out.Name,
-			&out.Status,
-			&out.ClaudeStatus,
-			&out.CurrentTaskID,
-			&out.LastSeen,
-			&metaJSON,
-			&out.CreatedAt,
-			&out.UpdatedAt,
-		)
+		err := scanAgent(s.pool.QueryRow(ctx, `
+			insert into public.agents (name, status, claude_status, current_task_id, last_seen, meta, user_id, labels)
+			values ($1, $2, $3, nullif($4, '')::uuid, $5, $6::jsonb, nullif($7, '')::uuid, $8::jsonb)
+			returning `+agentColumns+`
+		`, a.Name, string(a.Status), string(a.ClaudeStatus), a.CurrentTaskID, now, string(metaJSON), a.UserID, labelsJSON), &out)
 		if err != nil {
 			return model.Agent{}, mapPgErr(err)
 		}
-		_ = json.Unmarshal(metaJSON, &out.Meta)
 		return out, nil
 	}
 
 	var out model.Agent
-	err := s.pool.QueryRow(ctx, `
-		insert into public.agents (id, name, status, claude_status, current_task_id, last_seen, meta, user_id)
-		values ($1::uuid, $2, $3, $4, nullif($5, '')::uuid, $6, $7::jsonb, nullif($8, '')::uuid)
+	err := scanAgent(s.pool.QueryRow(ctx, `
+		insert into public.agents (id, name, status, claude_status, current_task_id, last_seen, meta, user_id, labels)
+		values ($1::uuid, $2, $3, $4, nullif($5, '')::uuid, $6, $7::jsonb, nullif($8, '')::uuid, $9::jsonb)
 		on conflict (id) do update
 		set name = excluded.name,
 		    status = excluded.status,
 		    claude_status = excluded.claude_status,
+		    claude_status_since = case
+		        when public.agents.claude_status is distinct from excluded.claude_status then now()
+		        else public.agents.claude_status_since
+		    end,
 		    current_task_id = excluded.current_task_id,
 		    last_seen = excluded.last_seen,
 		    meta = excluded.meta,
 		    user_id = coalesce(excluded.user_id, public.agents.user_id),
+		    labels = coalesce(excluded.labels, public.agents.labels),
 		    updated_at = now()
-		returning id::text, coalesce(user_id::text, ''), name, status, claude_status, coalesce(current_task_id::text, ''), last_seen, meta, created_at, updated_at
-	`, a.ID, a.Name, string(a.Status), string(a.ClaudeStatus), a.CurrentTaskID, now, string(metaJSON), a.UserID).Scan(
-		&out.ID,
-		&out.UserID,
-		&out.Name,
-		&out.Status,
-		&out.ClaudeStatus,
-		&out.CurrentTaskID,
-		&out.LastSeen,
-		&metaJSON,
-		&out.CreatedAt,
-		&out.UpdatedAt,
-	)
+		returning `+agentColumns+`
+	`, a.ID, a.Name, string(a.Status), string(a.ClaudeStatus), a.CurrentTaskID, now, string(metaJSON), a.UserID, labelsJSON), &out)
 	if err != nil {
 		return model.Agent{}, mapPgErr(err)
 	}
-	_ = json.Unmarshal(metaJSON, &out.Meta)
 	return out, nil
 }
 
 func (s *Store) ListAgents(ctx context.Context, userID string) ([]model.Agent, error) {
 	query := `
-		select id::text, coalesce(user_id::text, ''), name, status, claude_status, coalesce(current_task_id::text, ''), last_seen, meta, created_at, updated_at
+		select ` + agentColumns + `
 		from public.agents
 	`
 	var args []any
@@ -142,11 +147,9 @@ func (s *Store) ListAgents(ctx context.Context, userID string) ([]model.Agent, e
 	var out []model.Agent
 	for rows.Next() {
 		var a model.Agent
-		var metaJSON []byte
-		if err := rows.Scan(&a.ID, &a.UserID, &a.Name, &a.Status, &a.ClaudeStatus, &a.CurrentTaskID, &a.LastSeen, &metaJSON, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		if err := scanAgent(rows, &a); err != nil {
 			return nil, mapPgErr(err)
 		}
-		_ = json.Unmarshal(metaJSON, &a.Meta)
 		out = append(out, a)
 	}
 	return out, nil
@@ -154,18 +157,14 @@ func (s *Store) ListAgents(ctx context.Context, userID string) ([]model.Agent, e
 
 func (s *Store) GetAgent(ctx context.Context, id string) (*model.Agent, error) {
 	var a model.Agent
-	var metaJSON []byte
-	err := s.pool.QueryRow(ctx, `
-		select id::text, coalesce(user_id::text, ''), name, status, claude_status, coalesce(current_task_id::text, ''), last_seen, meta, created_at, updated_at
+	err := scanAgent(s.pool.QueryRow(ctx, `
+		select `+agentColumns+`
 		from public.agents
 		where id = $1
-	`, id).Scan(&a.ID, &a.UserID, &a.Name, &a.Status, &a.ClaudeStatus, &a.CurrentTaskID, &a.LastSeen, &metaJSON, &a.CreatedAt, &a.UpdatedAt)
-
+	`, id), &a)
 	if err != nil {
 		return nil, mapPgErr(err)
 	}
-
-	_ = json.Unmarshal(metaJSON, &a.Meta)
 	return &a, nil
 }
 
@@ -174,12 +173,19 @@ func (s *Store) CreateChannel(ctx context.Context, ch model.Channel) (model.Chan
 		return model.Channel{}, errors.New("name_required")
 	}
 
+	if ch.RetryMaxAttempts < 0 || ch.RetryBackoffSeconds < 0 {
+		return model.Channel{}, errors.New("retry_policy_invalid")
+	}
+	if ch.MaxInFlight < 0 {
+		return model.Channel{}, errors.New("max_in_flight_invalid")
+	}
+
 	var out model.Channel
-	err := s.pool.QueryRow(ctx, `
-		insert into public.channels (name, description, user_id)
-		values ($1, nullif($2, ''), nullif($3, '')::uuid)
-		returning id::text, coalesce(user_id::text, ''), name, coalesce(description, ''), created_at
-	`, ch.Name, ch.Description, ch.UserID).Scan(&out.ID, &out.UserID, &out.Name, &out.Description, &out.CreatedAt)
+	err := scanChannel(s.pool.QueryRow(ctx, `
+		insert into public.channels (name, description, user_id, retry_max_attempts, retry_backoff_seconds, max_in_flight)
+		values ($1, nullif($2, ''), nullif($3, '')::uuid, $4, $5, $6)
+		returning `+channelColumns+`
+	`, ch.Name, ch.Description, ch.UserID, ch.RetryMaxAttempts, ch.RetryBackoffSeconds, ch.MaxInFlight), &out)
 	if err != nil {
 		return model.Channel{}, mapPgErr(err)
 	}
@@ -188,7 +194,7 @@ func (s *Store) CreateChannel(ctx context.Context, ch model.Channel) (model.Chan
 
 func (s *Store) ListChannels(ctx context.Context, userID string) ([]model.Channel, error) {
 	query := `
-		select id::text, coalesce(user_id::text, ''), name, coalesce(description, ''), created_at
+		select ` + channelColumns + `
 		from public.channels
 	`
 	var args []any
@@ -207,7 +213,7 @@ func (s *Store) ListChannels(ctx context.Context, userID string) ([]model.Channe
 	var out []model.Channel
 	for rows.Next() {
 		var ch model.Channel
-		if err := rows.Scan(&ch.ID, &ch.UserID, &ch.Name, &ch.Description, &ch.CreatedAt); err != nil {
+		if err := scanChannel(rows, &ch); err != nil {
 			return nil, mapPgErr(err)
 		}
 		out = append(out, ch)
@@ -217,11 +223,27 @@ func (s *Store) ListChannels(ctx context.Context, userID string) ([]model.Channe
 
 func (s *Store) GetChannelByName(ctx context.Context, name string) (model.Channel, error) {
 	var ch model.Channel
-	err := s.pool.QueryRow(ctx, `
-		select id::text, name, coalesce(description, ''), created_at
+	err := scanChannel(s.pool.QueryRow(ctx, `
+		select `+channelColumns+`
 		from public.channels
 		where name = $1
-	`, name).Scan(&ch.ID, &ch.Name, &ch.Description, &ch.CreatedAt)
+	`, name), &ch)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Channel{}, store.ErrNotFound
+		}
+		return model.Channel{}, mapPgErr(err)
+	}
+	return ch, nil
+}
+
+func (s *Store) GetChannel(ctx context.Context, id string) (model.Channel, error) {
+	var ch model.Channel
+	err := scanChannel(s.pool.QueryRow(ctx, `
+		select `+channelColumns+`
+		from public.channels
+		where id = $1::uuid
+	`, id), &ch)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return model.Channel{}, store.ErrNotFound
@@ -231,6 +253,159 @@ func (s *Store) GetChannelByName(ctx context.Context, name string) (model.Channe
 	return ch, nil
 }
 
+func (s *Store) UpdateChannel(ctx context.Context, ch model.Channel) (model.Channel, error) {
+	if ch.RetryMaxAttempts < 0 || ch.RetryBackoffSeconds < 0 {
+		return model.Channel{}, errors.New("retry_policy_invalid")
+	}
+	if ch.MaxInFlight < 0 {
+		return model.Channel{}, errors.New("max_in_flight_invalid")
+	}
+
+	var out model.Channel
+	err := scanChannel(s.pool.QueryRow(ctx, `
+		update public.channels
+		set description = nullif($2, ''),
+		    retry_max_attempts = $3,
+		    retry_backoff_seconds = $4,
+		    max_in_flight = $5
+		where id = $1::uuid
+		returning `+channelColumns+`
+	`, ch.ID, ch.Description, ch.RetryMaxAttempts, ch.RetryBackoffSeconds, ch.MaxInFlight), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Channel{}, store.ErrNotFound
+		}
+		return model.Channel{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) SetChannelPause(ctx context.Context, channelID string, mode model.PauseMode) (model.Channel, []model.Task, error) {
+	if !store.ValidPauseMode(mode) {
+		return model.Channel{}, nil, errors.New("pause_mode_invalid")
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return model.Channel{}, nil, mapPgErr(err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// paused_at keeps its original time when switching between paused and draining.
+	var out model.Channel
+	err = scanChannel(tx.QueryRow(ctx, `
+		update public.channels
+		set pause = nullif($2, ''),
+		    paused_at = case when $2 = '' then null else coalesce(paused_at, now()) end
+		where id = $1::uuid
+		returning `+channelColumns+`
+	`, channelID, string(mode)), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Channel{}, nil, store.ErrNotFound
+		}
+		return model.Channel{}, nil, mapPgErr(err)
+	}
+
+	var requeued []model.Task
+	if mode == model.PauseModePaused {
+		if requeued, err = s.requeuePausedTx(ctx, tx, "channel_id", channelID); err != nil {
+			return model.Channel{}, nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.Channel{}, nil, mapPgErr(err)
+	}
+	return out, requeued, nil
+}
+
+// requeuePausedTx returns the in_progress tasks whose column (chain_id or channel_id)
+// is id to the queue when their chain or channel is paused, so the work stops now instead
+// of finishing as with a drain.
+func (s *Store) requeuePausedTx(ctx context.Context, tx pgx.Tx, column, id string) ([]model.Task, error) {
+	type inFlight struct{ taskID, agentID string }
+
+	rows, err := tx.Query(ctx, `
+		select id::text, coalesce(assigned_agent_id::text, '')
+		from public.tasks
+		where `+column+` = $1::uuid
+		  and status = 'in_progress'
+		for update
+	`, id)
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	var running []inFlight
+	for rows.Next() {
+		var f inFlight
+		if err := rows.Scan(&f.taskID, &f.agentID); err != nil {
+			rows.Close()
+			return nil, mapPgErr(err)
+		}
+		running = append(running, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, mapPgErr(err)
+	}
+
+	var out []model.Task
+	for _, f := range running {
+		t, err := s.requeueTaskTx(ctx, tx, f.taskID, f.agentID)
+		if err != nil {
+			return nil, err
+		}
+		if err := insertEventTx(ctx, tx, f.agentID, f.taskID, store.EventTypeTaskRequeued, map[string]any{
+			"reason": "paused",
+		}); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// channelColumns is the select list shared by every channel query; keep in sync with scanChannel.
+const channelColumns = `id::text, coalesce(user_id::text, ''), name, coalesce(description, ''),
+		       retry_max_attempts, retry_backoff_seconds, max_in_flight, coalesce(pause, ''), paused_at, created_at`
+
+func scanChannel(row pgx.Row, ch *model.Channel) error {
+	return row.Scan(
+		&ch.ID,
+		&ch.UserID,
+		&ch.Name,
+		&ch.Description,
+		&ch.RetryMaxAttempts,
+		&ch.RetryBackoffSeconds,
+		&ch.MaxInFlight,
+		&ch.Pause,
+		&ch.PausedAt,
+		&ch.CreatedAt,
+	)
+}
+
+// chainColumns is the select list shared by every chain query; keep in sync with scanChain.
+const chainColumns = `id::text, coalesce(user_id::text, ''), channel_id::text, name, coalesce(description, ''), status,
+		       coalesce(owner_agent_id::text, ''), coalesce(pause, ''), paused_at, coalesce(on_failure, ''), created_at, updated_at`
+
+func scanChain(row pgx.Row, c *model.Chain) error {
+	return row.Scan(
+		&c.ID,
+		&c.UserID,
+		&c.ChannelID,
+		&c.Name,
+		&c.Description,
+		&c.Status,
+		&c.OwnerAgentID,
+		&c.Pause,
+		&c.PausedAt,
+		&c.OnFailure,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+}
+
 func (s *Store) CreateChain(ctx context.Context, c model.Chain) (model.Chain, error) {
 	if strings.TrimSpace(c.ChannelID) == "" {
 		return model.Chain{}, errors.New("channel_id_required")
@@ -238,6 +413,9 @@ func (s *Store) CreateChain(ctx context.Context, c model.Chain) (model.Chain, er
 	if strings.TrimSpace(c.Name) == "" {
 		return model.Chain{}, errors.New("name_required")
 	}
+	if !store.ValidFailurePolicy(c.OnFailure) {
+		return model.Chain{}, errors.New("on_failure_invalid")
+	}
 
 	status := c.Status
 	if status == "" {
@@ -245,44 +423,99 @@ func (s *Store) CreateChain(ctx context.Context, c model.Chain) (model.Chain, er
 	}
 
 	var out model.Chain
-	err := s.pool.QueryRow(ctx, `
-		insert into public.chains (channel_id, name, description, status, user_id, owner_agent_id)
-		values ($1::uuid, $2, nullif($3, ''), $4, nullif($5, '')::uuid, nullif($6, '')::uuid)
-		returning id::text, coalesce(user_id::text, ''), channel_id::text, name, coalesce(description, ''), status, coalesce(owner_agent_id::text, ''), created_at, updated_at
-	`, c.ChannelID, c.Name, c.Description, string(status), c.UserID, c.OwnerAgentID).Scan(
-		&out.ID,
-		&out.UserID,
-		&out.ChannelID,
-		&out.Name,
-		&out.Description,
-		&out.Status,
-		&out.OwnerAgentID,
-		&out.CreatedAt,
-		&out.UpdatedAt,
-	)
+	err := scanChain(s.pool.QueryRow(ctx, `
+		insert into public.chains (channel_id, name, description, status, user_id, owner_agent_id, on_failure)
+		values ($1::uuid, $2, nullif($3, ''), $4, nullif($5, '')::uuid, nullif($6, '')::uuid, nullif($7, ''))
+		returning `+chainColumns+`
+	`, c.ChannelID, c.Name, c.Description, string(status), c.UserID, c.OwnerAgentID, string(c.OnFailure)), &out)
 	if err != nil {
 		return model.Chain{}, mapPgErr(err)
 	}
 	return out, nil
 }
 
+func (s *Store) CreateChainWithTasks(ctx context.Context, c model.Chain, tasks []store.NewChainTask) (model.Chain, []model.Task, error) {
+	if strings.TrimSpace(c.ChannelID) == "" {
+		return model.Chain{}, nil, errors.New("channel_id_required")
+	}
+	if strings.TrimSpace(c.Name) == "" {
+		return model.Chain{}, nil, errors.New("name_required")
+	}
+	if !store.ValidFailurePolicy(c.OnFailure) {
+		return model.Chain{}, nil, errors.New("on_failure_invalid")
+	}
+	if err := store.ValidateNewChainTasks(tasks); err != nil {
+		return model.Chain{}, nil, err
+	}
+
+	status := c.Status
+	if status == "" {
+		status = model.ChainStatusQueued
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return model.Chain{}, nil, mapPgErr(err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var chain model.Chain
+	err = scanChain(tx.QueryRow(ctx, `
+		insert into public.chains (channel_id, name, description, status, user_id, on_failure)
+		values ($1::uuid, $2, nullif($3, ''), $4, nullif($5, '')::uuid, nullif($6, ''))
+		returning `+chainColumns+`
+	`, c.ChannelID, c.Name, c.Description, string(status), c.UserID, string(c.OnFailure)), &chain)
+	if err != nil {
+		return model.Chain{}, nil, mapPgErr(err)
+	}
+
+	// Insert every task first, then point depends_on at the new IDs: a dependency may
+	// come later in the list than its dependent.
+	out := make([]model.Task, len(tasks))
+	for i, nt := range tasks {
+		t := nt.Task
+		taskStatus := t.Status
+		if taskStatus == "" {
+			taskStatus = model.TaskStatusQueued
+		}
+		err := scanTask(tx.QueryRow(ctx, `
+			insert into public.tasks (channel_id, chain_id, sequence, title, description, type, status, priority, execution_mode, user_id, max_attempts, retry_backoff_seconds, not_before, compensation, requires)
+			values ($1::uuid, $2::uuid, $3, $4, nullif($5, ''), nullif($6, ''), $7, $8, nullif($9, ''), nullif($10, '')::uuid, $11, $12, $13, $14, $15::jsonb)
+			returning `+taskColumns+`
+		`, chain.ChannelID, chain.ID, i+1, t.Title, t.Description, t.Type, string(taskStatus), t.Priority, string(t.ExecutionMode), chain.UserID, t.MaxAttempts, t.RetryBackoffSeconds, t.NotBefore, t.Compensation, requiresJSON(t.Requires)), &out[i])
+		if err != nil {
+			return model.Chain{}, nil, mapPgErr(err)
+		}
+	}
+	for i, nt := range tasks {
+		if len(nt.DependsOn) == 0 {
+			continue
+		}
+		deps := make([]string, len(nt.DependsOn))
+		for j, dep := range nt.DependsOn {
+			deps[j] = out[dep].ID
+		}
+		if _, err := tx.Exec(ctx, `
+			update public.tasks set depends_on = $2::uuid[] where id = $1::uuid
+		`, out[i].ID, deps); err != nil {
+			return model.Chain{}, nil, mapPgErr(err)
+		}
+		out[i].DependsOn = deps
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.Chain{}, nil, mapPgErr(err)
+	}
+	return chain, out, nil
+}
+
 func (s *Store) GetChain(ctx context.Context, id string) (model.Chain, error) {
 	var out model.Chain
-	err := s.pool.QueryRow(ctx, `
-		select id::text, coalesce(user_id::text, ''), channel_id::text, name, coalesce(description, ''), status, coalesce(owner_agent_id::text, ''), created_at, updated_at
+	err := scanChain(s.pool.QueryRow(ctx, `
+		select `+chainColumns+`
 		from public.chains
 		where id = $1::uuid
-	`, id).Scan(
-		&out.ID,
-		&out.UserID,
-		&out.ChannelID,
-		&out.Name,
-		&out.Description,
-		&out.Status,
-		&out.OwnerAgentID,
-		&out.CreatedAt,
-		&out.UpdatedAt,
-	)
+	`, id), &out)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return model.Chain{}, store.ErrNotFound
@@ -294,7 +527,7 @@ func (s *Store) GetChain(ctx context.Context, id string) (model.Chain, error) {
 
 func (s *Store) ListChains(ctx context.Context, userID string, channelID string) ([]model.Chain, error) {
 	query := `
-		select id::text, coalesce(user_id::text, ''), channel_id::text, name, coalesce(description, ''), status, coalesce(owner_agent_id::text, ''), created_at, updated_at
+		select ` + chainColumns + `
 		from public.chains
 	`
 	var args []any
@@ -321,17 +554,7 @@ func (s *Store) ListChains(ctx context.Context, userID string, channelID string)
 	var out []model.Chain
 	for rows.Next() {
 		var c model.Chain
-		if err := rows.Scan(
-			&c.ID,
-			&c.UserID,
-			&c.ChannelID,
-			&c.Name,
-			&c.Description,
-			&c.Status,
-			&c.OwnerAgentID,
-			&c.CreatedAt,
-			&c.UpdatedAt,
-		); err != nil {
+		if err := scanChain(rows, &c); err != nil {
 			return nil, mapPgErr(err)
 		}
 		out = append(out, c)
@@ -340,27 +563,22 @@ func (s *Store) ListChains(ctx context.Context, userID string, channelID string)
 }
 
 func (s *Store) UpdateChain(ctx context.Context, c model.Chain) (model.Chain, error) {
+	if !store.ValidFailurePolicy(c.OnFailure) {
+		return model.Chain{}, errors.New("on_failure_invalid")
+	}
+
 	var out model.Chain
-	err := s.pool.QueryRow(ctx, `
+	err := scanChain(s.pool.QueryRow(ctx, `
 		update public.chains
 		set name = $2,
 		    description = nullif($3, ''),
 		    status = $4,
 		    owner_agent_id = nullif($5, '')::uuid,
+		    on_failure = coalesce(nullif($6, ''), on_failure),
 		    updated_at = now()
 		where id = $1::uuid
-		returning id::text, coalesce(user_id::text, ''), channel_id::text, name, coalesce(description, ''), status, coalesce(owner_agent_id::text, ''), created_at, updated_at
-	`, c.ID, c.Name, c.Description, string(c.Status), c.OwnerAgentID).Scan(
-		&out.ID,
-		&out.UserID,
-		&out.ChannelID,
-		&out.Name,
-		&out.Description,
-		&out.Status,
-		&out.OwnerAgentID,
-		&out.CreatedAt,
-		&out.UpdatedAt,
-	)
+		returning `+chainColumns+`
+	`, c.ID, c.Name, c.Description, string(c.Status), c.OwnerAgentID, string(c.OnFailure)), &out)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return model.Chain{}, store.ErrNotFound
@@ -370,6 +588,52 @@ func (s *Store) UpdateChain(ctx context.Context, c model.Chain) (model.Chain, er
 	return out, nil
 }
 
+func (s *Store) SetChainPause(ctx context.Context, chainID string, mode model.PauseMode) (model.Chain, []model.Task, error) {
+	if !store.ValidPauseMode(mode) {
+		return model.Chain{}, nil, errors.New("pause_mode_invalid")
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return model.Chain{}, nil, mapPgErr(err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var out model.Chain
+	err = scanChain(tx.QueryRow(ctx, `
+		update public.chains
+		set pause = nullif($2, ''),
+		    paused_at = case when $2 = '' then null else coalesce(paused_at, now()) end,
+		    updated_at = now()
+		where id = $1::uuid
+		returning `+chainColumns+`
+	`, chainID, string(mode)), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Chain{}, nil, store.ErrNotFound
+		}
+		return model.Chain{}, nil, mapPgErr(err)
+	}
+
+	var requeued []model.Task
+	if mode == model.PauseModePaused {
+		if requeued, err = s.requeuePausedTx(ctx, tx, "chain_id", chainID); err != nil {
+			return model.Chain{}, nil, err
+		}
+		if len(requeued) > 0 {
+			// The requeue re-evaluated the chain status and released its owner.
+			if err := scanChain(tx.QueryRow(ctx, `select `+chainColumns+` from public.chains where id = $1::uuid`, chainID), &out); err != nil {
+				return model.Chain{}, nil, mapPgErr(err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.Chain{}, nil, mapPgErr(err)
+	}
+	return out, requeued, nil
+}
+
 func (s *Store) DeleteChain(ctx context.Context, id string) error {
 	cmdTag, err := s.pool.Exec(ctx, `
 		delete from public.chains
@@ -419,7 +683,7 @@ func (s *Store) DetachAgentFromChain(ctx context.Context, req store.DetachAgentF
 	// Set in_progress task to locked
 	_, err = tx.Exec(ctx, `
 		update public.tasks
-		set status = 'locked', updated_at = now()
+		set status = 'locked', lease_expires_at = null, updated_at = now()
 		where chain_id = $1::uuid
 		  and status = 'in_progress'
 	`, req.ChainID)
@@ -476,36 +740,25 @@ func (s *Store) UpdateTaskStatus(ctx context.Context, taskID string, newStatus m
 
 	var t model.Task
 	if newStatus == model.TaskStatusQueued {
-		err = tx.QueryRow(ctx, `
+		err = scanTask(tx.QueryRow(ctx, `
 			update public.tasks
 			set status = 'queued',
 			    assigned_agent_id = null,
 			    claimed_at = null,
+			    lease_expires_at = null,
 			    updated_at = now()
 			where id = $1::uuid and status = 'locked'
-			returning id::text, coalesce(user_id::text, ''), channel_id::text, coalesce(chain_id::text, ''), coalesce(sequence, 0),
-			          title, coalesce(description, ''), coalesce(type, ''), status, priority,
-			          coalesce(assigned_agent_id::text, ''), coalesce(execution_mode, ''), created_at, claimed_at, done_at, updated_at
-		`, taskID).Scan(
-			&t.ID, &t.UserID, &t.ChannelID, &t.ChainID, &t.Sequence,
-			&t.Title, &t.Description, &t.Type, &t.Status, &t.Priority,
-			&t.AssignedAgentID, &t.ExecutionMode, &t.CreatedAt, &t.ClaimedAt, &t.DoneAt, &t.UpdatedAt,
-		)
+			returning `+taskColumns+`
+		`, taskID), &t)
 	} else {
-		err = tx.QueryRow(ctx, `
+		err = scanTask(tx.QueryRow(ctx, `
 			update public.tasks
 			set status = 'done',
 			    done_at = now(),
 			    updated_at = now()
 			where id = $1::uuid and status = 'locked'
-			returning id::text, coalesce(user_id::text, ''), channel_id::text, coalesce(chain_id::text, ''), coalesce(sequence, 0),
-			          title, coalesce(description, ''), coalesce(type, ''), status, priority,
-			          coalesce(assigned_agent_id::text, ''), coalesce(execution_mode, ''), created_at, claimed_at, done_at, updated_at
-		`, taskID).Scan(
-			&t.ID, &t.UserID, &t.ChannelID, &t.ChainID, &t.Sequence,
-			&t.Title, &t.Description, &t.Type, &t.Status, &t.Priority,
-			&t.AssignedAgentID, &t.ExecutionMode, &t.CreatedAt, &t.ClaimedAt, &t.DoneAt, &t.UpdatedAt,
-		)
+			returning `+taskColumns+`
+		`, taskID), &t)
 	}
 
 	if err != nil {
@@ -535,9 +788,142 @@ func (s *Store) UpdateTaskStatus(ctx context.Context, taskID string, newStatus m
 	return &t, nil
 }
 
+func (s *Store) CancelTask(ctx context.Context, req store.CancelTaskRequest) (*model.Task, []model.Task, error) {
+	if strings.TrimSpace(req.TaskID) == "" {
+		return nil, nil, errors.New("task_id_required")
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, nil, mapPgErr(err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var t model.Task
+	err = scanTask(tx.QueryRow(ctx, `
+		select `+taskColumns+`
+		from public.tasks
+		where id = $1::uuid
+		for update
+	`, req.TaskID), &t)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, store.ErrNotFound
+		}
+		return nil, nil, mapPgErr(err)
+	}
+	switch t.Status {
+	case model.TaskStatusCancelled:
+		return &t, nil, nil // idempotent
+	case model.TaskStatusQueued, model.TaskStatusInProgress, model.TaskStatusLocked:
+	default:
+		return nil, nil, store.ErrConflict
+	}
+
+	running := t.Status == model.TaskStatusInProgress
+	agentID := t.AssignedAgentID
+	if t, err = cancelTaskTx(ctx, tx, t.ID, req); err != nil {
+		return nil, nil, err
+	}
+	if running && agentID != "" {
+		_, err = tx.Exec(ctx, `
+			update public.agents
+			set current_task_id = null, updated_at = now()
+			where id = $1::uuid and current_task_id = $2::uuid
+		`, agentID, t.ID)
+		if err != nil {
+			return nil, nil, mapPgErr(err)
+		}
+		if err := insertEventTx(ctx, tx, agentID, t.ID, store.EventTypeTaskCancelled, map[string]any{
+			"cancelled_by": req.CancelledBy,
+			"reason":       req.Reason,
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var downstream []model.Task
+	if t.ChainID != "" {
+		if req.CancelDownstream {
+			rows, err := tx.Query(ctx, `
+				select `+taskColumns+`
+				from public.tasks
+				where chain_id = $1::uuid
+				for update
+			`, t.ChainID)
+			if err != nil {
+				return nil, nil, mapPgErr(err)
+			}
+			var chainTasks []model.Task
+			for rows.Next() {
+				var ct model.Task
+				if err := scanTask(rows, &ct); err != nil {
+					rows.Close()
+					return nil, nil, mapPgErr(err)
+				}
+				chainTasks = append(chainTasks, ct)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return nil, nil, mapPgErr(err)
+			}
+
+			for _, dt := range store.Downstream(chainTasks, t.ID) {
+				if dt.Status != model.TaskStatusQueued && dt.Status != model.TaskStatusLocked {
+					continue
+				}
+				cancelled, err := cancelTaskTx(ctx, tx, dt.ID, req)
+				if err != nil {
+					return nil, nil, err
+				}
+				downstream = append(downstream, cancelled)
+			}
+		}
+		if err := s.reevaluateChainStatusTx(ctx, tx, t.ChainID, false); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, mapPgErr(err)
+	}
+
+	return &t, downstream, nil
+}
+
+// cancelTaskTx marks a task cancelled and releases its claim. The assignee is kept for a
+// task that was in progress so the agent can be told to stop.
+func cancelTaskTx(ctx context.Context, tx pgx.Tx, taskID string, req store.CancelTaskRequest) (model.Task, error) {
+	var t model.Task
+	err := scanTask(tx.QueryRow(ctx, `
+		update public.tasks
+		set assigned_agent_id = case when status = 'in_progress' then assigned_agent_id else null end,
+		    status = 'cancelled',
+		    cancelled_at = now(),
+		    cancelled_by = nullif($2, ''),
+		    cancel_reason = nullif($3, ''),
+		    lease_expires_at = null,
+		    next_eligible_at = null,
+		    updated_at = now()
+		where id = $1::uuid
+		returning `+taskColumns+`
+	`, taskID, req.CancelledBy, req.Reason), &t)
+	if err != nil {
+		return model.Task{}, mapPgErr(err)
+	}
+	return t, nil
+}
+
 func (s *Store) reevaluateChainStatusTx(ctx context.Context, tx pgx.Tx, chainID string, clearOwnerOnCompletion bool) error {
+	if _, err := settleChainTasksTx(ctx, tx, chainID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+
 	var hasLocked, hasInProgress, hasQueued bool
 	allDoneOrFailed := true
+	// A chain without tasks is done, not cancelled: only tasks can be cancelled.
+	var hasTasks bool
+	allCancelled := true
 	var hasFailed bool
 
 	rows, err := tx.Query(ctx, `
@@ -553,6 +939,10 @@ func (s *Store) reevaluateChainStatusTx(ctx context.Context, tx pgx.Tx, chainID
 		if err := rows.Scan(&st); err != nil {
 			return mapPgErr(err)
 		}
+		hasTasks = true
+		if st != model.TaskStatusCancelled {
+			allCancelled = false
+		}
 		switch st {
 		case model.TaskStatusLocked:
 			hasLocked = true
@@ -563,7 +953,7 @@ func (s *Store) reevaluateChainStatusTx(ctx context.Context, tx pgx.Tx, chainID
 		case model.TaskStatusQueued:
 			hasQueued = true
 			allDoneOrFailed = false
-		case model.TaskStatusFailed:
+		case model.TaskStatusFailed, model.TaskStatusDeadLetter:
 			hasFailed = true
 		}
 	}
@@ -577,6 +967,8 @@ func (s *Store) reevaluateChainStatusTx(ctx context.Context, tx pgx.Tx, chainID
 		clearOwner = clearOwnerOnCompletion
 		if hasFailed {
 			newChainStatus = model.ChainStatusFailed
+		} else if hasTasks && allCancelled {
+			newChainStatus = model.ChainStatusCancelled
 		} else {
 			newChainStatus = model.ChainStatusDone
 		}
@@ -606,51 +998,117 @@ func (s *Store) reevaluateChainStatusTx(ctx context.Context, tx pgx.Tx, chainID
 	return nil
 }
 
-func (s *Store) CreateTask(ctx context.Context, t model.Task) (model.Task, error) {
-	if strings.TrimSpace(t.ChannelID) == "" {
-		return model.Task{}, errors.New("channel_id_required")
-	}
-	if strings.TrimSpace(t.Title) == "" {
-		return model.Task{}, errors.New("title_required")
-	}
-	if strings.TrimSpace(t.ChainID) == "" {
-		return model.Task{}, errors.New("chain_id_required")
-	}
-	// Verify chain exists
-	if _, err := s.GetChain(ctx, t.ChainID); err != nil {
-		return model.Task{}, fmt.Errorf("chain_id not found: %w", err)
-	}
-
-	status := t.Status
-	if status == "" {
+// taskColumns is the select list shared by every task query; keep in sync with scanTask.
+const taskColumns = `id::text, coalesce(user_id::text, ''), channel_id::text, coalesce(chain_id::text, ''), coalesce(sequence, 0),
+		       title, coalesce(description, ''), coalesce(type, ''), status, priority,
+		       coalesce(assigned_agent_id::text, ''), coalesce(execution_mode, ''), coalesce(agent_session_request_token, ''),
+		       created_at, claimed_at, done_at, updated_at, lease_expires_at,
+		       max_attempts, retry_backoff_seconds, attempts, coalesce(last_failure_reason, ''), next_eligible_at,
+		       depends_on::text[], not_before, cancelled_at, coalesce(cancelled_by, ''), coalesce(cancel_reason, ''),
+		       reviewed_at, coalesce(reviewed_by, ''), coalesce(review_comment, ''), compensation, requires`
+
+func scanTask(row pgx.Row, t *model.Task) error {
+	var requiresJSON []byte
+	if err := row.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.ChannelID,
+		&t.ChainID,
+		&t.Sequence,
+		&t.Title,
+		&t.Description,
+		&t.Type,
+		&t.Status,
+		&t.Priority,
+		&t.AssignedAgentID,
+		&t.ExecutionMode,
+		&t.AgentSessionRequestToken,
+		&t.CreatedAt,
+		&t.ClaimedAt,
+		&t.DoneAt,
+		&t.UpdatedAt,
+		&t.LeaseExpiresAt,
+		&t.MaxAttempts,
+		&t.RetryBackoffSeconds,
+		&t.Attempts,
+		&t.LastFailureReason,
+		&t.NextEligibleAt,
+		&t.DependsOn,
+		&t.NotBefore,
+		&t.CancelledAt,
+		&t.CancelledBy,
+		&t.CancelReason,
+		&t.ReviewedAt,
+		&t.ReviewedBy,
+		&t.ReviewComment,
+		&t.Compensation,
+		&requiresJSON,
+	); err != nil {
+		return err
+	}
+	t.Requires = nil
+	if len(requiresJSON) > 0 {
+		_ = json.Unmarshal(requiresJSON, &t.Requires)
+	}
+	if len(t.Requires) == 0 {
+		t.Requires = nil
+	}
+	return nil
+}
+
+// requiresJSON encodes a task's label requirements for the requires jsonb column.
+func requiresJSON(requires map[string]string) string {
+	if len(requires) == 0 {
+		return "{}"
+	}
+	b, _ := json.Marshal(requires)
+	return string(b)
+}
+
+func (s *Store) CreateTask(ctx context.Context, t model.Task) (model.Task, error) {
+	if strings.TrimSpace(t.ChannelID) == "" {
+		return model.Task{}, errors.New("channel_id_required")
+	}
+	if strings.TrimSpace(t.Title) == "" {
+		return model.Task{}, errors.New("title_required")
+	}
+	if strings.TrimSpace(t.ChainID) == "" {
+		return model.Task{}, errors.New("chain_id_required")
+	}
+	if t.MaxAttempts < 0 || t.RetryBackoffSeconds < 0 {
+		return model.Task{}, errors.New("retry_policy_invalid")
+	}
+	if _, err := store.NormalizeLabels(t.Requires); err != nil {
+		return model.Task{}, err
+	}
+	// Verify chain exists
+	if _, err := s.GetChain(ctx, t.ChainID); err != nil {
+		return model.Task{}, fmt.Errorf("chain_id not found: %w", err)
+	}
+
+	dependsOn := store.NormalizeDependsOn(t.DependsOn)
+	if len(dependsOn) > 0 {
+		chainTasks, err := s.ListTasks(ctx, store.TaskFilter{ChainID: t.ChainID})
+		if err != nil {
+			return model.Task{}, err
+		}
+		t.DependsOn = dependsOn
+		if err := store.ValidateDependencies(t, chainTasks); err != nil {
+			return model.Task{}, err
+		}
+	}
+
+	status := t.Status
+	if status == "" {
 		status = model.TaskStatusQueued
 	}
 
 	var out model.Task
-	err := s.pool.QueryRow(ctx, `
-		insert into public.tasks (channel_id, chain_id, sequence, title, description, type, agent_session_request_token, status, priority, execution_mode, user_id)
-		values ($1::uuid, nullif($2, '')::uuid, $3, $4, nullif($5, ''), nullif($6, ''), nullif($7, ''), $8, $9, nullif($10, ''), nullif($11, '')::uuid)
-		returning id::text, coalesce(user_id::text, ''), channel_id::text, coalesce(chain_id::text, ''), coalesce(sequence, 0), title, coalesce(description, ''), coalesce(type, ''), status, priority,
-		          coalesce(assigned_agent_id::text, ''), coalesce(execution_mode, ''), coalesce(agent_session_request_token, ''), created_at, claimed_at, done_at, updated_at
-	`, t.ChannelID, t.ChainID, t.Sequence, t.Title, t.Description, t.Type, t.AgentSessionRequestToken, string(status), t.Priority, string(t.ExecutionMode), t.UserID).Scan(
-		&out.ID,
-		&out.UserID,
-		&out.ChannelID,
-		&out.ChainID,
-		&out.Sequence,
-		&out.Title,
-		&out.Description,
-		&out.Type,
-		&out.Status,
-		&out.Priority,
-		&out.AssignedAgentID,
-		&out.ExecutionMode,
-		&out.AgentSessionRequestToken,
-		&out.CreatedAt,
-		&out.ClaimedAt,
-		&out.DoneAt,
-		&out.UpdatedAt,
-	)
+	err := scanTask(s.pool.QueryRow(ctx, `
+		insert into public.tasks (channel_id, chain_id, sequence, title, description, type, agent_session_request_token, status, priority, execution_mode, user_id, max_attempts, retry_backoff_seconds, depends_on, not_before, compensation, requires)
+		values ($1::uuid, nullif($2, '')::uuid, $3, $4, nullif($5, ''), nullif($6, ''), nullif($7, ''), $8, $9, nullif($10, ''), nullif($11, '')::uuid, $12, $13, $14::uuid[], $15, $16, $17::jsonb)
+		returning `+taskColumns+`
+	`, t.ChannelID, t.ChainID, t.Sequence, t.Title, t.Description, t.Type, t.AgentSessionRequestToken, string(status), t.Priority, string(t.ExecutionMode), t.UserID, t.MaxAttempts, t.RetryBackoffSeconds, dependsOn, t.NotBefore, t.Compensation, requiresJSON(t.Requires)), &out)
 	if err != nil {
 		return model.Task{}, mapPgErr(err)
 	}
@@ -668,8 +1126,7 @@ func (s *Store) CreateTask(ctx context.Context, t model.Task) (model.Task, error
 
 func (s *Store) ListTasks(ctx context.Context, f store.TaskFilter) ([]model.Task, error) {
 	query := `
-		select id::text, coalesce(user_id::text, ''), channel_id::text, coalesce(chain_id::text, ''), coalesce(sequence, 0), title, coalesce(description, ''), coalesce(type, ''), status, priority,
-		       coalesce(assigned_agent_id::text, ''), coalesce(execution_mode, ''), coalesce(agent_session_request_token, ''), created_at, claimed_at, done_at, updated_at
+		select ` + taskColumns + `
 		from public.tasks
 	`
 	var where []string
@@ -691,10 +1148,14 @@ func (s *Store) ListTasks(ctx context.Context, f store.TaskFilter) ([]model.Task
 		args = append(args, string(f.Status))
 		where = append(where, fmt.Sprintf("status = $%d", len(args)))
 	}
+	if len(f.IDs) > 0 {
+		args = append(args, f.IDs)
+		where = append(where, fmt.Sprintf("id = any($%d::uuid[])", len(args)))
+	}
 	if len(where) > 0 {
 		query += " where " + strings.Join(where, " and ")
 	}
-	query += " order by created_at asc"
+	query += " order by created_at asc, sequence asc"
 	if f.Limit > 0 {
 		args = append(args, f.Limit)
 		query += fmt.Sprintf(" limit $%d", len(args))
@@ -709,25 +1170,7 @@ func (s *Store) ListTasks(ctx context.Context, f store.TaskFilter) ([]model.Task
 	var out []model.Task
 	for rows.Next() {
 		var t model.Task
-		if err := rows.Scan(
-			&t.ID,
-			&t.UserID,
-			&t.ChannelID,
-			&t.ChainID,
-			&t.Sequence,
-			&t.Title,
-			&t.Description,
-			&t.Type,
-			&t.Status,
-			&t.Priority,
-			&t.AssignedAgentID,
-			&t.ExecutionMode,
-			&t.AgentSessionRequestToken,
-			&t.CreatedAt,
-			&t.ClaimedAt,
-			&t.DoneAt,
-			&t.UpdatedAt,
-		); err != nil {
+		if err := scanTask(rows, &t); err != nil {
 			return nil, mapPgErr(err)
 		}
 		out = append(out, t)
@@ -740,19 +1183,9 @@ func (s *Store) ClaimTask(ctx context.Context, req store.ClaimTaskRequest) (*mod
 		return nil, errors.New("agent_id_required")
 	}
 
-	channelID := strings.TrimSpace(req.ChannelID)
-	if channelID == "" && strings.TrimSpace(req.Channel) != "" {
-		var id string
-		if err := s.pool.QueryRow(ctx, `select id::text from public.channels where lower(name) = lower($1)`, req.Channel).Scan(&id); err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				return nil, store.ErrNotFound
-			}
-			return nil, mapPgErr(err)
-		}
-		channelID = id
-	}
-	if channelID == "" {
-		return nil, errors.New("channel_id_or_channel_required")
+	channelID, err := s.resolveClaimChannel(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
 	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
@@ -781,26 +1214,11 @@ func (s *Store) ClaimTask(ctx context.Context, req store.ClaimTaskRequest) (*mod
 
 		if strings.TrimSpace(existingTaskID) != "" {
 			var out model.Task
-			err := tx.QueryRow(ctx, `
-				select id::text, channel_id::text, title, coalesce(description, ''), coalesce(type, ''), status, priority,
-				       coalesce(assigned_agent_id::text, ''), coalesce(execution_mode, ''), created_at, claimed_at, done_at, updated_at
+			err := scanTask(tx.QueryRow(ctx, `
+				select `+taskColumns+`
 				from public.tasks
 				where id = $1::uuid
-			`, existingTaskID).Scan(
-				&out.ID,
-				&out.ChannelID,
-				&out.Title,
-				&out.Description,
-				&out.Type,
-				&out.Status,
-				&out.Priority,
-				&out.AssignedAgentID,
-				&out.ExecutionMode,
-				&out.CreatedAt,
-				&out.ClaimedAt,
-				&out.DoneAt,
-				&out.UpdatedAt,
-			)
+			`, existingTaskID), &out)
 			if err != nil {
 				if errors.Is(err, pgx.ErrNoRows) {
 					return nil, store.ErrNotFound
@@ -825,33 +1243,39 @@ func (s *Store) ClaimTask(ctx context.Context, req store.ClaimTaskRequest) (*mod
 		}
 	}
 
-	// Claim next queued task atomically (requires migration function claim_task).
+	limits, err := lockClaimLimitsTx(ctx, tx, channelID, req.UserMaxInFlight)
+	if err != nil {
+		return nil, err
+	}
+
+	// Claim next queued task atomically in claim order (requires migration function claim_task, 0019+).
 	var t model.Task
-	err = tx.QueryRow(ctx, `
-		select id::text, channel_id::text, title, coalesce(description, ''), coalesce(type, ''), status, priority,
-		       coalesce(assigned_agent_id::text, ''), coalesce(execution_mode, ''), created_at, claimed_at, done_at, updated_at
-		from public.claim_task($1::uuid, $2::uuid)
-	`, channelID, req.AgentID).Scan(
-		&t.ID,
-		&t.ChannelID,
-		&t.Title,
-		&t.Description,
-		&t.Type,
-		&t.Status,
-		&t.Priority,
-		&t.AssignedAgentID,
-		&t.ExecutionMode,
-		&t.CreatedAt,
-		&t.ClaimedAt,
-		&t.DoneAt,
-		&t.UpdatedAt,
-	)
+	err = scanTask(tx.QueryRow(ctx, `
+		select `+taskColumns+`
+		from public.claim_task($1::uuid, $2::uuid, $3::int)
+	`, channelID, req.AgentID, req.PriorityAgingSeconds), &t)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, store.ErrNoQueuedTasks
 		}
 		return nil, mapPgErr(err)
 	}
+	// Over a limit: the deferred rollback hands the task back.
+	if err := limits.checkTx(ctx, tx, t.ID); err != nil {
+		return nil, err
+	}
+
+	// Count the attempt and start the claim lease (if enabled).
+	if err := tx.QueryRow(ctx, `
+		update public.tasks
+		set attempts = attempts + 1,
+		    next_eligible_at = null,
+		    lease_expires_at = case when $2::int > 0 then now() + $2::int * interval '1 second' end
+		where id = $1::uuid
+		returning attempts, next_eligible_at, lease_expires_at
+	`, t.ID, req.LeaseSeconds).Scan(&t.Attempts, &t.NextEligibleAt, &t.LeaseExpiresAt); err != nil {
+		return nil, mapPgErr(err)
+	}
 
 	if idemKey != "" {
 		_, _ = tx.Exec(ctx, `
@@ -877,6 +1301,40 @@ func (s *Store) ClaimTask(ctx context.Context, req store.ClaimTaskRequest) (*mod
 	return &t, nil
 }
 
+// resolveClaimChannel returns the channel ID of a claim request, looking the channel up
+// by name when only req.Channel is given.
+func (s *Store) resolveClaimChannel(ctx context.Context, req store.ClaimTaskRequest) (string, error) {
+	channelID := strings.TrimSpace(req.ChannelID)
+	if channelID == "" && strings.TrimSpace(req.Channel) != "" {
+		if err := s.pool.QueryRow(ctx, `select id::text from public.channels where lower(name) = lower($1)`, req.Channel).Scan(&channelID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return "", store.ErrNotFound
+			}
+			return "", mapPgErr(err)
+		}
+	}
+	if channelID == "" {
+		return "", errors.New("channel_id_or_channel_required")
+	}
+	return channelID, nil
+}
+
+// NextClaimableAt asks next_claimable_at (migration 0021) for the earliest time a task held
+// back only by not_before or retry backoff becomes claimable for the agent.
+func (s *Store) NextClaimableAt(ctx context.Context, req store.ClaimTaskRequest) (*time.Time, error) {
+	channelID, err := s.resolveClaimChannel(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var at *time.Time
+	if err := s.pool.QueryRow(ctx, `
+		select public.next_claimable_at($1::uuid, $2::uuid)
+	`, channelID, req.AgentID).Scan(&at); err != nil {
+		return nil, mapPgErr(err)
+	}
+	return at, nil
+}
+
 func (s *Store) AssignTask(ctx context.Context, req store.AssignTaskRequest) (*model.Task, error) {
 	if strings.TrimSpace(req.TaskID) == "" {
 		return nil, errors.New("task_id_required")
@@ -892,54 +1350,28 @@ func (s *Store) AssignTask(ctx context.Context, req store.AssignTaskRequest) (*m
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	var t model.Task
-	err = tx.QueryRow(ctx, `
+	err = scanTask(tx.QueryRow(ctx, `
 		update public.tasks
 		set status = 'in_progress',
 		    assigned_agent_id = $2::uuid,
 		    claimed_at = coalesce(claimed_at, now()),
+		    attempts = attempts + 1,
+		    next_eligible_at = null,
+		    lease_expires_at = case when $3::int > 0 then now() + $3::int * interval '1 second' end,
 		    updated_at = now()
 		where id = $1::uuid
 		  and status = 'queued'
-		returning id::text, channel_id::text, title, coalesce(description, ''), coalesce(type, ''), status, priority,
-		          coalesce(assigned_agent_id::text, ''), coalesce(execution_mode, ''), created_at, claimed_at, done_at, updated_at
-	`, req.TaskID, req.AgentID).Scan(
-		&t.ID,
-		&t.ChannelID,
-		&t.Title,
-		&t.Description,
-		&t.Type,
-		&t.Status,
-		&t.Priority,
-		&t.AssignedAgentID,
-		&t.ExecutionMode,
-		&t.CreatedAt,
-		&t.ClaimedAt,
-		&t.DoneAt,
-		&t.UpdatedAt,
-	)
+		  and coalesce(type, '') <> 'approval'
+		returning `+taskColumns+`
+	`, req.TaskID, req.AgentID, req.LeaseSeconds), &t)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			var existing model.Task
-			errGet := tx.QueryRow(ctx, `
-				select id::text, channel_id::text, title, coalesce(description, ''), coalesce(type, ''), status, priority,
-				       coalesce(assigned_agent_id::text, ''), coalesce(execution_mode, ''), created_at, claimed_at, done_at, updated_at
+			errGet := scanTask(tx.QueryRow(ctx, `
+				select `+taskColumns+`
 				from public.tasks
 				where id = $1::uuid
-			`, req.TaskID).Scan(
-				&existing.ID,
-				&existing.ChannelID,
-				&existing.Title,
-				&existing.Description,
-				&existing.Type,
-				&existing.Status,
-				&existing.Priority,
-				&existing.AssignedAgentID,
-				&existing.ExecutionMode,
-				&existing.CreatedAt,
-				&existing.ClaimedAt,
-				&existing.DoneAt,
-				&existing.UpdatedAt,
-			)
+			`, req.TaskID), &existing)
 			if errGet != nil {
 				if errors.Is(errGet, pgx.ErrNoRows) {
 					return nil, store.ErrNotFound
@@ -977,6 +1409,9 @@ func (s *Store) CompleteTask(ctx context.Context, req store.CompleteTaskRequest)
 	if strings.TrimSpace(req.TaskID) == "" {
 		return nil, errors.New("task_id_required")
 	}
+	if err := store.ValidateTaskResult(req.Artifacts); err != nil {
+		return nil, err
+	}
 
 	// Additional verification: agent's current_task_id must match this task
 	// (Prevents completing other agent's tasks due to state directory confusion)
@@ -1010,6 +1445,7 @@ func (s *Store) CompleteTask(ctx context.Context, req store.CompleteTaskRequest)
 		update public.tasks
 		set status = 'done',
 		    done_at = now(),
+		    lease_expires_at = null,
 		    updated_at = now()
 		where id = $1::uuid
 	`
@@ -1020,54 +1456,20 @@ func (s *Store) CompleteTask(ctx context.Context, req store.CompleteTaskRequest)
 	}
 	query += " and status = 'in_progress'"
 	query += `
-		returning id::text, channel_id::text, coalesce(chain_id::text, ''), coalesce(sequence, 0), title, coalesce(description, ''), coalesce(type, ''), status, priority,
-		          coalesce(assigned_agent_id::text, ''), coalesce(execution_mode, ''), created_at, claimed_at, done_at, updated_at
+		returning ` + taskColumns + `
 	`
 
 	var t model.Task
-	err = tx.QueryRow(ctx, query, args...).Scan(
-		&t.ID,
-		&t.ChannelID,
-		&t.ChainID,
-		&t.Sequence,
-		&t.Title,
-		&t.Description,
-		&t.Type,
-		&t.Status,
-		&t.Priority,
-		&t.AssignedAgentID,
-		&t.ExecutionMode,
-		&t.CreatedAt,
-		&t.ClaimedAt,
-		&t.DoneAt,
-		&t.UpdatedAt,
-	)
+	err = scanTask(tx.QueryRow(ctx, query, args...), &t)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			// Either task doesn't exist, agent mismatch, or already done.
 			var existing model.Task
-			errGet := tx.QueryRow(ctx, `
-				select id::text, channel_id::text, coalesce(chain_id::text, ''), coalesce(sequence, 0), title, coalesce(description, ''), coalesce(type, ''), status, priority,
-				       coalesce(assigned_agent_id::text, ''), coalesce(execution_mode, ''), created_at, claimed_at, done_at, updated_at
+			errGet := scanTask(tx.QueryRow(ctx, `
+				select `+taskColumns+`
 				from public.tasks
 				where id = $1::uuid
-			`, req.TaskID).Scan(
-				&existing.ID,
-				&existing.ChannelID,
-				&existing.ChainID,
-				&existing.Sequence,
-				&existing.Title,
-				&existing.Description,
-				&existing.Type,
-				&existing.Status,
-				&existing.Priority,
-				&existing.AssignedAgentID,
-				&existing.ExecutionMode,
-				&existing.CreatedAt,
-				&existing.ClaimedAt,
-				&existing.DoneAt,
-				&existing.UpdatedAt,
-			)
+			`, req.TaskID), &existing)
 			if errGet != nil {
 				if errors.Is(errGet, pgx.ErrNoRows) {
 					return nil, store.ErrNotFound
@@ -1089,30 +1491,44 @@ func (s *Store) CompleteTask(ctx context.Context, req store.CompleteTaskRequest)
 		} else {
 			return nil, mapPgErr(err)
 		}
+	} else if err := upsertTaskResultTx(ctx, tx, t, t.AssignedAgentID, req.Result, req.Artifacts); err != nil {
+		return nil, err
 	}
 
 	// If the task was part of a chain, check if all tasks in that chain are done
 	if t.ChainID != "" {
-		var inProgressTasksInChain int
-		err := tx.QueryRow(ctx, `
-			select count(id) from public.tasks
-			where chain_id = $1::uuid
-			and status != 'done' and status != 'failed'
-		`, t.ChainID).Scan(&inProgressTasksInChain)
+		// Finishing a task may leave compensation tasks with nothing to compensate.
+		chain, err := settleChainTasksTx(ctx, tx, t.ChainID)
 		if err != nil {
-			return nil, mapPgErr(err)
+			return nil, err
 		}
-
-		if inProgressTasksInChain == 0 {
-			// All tasks in the chain are done, update chain status
-			_, err := tx.Exec(ctx, `
-				update public.chains
-				set status = $1, updated_at = now()
-				where id = $2::uuid
-			`, model.ChainStatusDone, t.ChainID)
+		if !store.HaltsOnFailure(chain) {
+			// Failures did not stop the chain, so its final status depends on them.
+			if err := s.reevaluateChainStatusTx(ctx, tx, t.ChainID, false); err != nil {
+				return nil, err
+			}
+		} else {
+			var inProgressTasksInChain int
+			err := tx.QueryRow(ctx, `
+				select count(id) from public.tasks
+				where chain_id = $1::uuid
+				and status not in ('done', 'failed', 'dead_letter', 'cancelled')
+			`, t.ChainID).Scan(&inProgressTasksInChain)
 			if err != nil {
 				return nil, mapPgErr(err)
 			}
+
+			if inProgressTasksInChain == 0 {
+				// All tasks in the chain are done, update chain status
+				_, err := tx.Exec(ctx, `
+					update public.chains
+					set status = $1, updated_at = now()
+					where id = $2::uuid
+				`, model.ChainStatusDone, t.ChainID)
+				if err != nil {
+					return nil, mapPgErr(err)
+				}
+			}
 		}
 	}
 
@@ -1142,6 +1558,9 @@ func (s *Store) FailTask(ctx context.Context, req store.FailTaskRequest) (*model
 	if strings.TrimSpace(req.TaskID) == "" {
 		return nil, errors.New("task_id_required")
 	}
+	if err := store.ValidateTaskResult(req.Artifacts); err != nil {
+		return nil, err
+	}
 
 	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -1149,104 +1568,96 @@ func (s *Store) FailTask(ctx context.Context, req store.FailTaskRequest) (*model
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	query := `
-		update public.tasks
-		set status = 'failed',
-		    done_at = null,
-		    updated_at = now()
-		where id = $1::uuid
-	`
-	args := []any{req.TaskID}
-	if strings.TrimSpace(req.AgentID) != "" {
-		args = append(args, req.AgentID)
-		query += fmt.Sprintf(" and assigned_agent_id = $%d::uuid", len(args))
-	}
-	query += " and status = 'in_progress'"
-	query += `
-		returning id::text, channel_id::text, coalesce(chain_id::text, ''), coalesce(sequence, 0), title, coalesce(description, ''), coalesce(type, ''), status, priority,
-		          coalesce(assigned_agent_id::text, ''), coalesce(execution_mode, ''), created_at, claimed_at, done_at, updated_at
-	`
-
 	var t model.Task
-	err = tx.QueryRow(ctx, query, args...).Scan(
-		&t.ID,
-		&t.ChannelID,
-		&t.ChainID,
-		&t.Sequence,
-		&t.Title,
-		&t.Description,
-		&t.Type,
-		&t.Status,
-		&t.Priority,
-		&t.AssignedAgentID,
-		&t.ExecutionMode,
-		&t.CreatedAt,
-		&t.ClaimedAt,
-		&t.DoneAt,
-		&t.UpdatedAt,
-	)
+	err = scanTask(tx.QueryRow(ctx, `
+		select `+taskColumns+`
+		from public.tasks
+		where id = $1::uuid
+		for update
+	`, req.TaskID), &t)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			// Either task doesn't exist, agent mismatch, or already failed.
-			var existing model.Task
-			errGet := tx.QueryRow(ctx, `
-				select id::text, channel_id::text, coalesce(chain_id::text, ''), coalesce(sequence, 0), title, coalesce(description, ''), coalesce(type, ''), status, priority,
-				       coalesce(assigned_agent_id::text, ''), coalesce(execution_mode, ''), created_at, claimed_at, done_at, updated_at
-				from public.tasks
-				where id = $1::uuid
-			`, req.TaskID).Scan(
-				&existing.ID,
-				&existing.ChannelID,
-				&existing.ChainID,
-				&existing.Sequence,
-				&existing.Title,
-				&existing.Description,
-				&existing.Type,
-				&existing.Status,
-				&existing.Priority,
-				&existing.AssignedAgentID,
-				&existing.ExecutionMode,
-				&existing.CreatedAt,
-				&existing.ClaimedAt,
-				&existing.DoneAt,
-				&existing.UpdatedAt,
-			)
-			if errGet != nil {
-				if errors.Is(errGet, pgx.ErrNoRows) {
-					return nil, store.ErrNotFound
-				}
-				return nil, mapPgErr(errGet)
-			}
+			return nil, store.ErrNotFound
+		}
+		return nil, mapPgErr(err)
+	}
 
-			if strings.TrimSpace(req.AgentID) != "" && existing.AssignedAgentID != strings.TrimSpace(req.AgentID) {
-				return nil, store.ErrConflict
-			}
-			if existing.Status != model.TaskStatusFailed {
-				return nil, store.ErrConflict
-			}
-			t = existing
-		} else {
+	if strings.TrimSpace(req.AgentID) != "" && t.AssignedAgentID != strings.TrimSpace(req.AgentID) {
+		return nil, store.ErrConflict
+	}
+
+	assignedAgentID := t.AssignedAgentID
+	switch t.Status {
+	case model.TaskStatusFailed, model.TaskStatusDeadLetter:
+		// idempotent: already failed; no state changes.
+	case model.TaskStatusInProgress:
+		var ch model.Channel
+		err := tx.QueryRow(ctx, `
+			select retry_max_attempts, retry_backoff_seconds
+			from public.channels
+			where id = $1::uuid
+		`, t.ChannelID).Scan(&ch.RetryMaxAttempts, &ch.RetryBackoffSeconds)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 			return nil, mapPgErr(err)
 		}
-	}
 
-	// If the task was part of a chain, update chain status to failed
-	if t.ChainID != "" {
-		_, err := tx.Exec(ctx, `
-			update public.chains
-			set status = $1, updated_at = now()
-			where id = $2::uuid
-		`, model.ChainStatusFailed, t.ChainID)
+		maxAttempts, backoffSeconds := store.RetryPolicy(t, ch)
+		status, nextEligibleAt := store.RetryOutcome(t.Attempts, maxAttempts, backoffSeconds, time.Now().UTC())
+
+		// A retried task goes back to the queue for any agent; terminal states keep the assignee.
+		err = scanTask(tx.QueryRow(ctx, `
+			update public.tasks
+			set status = $2::text,
+			    last_failure_reason = nullif($3, ''),
+			    next_eligible_at = $4,
+			    assigned_agent_id = case when $2::text = 'queued' then null else assigned_agent_id end,
+			    claimed_at = case when $2::text = 'queued' then null else claimed_at end,
+			    done_at = null,
+			    lease_expires_at = null,
+			    updated_at = now()
+			where id = $1::uuid
+			returning `+taskColumns+`
+		`, req.TaskID, string(status), req.Reason, nextEligibleAt), &t)
 		if err != nil {
 			return nil, mapPgErr(err)
 		}
+		if err := upsertTaskResultTx(ctx, tx, t, assignedAgentID, req.Result, req.Artifacts); err != nil {
+			return nil, err
+		}
+
+		switch t.Status {
+		case model.TaskStatusQueued:
+			payload := map[string]any{"reason": req.Reason, "attempts": t.Attempts, "max_attempts": maxAttempts}
+			if t.NextEligibleAt != nil {
+				payload["next_eligible_at"] = t.NextEligibleAt.Format(time.RFC3339Nano)
+			}
+			err = insertEventTx(ctx, tx, assignedAgentID, t.ID, store.EventTypeTaskRetryScheduled, payload)
+		case model.TaskStatusDeadLetter:
+			err = insertEventTx(ctx, tx, assignedAgentID, t.ID, store.EventTypeTaskDeadLettered, map[string]any{
+				"reason":   req.Reason,
+				"attempts": t.Attempts,
+			})
+		}
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, store.ErrConflict
+	}
+
+	// If the task failed for good, apply the chain's on_failure policy. A scheduled retry
+	// keeps the chain running.
+	if t.ChainID != "" && t.Status != model.TaskStatusQueued {
+		if err := s.failChainTx(ctx, tx, t.ChainID); err != nil {
+			return nil, err
+		}
 	}
 
 	// Clear agent's current_task_id (task failed)
 	// NOTE: Do NOT update claude_status - heartbeat is sole source of truth
 	agentID := strings.TrimSpace(req.AgentID)
 	if agentID == "" {
-		agentID = strings.TrimSpace(t.AssignedAgentID)
+		agentID = strings.TrimSpace(assignedAgentID)
 	}
 	if agentID != "" {
 		_, _ = tx.Exec(ctx, `
@@ -1264,6 +1675,271 @@ func (s *Store) FailTask(ctx context.Context, req store.FailTaskRequest) (*model
 	return &t, nil
 }
 
+func (s *Store) ResubmitTask(ctx context.Context, taskID string) (*model.Task, error) {
+	if strings.TrimSpace(taskID) == "" {
+		return nil, errors.New("task_id_required")
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var t model.Task
+	err = scanTask(tx.QueryRow(ctx, `
+		update public.tasks
+		set status = 'queued',
+		    attempts = 0,
+		    next_eligible_at = null,
+		    assigned_agent_id = null,
+		    claimed_at = null,
+		    done_at = null,
+		    lease_expires_at = null,
+		    updated_at = now()
+		where id = $1::uuid
+		  and status in ('dead_letter', 'failed')
+		returning `+taskColumns+`
+	`, taskID), &t)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			var exists bool
+			if errGet := tx.QueryRow(ctx, `
+				select exists(select 1 from public.tasks where id = $1::uuid)
+			`, taskID).Scan(&exists); errGet != nil {
+				return nil, mapPgErr(errGet)
+			}
+			if !exists {
+				return nil, store.ErrNotFound
+			}
+			return nil, store.ErrConflict
+		}
+		return nil, mapPgErr(err)
+	}
+
+	// Chain leaves failed once no failed/dead_letter task remains.
+	if t.ChainID != "" {
+		if err := s.reevaluateChainStatusTx(ctx, tx, t.ChainID, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, mapPgErr(err)
+	}
+
+	return &t, nil
+}
+
+func (s *Store) RenewTaskLease(ctx context.Context, req store.RenewTaskLeaseRequest) (*model.Task, error) {
+	if strings.TrimSpace(req.TaskID) == "" {
+		return nil, errors.New("task_id_required")
+	}
+	if strings.TrimSpace(req.AgentID) == "" {
+		return nil, errors.New("agent_id_required")
+	}
+	if req.LeaseSeconds <= 0 {
+		return nil, errors.New("lease_seconds_required")
+	}
+
+	// Only the agent currently holding the claim may extend it.
+	var t model.Task
+	err := scanTask(s.pool.QueryRow(ctx, `
+		update public.tasks
+		set lease_expires_at = now() + $3::int * interval '1 second',
+		    updated_at = now()
+		where id = $1::uuid
+		  and assigned_agent_id = $2::uuid
+		  and status = 'in_progress'
+		returning `+taskColumns+`
+	`, req.TaskID, req.AgentID, req.LeaseSeconds), &t)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			var exists bool
+			_ = s.pool.QueryRow(ctx, `select exists(select 1 from public.tasks where id = $1::uuid)`, req.TaskID).Scan(&exists)
+			if !exists {
+				return nil, store.ErrNotFound
+			}
+			return nil, store.ErrConflict
+		}
+		return nil, mapPgErr(err)
+	}
+
+	return &t, nil
+}
+
+func (s *Store) RequeueExpiredTasks(ctx context.Context, now time.Time) ([]model.Task, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	type expiredLease struct {
+		taskID    string
+		agentID   string
+		expiredAt time.Time
+	}
+
+	rows, err := tx.Query(ctx, `
+		select id::text, coalesce(assigned_agent_id::text, ''), lease_expires_at
+		from public.tasks
+		where status = 'in_progress'
+		  and lease_expires_at < $1
+		for update skip locked
+	`, now)
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	var expired []expiredLease
+	for rows.Next() {
+		var e expiredLease
+		if err := rows.Scan(&e.taskID, &e.agentID, &e.expiredAt); err != nil {
+			rows.Close()
+			return nil, mapPgErr(err)
+		}
+		expired = append(expired, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, mapPgErr(err)
+	}
+
+	var out []model.Task
+	for _, e := range expired {
+		t, err := s.requeueTaskTx(ctx, tx, e.taskID, e.agentID)
+		if err != nil {
+			return nil, err
+		}
+		if err := insertEventTx(ctx, tx, e.agentID, e.taskID, store.EventTypeTaskLeaseExpired, map[string]any{
+			"reason":           "lease_expired",
+			"lease_expires_at": e.expiredAt.UTC().Format(time.RFC3339Nano),
+		}); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, mapPgErr(err)
+	}
+
+	return out, nil
+}
+
+func (s *Store) RequeueTask(ctx context.Context, req store.RequeueTaskRequest) (*model.Task, error) {
+	if strings.TrimSpace(req.TaskID) == "" {
+		return nil, errors.New("task_id_required")
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var status model.TaskStatus
+	var agentID string
+	err = tx.QueryRow(ctx, `
+		select status, coalesce(assigned_agent_id::text, '')
+		from public.tasks
+		where id = $1::uuid
+		for update
+	`, req.TaskID).Scan(&status, &agentID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrNotFound
+		}
+		return nil, mapPgErr(err)
+	}
+	if status != model.TaskStatusInProgress {
+		return nil, store.ErrConflict
+	}
+	if strings.TrimSpace(req.AgentID) != "" && agentID != req.AgentID {
+		return nil, store.ErrConflict
+	}
+
+	t, err := s.requeueTaskTx(ctx, tx, req.TaskID, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertEventTx(ctx, tx, agentID, req.TaskID, store.EventTypeTaskRequeued, map[string]any{
+		"reason": req.Reason,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, mapPgErr(err)
+	}
+
+	return &t, nil
+}
+
+// requeueTaskTx returns an in_progress task to the queue, clears the agent's
+// current_task_id and releases the agent's ownership of the task's chain. The claim is
+// given back to the retry budget: the task did not fail.
+func (s *Store) requeueTaskTx(ctx context.Context, tx pgx.Tx, taskID string, agentID string) (model.Task, error) {
+	var t model.Task
+	err := scanTask(tx.QueryRow(ctx, `
+		update public.tasks
+		set status = 'queued',
+		    attempts = greatest(attempts - 1, 0),
+		    assigned_agent_id = null,
+		    claimed_at = null,
+		    lease_expires_at = null,
+		    updated_at = now()
+		where id = $1::uuid
+		returning `+taskColumns+`
+	`, taskID), &t)
+	if err != nil {
+		return model.Task{}, mapPgErr(err)
+	}
+
+	if agentID != "" {
+		_, err = tx.Exec(ctx, `
+			update public.agents
+			set current_task_id = null, updated_at = now()
+			where id = $1::uuid and current_task_id = $2::uuid
+		`, agentID, taskID)
+		if err != nil {
+			return model.Task{}, mapPgErr(err)
+		}
+	}
+
+	if t.ChainID != "" {
+		_, err = tx.Exec(ctx, `
+			update public.chains
+			set owner_agent_id = null, updated_at = now()
+			where id = $1::uuid and owner_agent_id = nullif($2, '')::uuid
+		`, t.ChainID, agentID)
+		if err != nil {
+			return model.Task{}, mapPgErr(err)
+		}
+		if err := s.reevaluateChainStatusTx(ctx, tx, t.ChainID, false); err != nil {
+			return model.Task{}, err
+		}
+	}
+
+	return t, nil
+}
+
+// insertEventTx records a coordinator-generated event attributed to agentID.
+func insertEventTx(ctx context.Context, tx pgx.Tx, agentID, taskID, typ string, payload map[string]any) error {
+	if agentID == "" {
+		return nil
+	}
+	payloadJSON, _ := json.Marshal(payload)
+	_, err := tx.Exec(ctx, `
+		insert into public.events (agent_id, task_id, type, payload)
+		values ($1::uuid, nullif($2, '')::uuid, $3, $4::jsonb)
+	`, agentID, taskID, typ, string(payloadJSON))
+	if err != nil {
+		return mapPgErr(err)
+	}
+	return nil
+}
+
 func (s *Store) CreateEvent(ctx context.Context, e model.Event) (model.Event, error) {
 	if strings.TrimSpace(e.AgentID) == "" {
 		return model.Event{}, errors.New("agent_id_required")
@@ -1328,6 +2004,10 @@ func (s *Store) ListEvents(ctx context.Context, f store.EventFilter) ([]model.Ev
 		args = append(args, f.TaskID)
 		where = append(where, fmt.Sprintf("e.task_id = $%d::uuid", len(args)))
 	}
+	if len(f.IDs) > 0 {
+		args = append(args, f.IDs)
+		where = append(where, fmt.Sprintf("e.id = any($%d::uuid[])", len(args)))
+	}
 	if len(where) > 0 {
 		query += " where " + strings.Join(where, " and ")
 	}
@@ -1356,6 +2036,43 @@ func (s *Store) ListEvents(ctx context.Context, f store.EventFilter) ([]model.Ev
 	return out, nil
 }
 
+func (s *Store) ListEventArtifactIDs(ctx context.Context) ([]string, error) {
+	// Mirrors store.EventArtifactIDs: a string under artifact_id and the strings in an
+	// artifact_ids array.
+	rows, err := s.pool.Query(ctx, `
+		select distinct id from (
+		  select btrim(payload->>'artifact_id') as id
+		  from public.events
+		  where jsonb_typeof(payload->'artifact_id') = 'string'
+		  union all
+		  select btrim(v #>> '{}')
+		  from public.events e,
+		       jsonb_array_elements(case when jsonb_typeof(e.payload->'artifact_ids') = 'array'
+		                                 then e.payload->'artifact_ids' else '[]'::jsonb end) v
+		  where jsonb_typeof(v) = 'string'
+		) refs
+		where id <> ''
+		order by id
+	`)
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer rows.Close()
+
+	out := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, mapPgErr(err)
+		}
+		out = append(out, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, mapPgErr(err)
+	}
+	return out, nil
+}
+
 func (s *Store) PurgeEventsBefore(ctx context.Context, before time.Time) (int, error) {
 	var n int
 	err := s.pool.QueryRow(ctx, `
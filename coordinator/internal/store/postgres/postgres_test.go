@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 
 	"clwclw-monitor/coordinator/internal/model"
@@ -241,6 +243,8 @@ func setupTestDB(t *testing.T) (*Store, func()) {
 	`)
 	require.NoError(t, err)
 
+	applyRepoMigrations(t, pool)
+
 	s := &Store{pool: pool}
 
 	return s, func() {
@@ -249,6 +253,49 @@ func setupTestDB(t *testing.T) (*Store, func()) {
 	}
 }
 
+// migrationsDir is the repo's supabase migrations directory, relative to this package.
+var migrationsDir = filepath.Join("..", "..", "..", "..", "supabase", "migrations")
+
+// applyRepoMigrations brings the fixture schema above up to date: it adds the columns
+// introduced by migrations that are not reproduced here, then runs the repo migrations
+// from 0017 on, so claim_task() is the real function rather than a copy.
+func applyRepoMigrations(t *testing.T, pool *pgxpool.Pool) {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, `
+		create table if not exists public.users (
+			id uuid primary key default gen_random_uuid(),
+			username text not null,
+			password_hash text not null,
+			created_at timestamptz not null default now(),
+			updated_at timestamptz not null default now()
+		);
+		alter table public.agents add column if not exists user_id uuid null;
+		alter table public.channels add column if not exists user_id uuid null;
+		alter table public.chains add column if not exists user_id uuid null;
+		alter table public.chains add column if not exists owner_agent_id uuid null references public.agents(id) on delete set null;
+		alter table public.tasks add column if not exists user_id uuid null;
+		alter table public.tasks add column if not exists type text null;
+		alter table public.tasks add column if not exists execution_mode text null;
+		alter table public.tasks add column if not exists agent_session_request_token text null;
+	`)
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(migrationsDir, "*.sql"))
+	require.NoError(t, err)
+	sort.Strings(files)
+	for _, f := range files {
+		if filepath.Base(f) < "0017" {
+			continue
+		}
+		sql, err := os.ReadFile(f)
+		require.NoError(t, err)
+		_, err = pool.Exec(ctx, string(sql))
+		require.NoError(t, err, "apply %s", filepath.Base(f))
+	}
+}
+
 func TestPostgresStore_ChainCRUD(t *testing.T) {
 	s, teardown := setupTestDB(t)
 	defer teardown()
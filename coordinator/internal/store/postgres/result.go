@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// taskResultColumns is the select list shared by every task result query; keep in sync with scanTaskResult.
+const taskResultColumns = `r.task_id::text, coalesce(r.chain_id::text, ''), coalesce(r.agent_id::text, ''), r.status,
+		       r.attempt, r.result, r.artifacts, r.created_at`
+
+func scanTaskResult(row pgx.Row, r *model.TaskResult) error {
+	var resultJSON, artifactsJSON []byte
+	if err := row.Scan(
+		&r.TaskID,
+		&r.ChainID,
+		&r.AgentID,
+		&r.Status,
+		&r.Attempt,
+		&resultJSON,
+		&artifactsJSON,
+		&r.CreatedAt,
+	); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(resultJSON, &r.Result); err != nil {
+		return err
+	}
+	return json.Unmarshal(artifactsJSON, &r.Artifacts)
+}
+
+func (s *Store) GetTaskResult(ctx context.Context, taskID string) (model.TaskResult, error) {
+	var out model.TaskResult
+	err := scanTaskResult(s.pool.QueryRow(ctx, `
+		select `+taskResultColumns+`
+		from public.task_results r
+		where r.task_id = $1::uuid
+	`, taskID), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.TaskResult{}, store.ErrNotFound
+		}
+		return model.TaskResult{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) ListTaskResults(ctx context.Context, chainID string) ([]model.TaskResult, error) {
+	rows, err := s.pool.Query(ctx, `
+		select `+taskResultColumns+`
+		from public.task_results r
+		join public.tasks t on t.id = r.task_id
+		where r.chain_id = $1::uuid
+		order by t.sequence asc, t.created_at asc
+	`, chainID)
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer rows.Close()
+
+	out := []model.TaskResult{}
+	for rows.Next() {
+		var r model.TaskResult
+		if err := scanTaskResult(rows, &r); err != nil {
+			return nil, mapPgErr(err)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) ListTaskResultArtifactIDs(ctx context.Context) ([]string, error) {
+	// Mirrors store.TaskArtifactBlobID: the last path segment of a url ending in
+	// /v1/artifacts/{id}, before any query or fragment.
+	rows, err := s.pool.Query(ctx, `
+		select distinct id from (
+		  select substring(btrim(a->>'url') from '/v1/artifacts/([^/?#]+)(?:[?#].*)?$') as id
+		  from public.task_results r,
+		       jsonb_array_elements(case when jsonb_typeof(r.artifacts) = 'array'
+		                                 then r.artifacts else '[]'::jsonb end) a
+		  where jsonb_typeof(a->'url') = 'string'
+		) refs
+		where id is not null and id <> ''
+		order by id
+	`)
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer rows.Close()
+
+	out := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, mapPgErr(err)
+		}
+		out = append(out, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, mapPgErr(err)
+	}
+	return out, nil
+}
+
+// upsertTaskResultTx stores the result reported for t's current attempt, replacing any
+// earlier one. Nothing is stored when the report carries no result or artifacts.
+func upsertTaskResultTx(ctx context.Context, tx pgx.Tx, t model.Task, agentID string, result map[string]any, artifacts []model.TaskArtifact) error {
+	if !store.HasTaskResult(result, artifacts) {
+		return nil
+	}
+	if result == nil {
+		result = map[string]any{}
+	}
+	if artifacts == nil {
+		artifacts = []model.TaskArtifact{}
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	artifactsJSON, err := json.Marshal(artifacts)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		insert into public.task_results (task_id, chain_id, agent_id, status, attempt, result, artifacts)
+		values ($1::uuid, nullif($2, '')::uuid, nullif($3, '')::uuid, $4, $5, $6::jsonb, $7::jsonb)
+		on conflict (task_id) do update
+		set chain_id = excluded.chain_id,
+		    agent_id = excluded.agent_id,
+		    status = excluded.status,
+		    attempt = excluded.attempt,
+		    result = excluded.result,
+		    artifacts = excluded.artifacts,
+		    created_at = now()
+	`, t.ID, t.ChainID, agentID, string(t.Status), t.Attempts, string(resultJSON), string(artifactsJSON))
+	return mapPgErr(err)
+}
@@ -0,0 +1,232 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// scheduleColumns is the select list shared by every schedule query; keep in sync with scanSchedule.
+const scheduleColumns = `id::text, coalesce(user_id::text, ''), channel_id::text, name, coalesce(description, ''),
+		       cron, timezone, tasks, enabled, last_run_at, coalesce(last_chain_id::text, ''), next_run_at,
+		       created_at, updated_at`
+
+func scanSchedule(row pgx.Row, sc *model.Schedule) error {
+	var tasksJSON []byte
+	if err := row.Scan(
+		&sc.ID,
+		&sc.UserID,
+		&sc.ChannelID,
+		&sc.Name,
+		&sc.Description,
+		&sc.Cron,
+		&sc.Timezone,
+		&tasksJSON,
+		&sc.Enabled,
+		&sc.LastRunAt,
+		&sc.LastChainID,
+		&sc.NextRunAt,
+		&sc.CreatedAt,
+		&sc.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	return json.Unmarshal(tasksJSON, &sc.Tasks)
+}
+
+func validateSchedule(sc model.Schedule) error {
+	if strings.TrimSpace(sc.Name) == "" {
+		return errors.New("name_required")
+	}
+	if strings.TrimSpace(sc.Cron) == "" {
+		return errors.New("cron_required")
+	}
+	return store.ValidateScheduleTasks(sc.Tasks)
+}
+
+func (s *Store) CreateSchedule(ctx context.Context, sc model.Schedule) (model.Schedule, error) {
+	if strings.TrimSpace(sc.ChannelID) == "" {
+		return model.Schedule{}, errors.New("channel_id_required")
+	}
+	if err := validateSchedule(sc); err != nil {
+		return model.Schedule{}, err
+	}
+	if _, err := s.GetChannel(ctx, sc.ChannelID); err != nil {
+		return model.Schedule{}, err
+	}
+	tasksJSON, err := json.Marshal(sc.Tasks)
+	if err != nil {
+		return model.Schedule{}, err
+	}
+
+	var out model.Schedule
+	err = scanSchedule(s.pool.QueryRow(ctx, `
+		insert into public.schedules (user_id, channel_id, name, description, cron, timezone, tasks, enabled, next_run_at)
+		values (nullif($1, '')::uuid, $2::uuid, $3, nullif($4, ''), $5, coalesce(nullif($6, ''), 'UTC'), $7::jsonb, $8, $9)
+		returning `+scheduleColumns+`
+	`, sc.UserID, sc.ChannelID, sc.Name, sc.Description, sc.Cron, sc.Timezone, string(tasksJSON), sc.Enabled, sc.NextRunAt), &out)
+	if err != nil {
+		return model.Schedule{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) GetSchedule(ctx context.Context, id string) (model.Schedule, error) {
+	var out model.Schedule
+	err := scanSchedule(s.pool.QueryRow(ctx, `
+		select `+scheduleColumns+`
+		from public.schedules
+		where id = $1::uuid
+	`, id), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Schedule{}, store.ErrNotFound
+		}
+		return model.Schedule{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) ListSchedules(ctx context.Context, userID string) ([]model.Schedule, error) {
+	query := `
+		select ` + scheduleColumns + `
+		from public.schedules
+	`
+	var args []any
+	if strings.TrimSpace(userID) != "" {
+		query += " where user_id = $1::uuid"
+		args = append(args, userID)
+	}
+	query += " order by created_at asc"
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer rows.Close()
+
+	var out []model.Schedule
+	for rows.Next() {
+		var sc model.Schedule
+		if err := scanSchedule(rows, &sc); err != nil {
+			return nil, mapPgErr(err)
+		}
+		out = append(out, sc)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) UpdateSchedule(ctx context.Context, sc model.Schedule) (model.Schedule, error) {
+	if err := validateSchedule(sc); err != nil {
+		return model.Schedule{}, err
+	}
+	tasksJSON, err := json.Marshal(sc.Tasks)
+	if err != nil {
+		return model.Schedule{}, err
+	}
+
+	var out model.Schedule
+	err = scanSchedule(s.pool.QueryRow(ctx, `
+		update public.schedules
+		set name = $2,
+		    description = nullif($3, ''),
+		    cron = $4,
+		    timezone = coalesce(nullif($5, ''), 'UTC'),
+		    tasks = $6::jsonb,
+		    enabled = $7,
+		    next_run_at = $8
+		where id = $1::uuid
+		returning `+scheduleColumns+`
+	`, sc.ID, sc.Name, sc.Description, sc.Cron, sc.Timezone, string(tasksJSON), sc.Enabled, sc.NextRunAt), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Schedule{}, store.ErrNotFound
+		}
+		return model.Schedule{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) DeleteSchedule(ctx context.Context, id string) error {
+	cmdTag, err := s.pool.Exec(ctx, `
+		delete from public.schedules
+		where id = $1::uuid
+	`, id)
+	if err != nil {
+		return mapPgErr(err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) RecordScheduleRun(ctx context.Context, run model.ScheduleRun, nextRunAt *time.Time) (model.ScheduleRun, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return model.ScheduleRun{}, mapPgErr(err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	cmdTag, err := tx.Exec(ctx, `
+		update public.schedules
+		set last_run_at = $2,
+		    last_chain_id = coalesce(nullif($3, '')::uuid, last_chain_id),
+		    next_run_at = $4
+		where id = $1::uuid
+	`, run.ScheduleID, run.ScheduledFor, run.ChainID, nextRunAt)
+	if err != nil {
+		return model.ScheduleRun{}, mapPgErr(err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return model.ScheduleRun{}, store.ErrNotFound
+	}
+
+	out := run
+	if err := tx.QueryRow(ctx, `
+		insert into public.schedule_runs (schedule_id, status, chain_id, reason, scheduled_for)
+		values ($1::uuid, $2, nullif($3, '')::uuid, nullif($4, ''), $5)
+		returning id::text, created_at
+	`, run.ScheduleID, string(run.Status), run.ChainID, run.Reason, run.ScheduledFor).Scan(&out.ID, &out.CreatedAt); err != nil {
+		return model.ScheduleRun{}, mapPgErr(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.ScheduleRun{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) ListScheduleRuns(ctx context.Context, scheduleID string, limit int) ([]model.ScheduleRun, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.pool.Query(ctx, `
+		select id::text, schedule_id::text, status, coalesce(chain_id::text, ''), coalesce(reason, ''), scheduled_for, created_at
+		from public.schedule_runs
+		where schedule_id = $1::uuid
+		order by created_at desc
+		limit $2
+	`, scheduleID, limit)
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer rows.Close()
+
+	var out []model.ScheduleRun
+	for rows.Next() {
+		var run model.ScheduleRun
+		if err := rows.Scan(&run.ID, &run.ScheduleID, &run.Status, &run.ChainID, &run.Reason, &run.ScheduledFor, &run.CreatedAt); err != nil {
+			return nil, mapPgErr(err)
+		}
+		out = append(out, run)
+	}
+	return out, rows.Err()
+}
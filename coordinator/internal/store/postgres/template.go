@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// templateColumns is the select list shared by every template query; keep in sync with scanTemplate.
+const templateColumns = `id::text, coalesce(user_id::text, ''), name, coalesce(description, ''),
+		       coalesce(chain_name, ''), coalesce(chain_description, ''), coalesce(chain_on_failure, ''),
+		       params, tasks, created_at, updated_at`
+
+func scanTemplate(row pgx.Row, tpl *model.Template) error {
+	var paramsJSON, tasksJSON []byte
+	if err := row.Scan(
+		&tpl.ID,
+		&tpl.UserID,
+		&tpl.Name,
+		&tpl.Description,
+		&tpl.ChainName,
+		&tpl.ChainDescription,
+		&tpl.ChainOnFailure,
+		&paramsJSON,
+		&tasksJSON,
+		&tpl.CreatedAt,
+		&tpl.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(paramsJSON, &tpl.Params); err != nil {
+		return err
+	}
+	return json.Unmarshal(tasksJSON, &tpl.Tasks)
+}
+
+func marshalTemplate(tpl model.Template) (paramsJSON string, tasksJSON string, err error) {
+	params := tpl.Params
+	if params == nil {
+		params = []model.TemplateParam{}
+	}
+	p, err := json.Marshal(params)
+	if err != nil {
+		return "", "", err
+	}
+	t, err := json.Marshal(tpl.Tasks)
+	if err != nil {
+		return "", "", err
+	}
+	return string(p), string(t), nil
+}
+
+func (s *Store) CreateTemplate(ctx context.Context, tpl model.Template) (model.Template, error) {
+	if err := store.ValidateTemplate(tpl); err != nil {
+		return model.Template{}, err
+	}
+	paramsJSON, tasksJSON, err := marshalTemplate(tpl)
+	if err != nil {
+		return model.Template{}, err
+	}
+
+	var out model.Template
+	err = scanTemplate(s.pool.QueryRow(ctx, `
+		insert into public.templates (user_id, name, description, chain_name, chain_description, params, tasks, chain_on_failure)
+		values (nullif($1, '')::uuid, $2, nullif($3, ''), nullif($4, ''), nullif($5, ''), $6::jsonb, $7::jsonb, nullif($8, ''))
+		returning `+templateColumns+`
+	`, tpl.UserID, tpl.Name, tpl.Description, tpl.ChainName, tpl.ChainDescription, paramsJSON, tasksJSON, string(tpl.ChainOnFailure)), &out)
+	if err != nil {
+		return model.Template{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) GetTemplate(ctx context.Context, id string) (model.Template, error) {
+	var out model.Template
+	err := scanTemplate(s.pool.QueryRow(ctx, `
+		select `+templateColumns+`
+		from public.templates
+		where id = $1::uuid
+	`, id), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Template{}, store.ErrNotFound
+		}
+		return model.Template{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) ListTemplates(ctx context.Context, userID string) ([]model.Template, error) {
+	query := `
+		select ` + templateColumns + `
+		from public.templates
+	`
+	var args []any
+	if strings.TrimSpace(userID) != "" {
+		query += " where user_id = $1::uuid"
+		args = append(args, userID)
+	}
+	query += " order by created_at asc"
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer rows.Close()
+
+	var out []model.Template
+	for rows.Next() {
+		var tpl model.Template
+		if err := scanTemplate(rows, &tpl); err != nil {
+			return nil, mapPgErr(err)
+		}
+		out = append(out, tpl)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) UpdateTemplate(ctx context.Context, tpl model.Template) (model.Template, error) {
+	if err := store.ValidateTemplate(tpl); err != nil {
+		return model.Template{}, err
+	}
+	paramsJSON, tasksJSON, err := marshalTemplate(tpl)
+	if err != nil {
+		return model.Template{}, err
+	}
+
+	var out model.Template
+	err = scanTemplate(s.pool.QueryRow(ctx, `
+		update public.templates
+		set name = $2,
+		    description = nullif($3, ''),
+		    chain_name = nullif($4, ''),
+		    chain_description = nullif($5, ''),
+		    params = $6::jsonb,
+		    tasks = $7::jsonb,
+		    chain_on_failure = nullif($8, '')
+		where id = $1::uuid
+		returning `+templateColumns+`
+	`, tpl.ID, tpl.Name, tpl.Description, tpl.ChainName, tpl.ChainDescription, paramsJSON, tasksJSON, string(tpl.ChainOnFailure)), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Template{}, store.ErrNotFound
+		}
+		return model.Template{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) DeleteTemplate(ctx context.Context, id string) error {
+	cmdTag, err := s.pool.Exec(ctx, `
+		delete from public.templates
+		where id = $1::uuid
+	`, id)
+	if err != nil {
+		return mapPgErr(err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
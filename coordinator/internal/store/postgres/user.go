@@ -16,11 +16,12 @@ func (s *Store) CreateUser(ctx context.Context, u model.User) (model.User, error
 	err := s.pool.QueryRow(ctx, `
 		insert into public.users (username, password_hash)
 		values ($1, $2)
-		returning id::text, username, password_hash, created_at, updated_at
+		returning id::text, username, password_hash, max_in_flight, created_at, updated_at
 	`, u.Username, u.PasswordHash).Scan(
 		&out.ID,
 		&out.Username,
 		&out.PasswordHash,
+		&out.MaxInFlight,
 		&out.CreatedAt,
 		&out.UpdatedAt,
 	)
@@ -37,13 +38,14 @@ func (s *Store) CreateUser(ctx context.Context, u model.User) (model.User, error
 func (s *Store) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
 	var u model.User
 	err := s.pool.QueryRow(ctx, `
-		select id::text, username, password_hash, created_at, updated_at
+		select id::text, username, password_hash, max_in_flight, created_at, updated_at
 		from public.users
 		where lower(username) = lower($1)
 	`, username).Scan(
 		&u.ID,
 		&u.Username,
 		&u.PasswordHash,
+		&u.MaxInFlight,
 		&u.CreatedAt,
 		&u.UpdatedAt,
 	)
@@ -55,3 +57,23 @@ func (s *Store) GetUserByUsername(ctx context.Context, username string) (*model.
 	}
 	return &u, nil
 }
+
+func (s *Store) SetUserMaxInFlight(ctx context.Context, userID string, limit *int) (*model.User, error) {
+	if limit != nil && *limit < 0 {
+		return nil, errors.New("max_in_flight_invalid")
+	}
+	var u model.User
+	err := s.pool.QueryRow(ctx, `
+		update public.users
+		set max_in_flight = $2, updated_at = now()
+		where id = $1::uuid
+		returning id::text, username, password_hash, max_in_flight, created_at, updated_at
+	`, userID, limit).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.MaxInFlight, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrNotFound
+		}
+		return nil, mapPgErr(err)
+	}
+	return &u, nil
+}
@@ -0,0 +1,311 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// webhookColumns is the select list shared by every webhook query; keep in sync with scanWebhook.
+const webhookColumns = `id::text, coalesce(user_id::text, ''), name, url, secret, topics, enabled, created_at, updated_at`
+
+func scanWebhook(row pgx.Row, w *model.Webhook) error {
+	return row.Scan(
+		&w.ID,
+		&w.UserID,
+		&w.Name,
+		&w.URL,
+		&w.Secret,
+		&w.Topics,
+		&w.Enabled,
+		&w.CreatedAt,
+		&w.UpdatedAt,
+	)
+}
+
+// webhookDeliveryColumns is the select list shared by every delivery query; keep in sync
+// with scanWebhookDelivery.
+const webhookDeliveryColumns = `id::text, webhook_id::text, topic, payload, status, attempts, next_attempt_at,
+		       coalesce(response_code, 0), coalesce(error, ''),
+		       coalesce(replay_of::text, ''), delivered_at, created_at, updated_at`
+
+func scanWebhookDelivery(row pgx.Row, d *model.WebhookDelivery) error {
+	var status string
+	var payloadJSON []byte
+	if err := row.Scan(
+		&d.ID,
+		&d.WebhookID,
+		&d.Topic,
+		&payloadJSON,
+		&status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.ResponseCode,
+		&d.Error,
+		&d.ReplayOf,
+		&d.DeliveredAt,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	d.Status = model.WebhookDeliveryStatus(status)
+	return json.Unmarshal(payloadJSON, &d.Payload)
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, w model.Webhook) (model.Webhook, error) {
+	w, err := store.NormalizeWebhook(w)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+
+	var out model.Webhook
+	err = scanWebhook(s.pool.QueryRow(ctx, `
+		insert into public.webhooks (user_id, name, url, secret, topics, enabled)
+		values (nullif($1, '')::uuid, $2, $3, $4, $5::text[], $6)
+		returning `+webhookColumns+`
+	`, w.UserID, w.Name, w.URL, w.Secret, w.Topics, w.Enabled), &out)
+	if err != nil {
+		return model.Webhook{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) GetWebhook(ctx context.Context, id string) (model.Webhook, error) {
+	var out model.Webhook
+	err := scanWebhook(s.pool.QueryRow(ctx, `
+		select `+webhookColumns+`
+		from public.webhooks
+		where id = $1::uuid
+	`, id), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Webhook{}, store.ErrNotFound
+		}
+		return model.Webhook{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) ListWebhooks(ctx context.Context, userID string) ([]model.Webhook, error) {
+	query := `
+		select ` + webhookColumns + `
+		from public.webhooks
+	`
+	var args []any
+	if strings.TrimSpace(userID) != "" {
+		query += " where user_id = $1::uuid"
+		args = append(args, userID)
+	}
+	query += " order by created_at asc"
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer rows.Close()
+
+	out := make([]model.Webhook, 0)
+	for rows.Next() {
+		var w model.Webhook
+		if err := scanWebhook(rows, &w); err != nil {
+			return nil, mapPgErr(err)
+		}
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) UpdateWebhook(ctx context.Context, w model.Webhook) (model.Webhook, error) {
+	w, err := store.NormalizeWebhook(w)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+
+	var out model.Webhook
+	err = scanWebhook(s.pool.QueryRow(ctx, `
+		update public.webhooks
+		set name = $2,
+		    url = $3,
+		    secret = $4,
+		    topics = $5::text[],
+		    enabled = $6
+		where id = $1::uuid
+		returning `+webhookColumns+`
+	`, w.ID, w.Name, w.URL, w.Secret, w.Topics, w.Enabled), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Webhook{}, store.ErrNotFound
+		}
+		return model.Webhook{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, id string) error {
+	cmdTag, err := s.pool.Exec(ctx, `
+		delete from public.webhooks
+		where id = $1::uuid
+	`, id)
+	if err != nil {
+		return mapPgErr(err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) CreateWebhookDelivery(ctx context.Context, d model.WebhookDelivery) (model.WebhookDelivery, error) {
+	payloadJSON, err := json.Marshal(d.Payload)
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	if d.Payload == nil {
+		payloadJSON = []byte("{}")
+	}
+
+	var out model.WebhookDelivery
+	err = scanWebhookDelivery(s.pool.QueryRow(ctx, `
+		insert into public.webhook_deliveries (webhook_id, topic, payload, replay_of, next_attempt_at)
+		values ($1::uuid, $2, $3::jsonb, nullif($4, '')::uuid, now())
+		returning `+webhookDeliveryColumns+`
+	`, d.WebhookID, d.Topic, string(payloadJSON), d.ReplayOf), &out)
+	if err != nil {
+		return model.WebhookDelivery{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) GetWebhookDelivery(ctx context.Context, id string) (model.WebhookDelivery, error) {
+	var out model.WebhookDelivery
+	err := scanWebhookDelivery(s.pool.QueryRow(ctx, `
+		select `+webhookDeliveryColumns+`
+		from public.webhook_deliveries
+		where id = $1::uuid
+	`, id), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.WebhookDelivery{}, store.ErrNotFound
+		}
+		return model.WebhookDelivery{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) ListWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]model.WebhookDelivery, error) {
+	query := `
+		select ` + webhookDeliveryColumns + `
+		from public.webhook_deliveries
+		where webhook_id = $1::uuid
+		order by created_at desc, id desc
+	`
+	args := []any{webhookID}
+	if limit > 0 {
+		args = append(args, limit)
+		query += " limit $2"
+	}
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer rows.Close()
+
+	out := make([]model.WebhookDelivery, 0)
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &d); err != nil {
+			return nil, mapPgErr(err)
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	// skip locked: workers on other replicas claim other deliveries instead of waiting.
+	rows, err := s.pool.Query(ctx, `
+		update public.webhook_deliveries
+		set next_attempt_at = $1::timestamptz + make_interval(secs => $2)
+		where id in (
+		  select id
+		  from public.webhook_deliveries
+		  where status = 'pending'
+		    and next_attempt_at <= $1::timestamptz
+		  order by next_attempt_at asc
+		  limit $3
+		  for update skip locked
+		)
+		returning `+webhookDeliveryColumns+`
+	`, now, lease.Seconds(), limit)
+	if err != nil {
+		return nil, mapPgErr(err)
+	}
+	defer rows.Close()
+
+	out := make([]model.WebhookDelivery, 0)
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &d); err != nil {
+			return nil, mapPgErr(err)
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) RecordWebhookAttempt(ctx context.Context, req store.RecordWebhookAttemptRequest) (model.WebhookDelivery, error) {
+	status := store.WebhookAttemptStatus(req)
+	var retryAt *time.Time
+	if status == model.WebhookDeliveryPending {
+		retryAt = req.RetryAt
+	}
+
+	var out model.WebhookDelivery
+	err := scanWebhookDelivery(s.pool.QueryRow(ctx, `
+		update public.webhook_deliveries
+		set attempts = attempts + 1,
+		    status = $2,
+		    response_code = nullif($3, 0),
+		    error = nullif($4, ''),
+		    next_attempt_at = $5,
+		    delivered_at = case when $2::text = 'succeeded' then now() else delivered_at end
+		where id = $1::uuid
+		returning `+webhookDeliveryColumns+`
+	`, req.DeliveryID, string(status), req.ResponseCode, req.Error, retryAt), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.WebhookDelivery{}, store.ErrNotFound
+		}
+		return model.WebhookDelivery{}, mapPgErr(err)
+	}
+	return out, nil
+}
+
+func (s *Store) PurgeWebhookDeliveriesBefore(ctx context.Context, before time.Time) (int, error) {
+	var n int
+	err := s.pool.QueryRow(ctx, `
+		with d as (
+		  delete from public.webhook_deliveries
+		  where status <> 'pending'
+		    and updated_at < $1
+		  returning 1
+		)
+		select count(*) from d
+	`, before).Scan(&n)
+	if err != nil {
+		return 0, mapPgErr(err)
+	}
+	return n, nil
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+// Claim order, implemented identically by every store (see claim_task in
+// supabase/migrations/0019_claim_priority_order.sql):
+//
+//  1. highest effective priority (EffectivePriority)
+//  2. oldest chain (chain created_at)
+//  3. lowest sequence within the chain
+//  4. task id, so ties never depend on map or index order
+//
+// Ordering only applies to tasks that are already claimable: priority never lets a
+// task jump ahead of a queued/in_progress predecessor in its own chain.
+
+// EffectivePriority is the task priority plus one point for every agingSeconds the
+// task has been waiting since it was created, so low-priority work cannot starve.
+// agingSeconds <= 0 disables aging.
+func EffectivePriority(t model.Task, now time.Time, agingSeconds int) int {
+	p := t.Priority
+	if agingSeconds > 0 {
+		if waited := now.Sub(t.CreatedAt); waited > 0 {
+			p += int(waited / (time.Duration(agingSeconds) * time.Second))
+		}
+	}
+	return p
+}
+
+// ClaimsBefore reports whether task a (whose chain was created at chainA) is claimed
+// before task b (chain created at chainB).
+func ClaimsBefore(a model.Task, chainA time.Time, b model.Task, chainB time.Time, now time.Time, agingSeconds int) bool {
+	if pa, pb := EffectivePriority(a, now, agingSeconds), EffectivePriority(b, now, agingSeconds); pa != pb {
+		return pa > pb
+	}
+	if !chainA.Equal(chainB) {
+		return chainA.Before(chainB)
+	}
+	if a.Sequence != b.Sequence {
+		return a.Sequence < b.Sequence
+	}
+	return a.ID < b.ID
+}
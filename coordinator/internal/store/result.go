@@ -0,0 +1,47 @@
+package store
+
+import (
+	"errors"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+const (
+	// MaxTaskArtifacts caps the number of artifacts on one task result.
+	MaxTaskArtifacts = 32
+	// MaxTaskArtifactBytes caps the inline content of a single artifact; larger outputs
+	// should be referenced by URL.
+	MaxTaskArtifactBytes = 256 << 10
+)
+
+// ValidateTaskResult checks the artifacts of a completion/failure: each needs a unique
+// name and either inline content within MaxTaskArtifactBytes or a URL.
+func ValidateTaskResult(artifacts []model.TaskArtifact) error {
+	if len(artifacts) > MaxTaskArtifacts {
+		return errors.New("too_many_artifacts")
+	}
+	seen := make(map[string]bool, len(artifacts))
+	for _, a := range artifacts {
+		name := strings.TrimSpace(a.Name)
+		if name == "" {
+			return errors.New("artifact_name_required")
+		}
+		if seen[name] {
+			return errors.New("artifact_name_duplicate")
+		}
+		seen[name] = true
+		if len(a.Content) > MaxTaskArtifactBytes {
+			return errors.New("artifact_too_large")
+		}
+		if a.Content == "" && strings.TrimSpace(a.URL) == "" {
+			return errors.New("artifact_content_or_url_required")
+		}
+	}
+	return nil
+}
+
+// HasTaskResult reports whether a completion/failure carries anything to store.
+func HasTaskResult(result map[string]any, artifacts []model.TaskArtifact) bool {
+	return len(result) > 0 || len(artifacts) > 0
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+// maxRetryBackoff caps the exponential backoff between attempts.
+const maxRetryBackoff = 24 * time.Hour
+
+// RetryPolicy resolves the effective retry budget and base backoff for a task:
+// task-level values win, the channel policy fills in whatever the task leaves at 0.
+func RetryPolicy(t model.Task, ch model.Channel) (maxAttempts int, backoffSeconds int) {
+	maxAttempts = t.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = ch.RetryMaxAttempts
+	}
+	backoffSeconds = t.RetryBackoffSeconds
+	if backoffSeconds <= 0 {
+		backoffSeconds = ch.RetryBackoffSeconds
+	}
+	return maxAttempts, backoffSeconds
+}
+
+// RetryOutcome decides what a failed attempt turns into.
+//   - maxAttempts <= 0: no retry policy, the task fails terminally (failed).
+//   - attempts < maxAttempts: the task is requeued and becomes claimable again
+//     after backoffSeconds * 2^(attempts-1), capped at maxRetryBackoff.
+//   - otherwise the budget is exhausted and the task moves to dead_letter.
+func RetryOutcome(attempts, maxAttempts, backoffSeconds int, now time.Time) (model.TaskStatus, *time.Time) {
+	if maxAttempts <= 0 {
+		return model.TaskStatusFailed, nil
+	}
+	if attempts >= maxAttempts {
+		return model.TaskStatusDeadLetter, nil
+	}
+	if backoffSeconds <= 0 {
+		return model.TaskStatusQueued, nil
+	}
+
+	delay := time.Duration(backoffSeconds) * time.Second
+	for i := 1; i < attempts && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	eligibleAt := now.Add(delay)
+	return model.TaskStatusQueued, &eligibleAt
+}
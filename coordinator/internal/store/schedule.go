@@ -0,0 +1,35 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+// ClaimableAt returns when a queued task's time gates open: the later of its not_before
+// schedule and its retry backoff (next_eligible_at). Nil means neither applies.
+func ClaimableAt(t model.Task) *time.Time {
+	at := t.NotBefore
+	if t.NextEligibleAt != nil && (at == nil || t.NextEligibleAt.After(*at)) {
+		at = t.NextEligibleAt
+	}
+	return at
+}
+
+// ValidateScheduleTasks checks the task templates of a recurring schedule.
+func ValidateScheduleTasks(tasks []model.ScheduleTaskTemplate) error {
+	if len(tasks) == 0 {
+		return errors.New("tasks_required")
+	}
+	for _, t := range tasks {
+		if strings.TrimSpace(t.Title) == "" {
+			return errors.New("task_title_required")
+		}
+		if t.MaxAttempts < 0 || t.RetryBackoffSeconds < 0 {
+			return errors.New("retry_policy_invalid")
+		}
+	}
+	return nil
+}
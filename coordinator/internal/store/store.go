@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"errors"
+	"time"
 
 	"clwclw-monitor/coordinator/internal/model"
 )
@@ -12,6 +13,31 @@ var (
 	ErrConflict        = errors.New("conflict")
 	ErrNoQueuedTasks   = errors.New("no_queued_tasks")
 	ErrNoPendingInputs = errors.New("no_pending_inputs")
+	ErrDependencyCycle = errors.New("dependency_cycle")
+	// ErrConcurrencyLimited is wrapped by *ConcurrencyLimitError.
+	ErrConcurrencyLimited = errors.New("concurrency_limited")
+)
+
+// Event types recorded by the coordinator itself (agent-reported events use their own types).
+const (
+	// EventTypeTaskLeaseExpired is recorded when an expired claim lease sends a task back to the queue.
+	EventTypeTaskLeaseExpired = "task.lease_expired"
+	// EventTypeTaskRequeued is recorded when an in_progress task is explicitly returned to the queue.
+	EventTypeTaskRequeued = "task.requeued"
+	// EventTypeTaskRetryScheduled is recorded when a failed attempt is requeued under a retry policy.
+	EventTypeTaskRetryScheduled = "task.retry_scheduled"
+	// EventTypeTaskDeadLettered is recorded when a task exhausts its retry budget.
+	EventTypeTaskDeadLettered = "task.dead_lettered"
+	// EventTypeTaskCancelled is recorded when an in_progress task is cancelled under its agent.
+	EventTypeTaskCancelled = "task.cancelled"
+)
+
+// Control inputs are queued by the coordinator (not typed by a user) for the agent working
+// on a task; Text names the action.
+const (
+	TaskInputKindControl = "control"
+	// TaskControlCancel asks the agent to interrupt the task it is running.
+	TaskControlCancel = "cancel"
 )
 
 type TaskFilter struct {
@@ -19,6 +45,7 @@ type TaskFilter struct {
 	ChannelID string
 	ChainID   string
 	Status    model.TaskStatus
+	IDs       []string // only these tasks, when set
 	Limit     int
 }
 
@@ -26,33 +53,60 @@ type EventFilter struct {
 	UserID  string
 	AgentID string
 	TaskID  string
+	IDs     []string // only these events, when set
 	Limit   int
 }
 
+// NotificationFilter selects a page of notifications, newest first.
+type NotificationFilter struct {
+	UserID     string
+	UnreadOnly bool
+	// Cursor is the ID of the last notification of the previous page; ErrNotFound if unknown.
+	Cursor string
+	Limit  int
+}
+
 type ClaimTaskRequest struct {
 	AgentID        string `json:"agent_id"`
 	ChannelID      string `json:"channel_id,omitempty"`
 	Channel        string `json:"channel,omitempty"`
 	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	LeaseSeconds   int    `json:"lease_seconds,omitempty"` // 0 = no lease (claim never expires)
+	// PriorityAgingSeconds adds one priority point per this many seconds a task has waited (0 = no aging).
+	PriorityAgingSeconds int `json:"priority_aging_seconds,omitempty"`
+	// UserMaxInFlight caps the in-progress tasks of the channel owner's channels (0 = no limit)
+	// unless the owner has a limit of their own (model.User.MaxInFlight).
+	UserMaxInFlight int `json:"user_max_in_flight,omitempty"`
 }
 
 type CompleteTaskRequest struct {
-	TaskID         string `json:"task_id"`
-	AgentID        string `json:"agent_id,omitempty"`
-	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	TaskID         string               `json:"task_id"`
+	AgentID        string               `json:"agent_id,omitempty"`
+	IdempotencyKey string               `json:"idempotency_key,omitempty"`
+	Result         map[string]any       `json:"result,omitempty"`    // optional: stored as the task's TaskResult
+	Artifacts      []model.TaskArtifact `json:"artifacts,omitempty"` // optional: see ValidateTaskResult
 }
 
 type FailTaskRequest struct {
-	TaskID         string `json:"task_id"`
-	AgentID        string `json:"agent_id,omitempty"`
-	Reason         string `json:"reason,omitempty"`
-	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	TaskID         string               `json:"task_id"`
+	AgentID        string               `json:"agent_id,omitempty"`
+	Reason         string               `json:"reason,omitempty"`
+	IdempotencyKey string               `json:"idempotency_key,omitempty"`
+	Result         map[string]any       `json:"result,omitempty"`
+	Artifacts      []model.TaskArtifact `json:"artifacts,omitempty"`
 }
 
 type AssignTaskRequest struct {
 	TaskID         string `json:"task_id"`
 	AgentID        string `json:"agent_id"`
 	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	LeaseSeconds   int    `json:"lease_seconds,omitempty"` // 0 = no lease (claim never expires)
+}
+
+type RenewTaskLeaseRequest struct {
+	TaskID       string `json:"task_id"`
+	AgentID      string `json:"agent_id"`
+	LeaseSeconds int    `json:"lease_seconds"`
 }
 
 type CreateTaskInputRequest struct {
@@ -69,6 +123,38 @@ type ClaimTaskInputRequest struct {
 	AgentID string `json:"agent_id"`
 }
 
+type RequeueTaskRequest struct {
+	TaskID  string `json:"task_id"`
+	AgentID string `json:"agent_id,omitempty"` // optional: must match the assigned agent when set
+	Reason  string `json:"reason,omitempty"`
+}
+
+type CancelTaskRequest struct {
+	TaskID      string `json:"task_id"`
+	CancelledBy string `json:"cancelled_by,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	// CancelDownstream also cancels the queued/locked tasks that wait on this one. Otherwise
+	// they are skipped past: a cancelled task no longer holds back its dependents.
+	CancelDownstream bool `json:"cancel_downstream,omitempty"`
+}
+
+type ReviewTaskRequest struct {
+	TaskID     string `json:"task_id"`
+	Approve    bool   `json:"approve"`
+	ReviewedBy string `json:"reviewed_by,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+}
+
+// RecordWebhookAttemptRequest is the outcome of one webhook delivery attempt. The delivery
+// succeeds, is retried at RetryAt when set, or fails for good.
+type RecordWebhookAttemptRequest struct {
+	DeliveryID   string     `json:"delivery_id"`
+	Succeeded    bool       `json:"succeeded"`
+	ResponseCode int        `json:"response_code,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	RetryAt      *time.Time `json:"retry_at,omitempty"`
+}
+
 type DetachAgentFromChainRequest struct {
 	ChainID string `json:"chain_id"`
 	AgentID string `json:"agent_id"`
@@ -82,24 +168,71 @@ type Store interface {
 	CreateChannel(ctx context.Context, ch model.Channel) (model.Channel, error)
 	ListChannels(ctx context.Context, userID string) ([]model.Channel, error)
 	GetChannelByName(ctx context.Context, name string) (model.Channel, error)
+	GetChannel(ctx context.Context, id string) (model.Channel, error)
+	UpdateChannel(ctx context.Context, ch model.Channel) (model.Channel, error)
+	// SetChannelPause sets or clears (empty mode) the channel's pause mode. ClaimTask skips
+	// every task of a paused or draining channel. Draining leaves in-flight tasks to finish;
+	// pausing returns them to the queue (held until resume) and returns the requeued tasks.
+	SetChannelPause(ctx context.Context, channelID string, mode model.PauseMode) (model.Channel, []model.Task, error)
 
 	CreateChain(ctx context.Context, c model.Chain) (model.Chain, error)
 	GetChain(ctx context.Context, id string) (model.Chain, error)
 	ListChains(ctx context.Context, userID string, channelID string) ([]model.Chain, error)
 	UpdateChain(ctx context.Context, c model.Chain) (model.Chain, error)
 	DeleteChain(ctx context.Context, id string) error
+	// SetChainPause sets or clears (empty mode) the chain's pause mode, like SetChannelPause.
+	SetChainPause(ctx context.Context, chainID string, mode model.PauseMode) (model.Chain, []model.Task, error)
 	DetachAgentFromChain(ctx context.Context, req DetachAgentFromChainRequest) error
+	// CreateChainWithTasks creates a chain and all of its tasks atomically: either everything
+	// is stored or nothing is. Tasks get sequences 1..n in slice order.
+	CreateChainWithTasks(ctx context.Context, c model.Chain, tasks []NewChainTask) (model.Chain, []model.Task, error)
 
 	CreateTask(ctx context.Context, t model.Task) (model.Task, error)
 	ListTasks(ctx context.Context, f TaskFilter) ([]model.Task, error)
+	// ClaimTask claims the next eligible task in claim order (see ClaimsBefore).
 	ClaimTask(ctx context.Context, req ClaimTaskRequest) (*model.Task, error)
+	// NextClaimableAt reports when the earliest task that ClaimTask skips only because of its
+	// not_before schedule or retry backoff becomes claimable (nil if there is none).
+	NextClaimableAt(ctx context.Context, req ClaimTaskRequest) (*time.Time, error)
 	AssignTask(ctx context.Context, req AssignTaskRequest) (*model.Task, error)
+	// CompleteTask marks an in_progress task done. A result or artifacts in req are stored as
+	// the task's TaskResult in the same step; completing an already done task stores nothing.
 	CompleteTask(ctx context.Context, req CompleteTaskRequest) (*model.Task, error)
+	// FailTask records a failed attempt (and its result, like CompleteTask). Under a retry policy the task is requeued with
+	// backoff until its budget runs out, then moved to dead_letter (see RetryOutcome).
 	FailTask(ctx context.Context, req FailTaskRequest) (*model.Task, error)
+	// ResubmitTask puts a dead_letter (or failed) task back in the queue with a fresh retry budget.
+	ResubmitTask(ctx context.Context, taskID string) (*model.Task, error)
 	UpdateTaskStatus(ctx context.Context, taskID string, newStatus model.TaskStatus) (*model.Task, error)
+	// CancelTask moves a queued, in_progress or locked task to cancelled, clears the agent's
+	// current task and re-evaluates the chain. It returns the task and any downstream tasks
+	// cancelled with it. AssignedAgentID is kept only when the task was in progress, i.e.
+	// when an agent has to be told to stop. Cancelling a cancelled task is a no-op.
+	CancelTask(ctx context.Context, req CancelTaskRequest) (*model.Task, []model.Task, error)
+	RenewTaskLease(ctx context.Context, req RenewTaskLeaseRequest) (*model.Task, error)
+	// ReviewApprovalTask resolves an approval gate whose predecessors have finished: approving
+	// marks it done so its successors become claimable, rejecting marks it failed. Returns
+	// ErrConflict for a task that is not a queued gate, or whose predecessors are still
+	// running; repeating the same decision is a no-op.
+	ReviewApprovalTask(ctx context.Context, req ReviewTaskRequest) (*model.Task, error)
+	// RequeueExpiredTasks returns in_progress tasks whose lease ended before now to the queue,
+	// records a task.lease_expired event for each, and returns the requeued tasks.
+	RequeueExpiredTasks(ctx context.Context, now time.Time) ([]model.Task, error)
+	// RequeueTask returns an in_progress task to the queue and releases the agent's chain ownership.
+	RequeueTask(ctx context.Context, req RequeueTaskRequest) (*model.Task, error)
+	// GetTaskResult returns the result stored for a task, or ErrNotFound if none was reported.
+	GetTaskResult(ctx context.Context, taskID string) (model.TaskResult, error)
+	// ListTaskResults returns the results of every task in a chain, in task sequence order.
+	ListTaskResults(ctx context.Context, chainID string) ([]model.TaskResult, error)
+	// ListTaskResultArtifactIDs returns every blob ID a stored task result artifact links to
+	// (see TaskArtifactBlobID), sorted and deduplicated.
+	ListTaskResultArtifactIDs(ctx context.Context) ([]string, error)
 
 	CreateEvent(ctx context.Context, e model.Event) (model.Event, error)
 	ListEvents(ctx context.Context, f EventFilter) ([]model.Event, error)
+	// ListEventArtifactIDs returns every blob ID referenced by a stored event payload (see
+	// EventArtifactIDs), sorted and deduplicated.
+	ListEventArtifactIDs(ctx context.Context) ([]string, error)
 
 	CreateTaskInput(ctx context.Context, req CreateTaskInputRequest) (model.TaskInput, error)
 	ClaimTaskInput(ctx context.Context, req ClaimTaskInputRequest) (*model.TaskInput, error)
@@ -107,7 +240,72 @@ type Store interface {
 	CreateUser(ctx context.Context, u model.User) (model.User, error)
 	GetUserByUsername(ctx context.Context, username string) (*model.User, error)
 	GetUserByID(ctx context.Context, id string) (*model.User, error)
+	// SetUserMaxInFlight sets the in-flight limit of userID's channels, or with nil goes back
+	// to ClaimTaskRequest.UserMaxInFlight. ErrNotFound if the user does not exist.
+	SetUserMaxInFlight(ctx context.Context, userID string, limit *int) (*model.User, error)
 
 	CreateAuthCode(ctx context.Context, code model.AuthCode) error
 	ConsumeAuthCode(ctx context.Context, code string) (*model.AuthCode, error)
+
+	CreateTemplate(ctx context.Context, tpl model.Template) (model.Template, error)
+	GetTemplate(ctx context.Context, id string) (model.Template, error)
+	ListTemplates(ctx context.Context, userID string) ([]model.Template, error)
+	UpdateTemplate(ctx context.Context, tpl model.Template) (model.Template, error)
+	DeleteTemplate(ctx context.Context, id string) error
+
+	CreateSchedule(ctx context.Context, sc model.Schedule) (model.Schedule, error)
+	GetSchedule(ctx context.Context, id string) (model.Schedule, error)
+	ListSchedules(ctx context.Context, userID string) ([]model.Schedule, error)
+	UpdateSchedule(ctx context.Context, sc model.Schedule) (model.Schedule, error)
+	DeleteSchedule(ctx context.Context, id string) error
+	// RecordScheduleRun appends run to its schedule's history and advances the schedule:
+	// last_run_at becomes run.ScheduledFor, next_run_at becomes nextRunAt, and last_chain_id
+	// is set when the run created a chain.
+	RecordScheduleRun(ctx context.Context, run model.ScheduleRun, nextRunAt *time.Time) (model.ScheduleRun, error)
+	// ListScheduleRuns returns a schedule's run history, newest first.
+	ListScheduleRuns(ctx context.Context, scheduleID string, limit int) ([]model.ScheduleRun, error)
+
+	// RaiseNotification stores n as the active notification of its user and key, or updates
+	// the active one in place. It reports whether n should be pushed to the user: it is new,
+	// or its last push is at least cooldown old (see NotificationPushDue). A pushed
+	// notification records the push time and becomes unread again.
+	RaiseNotification(ctx context.Context, n model.Notification, cooldown time.Duration) (model.Notification, bool, error)
+	// ResolveNotifications resolves the active notifications with key, of userID or of every
+	// user when userID is empty, so the next raise creates (and pushes) a new one.
+	ResolveNotifications(ctx context.Context, userID string, key string) error
+	ListNotifications(ctx context.Context, f NotificationFilter) ([]model.Notification, error)
+	CountUnreadNotifications(ctx context.Context, userID string) (int, error)
+	// MarkNotificationRead marks one notification read; ErrNotFound if it is not userID's
+	// (any user's when userID is empty). Marking a read notification again is a no-op.
+	MarkNotificationRead(ctx context.Context, userID string, id string) (model.Notification, error)
+	// MarkAllNotificationsRead marks every unread notification of userID read and returns how many.
+	MarkAllNotificationsRead(ctx context.Context, userID string) (int, error)
+
+	CreateNotificationRule(ctx context.Context, r model.NotificationRule) (model.NotificationRule, error)
+	GetNotificationRule(ctx context.Context, id string) (model.NotificationRule, error)
+	ListNotificationRules(ctx context.Context, userID string) ([]model.NotificationRule, error)
+	UpdateNotificationRule(ctx context.Context, r model.NotificationRule) (model.NotificationRule, error)
+	DeleteNotificationRule(ctx context.Context, id string) error
+	// FireNotificationRule records that the rule delivers now, unless it already did within
+	// cooldown. It reports whether the rule may deliver.
+	FireNotificationRule(ctx context.Context, id string, cooldown time.Duration) (bool, error)
+
+	CreateWebhook(ctx context.Context, w model.Webhook) (model.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (model.Webhook, error)
+	ListWebhooks(ctx context.Context, userID string) ([]model.Webhook, error)
+	UpdateWebhook(ctx context.Context, w model.Webhook) (model.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	// CreateWebhookDelivery queues a pending delivery that is due right away.
+	CreateWebhookDelivery(ctx context.Context, d model.WebhookDelivery) (model.WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, id string) (model.WebhookDelivery, error)
+	// ListWebhookDeliveries returns the latest deliveries of a webhook, newest first.
+	ListWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]model.WebhookDelivery, error)
+	// ClaimWebhookDeliveries returns up to limit pending deliveries due at now, oldest first,
+	// and moves their next attempt to now+lease so that no other worker takes them meanwhile.
+	ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error)
+	// RecordWebhookAttempt stores the outcome of one attempt and counts it.
+	RecordWebhookAttempt(ctx context.Context, req RecordWebhookAttemptRequest) (model.WebhookDelivery, error)
+	// PurgeWebhookDeliveriesBefore deletes the succeeded and failed deliveries last updated
+	// before the cutoff and returns how many it removed. Pending deliveries are kept.
+	PurgeWebhookDeliveriesBefore(ctx context.Context, before time.Time) (int, error)
 }
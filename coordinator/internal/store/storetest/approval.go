@@ -0,0 +1,117 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunApprovalTests checks that approval gates are never claimed, hold back their
+// successors until reviewed, and can only be reviewed once their predecessors finished.
+func RunApprovalTests(t *testing.T, newStore Factory) {
+	t.Run("ApproveReleasesSuccessors", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "approval-approve")
+		chain, tasks, err := s.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "migrate"}, []store.NewChainTask{
+			{Task: model.Task{Title: "write migration"}},
+			{Task: model.Task{Title: "review migration", Type: model.TaskTypeApproval}},
+			{Task: model.Task{Title: "apply migration"}},
+		})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+		gate := tasks[1]
+
+		// Too early: the migration is not written yet.
+		if _, err := s.ReviewApprovalTask(ctx, store.ReviewTaskRequest{TaskID: gate.ID, Approve: true}); !errors.Is(err, store.ErrConflict) {
+			t.Fatalf("expected conflict before the gate is reached, got %v", err)
+		}
+
+		if got := claim(t, ctx, s, ch, agentIDs[0], 0); got.ID != tasks[0].ID {
+			t.Fatalf("expected %q, got %q", tasks[0].Title, got.Title)
+		}
+		if _, err := s.CompleteTask(ctx, store.CompleteTaskRequest{TaskID: tasks[0].ID, AgentID: agentIDs[0]}); err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+
+		// The gate itself is never handed out, and it holds back the apply step.
+		for _, agentID := range agentIDs[:2] {
+			if _, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentID, ChannelID: ch.ID}); !errors.Is(err, store.ErrNoQueuedTasks) {
+				t.Fatalf("expected nothing to claim while awaiting approval, got %v", err)
+			}
+		}
+		if _, err := s.AssignTask(ctx, store.AssignTaskRequest{TaskID: gate.ID, AgentID: agentIDs[0]}); !errors.Is(err, store.ErrConflict) {
+			t.Fatalf("expected a gate to refuse assignment, got %v", err)
+		}
+
+		approved, err := s.ReviewApprovalTask(ctx, store.ReviewTaskRequest{TaskID: gate.ID, Approve: true, ReviewedBy: "alice", Comment: "looks safe"})
+		if err != nil {
+			t.Fatalf("approve: %v", err)
+		}
+		if approved.Status != model.TaskStatusDone || approved.ReviewedBy != "alice" || approved.ReviewComment != "looks safe" || approved.ReviewedAt == nil {
+			t.Fatalf("unexpected approved gate: %+v", approved)
+		}
+		if again, err := s.ReviewApprovalTask(ctx, store.ReviewTaskRequest{TaskID: gate.ID, Approve: true, ReviewedBy: "bob"}); err != nil || again.ReviewedBy != "alice" {
+			t.Fatalf("expected approving again to be a no-op, got %+v (%v)", again, err)
+		}
+
+		if got := claim(t, ctx, s, ch, agentIDs[0], 0); got.ID != tasks[2].ID {
+			t.Fatalf("expected %q after approval, got %q", tasks[2].Title, got.Title)
+		}
+		if _, err := s.CompleteTask(ctx, store.CompleteTaskRequest{TaskID: tasks[2].ID, AgentID: agentIDs[0]}); err != nil {
+			t.Fatalf("complete apply: %v", err)
+		}
+		if got, _ := s.GetChain(ctx, chain.ID); got.Status != model.ChainStatusDone {
+			t.Fatalf("expected chain done, got %q", got.Status)
+		}
+	})
+
+	t.Run("RejectFailsChain", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "approval-reject")
+		chain, tasks, err := s.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "deploy"}, []store.NewChainTask{
+			{Task: model.Task{Title: "sign off", Type: model.TaskTypeApproval}},
+			{Task: model.Task{Title: "deploy"}},
+		})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+		gate := tasks[0]
+
+		rejected, err := s.ReviewApprovalTask(ctx, store.ReviewTaskRequest{TaskID: gate.ID, ReviewedBy: "alice", Comment: "not this week"})
+		if err != nil {
+			t.Fatalf("reject: %v", err)
+		}
+		if rejected.Status != model.TaskStatusFailed || rejected.ReviewedBy != "alice" || rejected.ReviewComment != "not this week" {
+			t.Fatalf("unexpected rejected gate: %+v", rejected)
+		}
+		if got, _ := s.GetChain(ctx, chain.ID); got.Status != model.ChainStatusFailed {
+			t.Fatalf("expected chain failed, got %q", got.Status)
+		}
+		if _, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[0], ChannelID: ch.ID}); !errors.Is(err, store.ErrNoQueuedTasks) {
+			t.Fatalf("expected nothing to claim after rejection, got %v", err)
+		}
+		if _, err := s.ReviewApprovalTask(ctx, store.ReviewTaskRequest{TaskID: gate.ID, Approve: true}); !errors.Is(err, store.ErrConflict) {
+			t.Fatalf("expected approving a rejected gate to conflict, got %v", err)
+		}
+	})
+
+	t.Run("OnlyGatesCanBeReviewed", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "approval-regular")
+		task := createChainTask(t, s, ch, "regular", 1, 0)
+
+		if _, err := s.ReviewApprovalTask(ctx, store.ReviewTaskRequest{TaskID: task.ID, Approve: true}); !errors.Is(err, store.ErrConflict) {
+			t.Fatalf("expected conflict for a regular task, got %v", err)
+		}
+		if _, err := s.ReviewApprovalTask(ctx, store.ReviewTaskRequest{TaskID: "c0000000-0000-4000-8000-000000000000", Approve: true}); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("expected not found, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package storetest
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunEventArtifactTests checks that ListEventArtifactIDs collects the blob IDs linked from
+// event payloads under artifact_id and artifact_ids, ignoring anything that is not a string.
+func RunEventArtifactTests(t *testing.T, newStore Factory) {
+	t.Run("ListsReferencedIDs", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		if _, err := s.UpsertAgent(ctx, model.Agent{ID: agentIDs[0], Name: "artifact-agent"}); err != nil {
+			t.Fatalf("upsert agent: %v", err)
+		}
+
+		a := strings.Repeat("a", 64)
+		b := strings.Repeat("b", 64)
+		c := strings.Repeat("c", 64)
+		for _, payload := range []map[string]any{
+			{"artifact_id": b},
+			{"artifact_ids": []any{a, c, float64(7)}},
+			{"artifact_id": " " + a + " ", "artifact_ids": "not-a-list"},
+			{"message": "no artifacts"},
+		} {
+			if _, err := s.CreateEvent(ctx, model.Event{AgentID: agentIDs[0], Type: "agent.trace", Payload: payload}); err != nil {
+				t.Fatalf("create event: %v", err)
+			}
+		}
+
+		got, err := s.ListEventArtifactIDs(ctx)
+		if err != nil {
+			t.Fatalf("list artifact ids: %v", err)
+		}
+		if want := []string{a, b, c}; !slices.Equal(got, want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("EmptyWithoutEvents", func(t *testing.T) {
+		s := newStore(t)
+		got, err := s.ListEventArtifactIDs(context.Background())
+		if err != nil || len(got) != 0 {
+			t.Fatalf("expected no artifact ids, got %v (%v)", got, err)
+		}
+	})
+}
+
+// RunTaskResultArtifactTests checks that ListTaskResultArtifactIDs collects the blob IDs that
+// task result artifacts link to through /v1/artifacts/{id} URLs, so artifact garbage
+// collection keeps them.
+func RunTaskResultArtifactTests(t *testing.T, newStore Factory) {
+	t.Run("ListsReferencedIDs", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "result-artifacts")
+		_, tasks, err := s.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "result-artifacts"}, []store.NewChainTask{
+			{Task: model.Task{Title: "build"}},
+			{Task: model.Task{Title: "package"}},
+		})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+
+		a := strings.Repeat("a", 64)
+		b := strings.Repeat("b", 64)
+		for i, artifacts := range [][]model.TaskArtifact{
+			{
+				{Name: "log", URL: "/v1/artifacts/" + b},
+				{Name: "report", URL: "https://coordinator.example.com/v1/artifacts/" + a + "?download=1"},
+				{Name: "external", URL: "https://example.com/logs/1"},
+				{Name: "diff", Content: "+inline"},
+			},
+			{
+				{Name: "bundle", URL: "/v1/artifacts/" + a},
+				{Name: "listing", URL: "/v1/artifacts/"},
+			},
+		} {
+			claim(t, ctx, s, ch, agentIDs[0], 0)
+			if _, err := s.CompleteTask(ctx, store.CompleteTaskRequest{TaskID: tasks[i].ID, AgentID: agentIDs[0], Artifacts: artifacts}); err != nil {
+				t.Fatalf("complete task %d: %v", i, err)
+			}
+		}
+
+		got, err := s.ListTaskResultArtifactIDs(ctx)
+		if err != nil {
+			t.Fatalf("list artifact ids: %v", err)
+		}
+		if want := []string{a, b}; !slices.Equal(got, want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("EmptyWithoutResults", func(t *testing.T) {
+		s := newStore(t)
+		got, err := s.ListTaskResultArtifactIDs(context.Background())
+		if err != nil || len(got) != 0 {
+			t.Fatalf("expected no artifact ids, got %v (%v)", got, err)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunCancelTests checks CancelTask: the running agent is released, dependents are either
+// released or cancelled, and the chain status follows.
+func RunCancelTests(t *testing.T, newStore Factory) {
+	t.Run("RunningTaskReleasesAgentAndDependents", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "cancel-running")
+		chain, tasks, err := s.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "cancel-running"}, []store.NewChainTask{
+			{Task: model.Task{Title: "long job"}},
+			{Task: model.Task{Title: "follow-up"}},
+		})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+		if got := claim(t, ctx, s, ch, agentIDs[0], 0); got.ID != tasks[0].ID {
+			t.Fatalf("expected %q to be claimed, got %q", tasks[0].Title, got.Title)
+		}
+
+		cancelled, downstream, err := s.CancelTask(ctx, store.CancelTaskRequest{TaskID: tasks[0].ID, CancelledBy: "alice", Reason: "wrong branch"})
+		if err != nil {
+			t.Fatalf("cancel: %v", err)
+		}
+		if cancelled.Status != model.TaskStatusCancelled || cancelled.CancelledAt == nil ||
+			cancelled.CancelledBy != "alice" || cancelled.CancelReason != "wrong branch" {
+			t.Fatalf("unexpected cancelled task: %+v", cancelled)
+		}
+		if cancelled.AssignedAgentID != agentIDs[0] {
+			t.Fatalf("expected the running agent to stay recorded, got %q", cancelled.AssignedAgentID)
+		}
+		if len(downstream) != 0 {
+			t.Fatalf("expected no downstream cancellation, got %d", len(downstream))
+		}
+		agent, err := s.GetAgent(ctx, agentIDs[0])
+		if err != nil {
+			t.Fatalf("get agent: %v", err)
+		}
+		if agent.CurrentTaskID != "" {
+			t.Fatalf("expected current task to be cleared, got %q", agent.CurrentTaskID)
+		}
+
+		// The follow-up no longer waits on the cancelled task.
+		if got := claim(t, ctx, s, ch, agentIDs[0], 0); got.ID != tasks[1].ID {
+			t.Fatalf("expected %q next, got %q", tasks[1].Title, got.Title)
+		}
+		if c, _ := s.GetChain(ctx, chain.ID); c.Status != model.ChainStatusInProgress {
+			t.Fatalf("expected chain in progress, got %s", c.Status)
+		}
+
+		// Cancelling again is a no-op.
+		again, _, err := s.CancelTask(ctx, store.CancelTaskRequest{TaskID: tasks[0].ID, CancelledBy: "bob"})
+		if err != nil || again.CancelledBy != "alice" {
+			t.Fatalf("expected idempotent cancel, got %+v (%v)", again, err)
+		}
+	})
+
+	t.Run("CancelDownstream", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "cancel-downstream")
+		chain, tasks, err := s.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "cancel-downstream"}, []store.NewChainTask{
+			{Task: model.Task{Title: "build"}},
+			{Task: model.Task{Title: "test"}, DependsOn: []int{0}},
+			{Task: model.Task{Title: "deploy"}, DependsOn: []int{1}},
+		})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+
+		_, downstream, err := s.CancelTask(ctx, store.CancelTaskRequest{TaskID: tasks[0].ID, CancelDownstream: true})
+		if err != nil {
+			t.Fatalf("cancel: %v", err)
+		}
+		if len(downstream) != 2 || downstream[0].ID != tasks[1].ID || downstream[1].ID != tasks[2].ID {
+			t.Fatalf("expected test and deploy to be cancelled, got %+v", downstream)
+		}
+		for _, d := range downstream {
+			if d.Status != model.TaskStatusCancelled {
+				t.Fatalf("expected %q cancelled, got %s", d.Title, d.Status)
+			}
+		}
+		if c, _ := s.GetChain(ctx, chain.ID); c.Status != model.ChainStatusCancelled {
+			t.Fatalf("expected chain cancelled, got %s", c.Status)
+		}
+		if _, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[0], ChannelID: ch.ID}); !errors.Is(err, store.ErrNoQueuedTasks) {
+			t.Fatalf("expected nothing to claim, got %v", err)
+		}
+	})
+
+	t.Run("FinishedTaskConflicts", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "cancel-finished")
+		task := createChainTask(t, s, ch, "cancel-finished", 1, 0)
+		claim(t, ctx, s, ch, agentIDs[0], 0)
+		if _, err := s.CompleteTask(ctx, store.CompleteTaskRequest{TaskID: task.ID, AgentID: agentIDs[0]}); err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+
+		if _, _, err := s.CancelTask(ctx, store.CancelTaskRequest{TaskID: task.ID}); !errors.Is(err, store.ErrConflict) {
+			t.Fatalf("expected conflict, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunChainWithTasksTests checks that CreateChainWithTasks stores a chain and its tasks
+// together, and stores nothing when any part is invalid.
+func RunChainWithTasksTests(t *testing.T, newStore Factory) {
+	t.Run("CreatesChainAndTasks", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "atomic-create")
+
+		chain, tasks, err := s.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "atomic"}, []store.NewChainTask{
+			{Task: model.Task{Title: "left"}},
+			{Task: model.Task{Title: "right"}},
+			{Task: model.Task{Title: "join"}, DependsOn: []int{0, 1}},
+		})
+		if err != nil {
+			t.Fatalf("create chain with tasks: %v", err)
+		}
+		if chain.Status != model.ChainStatusQueued || len(tasks) != 3 {
+			t.Fatalf("unexpected result: %+v %+v", chain, tasks)
+		}
+		for i, task := range tasks {
+			if task.ChainID != chain.ID || task.ChannelID != ch.ID || task.Sequence != i+1 || task.Status != model.TaskStatusQueued {
+				t.Fatalf("task %d not wired to the chain: %+v", i, task)
+			}
+		}
+		if deps := tasks[2].DependsOn; len(deps) != 2 || deps[0] != tasks[0].ID || deps[1] != tasks[1].ID {
+			t.Fatalf("expected join to depend on left and right, got %v", deps)
+		}
+
+		stored, err := s.ListTasks(ctx, store.TaskFilter{ChainID: chain.ID})
+		if err != nil || len(stored) != 3 {
+			t.Fatalf("expected 3 stored tasks, got %d (%v)", len(stored), err)
+		}
+		if got := claim(t, ctx, s, ch, agentIDs[0], 0); got.ID != tasks[0].ID {
+			t.Fatalf("expected %q first, got %q", tasks[0].Title, got.Title)
+		}
+	})
+
+	t.Run("CycleStoresNothing", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "atomic-cycle")
+
+		_, _, err := s.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "cyclic"}, []store.NewChainTask{
+			{Task: model.Task{Title: "a"}, DependsOn: []int{1}},
+			{Task: model.Task{Title: "b"}, DependsOn: []int{0}},
+		})
+		if !errors.Is(err, store.ErrDependencyCycle) {
+			t.Fatalf("expected dependency cycle, got %v", err)
+		}
+		chains, err := s.ListChains(ctx, "", ch.ID)
+		if err != nil || len(chains) != 0 {
+			t.Fatalf("expected no chain to be stored, got %d (%v)", len(chains), err)
+		}
+	})
+
+	t.Run("UnknownChannel", func(t *testing.T) {
+		s := newStore(t)
+		_, _, err := s.CreateChainWithTasks(context.Background(), model.Chain{ChannelID: "c0000000-0000-4000-8000-000000000000", Name: "orphan"}, []store.NewChainTask{
+			{Task: model.Task{Title: "a"}},
+		})
+		if !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("expected not found, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+// Package storetest holds behavior tests shared by every store.Store implementation,
+// so the memory and Postgres stores are checked against the same expectations.
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// Factory returns an empty store for one subtest.
+type Factory func(t *testing.T) store.Store
+
+// agentIDs are UUIDs so they are valid for both stores.
+var agentIDs = []string{
+	"a0000000-0000-4000-8000-000000000001",
+	"a0000000-0000-4000-8000-000000000002",
+	"a0000000-0000-4000-8000-000000000003",
+}
+
+// RunClaimOrderTests checks that ClaimTask follows store.ClaimsBefore.
+// Each claim uses a fresh agent, so chain ownership never narrows the candidates.
+func RunClaimOrderTests(t *testing.T, newStore Factory) {
+	t.Run("PriorityFirst", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "order-priority")
+
+		low := createChainTask(t, s, ch, "low", 1, 0)
+		high := createChainTask(t, s, ch, "high", 1, 10)
+		mid := createChainTask(t, s, ch, "mid", 1, 5)
+
+		expectClaims(t, ctx, s, ch, 0, high, mid, low)
+	})
+
+	t.Run("ChainAgeBreaksPriorityTies", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "order-age")
+
+		older := createChainTask(t, s, ch, "older", 1, 3)
+		newer := createChainTask(t, s, ch, "newer", 1, 3)
+
+		expectClaims(t, ctx, s, ch, 0, older, newer)
+	})
+
+	t.Run("PriorityDoesNotSkipPredecessors", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "order-gate")
+
+		first := createChainTask(t, s, ch, "gated", 1, 0)
+		if _, err := s.CreateTask(ctx, model.Task{ChannelID: ch.ID, ChainID: first.ChainID, Sequence: 2, Title: "gated-2", Priority: 100}); err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+		other := createChainTask(t, s, ch, "other", 1, 50)
+
+		expectClaims(t, ctx, s, ch, 0, other, first)
+	})
+
+	t.Run("AgingLiftsWaitingTasks", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("waits for aging")
+		}
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "order-aging")
+
+		waiting := createChainTask(t, s, ch, "waiting", 1, 0)
+		time.Sleep(2100 * time.Millisecond)
+		fresh := createChainTask(t, s, ch, "fresh", 1, 1)
+
+		// Without aging the fresh task wins on priority; with one point per second
+		// the waiting task has overtaken it.
+		if got := claim(t, ctx, s, ch, agentIDs[0], 1); got.ID != waiting.ID {
+			t.Fatalf("with aging: expected %q first, got %q", waiting.Title, got.Title)
+		}
+		if got := claim(t, ctx, s, ch, agentIDs[1], 0); got.ID != fresh.ID {
+			t.Fatalf("expected %q next, got %q", fresh.Title, got.Title)
+		}
+	})
+}
+
+func createChannel(t *testing.T, s store.Store, name string) model.Channel {
+	t.Helper()
+	ch, err := s.CreateChannel(context.Background(), model.Channel{Name: name})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	return ch
+}
+
+// createChainTask creates a queued chain holding one task.
+func createChainTask(t *testing.T, s store.Store, ch model.Channel, name string, sequence, priority int) model.Task {
+	t.Helper()
+	ctx := context.Background()
+	chain, err := s.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: name, Status: model.ChainStatusQueued})
+	if err != nil {
+		t.Fatalf("create chain %s: %v", name, err)
+	}
+	task, err := s.CreateTask(ctx, model.Task{ChannelID: ch.ID, ChainID: chain.ID, Sequence: sequence, Title: name, Priority: priority})
+	if err != nil {
+		t.Fatalf("create task %s: %v", name, err)
+	}
+	return task
+}
+
+func claim(t *testing.T, ctx context.Context, s store.Store, ch model.Channel, agentID string, agingSeconds int) *model.Task {
+	t.Helper()
+	if _, err := s.UpsertAgent(ctx, model.Agent{ID: agentID, Name: "agent-" + agentID[len(agentID)-1:]}); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+	got, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentID, ChannelID: ch.ID, PriorityAgingSeconds: agingSeconds})
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	return got
+}
+
+func expectClaims(t *testing.T, ctx context.Context, s store.Store, ch model.Channel, agingSeconds int, want ...model.Task) {
+	t.Helper()
+	if len(want) > len(agentIDs) {
+		t.Fatalf("storetest: at most %d claims per subtest", len(agentIDs))
+	}
+	for i, w := range want {
+		got := claim(t, ctx, s, ch, agentIDs[i], agingSeconds)
+		if got.ID != w.ID {
+			t.Fatalf("claim %d: expected %q, got %q", i+1, w.Title, got.Title)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunConcurrencyTests checks the in-flight limits of ClaimTask: a channel's max_in_flight
+// and the per-user limit (the default or the user's own) both turn claims away with a *store.ConcurrencyLimitError until
+// a running task finishes.
+func RunConcurrencyTests(t *testing.T, newStore Factory) {
+	expectLimited := func(t *testing.T, err error, scope string, inFlight, limit int) {
+		t.Helper()
+		var limited *store.ConcurrencyLimitError
+		if !errors.As(err, &limited) || !errors.Is(err, store.ErrConcurrencyLimited) {
+			t.Fatalf("expected a concurrency limit error, got %v", err)
+		}
+		if limited.Scope != scope || limited.InFlight != inFlight || limited.Limit != limit {
+			t.Fatalf("expected %s at %d/%d, got %+v", scope, inFlight, limit, limited)
+		}
+	}
+
+	t.Run("ChannelLimit", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch, err := s.CreateChannel(ctx, model.Channel{Name: "limit-channel", MaxInFlight: 1})
+		if err != nil {
+			t.Fatalf("create channel: %v", err)
+		}
+		if ch.MaxInFlight != 1 {
+			t.Fatalf("expected max_in_flight 1, got %d", ch.MaxInFlight)
+		}
+		first := createChainTask(t, s, ch, "first", 1, 0)
+		second := createChainTask(t, s, ch, "second", 1, 0)
+
+		if got := claim(t, ctx, s, ch, agentIDs[0], 0); got.ID != first.ID {
+			t.Fatalf("expected %q, got %q", first.Title, got.Title)
+		}
+		_, err = s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[1], ChannelID: ch.ID})
+		expectLimited(t, err, store.ConcurrencyScopeChannel, 1, 1)
+
+		if _, err := s.CompleteTask(ctx, store.CompleteTaskRequest{TaskID: first.ID, AgentID: agentIDs[0]}); err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+		if got := claim(t, ctx, s, ch, agentIDs[1], 0); got.ID != second.ID {
+			t.Fatalf("expected %q once a slot is free, got %q", second.Title, got.Title)
+		}
+	})
+
+	t.Run("UserLimitAcrossChannels", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		user, err := s.CreateUser(ctx, model.User{Username: "limit-user", PasswordHash: "x"})
+		if err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		var channels []model.Channel
+		for _, name := range []string{"limit-user-a", "limit-user-b"} {
+			ch, err := s.CreateChannel(ctx, model.Channel{Name: name, UserID: user.ID})
+			if err != nil {
+				t.Fatalf("create channel: %v", err)
+			}
+			if _, _, err := s.CreateChainWithTasks(ctx, model.Chain{UserID: user.ID, ChannelID: ch.ID, Name: name}, []store.NewChainTask{
+				{Task: model.Task{Title: name}},
+			}); err != nil {
+				t.Fatalf("create chain: %v", err)
+			}
+			channels = append(channels, ch)
+		}
+
+		if _, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[0], ChannelID: channels[0].ID, UserMaxInFlight: 1}); err != nil {
+			t.Fatalf("claim: %v", err)
+		}
+		_, err = s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[1], ChannelID: channels[1].ID, UserMaxInFlight: 1})
+		expectLimited(t, err, store.ConcurrencyScopeUser, 1, 1)
+
+		// Without a user limit the other channel is free.
+		if _, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[1], ChannelID: channels[1].ID}); err != nil {
+			t.Fatalf("claim without a user limit: %v", err)
+		}
+	})
+
+	t.Run("UserOverride", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		user, err := s.CreateUser(ctx, model.User{Username: "limit-override", PasswordHash: "x"})
+		if err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		ch, err := s.CreateChannel(ctx, model.Channel{Name: "limit-override", UserID: user.ID})
+		if err != nil {
+			t.Fatalf("create channel: %v", err)
+		}
+		for _, title := range []string{"first", "second", "third"} {
+			if _, _, err := s.CreateChainWithTasks(ctx, model.Chain{UserID: user.ID, ChannelID: ch.ID, Name: title}, []store.NewChainTask{
+				{Task: model.Task{Title: title}},
+			}); err != nil {
+				t.Fatalf("create chain: %v", err)
+			}
+		}
+
+		// The user's own limit replaces the default of 1.
+		two := 2
+		u, err := s.SetUserMaxInFlight(ctx, user.ID, &two)
+		if err != nil || u.MaxInFlight == nil || *u.MaxInFlight != 2 {
+			t.Fatalf("set limit: got %+v (%v)", u, err)
+		}
+		if got, err := s.GetUserByID(ctx, user.ID); err != nil || got.MaxInFlight == nil || *got.MaxInFlight != 2 {
+			t.Fatalf("expected the limit to be stored, got %+v (%v)", got, err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[i], ChannelID: ch.ID, UserMaxInFlight: 1}); err != nil {
+				t.Fatalf("claim %d under the user's limit: %v", i+1, err)
+			}
+		}
+		_, err = s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[2], ChannelID: ch.ID, UserMaxInFlight: 1})
+		expectLimited(t, err, store.ConcurrencyScopeUser, 2, 2)
+
+		// Clearing it goes back to the default.
+		if u, err := s.SetUserMaxInFlight(ctx, user.ID, nil); err != nil || u.MaxInFlight != nil {
+			t.Fatalf("clear limit: got %+v (%v)", u, err)
+		}
+		_, err = s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[2], ChannelID: ch.ID, UserMaxInFlight: 3})
+		if err != nil {
+			t.Fatalf("claim under the default: %v", err)
+		}
+
+		negative := -1
+		if _, err := s.SetUserMaxInFlight(ctx, user.ID, &negative); err == nil {
+			t.Fatalf("expected a negative limit to be rejected")
+		}
+		if _, err := s.SetUserMaxInFlight(ctx, "00000000-0000-4000-8000-000000000000", &two); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound for an unknown user, got %v", err)
+		}
+	})
+
+	t.Run("NoWorkStillReportsNoTasks", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch, err := s.CreateChannel(ctx, model.Channel{Name: "limit-empty", MaxInFlight: 1})
+		if err != nil {
+			t.Fatalf("create channel: %v", err)
+		}
+		createChainTask(t, s, ch, "only", 1, 0)
+		claim(t, ctx, s, ch, agentIDs[0], 0)
+		if _, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[1], ChannelID: ch.ID}); !errors.Is(err, store.ErrNoQueuedTasks) {
+			t.Fatalf("expected no_queued_tasks when nothing is left to claim, got %v", err)
+		}
+	})
+
+	t.Run("RejectsNegativeLimit", func(t *testing.T) {
+		s := newStore(t)
+		if _, err := s.CreateChannel(context.Background(), model.Channel{Name: "limit-invalid", MaxInFlight: -1}); err == nil {
+			t.Fatalf("expected a negative max_in_flight to be rejected")
+		}
+	})
+}
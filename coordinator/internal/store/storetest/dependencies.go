@@ -0,0 +1,84 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunDependencyTests checks that claim eligibility follows depends_on edges:
+// a root fans out to three independent tasks which fan back in to one.
+func RunDependencyTests(t *testing.T, newStore Factory) {
+	t.Run("FanOutFanIn", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "dag-channel")
+		chain, err := s.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: "dag-chain", Status: model.ChainStatusQueued})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+
+		create := func(seq int, title string, deps ...string) model.Task {
+			t.Helper()
+			task, err := s.CreateTask(ctx, model.Task{ChannelID: ch.ID, ChainID: chain.ID, Sequence: seq, Title: title, DependsOn: deps})
+			if err != nil {
+				t.Fatalf("create task %s: %v", title, err)
+			}
+			return task
+		}
+		root := create(1, "root")
+		a := create(2, "refactor-a", root.ID)
+		b := create(3, "refactor-b", root.ID)
+		c := create(4, "refactor-c", root.ID)
+		join := create(5, "integrate", a.ID, b.ID, c.ID)
+
+		agentID := agentIDs[0]
+		if got := claim(t, ctx, s, ch, agentID, 0); got.ID != root.ID {
+			t.Fatalf("expected root first, got %q", got.Title)
+		}
+		if _, err := s.CompleteTask(ctx, store.CompleteTaskRequest{TaskID: root.ID, AgentID: agentID}); err != nil {
+			t.Fatalf("complete root: %v", err)
+		}
+
+		// The three refactors only depend on root: claiming one does not block the others.
+		for _, want := range []model.Task{a, b, c} {
+			if got := claim(t, ctx, s, ch, agentID, 0); got.ID != want.ID {
+				t.Fatalf("expected %q, got %q", want.Title, got.Title)
+			}
+		}
+
+		// The join waits for all three.
+		_, err = s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentID, ChannelID: ch.ID})
+		if !errors.Is(err, store.ErrNoQueuedTasks) {
+			t.Fatalf("expected join to be blocked, got %v", err)
+		}
+		for _, done := range []model.Task{a, b, c} {
+			// No agent_id: the agent's current_task_id only tracks its latest claim.
+			if _, err := s.CompleteTask(ctx, store.CompleteTaskRequest{TaskID: done.ID}); err != nil {
+				t.Fatalf("complete %s: %v", done.Title, err)
+			}
+		}
+		if got := claim(t, ctx, s, ch, agentID, 0); got.ID != join.ID {
+			t.Fatalf("expected join, got %q", got.Title)
+		}
+	})
+
+	t.Run("RejectsForeignDependency", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "dag-foreign")
+		other := createChainTask(t, s, ch, "other-chain", 1, 0)
+		chain, err := s.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: "dag-foreign-chain", Status: model.ChainStatusQueued})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+
+		_, err = s.CreateTask(ctx, model.Task{ChannelID: ch.ID, ChainID: chain.ID, Sequence: 1, Title: "foreign", DependsOn: []string{other.ID}})
+		if err == nil {
+			t.Fatalf("expected depends_on outside the chain to be rejected")
+		}
+	})
+}
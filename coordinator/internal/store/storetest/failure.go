@@ -0,0 +1,163 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunFailurePolicyTests checks the chain on_failure policies: halt stops the chain at the
+// first failure, continue runs the rest, and compensate swaps the remaining tasks for the
+// chain's compensation tasks, which are skipped when nothing failed.
+func RunFailurePolicyTests(t *testing.T, newStore Factory) {
+	newChain := func(t *testing.T, s store.Store, ch model.Channel, policy model.FailurePolicy) (model.Chain, []model.Task) {
+		t.Helper()
+		chain, tasks, err := s.CreateChainWithTasks(context.Background(), model.Chain{ChannelID: ch.ID, Name: "release", OnFailure: policy}, []store.NewChainTask{
+			{Task: model.Task{Title: "migrate"}},
+			{Task: model.Task{Title: "deploy"}},
+			{Task: model.Task{Title: "roll back", Compensation: true}},
+		})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+		if chain.OnFailure != policy {
+			t.Fatalf("expected on_failure %q, got %q", policy, chain.OnFailure)
+		}
+		return chain, tasks
+	}
+	fail := func(t *testing.T, s store.Store, task model.Task, agentID string) {
+		t.Helper()
+		if _, err := s.FailTask(context.Background(), store.FailTaskRequest{TaskID: task.ID, AgentID: agentID, Reason: "exit 1"}); err != nil {
+			t.Fatalf("fail %q: %v", task.Title, err)
+		}
+	}
+	complete := func(t *testing.T, s store.Store, task model.Task, agentID string) {
+		t.Helper()
+		if _, err := s.CompleteTask(context.Background(), store.CompleteTaskRequest{TaskID: task.ID, AgentID: agentID}); err != nil {
+			t.Fatalf("complete %q: %v", task.Title, err)
+		}
+	}
+	taskByID := func(t *testing.T, s store.Store, chainID, id string) model.Task {
+		t.Helper()
+		tasks, err := s.ListTasks(context.Background(), store.TaskFilter{ChainID: chainID})
+		if err != nil {
+			t.Fatalf("list tasks: %v", err)
+		}
+		for _, task := range tasks {
+			if task.ID == id {
+				return task
+			}
+		}
+		t.Fatalf("task %s not found", id)
+		return model.Task{}
+	}
+	chainStatus := func(t *testing.T, s store.Store, chainID string) model.ChainStatus {
+		t.Helper()
+		chain, err := s.GetChain(context.Background(), chainID)
+		if err != nil {
+			t.Fatalf("get chain: %v", err)
+		}
+		return chain.Status
+	}
+
+	t.Run("HaltStopsChain", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "failure-halt")
+		chain, tasks := newChain(t, s, ch, "")
+
+		claim(t, ctx, s, ch, agentIDs[0], 0)
+		fail(t, s, tasks[0], agentIDs[0])
+		if got := chainStatus(t, s, chain.ID); got != model.ChainStatusFailed {
+			t.Fatalf("expected chain failed, got %q", got)
+		}
+		if _, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[1], ChannelID: ch.ID}); !errors.Is(err, store.ErrNoQueuedTasks) {
+			t.Fatalf("expected a halted chain to hand out nothing, got %v", err)
+		}
+	})
+
+	t.Run("ContinueRunsRemainingTasks", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "failure-continue")
+		chain, tasks := newChain(t, s, ch, model.FailurePolicyContinue)
+
+		claim(t, ctx, s, ch, agentIDs[0], 0)
+		fail(t, s, tasks[0], agentIDs[0])
+		if got := chainStatus(t, s, chain.ID); got == model.ChainStatusFailed {
+			t.Fatalf("expected the chain to keep running after a failure")
+		}
+
+		if got := claim(t, ctx, s, ch, agentIDs[0], 0); got.ID != tasks[1].ID {
+			t.Fatalf("expected %q after the failure, got %q", tasks[1].Title, got.Title)
+		}
+		complete(t, s, tasks[1], agentIDs[0])
+		// Something failed, so the compensation task runs too.
+		if got := claim(t, ctx, s, ch, agentIDs[0], 0); got.ID != tasks[2].ID {
+			t.Fatalf("expected %q, got %q", tasks[2].Title, got.Title)
+		}
+		complete(t, s, tasks[2], agentIDs[0])
+		if got := chainStatus(t, s, chain.ID); got != model.ChainStatusFailed {
+			t.Fatalf("expected the finished chain to report the failure, got %q", got)
+		}
+	})
+
+	t.Run("CompensateSkipsToCompensation", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "failure-compensate")
+		chain, tasks := newChain(t, s, ch, model.FailurePolicyCompensate)
+
+		claim(t, ctx, s, ch, agentIDs[0], 0)
+		fail(t, s, tasks[0], agentIDs[0])
+
+		skipped := taskByID(t, s, chain.ID, tasks[1].ID)
+		if skipped.Status != model.TaskStatusCancelled || skipped.CancelReason != store.SkipReasonUpstreamFailed {
+			t.Fatalf("expected %q to be skipped, got %s (%q)", tasks[1].Title, skipped.Status, skipped.CancelReason)
+		}
+		if got := chainStatus(t, s, chain.ID); got == model.ChainStatusFailed {
+			t.Fatalf("expected the chain to keep running for compensation")
+		}
+
+		if got := claim(t, ctx, s, ch, agentIDs[0], 0); got.ID != tasks[2].ID {
+			t.Fatalf("expected %q, got %q", tasks[2].Title, got.Title)
+		}
+		complete(t, s, tasks[2], agentIDs[0])
+		if got := chainStatus(t, s, chain.ID); got != model.ChainStatusFailed {
+			t.Fatalf("expected a compensated chain to end failed, got %q", got)
+		}
+	})
+
+	t.Run("CompensationSkippedWithoutFailure", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "failure-none")
+		chain, tasks := newChain(t, s, ch, model.FailurePolicyCompensate)
+
+		for _, task := range tasks[:2] {
+			if got := claim(t, ctx, s, ch, agentIDs[0], 0); got.ID != task.ID {
+				t.Fatalf("expected %q, got %q", task.Title, got.Title)
+			}
+			complete(t, s, task, agentIDs[0])
+		}
+
+		skipped := taskByID(t, s, chain.ID, tasks[2].ID)
+		if skipped.Status != model.TaskStatusCancelled || skipped.CancelReason != store.SkipReasonNoFailure {
+			t.Fatalf("expected the compensation task to be skipped, got %s (%q)", skipped.Status, skipped.CancelReason)
+		}
+		if got := chainStatus(t, s, chain.ID); got != model.ChainStatusDone {
+			t.Fatalf("expected chain done, got %q", got)
+		}
+	})
+
+	t.Run("RejectsUnknownPolicy", func(t *testing.T) {
+		s := newStore(t)
+		ch := createChannel(t, s, "failure-invalid")
+		if _, err := s.CreateChain(context.Background(), model.Chain{ChannelID: ch.ID, Name: "bad", OnFailure: "retry"}); err == nil {
+			t.Fatalf("expected an unknown on_failure policy to be rejected")
+		}
+	})
+}
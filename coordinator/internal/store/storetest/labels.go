@@ -0,0 +1,79 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunLabelTests checks agent labels and task requires: only an agent with every required
+// label can claim a task, and a heartbeat without labels keeps the ones reported before.
+func RunLabelTests(t *testing.T, newStore Factory) {
+	t.Run("ClaimRequiresMatchingLabels", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "labels-claim")
+		_, tasks, err := s.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "train"}, []store.NewChainTask{
+			{Task: model.Task{Title: "train model", Requires: map[string]string{"gpu": "true"}}},
+		})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+		if tasks[0].Requires["gpu"] != "true" {
+			t.Fatalf("expected requires to be stored, got %+v", tasks[0].Requires)
+		}
+
+		if _, err := s.UpsertAgent(ctx, model.Agent{ID: agentIDs[0], Name: "laptop", Labels: map[string]string{"gpu": "false"}}); err != nil {
+			t.Fatalf("upsert agent: %v", err)
+		}
+		if _, err := s.UpsertAgent(ctx, model.Agent{ID: agentIDs[1], Name: "build-box", Labels: map[string]string{"gpu": "true", "size": "large"}}); err != nil {
+			t.Fatalf("upsert agent: %v", err)
+		}
+
+		if _, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[0], ChannelID: ch.ID}); !errors.Is(err, store.ErrNoQueuedTasks) {
+			t.Fatalf("expected an agent without gpu=true to get nothing, got %v", err)
+		}
+		if got := claim(t, ctx, s, ch, agentIDs[1], 0); got.ID != tasks[0].ID {
+			t.Fatalf("expected %q, got %q", tasks[0].Title, got.Title)
+		}
+	})
+
+	t.Run("HeartbeatWithoutLabelsKeepsThem", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		if _, err := s.UpsertAgent(ctx, model.Agent{ID: agentIDs[0], Name: "build-box", Labels: map[string]string{"repo": "web"}}); err != nil {
+			t.Fatalf("upsert agent: %v", err)
+		}
+		if _, err := s.UpsertAgent(ctx, model.Agent{ID: agentIDs[0], Name: "build-box"}); err != nil {
+			t.Fatalf("upsert agent: %v", err)
+		}
+		a, err := s.GetAgent(ctx, agentIDs[0])
+		if err != nil {
+			t.Fatalf("get agent: %v", err)
+		}
+		if a.Labels["repo"] != "web" {
+			t.Fatalf("expected labels to survive a heartbeat without labels, got %+v", a.Labels)
+		}
+
+		if _, err := s.UpsertAgent(ctx, model.Agent{ID: agentIDs[0], Name: "build-box", Labels: map[string]string{}}); err != nil {
+			t.Fatalf("upsert agent: %v", err)
+		}
+		if a, _ := s.GetAgent(ctx, agentIDs[0]); len(a.Labels) != 0 {
+			t.Fatalf("expected empty labels to clear them, got %+v", a.Labels)
+		}
+	})
+
+	t.Run("RejectsInvalidRequires", func(t *testing.T) {
+		s := newStore(t)
+		ch := createChannel(t, s, "labels-invalid")
+		_, _, err := s.CreateChainWithTasks(context.Background(), model.Chain{ChannelID: ch.ID, Name: "bad"}, []store.NewChainTask{
+			{Task: model.Task{Title: "build", Requires: map[string]string{"no spaces": "x"}}},
+		})
+		if err == nil {
+			t.Fatalf("expected an invalid label key to be rejected")
+		}
+	})
+}
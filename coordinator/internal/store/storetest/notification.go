@@ -0,0 +1,133 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunNotificationTests checks that notifications deduplicate by key while active, resend
+// only after the cooldown, keep read state, and page newest first.
+func RunNotificationTests(t *testing.T, newStore Factory) {
+	t.Run("RaiseDeduplicatesActiveKey", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		n := model.Notification{Key: agentIDs[0] + ":setup_waiting", Type: "setup_waiting", AgentID: agentIDs[0], Message: "waiting"}
+
+		first, push, err := s.RaiseNotification(ctx, n, time.Hour)
+		if err != nil {
+			t.Fatalf("raise: %v", err)
+		}
+		if !push || first.Severity != model.NotificationSeverityInfo || first.ReadAt != nil {
+			t.Fatalf("expected a new unread info notification to be pushed, got push=%v %+v", push, first)
+		}
+
+		n.Message = "still waiting"
+		again, push, err := s.RaiseNotification(ctx, n, time.Hour)
+		if err != nil {
+			t.Fatalf("raise again: %v", err)
+		}
+		if push || again.ID != first.ID || again.Message != "still waiting" {
+			t.Fatalf("expected the active notification to be updated without a push, got push=%v %+v", push, again)
+		}
+
+		if _, err := s.MarkNotificationRead(ctx, "", first.ID); err != nil {
+			t.Fatalf("mark read: %v", err)
+		}
+		// Within the cooldown the read state is kept; once it passes the notification is pushed
+		// again and becomes unread.
+		if again, _, _ := s.RaiseNotification(ctx, n, time.Hour); again.ReadAt == nil {
+			t.Fatalf("expected the read state to be kept within the cooldown")
+		}
+		time.Sleep(10 * time.Millisecond)
+		again, push, err = s.RaiseNotification(ctx, n, time.Millisecond)
+		if err != nil {
+			t.Fatalf("raise after cooldown: %v", err)
+		}
+		if !push || again.ID != first.ID || again.ReadAt != nil {
+			t.Fatalf("expected a push of the same notification, unread again, got push=%v %+v", push, again)
+		}
+
+		if err := s.ResolveNotifications(ctx, "", n.Key); err != nil {
+			t.Fatalf("resolve: %v", err)
+		}
+		fresh, push, err := s.RaiseNotification(ctx, n, time.Hour)
+		if err != nil {
+			t.Fatalf("raise after resolve: %v", err)
+		}
+		if !push || fresh.ID == first.ID {
+			t.Fatalf("expected a new notification after resolving, got push=%v %+v", push, fresh)
+		}
+
+		list, err := s.ListNotifications(ctx, store.NotificationFilter{})
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(list) != 2 || list[0].ID != fresh.ID || list[1].ResolvedAt == nil {
+			t.Fatalf("expected the new notification first and the resolved one kept, got %+v", list)
+		}
+
+		if _, _, err := s.RaiseNotification(ctx, model.Notification{Type: "x"}, 0); err == nil {
+			t.Fatalf("expected a notification without key to be rejected")
+		}
+	})
+
+	t.Run("ReadStateAndPaging", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+
+		var raised []model.Notification
+		for _, key := range []string{"a", "b", "c"} {
+			n, _, err := s.RaiseNotification(ctx, model.Notification{Key: key, Type: "approval_required", Severity: model.NotificationSeverityWarning}, 0)
+			if err != nil {
+				t.Fatalf("raise %s: %v", key, err)
+			}
+			raised = append(raised, n)
+			time.Sleep(2 * time.Millisecond)
+		}
+
+		page, err := s.ListNotifications(ctx, store.NotificationFilter{Limit: 2})
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(page) != 2 || page[0].ID != raised[2].ID || page[1].ID != raised[1].ID {
+			t.Fatalf("expected the two newest notifications, got %+v", page)
+		}
+		page, err = s.ListNotifications(ctx, store.NotificationFilter{Limit: 2, Cursor: page[1].ID})
+		if err != nil {
+			t.Fatalf("list next page: %v", err)
+		}
+		if len(page) != 1 || page[0].ID != raised[0].ID {
+			t.Fatalf("expected the oldest notification on the next page, got %+v", page)
+		}
+
+		if _, err := s.MarkNotificationRead(ctx, "", raised[1].ID); err != nil {
+			t.Fatalf("mark read: %v", err)
+		}
+		if n, err := s.CountUnreadNotifications(ctx, ""); err != nil || n != 2 {
+			t.Fatalf("expected 2 unread, got %d (%v)", n, err)
+		}
+		unread, err := s.ListNotifications(ctx, store.NotificationFilter{UnreadOnly: true})
+		if err != nil || len(unread) != 2 {
+			t.Fatalf("expected 2 unread notifications, got %d (%v)", len(unread), err)
+		}
+
+		if n, err := s.MarkAllNotificationsRead(ctx, ""); err != nil || n != 2 {
+			t.Fatalf("expected mark-all-read to mark 2, got %d (%v)", n, err)
+		}
+		if n, err := s.CountUnreadNotifications(ctx, ""); err != nil || n != 0 {
+			t.Fatalf("expected no unread notifications, got %d (%v)", n, err)
+		}
+
+		if _, err := s.MarkNotificationRead(ctx, "", agentIDs[0]); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound for an unknown notification, got %v", err)
+		}
+		if _, err := s.ListNotifications(ctx, store.NotificationFilter{Cursor: agentIDs[0]}); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound for an unknown cursor, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunNotificationRuleTests checks rule validation and storage, the rule cooldown, and the
+// agent and task fields rules are evaluated against.
+func RunNotificationRuleTests(t *testing.T, newStore Factory) {
+	t.Run("CreateValidatesAndDefaults", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "rules")
+
+		r, err := s.CreateNotificationRule(ctx, model.NotificationRule{
+			Name:       "failed in rules",
+			Trigger:    model.NotificationTriggerTaskStatus,
+			ChannelID:  ch.ID,
+			Status:     string(model.TaskStatusFailed),
+			ForSeconds: 60, // not used by task_status
+			Enabled:    true,
+		})
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if r.Severity != model.NotificationSeverityInfo || len(r.Targets) != 1 || r.Targets[0] != model.NotificationTargetDashboard || r.ForSeconds != 0 {
+			t.Fatalf("expected defaults to be filled in, got %+v", r)
+		}
+
+		for _, bad := range []model.NotificationRule{
+			{Name: "no trigger"},
+			{Name: "bad status", Trigger: model.NotificationTriggerTaskStatus, Status: "exploded"},
+			{Name: "no pattern", Trigger: model.NotificationTriggerEventType},
+			{Name: "bad pattern", Trigger: model.NotificationTriggerEventType, EventPattern: "["},
+			{Name: "bad target", Trigger: model.NotificationTriggerAgentOffline, Targets: []model.NotificationTarget{"pager"}},
+			{Trigger: model.NotificationTriggerAgentOffline},
+		} {
+			if _, err := s.CreateNotificationRule(ctx, bad); err == nil {
+				t.Fatalf("expected %q to be rejected", bad.Name)
+			}
+		}
+
+		r.Name = "renamed"
+		r.Trigger = model.NotificationTriggerClaudeStatus
+		r.Status = string(model.ClaudeStatusWaiting)
+		r.ForSeconds = 120
+		r.Targets = []model.NotificationTarget{model.NotificationTargetDashboard, model.NotificationTargetWebhook}
+		updated, err := s.UpdateNotificationRule(ctx, r)
+		if err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if updated.Name != "renamed" || updated.ChannelID != "" || updated.ForSeconds != 120 || len(updated.Targets) != 2 || updated.Targets[1] != model.NotificationTargetWebhook {
+			t.Fatalf("unexpected updated rule: %+v", updated)
+		}
+		if list, err := s.ListNotificationRules(ctx, ""); err != nil || len(list) != 1 {
+			t.Fatalf("expected one rule, got %d (%v)", len(list), err)
+		}
+		if err := s.DeleteNotificationRule(ctx, r.ID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := s.GetNotificationRule(ctx, r.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound after delete, got %v", err)
+		}
+	})
+
+	t.Run("FireHonorsCooldown", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		r, err := s.CreateNotificationRule(ctx, model.NotificationRule{Name: "offline", Trigger: model.NotificationTriggerAgentOffline, Enabled: true})
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+
+		if ok, err := s.FireNotificationRule(ctx, r.ID, time.Hour); err != nil || !ok {
+			t.Fatalf("expected the first delivery to be allowed, got %v (%v)", ok, err)
+		}
+		if ok, err := s.FireNotificationRule(ctx, r.ID, time.Hour); err != nil || ok {
+			t.Fatalf("expected a delivery within the cooldown to be refused, got %v (%v)", ok, err)
+		}
+		if ok, err := s.FireNotificationRule(ctx, r.ID, 0); err != nil || !ok {
+			t.Fatalf("expected a rule without cooldown to deliver, got %v (%v)", ok, err)
+		}
+		if got, _ := s.GetNotificationRule(ctx, r.ID); got.LastFiredAt == nil {
+			t.Fatalf("expected last_fired_at to be recorded")
+		}
+	})
+
+	t.Run("ClaudeStatusSince", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+
+		a, err := s.UpsertAgent(ctx, model.Agent{ID: agentIDs[0], Name: "rule-agent", ClaudeStatus: model.ClaudeStatusRunning})
+		if err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+		since := a.ClaudeStatusSince
+		time.Sleep(10 * time.Millisecond)
+		if a, err = s.UpsertAgent(ctx, model.Agent{ID: agentIDs[0], Name: "rule-agent", ClaudeStatus: model.ClaudeStatusRunning}); err != nil || !a.ClaudeStatusSince.Equal(since) {
+			t.Fatalf("expected claude_status_since to stay while the status is unchanged, got %v (%v)", a.ClaudeStatusSince, err)
+		}
+		if a, err = s.UpsertAgent(ctx, model.Agent{ID: agentIDs[0], Name: "rule-agent", ClaudeStatus: model.ClaudeStatusWaiting}); err != nil || !a.ClaudeStatusSince.After(since) {
+			t.Fatalf("expected claude_status_since to move with the status, got %v (%v)", a.ClaudeStatusSince, err)
+		}
+	})
+
+	t.Run("ListTasksByIDs", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "rules-ids")
+		chain, err := s.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: "rules-ids-chain", Status: model.ChainStatusQueued})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+		var ids []string
+		for i, title := range []string{"one", "two", "three"} {
+			task, err := s.CreateTask(ctx, model.Task{ChannelID: ch.ID, ChainID: chain.ID, Sequence: i + 1, Title: title})
+			if err != nil {
+				t.Fatalf("create task: %v", err)
+			}
+			ids = append(ids, task.ID)
+		}
+
+		got, err := s.ListTasks(ctx, store.TaskFilter{IDs: []string{ids[0], ids[2]}})
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(got) != 2 || got[0].ID != ids[0] || got[1].ID != ids[2] {
+			t.Fatalf("expected tasks one and three, got %+v", got)
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunPauseTests checks that ClaimTask skips paused and draining chains and channels, that
+// in-flight work can still finish while draining but is requeued by a pause, and that
+// resuming makes the tasks claimable again.
+func RunPauseTests(t *testing.T, newStore Factory) {
+	t.Run("PausedChainIsSkipped", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "pause-chain")
+		held := createChainTask(t, s, ch, "held", 1, 10)
+		other := createChainTask(t, s, ch, "other", 1, 0)
+
+		chain, _, err := s.SetChainPause(ctx, held.ChainID, model.PauseModePaused)
+		if err != nil {
+			t.Fatalf("pause: %v", err)
+		}
+		if chain.Pause != model.PauseModePaused || chain.PausedAt == nil {
+			t.Fatalf("unexpected paused chain: %+v", chain)
+		}
+		if got, _ := s.GetChain(ctx, held.ChainID); got.Pause != model.PauseModePaused {
+			t.Fatalf("expected pause to be stored, got %q", got.Pause)
+		}
+
+		// The higher priority task is skipped while its chain is paused.
+		if got := claim(t, ctx, s, ch, agentIDs[0], 0); got.ID != other.ID {
+			t.Fatalf("expected %q, got %q", other.Title, got.Title)
+		}
+		if _, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[1], ChannelID: ch.ID}); !errors.Is(err, store.ErrNoQueuedTasks) {
+			t.Fatalf("expected nothing to claim, got %v", err)
+		}
+
+		resumed, _, err := s.SetChainPause(ctx, held.ChainID, "")
+		if err != nil {
+			t.Fatalf("resume: %v", err)
+		}
+		if resumed.Pause != "" || resumed.PausedAt != nil {
+			t.Fatalf("expected pause to be cleared, got %+v", resumed)
+		}
+		if got := claim(t, ctx, s, ch, agentIDs[1], 0); got.ID != held.ID {
+			t.Fatalf("expected %q after resume, got %q", held.Title, got.Title)
+		}
+	})
+
+	t.Run("DrainLetsInFlightTaskFinish", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "pause-drain")
+		chain, tasks, err := s.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "drain"}, []store.NewChainTask{
+			{Task: model.Task{Title: "first"}},
+			{Task: model.Task{Title: "second"}},
+		})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+		claim(t, ctx, s, ch, agentIDs[0], 0)
+
+		if _, requeued, err := s.SetChainPause(ctx, chain.ID, model.PauseModeDraining); err != nil || len(requeued) != 0 {
+			t.Fatalf("drain: expected nothing requeued, got %+v (%v)", requeued, err)
+		}
+		if _, err := s.CompleteTask(ctx, store.CompleteTaskRequest{TaskID: tasks[0].ID, AgentID: agentIDs[0]}); err != nil {
+			t.Fatalf("expected the in-flight task to complete, got %v", err)
+		}
+		if _, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[0], ChannelID: ch.ID}); !errors.Is(err, store.ErrNoQueuedTasks) {
+			t.Fatalf("expected the next sequence to be held, got %v", err)
+		}
+
+		if _, _, err := s.SetChainPause(ctx, chain.ID, ""); err != nil {
+			t.Fatalf("resume: %v", err)
+		}
+		if got := claim(t, ctx, s, ch, agentIDs[0], 0); got.ID != tasks[1].ID {
+			t.Fatalf("expected %q after resume, got %q", tasks[1].Title, got.Title)
+		}
+	})
+
+	t.Run("PauseRequeuesInFlightTask", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "pause-stop")
+		chain, tasks, err := s.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "stop"}, []store.NewChainTask{
+			{Task: model.Task{Title: "first"}},
+			{Task: model.Task{Title: "second"}},
+		})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+		claim(t, ctx, s, ch, agentIDs[0], 0)
+
+		paused, requeued, err := s.SetChainPause(ctx, chain.ID, model.PauseModePaused)
+		if err != nil {
+			t.Fatalf("pause: %v", err)
+		}
+		if len(requeued) != 1 || requeued[0].ID != tasks[0].ID || requeued[0].Status != model.TaskStatusQueued || requeued[0].AssignedAgentID != "" {
+			t.Fatalf("expected %q to be requeued, got %+v", tasks[0].Title, requeued)
+		}
+		if requeued[0].Attempts != 0 {
+			t.Fatalf("expected the attempt to be given back, got %d", requeued[0].Attempts)
+		}
+		if paused.OwnerAgentID != "" {
+			t.Fatalf("expected chain ownership to be released, got %q", paused.OwnerAgentID)
+		}
+
+		// The agent's claim is gone, and nothing is handed out until resume.
+		if _, err := s.CompleteTask(ctx, store.CompleteTaskRequest{TaskID: tasks[0].ID, AgentID: agentIDs[0]}); err == nil {
+			t.Fatalf("expected completing a requeued task to fail")
+		}
+		if _, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[1], ChannelID: ch.ID}); !errors.Is(err, store.ErrNoQueuedTasks) {
+			t.Fatalf("expected nothing to claim while paused, got %v", err)
+		}
+
+		if _, _, err := s.SetChainPause(ctx, chain.ID, ""); err != nil {
+			t.Fatalf("resume: %v", err)
+		}
+		if got := claim(t, ctx, s, ch, agentIDs[1], 0); got.ID != tasks[0].ID {
+			t.Fatalf("expected %q to run again after resume, got %q", tasks[0].Title, got.Title)
+		}
+
+		// Pausing the channel stops its in-flight work the same way.
+		_, requeued, err = s.SetChannelPause(ctx, ch.ID, model.PauseModePaused)
+		if err != nil || len(requeued) != 1 || requeued[0].ID != tasks[0].ID {
+			t.Fatalf("expected the channel pause to requeue %q, got %+v (%v)", tasks[0].Title, requeued, err)
+		}
+	})
+
+	t.Run("PausedChannelIsSkipped", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "pause-channel")
+		task := createChainTask(t, s, ch, "pause-channel", 1, 0)
+
+		paused, _, err := s.SetChannelPause(ctx, ch.ID, model.PauseModeDraining)
+		if err != nil {
+			t.Fatalf("pause channel: %v", err)
+		}
+		if paused.Pause != model.PauseModeDraining || paused.PausedAt == nil {
+			t.Fatalf("unexpected paused channel: %+v", paused)
+		}
+		if _, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentIDs[0], ChannelID: ch.ID}); !errors.Is(err, store.ErrNoQueuedTasks) {
+			t.Fatalf("expected nothing to claim, got %v", err)
+		}
+
+		// Switching from draining to paused keeps the original paused_at.
+		again, _, err := s.SetChannelPause(ctx, ch.ID, model.PauseModePaused)
+		if err != nil || again.PausedAt == nil || !again.PausedAt.Equal(*paused.PausedAt) {
+			t.Fatalf("expected paused_at to be kept, got %+v (%v)", again, err)
+		}
+
+		if _, _, err := s.SetChannelPause(ctx, ch.ID, ""); err != nil {
+			t.Fatalf("resume channel: %v", err)
+		}
+		if got := claim(t, ctx, s, ch, agentIDs[0], 0); got.ID != task.ID {
+			t.Fatalf("expected %q after resume, got %q", task.Title, got.Title)
+		}
+	})
+
+	t.Run("InvalidModeAndUnknownIDs", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "pause-invalid")
+
+		if _, _, err := s.SetChannelPause(ctx, ch.ID, "frozen"); err == nil {
+			t.Fatalf("expected an invalid pause mode to be rejected")
+		}
+		if _, _, err := s.SetChainPause(ctx, "c0000000-0000-4000-8000-000000000000", model.PauseModePaused); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("expected not found for chain, got %v", err)
+		}
+		if _, _, err := s.SetChannelPause(ctx, "c0000000-0000-4000-8000-000000000000", model.PauseModePaused); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("expected not found for channel, got %v", err)
+		}
+	})
+}
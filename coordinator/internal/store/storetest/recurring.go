@@ -0,0 +1,114 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunRecurringScheduleTests checks schedule persistence and run history.
+func RunRecurringScheduleTests(t *testing.T, newStore Factory) {
+	t.Run("CRUD", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "recurring-crud")
+		next := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+
+		created, err := s.CreateSchedule(ctx, model.Schedule{
+			ChannelID: ch.ID,
+			Name:      "nightly",
+			Cron:      "0 2 * * *",
+			Tasks:     []model.ScheduleTaskTemplate{{Title: "update deps"}, {Title: "run lint fix", Priority: 5}},
+			Enabled:   true,
+			NextRunAt: &next,
+		})
+		if err != nil {
+			t.Fatalf("create schedule: %v", err)
+		}
+		if created.Timezone != "UTC" || len(created.Tasks) != 2 || created.NextRunAt == nil || !created.NextRunAt.Equal(next) {
+			t.Fatalf("unexpected schedule: %+v", created)
+		}
+
+		created.Enabled = false
+		created.NextRunAt = nil
+		created.Tasks = created.Tasks[:1]
+		updated, err := s.UpdateSchedule(ctx, created)
+		if err != nil {
+			t.Fatalf("update schedule: %v", err)
+		}
+		if updated.Enabled || updated.NextRunAt != nil || len(updated.Tasks) != 1 {
+			t.Fatalf("update not applied: %+v", updated)
+		}
+
+		list, err := s.ListSchedules(ctx, "")
+		if err != nil || len(list) != 1 || list[0].ID != created.ID {
+			t.Fatalf("list schedules: %v %+v", err, list)
+		}
+
+		if err := s.DeleteSchedule(ctx, created.ID); err != nil {
+			t.Fatalf("delete schedule: %v", err)
+		}
+		if _, err := s.GetSchedule(ctx, created.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("expected not found after delete, got %v", err)
+		}
+	})
+
+	t.Run("RejectsEmptyTaskList", func(t *testing.T) {
+		s := newStore(t)
+		ch := createChannel(t, s, "recurring-empty")
+		_, err := s.CreateSchedule(context.Background(), model.Schedule{ChannelID: ch.ID, Name: "empty", Cron: "@daily"})
+		if err == nil {
+			t.Fatalf("expected a schedule without tasks to be rejected")
+		}
+	})
+
+	t.Run("RecordRunAdvancesSchedule", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "recurring-runs")
+		first := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+		sc, err := s.CreateSchedule(ctx, model.Schedule{
+			ChannelID: ch.ID, Name: "runs", Cron: "* * * * *", Enabled: true, NextRunAt: &first,
+			Tasks: []model.ScheduleTaskTemplate{{Title: "summarize failures"}},
+		})
+		if err != nil {
+			t.Fatalf("create schedule: %v", err)
+		}
+		chain, err := s.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: "runs 1", Status: model.ChainStatusQueued})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+
+		second := first.Add(time.Minute)
+		if _, err := s.RecordScheduleRun(ctx, model.ScheduleRun{ScheduleID: sc.ID, Status: model.ScheduleRunStatusCreated, ChainID: chain.ID, ScheduledFor: first}, &second); err != nil {
+			t.Fatalf("record created run: %v", err)
+		}
+		third := second.Add(time.Minute)
+		if _, err := s.RecordScheduleRun(ctx, model.ScheduleRun{ScheduleID: sc.ID, Status: model.ScheduleRunStatusSkipped, Reason: "previous_run_active", ScheduledFor: second}, &third); err != nil {
+			t.Fatalf("record skipped run: %v", err)
+		}
+
+		got, err := s.GetSchedule(ctx, sc.ID)
+		if err != nil {
+			t.Fatalf("get schedule: %v", err)
+		}
+		if got.LastChainID != chain.ID {
+			t.Fatalf("a skipped run must keep last_chain_id, got %q", got.LastChainID)
+		}
+		if got.LastRunAt == nil || !got.LastRunAt.Equal(second) || got.NextRunAt == nil || !got.NextRunAt.Equal(third) {
+			t.Fatalf("schedule not advanced: last=%v next=%v", got.LastRunAt, got.NextRunAt)
+		}
+
+		runs, err := s.ListScheduleRuns(ctx, sc.ID, 10)
+		if err != nil {
+			t.Fatalf("list runs: %v", err)
+		}
+		if len(runs) != 2 || runs[0].Status != model.ScheduleRunStatusSkipped || runs[1].ChainID != chain.ID {
+			t.Fatalf("expected newest-first history, got %+v", runs)
+		}
+	})
+}
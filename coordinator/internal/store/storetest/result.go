@@ -0,0 +1,140 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunTaskResultTests checks that CompleteTask and FailTask store the reported result and
+// artifacts, that a later attempt replaces them, and that chain results list in order.
+func RunTaskResultTests(t *testing.T, newStore Factory) {
+	t.Run("CompleteStoresResult", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "result-complete")
+		chain, tasks, err := s.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "result-complete"}, []store.NewChainTask{
+			{Task: model.Task{Title: "write migration"}},
+			{Task: model.Task{Title: "apply migration"}},
+		})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+
+		claim(t, ctx, s, ch, agentIDs[0], 0)
+		if _, err := s.GetTaskResult(ctx, tasks[0].ID); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("expected no result before completion, got %v", err)
+		}
+		_, err = s.CompleteTask(ctx, store.CompleteTaskRequest{
+			TaskID:  tasks[0].ID,
+			AgentID: agentIDs[0],
+			Result:  map[string]any{"summary": "added 0042_users.sql", "pr_url": "https://example.com/pr/7"},
+			Artifacts: []model.TaskArtifact{
+				{Name: "diff", ContentType: "text/x-diff", Content: "+create table users"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+
+		got, err := s.GetTaskResult(ctx, tasks[0].ID)
+		if err != nil {
+			t.Fatalf("get result: %v", err)
+		}
+		if got.ChainID != chain.ID || got.AgentID != agentIDs[0] || got.Status != model.TaskStatusDone || got.Attempt != 1 {
+			t.Fatalf("unexpected result metadata: %+v", got)
+		}
+		if got.Result["pr_url"] != "https://example.com/pr/7" {
+			t.Fatalf("unexpected result payload: %v", got.Result)
+		}
+		if len(got.Artifacts) != 1 || got.Artifacts[0].Name != "diff" || got.Artifacts[0].Content != "+create table users" {
+			t.Fatalf("unexpected artifacts: %+v", got.Artifacts)
+		}
+
+		// Completing again is a no-op and keeps the stored result.
+		if _, err := s.CompleteTask(ctx, store.CompleteTaskRequest{TaskID: tasks[0].ID, Result: map[string]any{"summary": "again"}}); err != nil {
+			t.Fatalf("complete again: %v", err)
+		}
+		if again, _ := s.GetTaskResult(ctx, tasks[0].ID); again.Result["summary"] != "added 0042_users.sql" {
+			t.Fatalf("expected the first result to be kept, got %v", again.Result)
+		}
+
+		// A completion without result stores nothing.
+		claim(t, ctx, s, ch, agentIDs[0], 0)
+		if _, err := s.CompleteTask(ctx, store.CompleteTaskRequest{TaskID: tasks[1].ID, AgentID: agentIDs[0]}); err != nil {
+			t.Fatalf("complete second: %v", err)
+		}
+		if _, err := s.GetTaskResult(ctx, tasks[1].ID); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("expected no result without payload, got %v", err)
+		}
+
+		results, err := s.ListTaskResults(ctx, chain.ID)
+		if err != nil || len(results) != 1 || results[0].TaskID != tasks[0].ID {
+			t.Fatalf("expected one chain result, got %+v (%v)", results, err)
+		}
+	})
+
+	t.Run("RetryReplacesFailureResult", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "result-retry")
+		_, tasks, err := s.CreateChainWithTasks(ctx, model.Chain{ChannelID: ch.ID, Name: "result-retry"}, []store.NewChainTask{
+			{Task: model.Task{Title: "flaky", MaxAttempts: 2}},
+		})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+		task := tasks[0]
+
+		claim(t, ctx, s, ch, agentIDs[0], 0)
+		if _, err := s.FailTask(ctx, store.FailTaskRequest{
+			TaskID:    task.ID,
+			AgentID:   agentIDs[0],
+			Reason:    "tests failed",
+			Result:    map[string]any{"failed_tests": float64(3)},
+			Artifacts: []model.TaskArtifact{{Name: "log", URL: "https://example.com/logs/1"}},
+		}); err != nil {
+			t.Fatalf("fail: %v", err)
+		}
+		failed, err := s.GetTaskResult(ctx, task.ID)
+		if err != nil || failed.Status != model.TaskStatusQueued || failed.Result["failed_tests"] != float64(3) {
+			t.Fatalf("unexpected failure result: %+v (%v)", failed, err)
+		}
+
+		claim(t, ctx, s, ch, agentIDs[0], 0)
+		if _, err := s.CompleteTask(ctx, store.CompleteTaskRequest{TaskID: task.ID, AgentID: agentIDs[0], Result: map[string]any{"summary": "green"}}); err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+		done, err := s.GetTaskResult(ctx, task.ID)
+		if err != nil || done.Status != model.TaskStatusDone || done.Attempt != 2 || len(done.Artifacts) != 0 {
+			t.Fatalf("expected the retry to replace the result, got %+v (%v)", done, err)
+		}
+	})
+
+	t.Run("InvalidArtifactsRejected", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "result-invalid")
+		task := createChainTask(t, s, ch, "result-invalid", 1, 0)
+		claim(t, ctx, s, ch, agentIDs[0], 0)
+
+		for name, artifacts := range map[string][]model.TaskArtifact{
+			"unnamed":   {{Content: "x"}},
+			"duplicate": {{Name: "a", Content: "x"}, {Name: "a", Content: "y"}},
+			"empty":     {{Name: "a"}},
+			"too large": {{Name: "a", Content: strings.Repeat("x", store.MaxTaskArtifactBytes+1)}},
+		} {
+			if _, err := s.CompleteTask(ctx, store.CompleteTaskRequest{TaskID: task.ID, AgentID: agentIDs[0], Artifacts: artifacts}); err == nil {
+				t.Fatalf("%s: expected artifacts to be rejected", name)
+			}
+		}
+		// Nothing changed: the task can still be completed.
+		if got, err := s.CompleteTask(ctx, store.CompleteTaskRequest{TaskID: task.ID, AgentID: agentIDs[0]}); err != nil || got.Status != model.TaskStatusDone {
+			t.Fatalf("expected completion after rejected attempts, got %+v (%v)", got, err)
+		}
+	})
+}
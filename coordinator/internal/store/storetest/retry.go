@@ -0,0 +1,77 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunRetryTests checks that only failed attempts use up a task's retry budget: a claim
+// that is requeued (lease expiry, offline agent) gives its attempt back.
+func RunRetryTests(t *testing.T, newStore Factory) {
+	t.Run("RequeueKeepsRetryBudget", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "retry-requeue")
+		chain, err := s.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: "flaky", Status: model.ChainStatusQueued})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+		task, err := s.CreateTask(ctx, model.Task{ChannelID: ch.ID, ChainID: chain.ID, Sequence: 1, Title: "flaky", MaxAttempts: 2})
+		if err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+		attempts := func() int {
+			t.Helper()
+			tasks, err := s.ListTasks(ctx, store.TaskFilter{IDs: []string{task.ID}})
+			if err != nil || len(tasks) != 1 {
+				t.Fatalf("list task: %v (%v)", tasks, err)
+			}
+			return tasks[0].Attempts
+		}
+		claimLeased := func(agentID string) {
+			t.Helper()
+			if _, err := s.UpsertAgent(ctx, model.Agent{ID: agentID, Name: "agent-" + agentID[len(agentID)-1:]}); err != nil {
+				t.Fatalf("upsert agent: %v", err)
+			}
+			got, err := s.ClaimTask(ctx, store.ClaimTaskRequest{AgentID: agentID, ChannelID: ch.ID, LeaseSeconds: 1})
+			if err != nil || got.ID != task.ID {
+				t.Fatalf("claim: %v (%v)", got, err)
+			}
+		}
+
+		// Three claims lost to a lease expiry and an offline agent do not exhaust two attempts.
+		claimLeased(agentIDs[0])
+		if _, err := s.RequeueExpiredTasks(ctx, time.Now().UTC().Add(time.Hour)); err != nil {
+			t.Fatalf("requeue expired: %v", err)
+		}
+		claimLeased(agentIDs[1])
+		if _, err := s.RequeueTask(ctx, store.RequeueTaskRequest{TaskID: task.ID, AgentID: agentIDs[1], Reason: "agent_offline"}); err != nil {
+			t.Fatalf("requeue: %v", err)
+		}
+		if n := attempts(); n != 0 {
+			t.Fatalf("expected requeues to give their attempts back, got %d", n)
+		}
+
+		claimLeased(agentIDs[2])
+		failed, err := s.FailTask(ctx, store.FailTaskRequest{TaskID: task.ID, AgentID: agentIDs[2], Reason: "exit 1"})
+		if err != nil {
+			t.Fatalf("fail: %v", err)
+		}
+		if failed.Status != model.TaskStatusQueued || failed.Attempts != 1 {
+			t.Fatalf("expected the first failure to be retried, got status %q after %d attempts", failed.Status, failed.Attempts)
+		}
+
+		claimLeased(agentIDs[2]) // still the chain owner
+		failed, err = s.FailTask(ctx, store.FailTaskRequest{TaskID: task.ID, AgentID: agentIDs[2], Reason: "exit 1"})
+		if err != nil {
+			t.Fatalf("fail: %v", err)
+		}
+		if failed.Status != model.TaskStatusDeadLetter || failed.Attempts != 2 {
+			t.Fatalf("expected the second failure to exhaust the budget, got status %q after %d attempts", failed.Status, failed.Attempts)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunScheduleTests checks that ClaimTask honors not_before and that NextClaimableAt
+// reports when a scheduled task opens up.
+func RunScheduleTests(t *testing.T, newStore Factory) {
+	t.Run("FutureNotBeforeIsSkipped", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "schedule-future")
+		chain, err := s.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: "schedule-future-chain", Status: model.ChainStatusQueued})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+		notBefore := time.Now().UTC().Add(2 * time.Hour).Truncate(time.Second)
+		if _, err := s.CreateTask(ctx, model.Task{ChannelID: ch.ID, ChainID: chain.ID, Sequence: 1, Title: "overnight", NotBefore: &notBefore}); err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+
+		req := store.ClaimTaskRequest{AgentID: agentIDs[0], ChannelID: ch.ID}
+		if _, err := s.ClaimTask(ctx, req); !errors.Is(err, store.ErrNoQueuedTasks) {
+			t.Fatalf("expected scheduled task to be skipped, got %v", err)
+		}
+		next, err := s.NextClaimableAt(ctx, req)
+		if err != nil {
+			t.Fatalf("next claimable: %v", err)
+		}
+		if next == nil || !next.Equal(notBefore) {
+			t.Fatalf("expected next claimable at %v, got %v", notBefore, next)
+		}
+	})
+
+	t.Run("PastNotBeforeIsClaimable", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "schedule-past")
+		chain, err := s.CreateChain(ctx, model.Chain{ChannelID: ch.ID, Name: "schedule-past-chain", Status: model.ChainStatusQueued})
+		if err != nil {
+			t.Fatalf("create chain: %v", err)
+		}
+		notBefore := time.Now().UTC().Add(-time.Minute)
+		task, err := s.CreateTask(ctx, model.Task{ChannelID: ch.ID, ChainID: chain.ID, Sequence: 1, Title: "due", NotBefore: &notBefore})
+		if err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+
+		if got := claim(t, ctx, s, ch, agentIDs[0], 0); got.ID != task.ID {
+			t.Fatalf("expected due task, got %q", got.Title)
+		}
+	})
+
+	t.Run("NoHintWithoutScheduledTasks", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+		ch := createChannel(t, s, "schedule-empty")
+
+		next, err := s.NextClaimableAt(ctx, store.ClaimTaskRequest{AgentID: agentIDs[0], ChannelID: ch.ID})
+		if err != nil {
+			t.Fatalf("next claimable: %v", err)
+		}
+		if next != nil {
+			t.Fatalf("expected no hint, got %v", next)
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"clwclw-monitor/coordinator/internal/model"
+	"clwclw-monitor/coordinator/internal/store"
+)
+
+// RunWebhookTests checks webhook validation and storage, and the delivery queue: claiming
+// due deliveries under a lease, recording attempts and listing the delivery log.
+func RunWebhookTests(t *testing.T, newStore Factory) {
+	t.Run("CreateValidates", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+
+		w, err := s.CreateWebhook(ctx, model.Webhook{
+			Name:    "ci",
+			URL:     "https://ci.example.com/hook",
+			Secret:  "s3cret",
+			Topics:  []string{model.WebhookTopicTasks, model.WebhookTopicTasks, model.WebhookTopicChains},
+			Enabled: true,
+		})
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if len(w.Topics) != 2 || w.Secret != "s3cret" {
+			t.Fatalf("unexpected webhook: %+v", w)
+		}
+
+		for _, bad := range []model.Webhook{
+			{Name: "no url", Secret: "x", Topics: []string{model.WebhookTopicTasks}},
+			{Name: "bad scheme", URL: "ftp://example.com", Secret: "x", Topics: []string{model.WebhookTopicTasks}},
+			{Name: "no secret", URL: "https://example.com", Topics: []string{model.WebhookTopicTasks}},
+			{Name: "no topics", URL: "https://example.com", Secret: "x"},
+			{Name: "bad topic", URL: "https://example.com", Secret: "x", Topics: []string{"schedules"}},
+		} {
+			if _, err := s.CreateWebhook(ctx, bad); err == nil {
+				t.Fatalf("expected %q to be rejected", bad.Name)
+			}
+		}
+
+		w.Enabled = false
+		w.Topics = []string{model.WebhookTopicAgents}
+		updated, err := s.UpdateWebhook(ctx, w)
+		if err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if updated.Enabled || len(updated.Topics) != 1 || updated.Topics[0] != model.WebhookTopicAgents {
+			t.Fatalf("unexpected updated webhook: %+v", updated)
+		}
+		if list, err := s.ListWebhooks(ctx, ""); err != nil || len(list) != 1 {
+			t.Fatalf("expected one webhook, got %v (%v)", list, err)
+		}
+
+		if err := s.DeleteWebhook(ctx, w.ID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := s.GetWebhook(ctx, w.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound after delete, got %v", err)
+		}
+	})
+
+	t.Run("DeliveryQueue", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+
+		w, err := s.CreateWebhook(ctx, model.Webhook{Name: "chat", URL: "http://chat.example.com", Secret: "x", Topics: []string{model.WebhookTopicEvents}, Enabled: true})
+		if err != nil {
+			t.Fatalf("create webhook: %v", err)
+		}
+		d, err := s.CreateWebhookDelivery(ctx, model.WebhookDelivery{WebhookID: w.ID, Topic: model.WebhookTopicEvents, Payload: map[string]any{"n": 1.0}})
+		if err != nil {
+			t.Fatalf("create delivery: %v", err)
+		}
+		if d.Status != model.WebhookDeliveryPending || d.NextAttemptAt == nil || d.Payload["n"] != 1.0 {
+			t.Fatalf("unexpected delivery: %+v", d)
+		}
+
+		now := time.Now().UTC().Add(time.Second)
+		claimed, err := s.ClaimWebhookDeliveries(ctx, now, time.Minute, 10)
+		if err != nil || len(claimed) != 1 || claimed[0].ID != d.ID {
+			t.Fatalf("expected to claim the delivery, got %v (%v)", claimed, err)
+		}
+		// The lease keeps the delivery from being claimed twice.
+		if again, err := s.ClaimWebhookDeliveries(ctx, now, time.Minute, 10); err != nil || len(again) != 0 {
+			t.Fatalf("expected nothing to claim under the lease, got %v (%v)", again, err)
+		}
+
+		retryAt := now.Add(time.Hour)
+		d, err = s.RecordWebhookAttempt(ctx, store.RecordWebhookAttemptRequest{DeliveryID: d.ID, ResponseCode: 503, RetryAt: &retryAt})
+		if err != nil {
+			t.Fatalf("record attempt: %v", err)
+		}
+		if d.Status != model.WebhookDeliveryPending || d.Attempts != 1 || d.ResponseCode != 503 || d.NextAttemptAt == nil {
+			t.Fatalf("expected a pending retry, got %+v", d)
+		}
+		if due, _ := s.ClaimWebhookDeliveries(ctx, now, time.Minute, 10); len(due) != 0 {
+			t.Fatalf("expected the retry to wait for its backoff, got %v", due)
+		}
+		if due, _ := s.ClaimWebhookDeliveries(ctx, retryAt.Add(time.Second), time.Minute, 10); len(due) != 1 {
+			t.Fatalf("expected the retry to be due after its backoff, got %v", due)
+		}
+
+		d, err = s.RecordWebhookAttempt(ctx, store.RecordWebhookAttemptRequest{DeliveryID: d.ID, Succeeded: true, ResponseCode: 200})
+		if err != nil {
+			t.Fatalf("record attempt: %v", err)
+		}
+		if d.Status != model.WebhookDeliverySucceeded || d.Attempts != 2 || d.DeliveredAt == nil || d.NextAttemptAt != nil {
+			t.Fatalf("expected a succeeded delivery, got %+v", d)
+		}
+
+		replay, err := s.CreateWebhookDelivery(ctx, model.WebhookDelivery{WebhookID: w.ID, Topic: d.Topic, Payload: d.Payload, ReplayOf: d.ID})
+		if err != nil {
+			t.Fatalf("create replay: %v", err)
+		}
+		deliveries, err := s.ListWebhookDeliveries(ctx, w.ID, 10)
+		if err != nil || len(deliveries) != 2 || deliveries[0].ID != replay.ID || deliveries[0].ReplayOf != d.ID {
+			t.Fatalf("expected the replay first in the log, got %v (%v)", deliveries, err)
+		}
+
+		if _, err := s.CreateWebhookDelivery(ctx, model.WebhookDelivery{WebhookID: "00000000-0000-4000-8000-000000000000", Topic: model.WebhookTopicEvents}); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound for an unknown webhook, got %v", err)
+		}
+	})
+
+	t.Run("PurgeDeliveries", func(t *testing.T) {
+		s := newStore(t)
+		ctx := context.Background()
+
+		w, err := s.CreateWebhook(ctx, model.Webhook{Name: "chat", URL: "http://chat.example.com", Secret: "x", Topics: []string{model.WebhookTopicEvents}, Enabled: true})
+		if err != nil {
+			t.Fatalf("create webhook: %v", err)
+		}
+		create := func() model.WebhookDelivery {
+			t.Helper()
+			d, err := s.CreateWebhookDelivery(ctx, model.WebhookDelivery{WebhookID: w.ID, Topic: model.WebhookTopicEvents})
+			if err != nil {
+				t.Fatalf("create delivery: %v", err)
+			}
+			return d
+		}
+		succeeded, failed, pending := create(), create(), create()
+		if _, err := s.RecordWebhookAttempt(ctx, store.RecordWebhookAttemptRequest{DeliveryID: succeeded.ID, Succeeded: true, ResponseCode: 200}); err != nil {
+			t.Fatalf("record attempt: %v", err)
+		}
+		if _, err := s.RecordWebhookAttempt(ctx, store.RecordWebhookAttemptRequest{DeliveryID: failed.ID, ResponseCode: 500}); err != nil {
+			t.Fatalf("record attempt: %v", err)
+		}
+		replay, err := s.CreateWebhookDelivery(ctx, model.WebhookDelivery{WebhookID: w.ID, Topic: model.WebhookTopicEvents, ReplayOf: failed.ID})
+		if err != nil {
+			t.Fatalf("create replay: %v", err)
+		}
+
+		if n, err := s.PurgeWebhookDeliveriesBefore(ctx, time.Now().UTC().Add(-time.Hour)); err != nil || n != 0 {
+			t.Fatalf("expected nothing older than the cutoff, removed %d (%v)", n, err)
+		}
+		n, err := s.PurgeWebhookDeliveriesBefore(ctx, time.Now().UTC().Add(time.Hour))
+		if err != nil || n != 2 {
+			t.Fatalf("expected the succeeded and failed deliveries to be purged, removed %d (%v)", n, err)
+		}
+		for _, id := range []string{succeeded.ID, failed.ID} {
+			if _, err := s.GetWebhookDelivery(ctx, id); !errors.Is(err, store.ErrNotFound) {
+				t.Fatalf("expected %s to be purged, got %v", id, err)
+			}
+		}
+		if _, err := s.GetWebhookDelivery(ctx, pending.ID); err != nil {
+			t.Fatalf("expected the pending delivery to be kept: %v", err)
+		}
+		if got, err := s.GetWebhookDelivery(ctx, replay.ID); err != nil || got.ReplayOf != "" {
+			t.Fatalf("expected the replay to be kept without its original, got %+v (%v)", got, err)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+// placeholderRe matches {{name}} (surrounding spaces allowed) in template text.
+var placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+var paramNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateTemplate checks a template before it is stored: named, at least one titled task,
+// unique task keys, depends_on referring to known keys without cycles, and every
+// placeholder declared as a parameter.
+func ValidateTemplate(tpl model.Template) error {
+	if strings.TrimSpace(tpl.Name) == "" {
+		return errors.New("name_required")
+	}
+	if len(tpl.Tasks) == 0 {
+		return errors.New("tasks_required")
+	}
+	if !ValidFailurePolicy(tpl.ChainOnFailure) {
+		return fmt.Errorf("on_failure_invalid: %s", tpl.ChainOnFailure)
+	}
+
+	params := make(map[string]struct{}, len(tpl.Params))
+	for _, p := range tpl.Params {
+		if !paramNameRe.MatchString(p.Name) {
+			return fmt.Errorf("param_invalid: %q", p.Name)
+		}
+		if _, ok := params[p.Name]; ok {
+			return fmt.Errorf("param_duplicate: %s", p.Name)
+		}
+		params[p.Name] = struct{}{}
+	}
+
+	keys := make(map[string]struct{}, len(tpl.Tasks))
+	for _, t := range tpl.Tasks {
+		if t.Key == "" {
+			continue
+		}
+		if _, ok := keys[t.Key]; ok {
+			return fmt.Errorf("task_key_duplicate: %s", t.Key)
+		}
+		keys[t.Key] = struct{}{}
+	}
+
+	texts := []string{tpl.ChainName, tpl.ChainDescription}
+	for _, t := range tpl.Tasks {
+		if strings.TrimSpace(t.Title) == "" {
+			return errors.New("task_title_required")
+		}
+		if !ValidTaskType(t.Type) {
+			return fmt.Errorf("task_type_invalid: %s", t.Type)
+		}
+		if _, err := NormalizeLabels(t.Requires); err != nil {
+			return err
+		}
+		for _, dep := range t.DependsOn {
+			if _, ok := keys[dep]; !ok {
+				return fmt.Errorf("depends_on_unknown_key: %s", dep)
+			}
+		}
+		texts = append(texts, t.Title, t.Description)
+	}
+	for _, text := range texts {
+		for _, m := range placeholderRe.FindAllStringSubmatch(text, -1) {
+			if _, ok := params[m[1]]; !ok {
+				return fmt.Errorf("param_undeclared: %s", m[1])
+			}
+		}
+	}
+
+	_, tasks, err := InstantiateTemplate(tpl, dryRunParams(tpl))
+	if err != nil {
+		return err
+	}
+	return ValidateNewChainTasks(tasks)
+}
+
+// dryRunParams gives every parameter a non-empty value so a template can be instantiated
+// once at validation time to check its dependency graph.
+func dryRunParams(tpl model.Template) map[string]string {
+	values := make(map[string]string, len(tpl.Params))
+	for _, p := range tpl.Params {
+		values[p.Name] = p.Name
+	}
+	return values
+}
+
+// InstantiateTemplate renders tpl with params into the chain and task list to pass to
+// CreateChainWithTasks. Missing required parameters and unknown ones are errors.
+func InstantiateTemplate(tpl model.Template, params map[string]string) (model.Chain, []NewChainTask, error) {
+	values := make(map[string]string, len(tpl.Params))
+	var missing []string
+	for _, p := range tpl.Params {
+		if v, ok := params[p.Name]; ok {
+			values[p.Name] = v
+		} else if p.Default != nil {
+			values[p.Name] = *p.Default
+		} else {
+			missing = append(missing, p.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return model.Chain{}, nil, fmt.Errorf("param_required: %s", strings.Join(missing, ", "))
+	}
+	var unknown []string
+	for name := range params {
+		if _, ok := values[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return model.Chain{}, nil, fmt.Errorf("param_unknown: %s", strings.Join(unknown, ", "))
+	}
+
+	render := func(text string) string {
+		return placeholderRe.ReplaceAllStringFunc(text, func(m string) string {
+			return values[placeholderRe.FindStringSubmatch(m)[1]]
+		})
+	}
+
+	chainName := tpl.ChainName
+	if strings.TrimSpace(chainName) == "" {
+		chainName = tpl.Name
+	}
+	chain := model.Chain{
+		Name:        strings.TrimSpace(render(chainName)),
+		Description: strings.TrimSpace(render(tpl.ChainDescription)),
+		Status:      model.ChainStatusQueued,
+		OnFailure:   tpl.ChainOnFailure,
+	}
+
+	index := make(map[string]int, len(tpl.Tasks))
+	for i, t := range tpl.Tasks {
+		if t.Key != "" {
+			index[t.Key] = i
+		}
+	}
+	tasks := make([]NewChainTask, len(tpl.Tasks))
+	for i, t := range tpl.Tasks {
+		tasks[i].Task = model.Task{
+			Title:               strings.TrimSpace(render(t.Title)),
+			Description:         strings.TrimSpace(render(t.Description)),
+			Type:                t.Type,
+			Compensation:        t.Compensation,
+			Requires:            t.Requires,
+			Priority:            t.Priority,
+			ExecutionMode:       t.ExecutionMode,
+			MaxAttempts:         t.MaxAttempts,
+			RetryBackoffSeconds: t.RetryBackoffSeconds,
+		}
+		for _, dep := range t.DependsOn {
+			j, ok := index[dep]
+			if !ok {
+				return model.Chain{}, nil, fmt.Errorf("depends_on_unknown_key: %s", dep)
+			}
+			tasks[i].DependsOn = append(tasks[i].DependsOn, j)
+		}
+	}
+	return chain, tasks, nil
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+func refactorTemplate() model.Template {
+	defaultBranch := "main"
+	return model.Template{
+		Name:      "refactor",
+		ChainName: "Refactor {{module}}",
+		Params: []model.TemplateParam{
+			{Name: "module"},
+			{Name: "branch", Default: &defaultBranch},
+		},
+		Tasks: []model.TemplateTask{
+			{Key: "plan", Title: "Plan refactor of {{ module }}"},
+			{Key: "code", Title: "Refactor {{module}} on {{branch}}", DependsOn: []string{"plan"}},
+			{Key: "docs", Title: "Update docs", DependsOn: []string{"plan"}},
+			{Title: "Open PR", DependsOn: []string{"code", "docs"}},
+		},
+	}
+}
+
+func TestInstantiateTemplate(t *testing.T) {
+	chain, tasks, err := InstantiateTemplate(refactorTemplate(), map[string]string{"module": "store"})
+	if err != nil {
+		t.Fatalf("instantiate: %v", err)
+	}
+	if chain.Name != "Refactor store" {
+		t.Fatalf("unexpected chain name %q", chain.Name)
+	}
+	if got := tasks[0].Task.Title; got != "Plan refactor of store" {
+		t.Fatalf("unexpected title %q", got)
+	}
+	if got := tasks[1].Task.Title; got != "Refactor store on main" {
+		t.Fatalf("default not applied: %q", got)
+	}
+	if deps := tasks[3].DependsOn; len(deps) != 2 || deps[0] != 1 || deps[1] != 2 {
+		t.Fatalf("expected fan-in on tasks 1 and 2, got %v", deps)
+	}
+
+	if _, _, err := InstantiateTemplate(refactorTemplate(), nil); err == nil || !strings.HasPrefix(err.Error(), "param_required") {
+		t.Fatalf("expected param_required, got %v", err)
+	}
+	if _, _, err := InstantiateTemplate(refactorTemplate(), map[string]string{"module": "x", "typo": "y"}); err == nil || !strings.HasPrefix(err.Error(), "param_unknown") {
+		t.Fatalf("expected param_unknown, got %v", err)
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	if err := ValidateTemplate(refactorTemplate()); err != nil {
+		t.Fatalf("expected valid template, got %v", err)
+	}
+
+	undeclared := refactorTemplate()
+	undeclared.Tasks[2].Description = "see {{ticket}}"
+	if err := ValidateTemplate(undeclared); err == nil || !strings.HasPrefix(err.Error(), "param_undeclared") {
+		t.Fatalf("expected param_undeclared, got %v", err)
+	}
+
+	unknownKey := refactorTemplate()
+	unknownKey.Tasks[1].DependsOn = []string{"nope"}
+	if err := ValidateTemplate(unknownKey); err == nil || !strings.HasPrefix(err.Error(), "depends_on_unknown_key") {
+		t.Fatalf("expected depends_on_unknown_key, got %v", err)
+	}
+
+	cyclic := refactorTemplate()
+	cyclic.Tasks[0].DependsOn = []string{"code"}
+	if err := ValidateTemplate(cyclic); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("expected a cycle, got %v", err)
+	}
+}
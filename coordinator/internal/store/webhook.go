@@ -0,0 +1,59 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"clwclw-monitor/coordinator/internal/model"
+)
+
+// NormalizeWebhook checks a webhook before it is stored: an absolute http(s) URL, a secret
+// and at least one known topic (duplicates removed).
+func NormalizeWebhook(w model.Webhook) (model.Webhook, error) {
+	w.Name = strings.TrimSpace(w.Name)
+	w.URL = strings.TrimSpace(w.URL)
+	if w.Name == "" {
+		return w, errors.New("name_required")
+	}
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return w, fmt.Errorf("url_invalid: %s", w.URL)
+	}
+	if w.Secret == "" {
+		return w, errors.New("secret_required")
+	}
+
+	if len(w.Topics) == 0 {
+		return w, errors.New("topics_required")
+	}
+	seen := make(map[string]bool, len(w.Topics))
+	topics := make([]string, 0, len(w.Topics))
+	for _, t := range w.Topics {
+		t = strings.TrimSpace(t)
+		switch t {
+		case model.WebhookTopicTasks, model.WebhookTopicChains, model.WebhookTopicAgents,
+			model.WebhookTopicEvents, model.WebhookTopicNotifications:
+		default:
+			return w, fmt.Errorf("topic_invalid: %s", t)
+		}
+		if !seen[t] {
+			seen[t] = true
+			topics = append(topics, t)
+		}
+	}
+	w.Topics = topics
+	return w, nil
+}
+
+// WebhookAttemptStatus is the status of a delivery after the attempt req.
+func WebhookAttemptStatus(req RecordWebhookAttemptRequest) model.WebhookDeliveryStatus {
+	switch {
+	case req.Succeeded:
+		return model.WebhookDeliverySucceeded
+	case req.RetryAt != nil:
+		return model.WebhookDeliveryPending
+	default:
+		return model.WebhookDeliveryFailed
+	}
+}
@@ -0,0 +1,132 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newEchoServer(t *testing.T, limit int64) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r, []string{"https://dashboard.example.com"})
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		if limit > 0 {
+			c.SetReadLimit(limit)
+		}
+		for {
+			msg, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := c.WriteMessage(msg); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dial(t *testing.T, srv *httptest.Server) *Conn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return c
+}
+
+func TestEcho(t *testing.T) {
+	c := dial(t, newEchoServer(t, 0))
+
+	for _, size := range []int{0, 5, 125, 126, 70000} {
+		msg := bytes.Repeat([]byte("x"), size)
+		if err := c.WriteMessage(msg); err != nil {
+			t.Fatalf("write %d bytes: %v", size, err)
+		}
+		if err := c.Ping(); err != nil {
+			t.Fatalf("ping: %v", err)
+		}
+		got, err := c.ReadMessage()
+		if err != nil {
+			t.Fatalf("read %d bytes: %v", size, err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Fatalf("echo of %d bytes came back as %d bytes", size, len(got))
+		}
+	}
+}
+
+func TestReadLimitClosesConnection(t *testing.T) {
+	c := dial(t, newEchoServer(t, 16))
+
+	if err := c.WriteMessage(bytes.Repeat([]byte("x"), 64)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_, err := c.ReadMessage()
+	var ce *CloseError
+	if !errors.As(err, &ce) || ce.Code != CloseMessageTooBig {
+		t.Fatalf("expected close %d, got %v", CloseMessageTooBig, err)
+	}
+}
+
+func TestUpgradeRejectsPlainRequests(t *testing.T) {
+	srv := newEchoServer(t, 0)
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Fatalf("expected status %d, got %d", http.StatusUpgradeRequired, resp.StatusCode)
+	}
+}
+
+func TestUpgradeChecksOrigin(t *testing.T) {
+	srv := newEchoServer(t, 0)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	for _, tc := range []struct {
+		origin string
+		ok     bool
+	}{
+		{"", true},
+		{srv.URL, true},
+		{"https://dashboard.example.com", true},
+		{"https://evil.example.com", false},
+		{"null", false},
+	} {
+		header := http.Header{}
+		if tc.origin != "" {
+			header.Set("Origin", tc.origin)
+		}
+		c, resp, err := Dial(context.Background(), url, header)
+		if tc.ok {
+			if err != nil {
+				t.Fatalf("origin %q: expected the upgrade to succeed, got %v", tc.origin, err)
+			}
+			c.Close()
+			continue
+		}
+		if err == nil {
+			c.Close()
+			t.Fatalf("origin %q: expected the upgrade to be refused", tc.origin)
+		}
+		if resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("origin %q: expected status %d, got %v (%v)", tc.origin, http.StatusForbidden, resp, err)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+-- Lease-based task claims
+-- A claimed task carries a lease that the agent extends via heartbeat or
+-- POST /v1/tasks/{id}/renew. The coordinator's reaper requeues in_progress
+-- tasks whose lease has passed (null = no lease, never expires).
+
+alter table public.tasks
+add column if not exists lease_expires_at timestamptz null;
+
+comment on column public.tasks.lease_expires_at is
+'Claim lease deadline; the coordinator requeues in_progress tasks once it has passed (null = no lease)';
+
+create index if not exists idx_tasks_in_progress_lease
+on public.tasks (lease_expires_at)
+where status = 'in_progress' and lease_expires_at is not null;
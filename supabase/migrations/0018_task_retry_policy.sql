@@ -0,0 +1,94 @@
+-- Task retry policy + dead-letter state
+-- A failed attempt is requeued (with exponential backoff via next_eligible_at)
+-- until the retry budget is exhausted, then the task moves to 'dead_letter'.
+-- Budget/backoff come from the task, falling back to the channel (0 = inherit / no retries).
+
+alter table public.tasks
+add column if not exists max_attempts int not null default 0,
+add column if not exists retry_backoff_seconds int not null default 0,
+add column if not exists attempts int not null default 0,
+add column if not exists last_failure_reason text null,
+add column if not exists next_eligible_at timestamptz null;
+
+comment on column public.tasks.max_attempts is
+'Retry budget for this task (0 = use channel retry_max_attempts)';
+comment on column public.tasks.retry_backoff_seconds is
+'Base retry backoff in seconds, doubled per attempt (0 = use channel retry_backoff_seconds)';
+comment on column public.tasks.attempts is
+'Number of times the task was claimed/assigned';
+comment on column public.tasks.next_eligible_at is
+'Retry backoff: claim_task() skips the task until this time (null = eligible now)';
+
+alter table public.channels
+add column if not exists retry_max_attempts int not null default 0,
+add column if not exists retry_backoff_seconds int not null default 0;
+
+comment on column public.channels.retry_max_attempts is
+'Default retry budget for tasks in this channel (0 = failures are terminal)';
+
+create index if not exists idx_tasks_dead_letter
+on public.tasks (updated_at)
+where status = 'dead_letter';
+
+-- claim_task: same ownership and chain gate rules as before, plus the retry backoff gate.
+create or replace function public.claim_task(p_channel_id uuid, p_agent_id uuid)
+returns setof public.tasks
+language plpgsql
+as $$
+declare
+  v_task_id uuid;
+  v_chain_id uuid;
+  v_owned_chain_id uuid;
+begin
+  -- Chain the agent already owns (ownership persists until detach).
+  select id into v_owned_chain_id
+  from public.chains
+  where owner_agent_id = p_agent_id
+  limit 1;
+
+  select t.id, t.chain_id
+  into v_task_id, v_chain_id
+  from public.tasks t
+  join public.chains c on t.chain_id = c.id
+  where t.channel_id = p_channel_id
+    and t.status = 'queued'
+    and (t.next_eligible_at is null or t.next_eligible_at <= now())
+    and (
+      (v_owned_chain_id is not null and c.id = v_owned_chain_id and c.status <> 'locked')
+      or (v_owned_chain_id is null and c.owner_agent_id is null and c.status in ('queued', 'in_progress'))
+    )
+    and not exists (
+      select 1 from public.tasks lt
+      where lt.chain_id = t.chain_id and lt.status = 'locked'
+    )
+    and not exists (
+      select 1 from public.tasks pt
+      where pt.chain_id = t.chain_id
+        and pt.sequence < t.sequence
+        and pt.status in ('queued', 'in_progress')
+    )
+  order by c.created_at asc, t.sequence asc
+  for update of t skip locked
+  limit 1;
+
+  if v_task_id is null then
+    return;
+  end if;
+
+  update public.chains
+  set status = 'in_progress',
+      owner_agent_id = p_agent_id,
+      updated_at = now()
+  where id = v_chain_id
+    and status = 'queued';
+
+  return query
+  update public.tasks
+  set status = 'in_progress',
+      assigned_agent_id = p_agent_id,
+      claimed_at = now(),
+      updated_at = now()
+  where id = v_task_id
+  returning *;
+end;
+$$;
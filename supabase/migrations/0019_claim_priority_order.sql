@@ -0,0 +1,85 @@
+-- Priority-aware claim ordering
+-- claim_task() now orders claimable tasks exactly like the memory store
+-- (store.ClaimsBefore):
+--   1. effective priority desc = priority + floor(waited_seconds / p_aging_seconds)
+--      (aging disabled when p_aging_seconds <= 0)
+--   2. chain created_at asc
+--   3. sequence asc
+--   4. id asc
+-- Eligibility (ownership, locked chains, predecessor gate, retry backoff) is unchanged from 0018.
+
+drop function if exists public.claim_task(uuid, uuid);
+
+create or replace function public.claim_task(p_channel_id uuid, p_agent_id uuid, p_aging_seconds int default 0)
+returns setof public.tasks
+language plpgsql
+as $$
+declare
+  v_task_id uuid;
+  v_chain_id uuid;
+  v_owned_chain_id uuid;
+begin
+  -- Chain the agent already owns (ownership persists until detach).
+  select id into v_owned_chain_id
+  from public.chains
+  where owner_agent_id = p_agent_id
+  limit 1;
+
+  select t.id, t.chain_id
+  into v_task_id, v_chain_id
+  from public.tasks t
+  join public.chains c on t.chain_id = c.id
+  where t.channel_id = p_channel_id
+    and t.status = 'queued'
+    and (t.next_eligible_at is null or t.next_eligible_at <= now())
+    and (
+      (v_owned_chain_id is not null and c.id = v_owned_chain_id and c.status <> 'locked')
+      or (v_owned_chain_id is null and c.owner_agent_id is null and c.status in ('queued', 'in_progress'))
+    )
+    and not exists (
+      select 1 from public.tasks lt
+      where lt.chain_id = t.chain_id and lt.status = 'locked'
+    )
+    and not exists (
+      select 1 from public.tasks pt
+      where pt.chain_id = t.chain_id
+        and pt.sequence < t.sequence
+        and pt.status in ('queued', 'in_progress')
+    )
+  order by
+    t.priority + case
+      when p_aging_seconds > 0
+        then greatest(0, floor(extract(epoch from (now() - t.created_at)) / p_aging_seconds))::int
+      else 0
+    end desc,
+    c.created_at asc,
+    t.sequence asc,
+    t.id asc
+  for update of t skip locked
+  limit 1;
+
+  if v_task_id is null then
+    return;
+  end if;
+
+  update public.chains
+  set status = 'in_progress',
+      owner_agent_id = p_agent_id,
+      updated_at = now()
+  where id = v_chain_id
+    and status = 'queued';
+
+  return query
+  update public.tasks
+  set status = 'in_progress',
+      assigned_agent_id = p_agent_id,
+      claimed_at = now(),
+      updated_at = now()
+  where id = v_task_id
+  returning *;
+end;
+$$;
+
+create index if not exists idx_tasks_channel_queued_priority
+on public.tasks (channel_id, priority desc)
+where status = 'queued';
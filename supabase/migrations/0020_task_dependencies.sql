@@ -0,0 +1,93 @@
+-- Task dependencies (DAG)
+-- A task may list depends_on task IDs from its own chain. Such a task is claimable
+-- once none of its dependencies is queued or in_progress; tasks without depends_on
+-- keep the linear sequence gate. Cycles are rejected by the coordinator on create.
+-- Ordering and the other eligibility rules are unchanged from 0019.
+
+alter table public.tasks
+add column if not exists depends_on uuid[] not null default '{}';
+
+comment on column public.tasks.depends_on is
+'Task IDs (same chain) that must finish before this task can be claimed (empty = sequence order)';
+
+create index if not exists idx_tasks_depends_on
+on public.tasks using gin (depends_on);
+
+create or replace function public.claim_task(p_channel_id uuid, p_agent_id uuid, p_aging_seconds int default 0)
+returns setof public.tasks
+language plpgsql
+as $$
+declare
+  v_task_id uuid;
+  v_chain_id uuid;
+  v_owned_chain_id uuid;
+begin
+  -- Chain the agent already owns (ownership persists until detach).
+  select id into v_owned_chain_id
+  from public.chains
+  where owner_agent_id = p_agent_id
+  limit 1;
+
+  select t.id, t.chain_id
+  into v_task_id, v_chain_id
+  from public.tasks t
+  join public.chains c on t.chain_id = c.id
+  where t.channel_id = p_channel_id
+    and t.status = 'queued'
+    and (t.next_eligible_at is null or t.next_eligible_at <= now())
+    and (
+      (v_owned_chain_id is not null and c.id = v_owned_chain_id and c.status <> 'locked')
+      or (v_owned_chain_id is null and c.owner_agent_id is null and c.status in ('queued', 'in_progress'))
+    )
+    and not exists (
+      select 1 from public.tasks lt
+      where lt.chain_id = t.chain_id and lt.status = 'locked'
+    )
+    and (
+      -- DAG: only the declared dependencies gate the task
+      (cardinality(t.depends_on) > 0 and not exists (
+        select 1 from public.tasks dt
+        where dt.id = any(t.depends_on)
+          and dt.status in ('queued', 'in_progress')
+      ))
+      -- Linear: every lower sequence in the chain gates the task
+      or (cardinality(t.depends_on) = 0 and not exists (
+        select 1 from public.tasks pt
+        where pt.chain_id = t.chain_id
+          and pt.sequence < t.sequence
+          and pt.status in ('queued', 'in_progress')
+      ))
+    )
+  order by
+    t.priority + case
+      when p_aging_seconds > 0
+        then greatest(0, floor(extract(epoch from (now() - t.created_at)) / p_aging_seconds))::int
+      else 0
+    end desc,
+    c.created_at asc,
+    t.sequence asc,
+    t.id asc
+  for update of t skip locked
+  limit 1;
+
+  if v_task_id is null then
+    return;
+  end if;
+
+  update public.chains
+  set status = 'in_progress',
+      owner_agent_id = p_agent_id,
+      updated_at = now()
+  where id = v_chain_id
+    and status = 'queued';
+
+  return query
+  update public.tasks
+  set status = 'in_progress',
+      assigned_agent_id = p_agent_id,
+      claimed_at = now(),
+      updated_at = now()
+  where id = v_task_id
+  returning *;
+end;
+$$;
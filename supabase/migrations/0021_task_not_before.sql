@@ -0,0 +1,143 @@
+-- Scheduled / delayed tasks
+-- tasks.not_before holds a task back until the given time. claim_task() skips it
+-- like a task in retry backoff; ordering and the other rules are unchanged from 0020.
+-- next_claimable_at() returns the earliest time a task that is eligible apart from
+-- not_before / next_eligible_at becomes claimable, used as the "no_tasks" sleep hint.
+
+alter table public.tasks
+add column if not exists not_before timestamptz null;
+
+comment on column public.tasks.not_before is
+'Scheduled start: claim_task() skips the task until this time (null = claimable now)';
+
+create index if not exists idx_tasks_queued_not_before
+on public.tasks (channel_id, not_before)
+where status = 'queued' and not_before is not null;
+
+create or replace function public.claim_task(p_channel_id uuid, p_agent_id uuid, p_aging_seconds int default 0)
+returns setof public.tasks
+language plpgsql
+as $$
+declare
+  v_task_id uuid;
+  v_chain_id uuid;
+  v_owned_chain_id uuid;
+begin
+  -- Chain the agent already owns (ownership persists until detach).
+  select id into v_owned_chain_id
+  from public.chains
+  where owner_agent_id = p_agent_id
+  limit 1;
+
+  select t.id, t.chain_id
+  into v_task_id, v_chain_id
+  from public.tasks t
+  join public.chains c on t.chain_id = c.id
+  where t.channel_id = p_channel_id
+    and t.status = 'queued'
+    and (t.next_eligible_at is null or t.next_eligible_at <= now())
+    and (t.not_before is null or t.not_before <= now())
+    and (
+      (v_owned_chain_id is not null and c.id = v_owned_chain_id and c.status <> 'locked')
+      or (v_owned_chain_id is null and c.owner_agent_id is null and c.status in ('queued', 'in_progress'))
+    )
+    and not exists (
+      select 1 from public.tasks lt
+      where lt.chain_id = t.chain_id and lt.status = 'locked'
+    )
+    and (
+      -- DAG: only the declared dependencies gate the task
+      (cardinality(t.depends_on) > 0 and not exists (
+        select 1 from public.tasks dt
+        where dt.id = any(t.depends_on)
+          and dt.status in ('queued', 'in_progress')
+      ))
+      -- Linear: every lower sequence in the chain gates the task
+      or (cardinality(t.depends_on) = 0 and not exists (
+        select 1 from public.tasks pt
+        where pt.chain_id = t.chain_id
+          and pt.sequence < t.sequence
+          and pt.status in ('queued', 'in_progress')
+      ))
+    )
+  order by
+    t.priority + case
+      when p_aging_seconds > 0
+        then greatest(0, floor(extract(epoch from (now() - t.created_at)) / p_aging_seconds))::int
+      else 0
+    end desc,
+    c.created_at asc,
+    t.sequence asc,
+    t.id asc
+  for update of t skip locked
+  limit 1;
+
+  if v_task_id is null then
+    return;
+  end if;
+
+  update public.chains
+  set status = 'in_progress',
+      owner_agent_id = p_agent_id,
+      updated_at = now()
+  where id = v_chain_id
+    and status = 'queued';
+
+  return query
+  update public.tasks
+  set status = 'in_progress',
+      assigned_agent_id = p_agent_id,
+      claimed_at = now(),
+      updated_at = now()
+  where id = v_task_id
+  returning *;
+end;
+$$;
+
+-- Same eligibility as claim_task() minus the time gates; stable so callers may poll it freely.
+create or replace function public.next_claimable_at(p_channel_id uuid, p_agent_id uuid)
+returns timestamptz
+language plpgsql
+stable
+as $$
+declare
+  v_owned_chain_id uuid;
+  v_at timestamptz;
+begin
+  select id into v_owned_chain_id
+  from public.chains
+  where owner_agent_id = p_agent_id
+  limit 1;
+
+  select min(greatest(coalesce(t.not_before, '-infinity'), coalesce(t.next_eligible_at, '-infinity')))
+  into v_at
+  from public.tasks t
+  join public.chains c on t.chain_id = c.id
+  where t.channel_id = p_channel_id
+    and t.status = 'queued'
+    and greatest(coalesce(t.not_before, '-infinity'), coalesce(t.next_eligible_at, '-infinity')) > now()
+    and (
+      (v_owned_chain_id is not null and c.id = v_owned_chain_id and c.status <> 'locked')
+      or (v_owned_chain_id is null and c.owner_agent_id is null and c.status in ('queued', 'in_progress'))
+    )
+    and not exists (
+      select 1 from public.tasks lt
+      where lt.chain_id = t.chain_id and lt.status = 'locked'
+    )
+    and (
+      (cardinality(t.depends_on) > 0 and not exists (
+        select 1 from public.tasks dt
+        where dt.id = any(t.depends_on)
+          and dt.status in ('queued', 'in_progress')
+      ))
+      or (cardinality(t.depends_on) = 0 and not exists (
+        select 1 from public.tasks pt
+        where pt.chain_id = t.chain_id
+          and pt.sequence < t.sequence
+          and pt.status in ('queued', 'in_progress')
+      ))
+    );
+
+  return v_at;
+end;
+$$;
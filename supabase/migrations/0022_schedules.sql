@@ -0,0 +1,47 @@
+-- Recurring chain schedules
+-- A schedule materializes a new chain (plus its templated tasks) in its channel every time
+-- its cron expression fires. The coordinator's in-process scheduler evaluates the cron
+-- expression; the database only stores next_run_at and the run history.
+
+create table if not exists public.schedules (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid null references public.users(id) on delete cascade,
+  channel_id uuid not null references public.channels(id) on delete cascade,
+  name text not null,
+  description text null,
+  cron text not null,
+  timezone text not null default 'UTC',
+  tasks jsonb not null default '[]'::jsonb,
+  enabled boolean not null default true,
+  last_run_at timestamptz null,
+  last_chain_id uuid null references public.chains(id) on delete set null,
+  next_run_at timestamptz null,
+  created_at timestamptz not null default now(),
+  updated_at timestamptz not null default now()
+);
+
+comment on column public.schedules.tasks is
+'Task templates (title, description, priority, execution_mode, retry policy) created in order on each run';
+comment on column public.schedules.last_chain_id is
+'Chain created by the latest run; a run is skipped while this chain is still active';
+
+create index if not exists idx_schedules_user_id on public.schedules (user_id);
+create index if not exists idx_schedules_next_run on public.schedules (next_run_at)
+where enabled;
+
+create trigger trg_schedules_updated_at
+before update on public.schedules
+for each row execute function set_updated_at();
+
+create table if not exists public.schedule_runs (
+  id uuid primary key default gen_random_uuid(),
+  schedule_id uuid not null references public.schedules(id) on delete cascade,
+  status text not null check (status in ('created', 'skipped', 'failed')),
+  chain_id uuid null references public.chains(id) on delete set null,
+  reason text null,
+  scheduled_for timestamptz not null,
+  created_at timestamptz not null default now()
+);
+
+create index if not exists idx_schedule_runs_schedule_created
+on public.schedule_runs (schedule_id, created_at desc);
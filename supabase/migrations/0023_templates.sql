@@ -0,0 +1,28 @@
+-- Chain/task templates
+-- A template describes a chain as an ordered (or depends_on DAG) list of tasks with
+-- {{param}} placeholders. POST /v1/templates/{id}/instantiate renders it and creates the
+-- chain and all tasks in one transaction (store.CreateChainWithTasks).
+
+create table if not exists public.templates (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid null references public.users(id) on delete cascade,
+  name text not null,
+  description text null,
+  chain_name text null,
+  chain_description text null,
+  params jsonb not null default '[]'::jsonb,
+  tasks jsonb not null default '[]'::jsonb,
+  created_at timestamptz not null default now(),
+  updated_at timestamptz not null default now()
+);
+
+comment on column public.templates.params is
+'Declared {{param}} placeholders: [{name, description, default}] (no default = required)';
+comment on column public.templates.tasks is
+'Task templates [{key, title, description, priority, execution_mode, max_attempts, retry_backoff_seconds, depends_on}]';
+
+create index if not exists idx_templates_user_id on public.templates (user_id);
+
+create trigger trg_templates_updated_at
+before update on public.templates
+for each row execute function set_updated_at();
@@ -0,0 +1,14 @@
+-- Task cancellation
+-- A cancelled task is terminal and, like done/failed, no longer holds back its dependents
+-- (claim_task only waits on queued/in_progress tasks, so no function change is needed).
+-- A chain whose tasks are all cancelled becomes 'cancelled'.
+
+alter table public.tasks
+add column if not exists cancelled_at timestamptz null,
+add column if not exists cancelled_by text null,
+add column if not exists cancel_reason text null;
+
+comment on column public.tasks.cancelled_by is
+'Who cancelled the task (user ID, or the caller-supplied name for shared-token access)';
+comment on column public.tasks.cancel_reason is
+'Free-form reason given on cancel';
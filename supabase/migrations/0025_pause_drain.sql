@@ -0,0 +1,158 @@
+-- Pause / drain for chains and channels
+-- chains.pause and channels.pause hold 'paused' or 'draining' (null = running). Both modes
+-- make claim_task() hand out nothing from the chain or channel; they differ only in what
+-- happens to in-flight tasks ('paused' requeues them, which the API does via RequeueTask).
+-- next_claimable_at() ignores paused work so the "no_tasks" hint does not wake agents
+-- for it. Otherwise both functions are unchanged from 0021.
+
+alter table public.chains
+add column if not exists pause text null check (pause in ('paused', 'draining')),
+add column if not exists paused_at timestamptz null;
+
+alter table public.channels
+add column if not exists pause text null check (pause in ('paused', 'draining')),
+add column if not exists paused_at timestamptz null;
+
+comment on column public.chains.pause is
+'paused | draining: claim_task() skips the chain (null = claims run normally)';
+comment on column public.channels.pause is
+'paused | draining: claim_task() skips every task of the channel (null = claims run normally)';
+
+create or replace function public.claim_task(p_channel_id uuid, p_agent_id uuid, p_aging_seconds int default 0)
+returns setof public.tasks
+language plpgsql
+as $$
+declare
+  v_task_id uuid;
+  v_chain_id uuid;
+  v_owned_chain_id uuid;
+begin
+  -- A paused or draining channel hands out nothing.
+  if exists (select 1 from public.channels where id = p_channel_id and pause is not null) then
+    return;
+  end if;
+
+  -- Chain the agent already owns (ownership persists until detach).
+  select id into v_owned_chain_id
+  from public.chains
+  where owner_agent_id = p_agent_id
+  limit 1;
+
+  select t.id, t.chain_id
+  into v_task_id, v_chain_id
+  from public.tasks t
+  join public.chains c on t.chain_id = c.id
+  where t.channel_id = p_channel_id
+    and t.status = 'queued'
+    and (t.next_eligible_at is null or t.next_eligible_at <= now())
+    and (t.not_before is null or t.not_before <= now())
+    and c.pause is null
+    and (
+      (v_owned_chain_id is not null and c.id = v_owned_chain_id and c.status <> 'locked')
+      or (v_owned_chain_id is null and c.owner_agent_id is null and c.status in ('queued', 'in_progress'))
+    )
+    and not exists (
+      select 1 from public.tasks lt
+      where lt.chain_id = t.chain_id and lt.status = 'locked'
+    )
+    and (
+      -- DAG: only the declared dependencies gate the task
+      (cardinality(t.depends_on) > 0 and not exists (
+        select 1 from public.tasks dt
+        where dt.id = any(t.depends_on)
+          and dt.status in ('queued', 'in_progress')
+      ))
+      -- Linear: every lower sequence in the chain gates the task
+      or (cardinality(t.depends_on) = 0 and not exists (
+        select 1 from public.tasks pt
+        where pt.chain_id = t.chain_id
+          and pt.sequence < t.sequence
+          and pt.status in ('queued', 'in_progress')
+      ))
+    )
+  order by
+    t.priority + case
+      when p_aging_seconds > 0
+        then greatest(0, floor(extract(epoch from (now() - t.created_at)) / p_aging_seconds))::int
+      else 0
+    end desc,
+    c.created_at asc,
+    t.sequence asc,
+    t.id asc
+  for update of t skip locked
+  limit 1;
+
+  if v_task_id is null then
+    return;
+  end if;
+
+  update public.chains
+  set status = 'in_progress',
+      owner_agent_id = p_agent_id,
+      updated_at = now()
+  where id = v_chain_id
+    and status = 'queued';
+
+  return query
+  update public.tasks
+  set status = 'in_progress',
+      assigned_agent_id = p_agent_id,
+      claimed_at = now(),
+      updated_at = now()
+  where id = v_task_id
+  returning *;
+end;
+$$;
+
+-- Same eligibility as claim_task() minus the time gates; stable so callers may poll it freely.
+create or replace function public.next_claimable_at(p_channel_id uuid, p_agent_id uuid)
+returns timestamptz
+language plpgsql
+stable
+as $$
+declare
+  v_owned_chain_id uuid;
+  v_at timestamptz;
+begin
+  if exists (select 1 from public.channels where id = p_channel_id and pause is not null) then
+    return null;
+  end if;
+
+  select id into v_owned_chain_id
+  from public.chains
+  where owner_agent_id = p_agent_id
+  limit 1;
+
+  select min(greatest(coalesce(t.not_before, '-infinity'), coalesce(t.next_eligible_at, '-infinity')))
+  into v_at
+  from public.tasks t
+  join public.chains c on t.chain_id = c.id
+  where t.channel_id = p_channel_id
+    and t.status = 'queued'
+    and greatest(coalesce(t.not_before, '-infinity'), coalesce(t.next_eligible_at, '-infinity')) > now()
+    and c.pause is null
+    and (
+      (v_owned_chain_id is not null and c.id = v_owned_chain_id and c.status <> 'locked')
+      or (v_owned_chain_id is null and c.owner_agent_id is null and c.status in ('queued', 'in_progress'))
+    )
+    and not exists (
+      select 1 from public.tasks lt
+      where lt.chain_id = t.chain_id and lt.status = 'locked'
+    )
+    and (
+      (cardinality(t.depends_on) > 0 and not exists (
+        select 1 from public.tasks dt
+        where dt.id = any(t.depends_on)
+          and dt.status in ('queued', 'in_progress')
+      ))
+      or (cardinality(t.depends_on) = 0 and not exists (
+        select 1 from public.tasks pt
+        where pt.chain_id = t.chain_id
+          and pt.sequence < t.sequence
+          and pt.status in ('queued', 'in_progress')
+      ))
+    );
+
+  return v_at;
+end;
+$$;
@@ -0,0 +1,23 @@
+-- Task results and artifacts
+-- One row per task holding the structured outcome an agent reported on complete/fail
+-- (summary, diff stats, PR URL, files touched) and named artifacts. A later attempt
+-- replaces the row. GET /v1/tasks/{id}/result returns it together with the results of
+-- the tasks it waits on, so later tasks in a chain can build on them.
+
+create table if not exists public.task_results (
+  task_id uuid primary key references public.tasks(id) on delete cascade,
+  chain_id uuid null references public.chains(id) on delete cascade,
+  agent_id uuid null references public.agents(id) on delete set null,
+  status text not null,
+  attempt int not null default 0,
+  result jsonb not null default '{}'::jsonb,
+  artifacts jsonb not null default '[]'::jsonb,
+  created_at timestamptz not null default now()
+);
+
+comment on column public.task_results.status is
+'Task status the result was recorded with (done, or queued/failed/dead_letter after a failure)';
+comment on column public.task_results.artifacts is
+'Named artifacts [{name, content_type, content, url}] (inline content or a URL)';
+
+create index if not exists idx_task_results_chain_id on public.task_results (chain_id);
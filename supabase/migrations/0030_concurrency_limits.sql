@@ -0,0 +1,19 @@
+-- Concurrency limits on in-progress tasks
+-- channels.max_in_flight caps how many tasks of the channel run at once (0 = no limit).
+-- The per-user limit is coordinator config (COORDINATOR_USER_MAX_IN_FLIGHT) and counts the
+-- in-progress tasks of the channel owner. ClaimTask takes advisory locks on the channel and
+-- user before claiming, so the count and the claim happen atomically.
+
+alter table public.channels
+add column if not exists max_in_flight int not null default 0 check (max_in_flight >= 0);
+
+comment on column public.channels.max_in_flight is
+'Most tasks of this channel in progress at once; 0 = no limit';
+
+create index if not exists idx_tasks_in_progress_channel
+on public.tasks (channel_id)
+where status = 'in_progress';
+
+create index if not exists idx_tasks_in_progress_user
+on public.tasks (user_id)
+where status = 'in_progress';
@@ -0,0 +1,53 @@
+-- Durable notifications
+-- Notifications used to live in coordinator process memory, so they vanished on restart and
+-- differed between replicas. A notification belongs to a user and may link to the agent,
+-- task or chain it is about. key deduplicates: a user has at most one active (unresolved)
+-- notification per key, e.g. '<agent_id>:setup_waiting'. pushed_at is when the coordinator
+-- last pushed it over the event stream; it replaces the in-memory resend cooldown.
+
+create table if not exists public.notifications (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid null references public.users(id) on delete cascade,
+  key text not null,
+  type text not null,
+  severity text not null default 'info' check (severity in ('info', 'warning', 'critical')),
+  message text not null default '',
+  agent_id uuid null,
+  agent_name text null,
+  task_id uuid null,
+  chain_id uuid null,
+  channel text null,
+  extra jsonb not null default '{}'::jsonb,
+  read_at timestamptz null,
+  resolved_at timestamptz null,
+  pushed_at timestamptz null,
+  created_at timestamptz not null default now(),
+  updated_at timestamptz not null default now()
+);
+
+comment on column public.notifications.key is
+'Deduplication key; at most one unresolved notification per user and key';
+comment on column public.notifications.resolved_at is
+'Set when the condition went away (agent back online, gate reviewed) or the user dismissed it';
+comment on column public.notifications.pushed_at is
+'Last push over the event stream; a still active notification is pushed again only after a cooldown';
+
+create unique index if not exists idx_notifications_active_key
+on public.notifications (coalesce(user_id, '00000000-0000-0000-0000-000000000000'::uuid), key)
+where resolved_at is null;
+
+-- Resolving by key (agent back online, gate reviewed) looks across users.
+create index if not exists idx_notifications_active_key_any_user
+on public.notifications (key)
+where resolved_at is null;
+
+create index if not exists idx_notifications_user_created
+on public.notifications (user_id, created_at desc, id desc);
+
+create index if not exists idx_notifications_user_unread
+on public.notifications (user_id)
+where read_at is null;
+
+create trigger trg_notifications_updated_at
+before update on public.notifications
+for each row execute function set_updated_at();
@@ -0,0 +1,45 @@
+-- Notification rules
+-- Users define when they want to be notified: a task or chain reaching a status, an agent
+-- offline or in a claude_status for a while, or an agent event whose type matches a glob.
+-- The coordinator evaluates the rules; it raises the resulting notifications in
+-- public.notifications keyed by '<rule_id>:<subject_id>'. cooldown_seconds limits how often a
+-- rule delivers, tracked in last_fired_at so every replica shares it.
+
+alter table public.agents
+add column if not exists claude_status_since timestamptz not null default now();
+
+comment on column public.agents.claude_status_since is
+'When claude_status last changed; used by claude_status notification rules';
+
+create table if not exists public.notification_rules (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid null references public.users(id) on delete cascade,
+  name text not null,
+  trigger text not null check (trigger in ('task_status', 'chain_status', 'agent_offline', 'claude_status', 'event_type')),
+  channel_id uuid null references public.channels(id) on delete cascade,
+  status text null,
+  event_pattern text null,
+  for_seconds int not null default 0 check (for_seconds >= 0),
+  severity text not null default 'info' check (severity in ('info', 'warning', 'critical')),
+  targets jsonb not null default '["dashboard"]'::jsonb,
+  cooldown_seconds int not null default 0 check (cooldown_seconds >= 0),
+  enabled boolean not null default true,
+  last_fired_at timestamptz null,
+  created_at timestamptz not null default now(),
+  updated_at timestamptz not null default now()
+);
+
+comment on column public.notification_rules.status is
+'Task/chain status for task_status/chain_status, claude_status for claude_status rules';
+comment on column public.notification_rules.event_pattern is
+'Glob (path.Match syntax) matched against agent event types for event_type rules';
+comment on column public.notification_rules.for_seconds is
+'How long an agent must stay offline (agent_offline) or in status (claude_status)';
+comment on column public.notification_rules.targets is
+'Delivery targets, e.g. ["dashboard"]';
+
+create index if not exists idx_notification_rules_user_id on public.notification_rules (user_id);
+
+create trigger trg_notification_rules_updated_at
+before update on public.notification_rules
+for each row execute function set_updated_at();
@@ -0,0 +1,51 @@
+-- Outgoing webhooks
+-- A webhook subscribes its user's task, chain, agent, event or notification changes (the
+-- /v1/stream topics). Each change becomes a row in public.webhook_deliveries; the coordinator
+-- posts it signed with HMAC-SHA256 of the secret and retries with exponential backoff.
+-- Delivery rows double as the delivery log and can be replayed.
+
+create table if not exists public.webhooks (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid null references public.users(id) on delete cascade,
+  name text not null,
+  url text not null,
+  secret text not null,
+  topics text[] not null,
+  enabled boolean not null default true,
+  created_at timestamptz not null default now(),
+  updated_at timestamptz not null default now()
+);
+
+create index if not exists idx_webhooks_user_id on public.webhooks (user_id);
+
+create trigger trg_webhooks_updated_at
+before update on public.webhooks
+for each row execute function set_updated_at();
+
+create table if not exists public.webhook_deliveries (
+  id uuid primary key default gen_random_uuid(),
+  webhook_id uuid not null references public.webhooks(id) on delete cascade,
+  topic text not null,
+  payload jsonb not null default '{}'::jsonb,
+  status text not null default 'pending' check (status in ('pending', 'succeeded', 'failed')),
+  attempts int not null default 0,
+  next_attempt_at timestamptz null,
+  response_code int null,
+  response_body text null,
+  error text null,
+  replay_of uuid null references public.webhook_deliveries(id) on delete set null,
+  delivered_at timestamptz null,
+  created_at timestamptz not null default now(),
+  updated_at timestamptz not null default now()
+);
+
+comment on column public.webhook_deliveries.next_attempt_at is
+'When a pending delivery is due; a worker claiming it pushes this forward by a lease';
+
+create index if not exists idx_webhook_deliveries_webhook on public.webhook_deliveries (webhook_id, created_at desc);
+create index if not exists idx_webhook_deliveries_due on public.webhook_deliveries (next_attempt_at)
+where status = 'pending';
+
+create trigger trg_webhook_deliveries_updated_at
+before update on public.webhook_deliveries
+for each row execute function set_updated_at();
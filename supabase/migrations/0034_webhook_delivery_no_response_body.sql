@@ -0,0 +1,7 @@
+-- Webhook delivery log without response bodies
+-- Webhook URLs are chosen by users, so the log no longer keeps what the receiver answered:
+-- it could expose an internal service a webhook was pointed at. The status code and
+-- transport error are still recorded.
+
+alter table public.webhook_deliveries
+drop column if exists response_body;
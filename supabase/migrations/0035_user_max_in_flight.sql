@@ -0,0 +1,10 @@
+-- Per-user in-flight limits
+-- users.max_in_flight overrides COORDINATOR_USER_MAX_IN_FLIGHT for the channels the user
+-- owns (0 = no limit); null keeps the coordinator default. It is set by the operator with
+-- PUT /v1/users/{id}/max-in-flight and read by ClaimTask together with the channel limit.
+
+alter table public.users
+add column if not exists max_in_flight int null check (max_in_flight >= 0);
+
+comment on column public.users.max_in_flight is
+'Most tasks of this user''s channels in progress at once; 0 = no limit, null = coordinator default';
@@ -0,0 +1,6 @@
+-- Webhook delivery retention
+-- The leader deletes succeeded and failed deliveries last updated more than
+-- COORDINATOR_WEBHOOK_RETENTION_DAYS ago; this index keeps that scan off the pending rows.
+
+create index if not exists idx_webhook_deliveries_finished on public.webhook_deliveries (updated_at)
+where status <> 'pending';